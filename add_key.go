@@ -0,0 +1,134 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jacobsa/comeback/internal/wiring"
+)
+
+var cmdAddKey = &Command{
+	Name: "add_key",
+}
+
+var fAddKeyLabel = cmdAddKey.Flags.String(
+	"label",
+	"",
+	"A unique name for the new key slot, used to revoke it later.")
+
+var fAddKeyRecovery = cmdAddKey.Flags.Bool(
+	"recovery",
+	false,
+	"If set, generate and print a random recovery key rather than prompting "+
+		"for a password.")
+
+var fAddKeyWriteOnly = cmdAddKey.Flags.Bool(
+	"write_only",
+	false,
+	"If set, the new password can be used to save backups but not to read "+
+		"them. Requires a bucket set up with envelope_encryption.")
+
+var fAddKeyNewPassword = registerNewPasswordFlags(&cmdAddKey.Flags)
+
+func init() {
+	cmdAddKey.Run = runAddKey // Break flag-related dependency loop.
+}
+
+func runAddKey(ctx context.Context, args []string) (err error) {
+	if len(args) != 0 {
+		err = fmt.Errorf(
+			"Usage: %s add_key --label=name [--recovery | --write_only] "+
+				"[--new_password_file=path | --new_password_fd=n]",
+			os.Args[0])
+		return
+	}
+
+	if *fAddKeyLabel == "" {
+		err = fmt.Errorf("You must set --label.")
+		return
+	}
+
+	if *fAddKeyRecovery && *fAddKeyWriteOnly {
+		err = fmt.Errorf("--recovery and --write_only are mutually exclusive.")
+		return
+	}
+
+	if *fAddKeyRecovery && fAddKeyNewPassword.set() {
+		err = fmt.Errorf("--recovery doesn't take a new password.")
+		return
+	}
+
+	bucket := getBucket(ctx)
+	password := getPassword()
+
+	// Special case: recovery keys.
+	if *fAddKeyRecovery {
+		var recoveryKey string
+		recoveryKey, err = wiring.AddRecoveryKey(
+			ctx,
+			bucket,
+			password,
+			*fAddKeyLabel)
+
+		if err != nil {
+			err = fmt.Errorf("AddRecoveryKey: %v", err)
+			return
+		}
+
+		fmt.Printf("Added recovery key slot %q. The recovery key is:\n", *fAddKeyLabel)
+		fmt.Println()
+		fmt.Printf("    %s\n", recoveryKey)
+		fmt.Println()
+		fmt.Println("It is not stored anywhere else and won't be shown again.")
+		fmt.Println("Enter it in place of the crypto password to use it.")
+		return
+	}
+
+	newPassword, err := readNewPassword(fAddKeyNewPassword)
+	if err != nil {
+		return
+	}
+
+	// Special case: write-only slots.
+	if *fAddKeyWriteOnly {
+		err = wiring.AddWriteOnlyKeySlot(
+			ctx,
+			bucket,
+			password,
+			*fAddKeyLabel,
+			newPassword)
+
+		if err != nil {
+			err = fmt.Errorf("AddWriteOnlyKeySlot: %v", err)
+			return
+		}
+
+		fmt.Printf("Added write-only key slot %q.\n", *fAddKeyLabel)
+		return
+	}
+
+	err = wiring.AddKeySlot(ctx, bucket, password, *fAddKeyLabel, newPassword)
+	if err != nil {
+		err = fmt.Errorf("AddKeySlot: %v", err)
+		return
+	}
+
+	fmt.Printf("Added key slot %q.\n", *fAddKeyLabel)
+	return
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jacobsa/comeback/internal/browse"
+)
+
+var cmdCat = &Command{
+	Name: "cat",
+	Run:  runCat,
+}
+
+// Exit statuses for cat, so that scripts can tell a missing file apart from
+// trouble reading the backup.
+const (
+	catExitNotFound = 2
+)
+
+func runCat(ctx context.Context, args []string) (err error) {
+	// Extract and parse arguments.
+	if len(args) != 1 {
+		err = fmt.Errorf("Usage: %s cat snapshot:path", os.Args[0])
+		return
+	}
+
+	score, relPath, err := resolveSnapshotPath(ctx, args[0])
+	if err != nil {
+		err = fmt.Errorf("resolveSnapshotPath(%q): %v", args[0], err)
+		return
+	}
+
+	// Find the file.
+	blobStore := getBlobStore(ctx)
+	fi, err := browse.LookUp(ctx, blobStore, score, relPath)
+	if _, ok := err.(*browse.NotFoundError); ok {
+		err = &exitError{code: catExitNotFound, err: err}
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("LookUp: %v", err)
+		return
+	}
+
+	// Stream it out.
+	err = browse.WriteContents(ctx, blobStore, fi, os.Stdout)
+	if err != nil {
+		err = fmt.Errorf("WriteContents: %v", err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,190 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jacobsa/comeback/internal/diff"
+)
+
+var cmdDiff = &Command{
+	Name: "diff",
+}
+
+var fDiffJSON = cmdDiff.Flags.Bool(
+	"json",
+	false,
+	"Print the changes and totals as a JSON document.")
+
+func init() {
+	cmdDiff.Run = runDiff // Break flag-related dependency loop.
+}
+
+// The JSON representation of a change.
+type jsonChange struct {
+	Path     string     `json:"path"`
+	Kind     string     `json:"kind"`
+	Content  bool       `json:"content,omitempty"`
+	Metadata []string   `json:"metadata,omitempty"`
+	Old      *jsonEntry `json:"old,omitempty"`
+	New      *jsonEntry `json:"new,omitempty"`
+}
+
+func makeJSONChange(c *diff.Change) (jc jsonChange) {
+	jc = jsonChange{
+		Path:     c.Path,
+		Kind:     c.Kind.String(),
+		Content:  c.Content,
+		Metadata: c.Metadata,
+	}
+
+	if c.Old != nil {
+		e := makeJSONEntry(c.Path, c.Old)
+		jc.Old = &e
+	}
+
+	if c.New != nil {
+		e := makeJSONEntry(c.Path, c.New)
+		jc.New = &e
+	}
+
+	return
+}
+
+// Format a change as a line of text, with a leading letter giving its kind.
+func formatChange(c *diff.Change) string {
+	switch c.Kind {
+	case diff.Added:
+		return fmt.Sprintf("A  %s", c.Path)
+
+	case diff.Removed:
+		return fmt.Sprintf("D  %s", c.Path)
+
+	case diff.TypeChanged:
+		return fmt.Sprintf(
+			"T  %s (%s -> %s)",
+			c.Path,
+			typeNames[c.Old.Type],
+			typeNames[c.New.Type])
+
+	default:
+		var what []string
+		if c.Content {
+			what = append(what, "content")
+		}
+
+		what = append(what, c.Metadata...)
+		return fmt.Sprintf("M  %s (%s)", c.Path, strings.Join(what, ", "))
+	}
+}
+
+func runDiff(ctx context.Context, args []string) (err error) {
+	// Extract and parse arguments.
+	if len(args) < 2 || len(args) > 3 {
+		err = fmt.Errorf(
+			"Usage: %s diff [--json] old_snapshot new_snapshot [path]",
+			os.Args[0])
+		return
+	}
+
+	oldScore, err := resolveSnapshot(ctx, args[0])
+	if err != nil {
+		err = fmt.Errorf("resolveSnapshot(%q): %v", args[0], err)
+		return
+	}
+
+	newScore, err := resolveSnapshot(ctx, args[1])
+	if err != nil {
+		err = fmt.Errorf("resolveSnapshot(%q): %v", args[1], err)
+		return
+	}
+
+	var relPath string
+	if len(args) > 2 {
+		relPath = args[2]
+	}
+
+	// Compare, printing text output as we go.
+	var changes []jsonChange
+	var summary diff.Summary
+
+	err = diff.Diff(
+		ctx,
+		getBlobStore(ctx),
+		oldScore,
+		newScore,
+		relPath,
+		func(c *diff.Change) (err error) {
+			summary.Add(c)
+
+			if *fDiffJSON {
+				changes = append(changes, makeJSONChange(c))
+				return
+			}
+
+			_, err = fmt.Println(formatChange(c))
+			return
+		})
+
+	if err != nil {
+		err = fmt.Errorf("Diff: %v", err)
+		return
+	}
+
+	// Finish up.
+	if *fDiffJSON {
+		doc := struct {
+			Changes []jsonChange `json:"changes"`
+			Summary diff.Summary `json:"summary"`
+		}{changes, summary}
+
+		if doc.Changes == nil {
+			doc.Changes = []jsonChange{}
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(doc)
+		if err != nil {
+			err = fmt.Errorf("Encode: %v", err)
+			return
+		}
+
+		return
+	}
+
+	printTotals := func(name string, t diff.Totals) {
+		fmt.Printf(
+			"%-18s %8d entries  %s\n",
+			name+":",
+			t.Entries,
+			formatBytes(t.Bytes))
+	}
+
+	fmt.Println()
+	printTotals("Added", summary.Added)
+	printTotals("Removed", summary.Removed)
+	printTotals("Content changed", summary.ContentChanged)
+	printTotals("Metadata changed", summary.MetadataChanged)
+	printTotals("Type changed", summary.TypeChanged)
+
+	return
+}
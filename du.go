@@ -0,0 +1,167 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jacobsa/comeback/internal/browse"
+	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/registry"
+	"github.com/jacobsa/comeback/internal/stats"
+	"github.com/jacobsa/comeback/internal/wiring"
+)
+
+var cmdDu = &Command{
+	Name: "du",
+}
+
+var fDuDepth = cmdDu.Flags.Int(
+	"depth",
+	-1,
+	"Report directories at most this many levels below the starting "+
+		"directory. Negative means no limit.")
+
+var fDuCache = cmdDu.Flags.String(
+	"cache",
+	"",
+	"Path to the stats cache. Defaults to a file alongside the state file.")
+
+func init() {
+	cmdDu.Run = runDu // Break flag-related dependency loop.
+}
+
+func runDu(ctx context.Context, args []string) (err error) {
+	// Extract and parse arguments.
+	args, err = parseInterspersed(&cmdDu.Flags, args)
+	if err != nil {
+		return
+	}
+
+	if len(args) != 1 {
+		err = fmt.Errorf(
+			"Usage: %s du [--depth N] [--cache path] snapshot[:path]",
+			os.Args[0])
+		return
+	}
+
+	score, relPath, err := resolveSnapshotPath(ctx, args[0])
+	if err != nil {
+		err = fmt.Errorf("resolveSnapshotPath(%q): %v", args[0], err)
+		return
+	}
+
+	// Find the directory.
+	relPath = strings.Trim(path.Clean("/"+relPath), "/")
+	blobStore := getBlobStore(ctx)
+	fi, err := browse.LookUp(ctx, blobStore, score, relPath)
+	if err != nil {
+		return
+	}
+
+	if fi.Type != fs.TypeDirectory {
+		err = fmt.Errorf("%q is not a directory", relPath)
+		return
+	}
+
+	// Count the references to each blob from all other backups. If the backup
+	// was recorded more than once, the other records count.
+	jobs, scans, err := scanBackups(ctx, getStatsCachePath(ctx, *fDuCache))
+	if err != nil {
+		err = fmt.Errorf("scanBackups: %v", err)
+		return
+	}
+
+	var otherJobs []registry.CompletedJob
+	for i, j := range jobs {
+		if j.Score == score {
+			otherJobs = append(otherJobs, jobs[i+1:]...)
+			break
+		}
+
+		otherJobs = append(otherJobs, j)
+	}
+
+	others, err := stats.CountReferences(otherJobs, scans)
+	if err != nil {
+		err = fmt.Errorf("CountReferences: %v", err)
+		return
+	}
+
+	sizes, err := stats.ListBlobSizes(
+		ctx,
+		getBucket(ctx),
+		wiring.BlobObjectNamePrefix)
+
+	if err != nil {
+		err = fmt.Errorf("ListBlobSizes: %v", err)
+		return
+	}
+
+	// Print a line for each directory.
+	const minwidth = 0
+	const tabwidth = 8
+	const padding = 4
+	const padchar = '\t'
+	const flags = 0
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+
+	fmt.Fprintln(w, "Apparent\tUnique\tPath")
+
+	err = stats.DiskUsage(
+		ctx,
+		blobStore,
+		fi.Scores[0],
+		relPath,
+		others,
+		sizes,
+		*fDuDepth,
+		func(u *stats.DirUsage) (err error) {
+			p := u.Path
+			if p == "" {
+				p = "."
+			}
+
+			_, err = fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\n",
+				formatBytes(u.Apparent),
+				formatBytes(u.Unique),
+				p)
+
+			return
+		})
+
+	if err != nil {
+		err = fmt.Errorf("DiskUsage: %v", err)
+		return
+	}
+
+	err = w.Flush()
+	if err != nil {
+		err = fmt.Errorf("Flush: %v", err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,194 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/fs"
+)
+
+// An entryWriter prints backed up directory entries for the ls and find
+// commands, either as names alone, in a long format resembling `ls -l`, or as
+// JSON objects one per line.
+type entryWriter struct {
+	long   bool
+	json   bool
+	tw     *tabwriter.Writer
+	encode *json.Encoder
+}
+
+func newEntryWriter(w io.Writer, long bool, jsonOutput bool) (ew *entryWriter) {
+	const minwidth = 0
+	const tabwidth = 8
+	const padding = 2
+	const padchar = ' '
+	const flags = 0
+
+	ew = &entryWriter{
+		long:   long,
+		json:   jsonOutput,
+		tw:     tabwriter.NewWriter(w, minwidth, tabwidth, padding, padchar, flags),
+		encode: json.NewEncoder(w),
+	}
+
+	return
+}
+
+// The JSON representation of an entry.
+type jsonEntry struct {
+	Path   string    `json:"path"`
+	Type   string    `json:"type"`
+	Mode   string    `json:"mode"`
+	Uid    uint32    `json:"uid"`
+	User   string    `json:"user,omitempty"`
+	Gid    uint32    `json:"gid"`
+	Group  string    `json:"group,omitempty"`
+	Size   uint64    `json:"size"`
+	MTime  time.Time `json:"mtime"`
+	Chunks int       `json:"chunks"`
+	Target string    `json:"target,omitempty"`
+	Scores []string  `json:"scores,omitempty"`
+}
+
+var typeNames = map[fs.Type]string{
+	fs.TypeFile:        "file",
+	fs.TypeDirectory:   "directory",
+	fs.TypeSymlink:     "symlink",
+	fs.TypeBlockDevice: "block_device",
+	fs.TypeCharDevice:  "char_device",
+	fs.TypeNamedPipe:   "named_pipe",
+	fs.TypeSocket:      "socket",
+}
+
+// Print the supplied entry, with the given path relative to the root of the
+// backup. In the non-JSON formats, name is printed in place of the path.
+func (ew *entryWriter) Write(
+	name string,
+	relPath string,
+	fi *fs.FileInfo) (err error) {
+	if ew.json {
+		err = ew.encode.Encode(makeJSONEntry(relPath, fi))
+		return
+	}
+
+	if !ew.long {
+		_, err = fmt.Fprintln(ew.tw, name)
+		return
+	}
+
+	chunks := "-"
+	if fi.Type == fs.TypeFile {
+		chunks = strconv.Itoa(len(fi.Scores))
+	}
+
+	if fi.Type == fs.TypeSymlink {
+		name = fmt.Sprintf("%s -> %s", name, fi.Target)
+	}
+
+	_, err = fmt.Fprintf(
+		ew.tw,
+		"%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+		fileMode(fi),
+		userName(fi),
+		groupName(fi),
+		fi.Size,
+		fi.MTime.Local().Format("2006-01-02 15:04"),
+		chunks,
+		name)
+
+	return
+}
+
+// Flush any buffered output.
+func (ew *entryWriter) Flush() (err error) {
+	err = ew.tw.Flush()
+	return
+}
+
+func makeJSONEntry(relPath string, fi *fs.FileInfo) (e jsonEntry) {
+	e = jsonEntry{
+		Path:   relPath,
+		Type:   typeNames[fi.Type],
+		Mode:   fileMode(fi).String(),
+		Uid:    uint32(fi.Uid),
+		Gid:    uint32(fi.Gid),
+		Size:   fi.Size,
+		MTime:  fi.MTime,
+		Target: fi.Target,
+	}
+
+	if fi.Username != nil {
+		e.User = *fi.Username
+	}
+
+	if fi.Groupname != nil {
+		e.Group = *fi.Groupname
+	}
+
+	if fi.Type == fs.TypeFile {
+		e.Chunks = len(fi.Scores)
+	}
+
+	for _, s := range fi.Scores {
+		e.Scores = append(e.Scores, s.Hex())
+	}
+
+	return
+}
+
+// Return a mode including type bits, for display.
+func fileMode(fi *fs.FileInfo) (m os.FileMode) {
+	m = fi.Permissions
+	switch fi.Type {
+	case fs.TypeDirectory:
+		m |= os.ModeDir
+	case fs.TypeSymlink:
+		m |= os.ModeSymlink
+	case fs.TypeBlockDevice:
+		m |= os.ModeDevice
+	case fs.TypeCharDevice:
+		m |= os.ModeDevice | os.ModeCharDevice
+	case fs.TypeNamedPipe:
+		m |= os.ModeNamedPipe
+	case fs.TypeSocket:
+		m |= os.ModeSocket
+	}
+
+	return
+}
+
+func userName(fi *fs.FileInfo) string {
+	if fi.Username != nil {
+		return *fi.Username
+	}
+
+	return strconv.FormatUint(uint64(fi.Uid), 10)
+}
+
+func groupName(fi *fs.FileInfo) string {
+	if fi.Groupname != nil {
+		return *fi.Groupname
+	}
+
+	return strconv.FormatUint(uint64(fi.Gid), 10)
+}
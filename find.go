@@ -0,0 +1,169 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/browse"
+	"github.com/jacobsa/comeback/internal/fs"
+)
+
+var cmdFind = &Command{
+	Name: "find",
+}
+
+var fFindName = cmdFind.Flags.String(
+	"name",
+	"",
+	"Print only entries whose names match this glob pattern.")
+
+var fFindType = cmdFind.Flags.String(
+	"type",
+	"",
+	"Print only entries of this type: f, d, l, b, c, p, or s.")
+
+var fFindSize = cmdFind.Flags.String(
+	"size",
+	"",
+	"Print only files of this size in bytes, with an optional k, M, or G "+
+		"suffix. A leading + or - means larger or smaller than the size.")
+
+var fFindMTime = cmdFind.Flags.String(
+	"mtime",
+	"",
+	"Print only entries modified this many days ago. A leading + or - means "+
+		"longer ago or more recently.")
+
+var fFindLong = cmdFind.Flags.Bool(
+	"l",
+	false,
+	"Print mode, owner, size, mtime, and chunk count for each entry.")
+
+var fFindJSON = cmdFind.Flags.Bool(
+	"json",
+	false,
+	"Print a JSON object for each entry, one per line.")
+
+func init() {
+	cmdFind.Run = runFind // Break flag-related dependency loop.
+}
+
+// Build a predicate from the filter flags.
+func findPredicate() (p browse.Predicate, err error) {
+	var preds []browse.Predicate
+	add := func(pred browse.Predicate, predErr error) {
+		if err == nil {
+			err = predErr
+			preds = append(preds, pred)
+		}
+	}
+
+	if *fFindName != "" {
+		add(browse.NameMatches(*fFindName))
+	}
+
+	if *fFindType != "" {
+		add(browse.TypeIs(*fFindType))
+	}
+
+	if *fFindSize != "" {
+		add(browse.SizeMatches(*fFindSize))
+	}
+
+	if *fFindMTime != "" {
+		add(browse.MTimeMatches(*fFindMTime, time.Now()))
+	}
+
+	p = browse.All(preds...)
+	return
+}
+
+func runFind(ctx context.Context, args []string) (err error) {
+	// Extract and parse arguments.
+	args, err = parseInterspersed(&cmdFind.Flags, args)
+	if err != nil {
+		return
+	}
+
+	if len(args) != 1 {
+		err = fmt.Errorf(
+			"Usage: %s find snapshot[:path] [-name pattern] [-type t] "+
+				"[-size n] [-mtime n] [-l] [--json]",
+			os.Args[0])
+		return
+	}
+
+	pred, err := findPredicate()
+	if err != nil {
+		return
+	}
+
+	score, relPath, err := resolveSnapshotPath(ctx, args[0])
+	if err != nil {
+		err = fmt.Errorf("resolveSnapshotPath(%q): %v", args[0], err)
+		return
+	}
+
+	relPath = strings.Trim(path.Clean("/"+relPath), "/")
+
+	// Find the starting point.
+	blobStore := getBlobStore(ctx)
+	fi, err := browse.LookUp(ctx, blobStore, score, relPath)
+	if err != nil {
+		return
+	}
+
+	ew := newEntryWriter(os.Stdout, *fFindLong, *fFindJSON)
+	visit := func(p string, fi *fs.FileInfo) (err error) {
+		if !pred(p, fi) {
+			return
+		}
+
+		err = ew.Write(p, p, fi)
+		return
+	}
+
+	// Like find(1), consider the starting point itself.
+	if relPath != "" {
+		err = visit(relPath, fi)
+		if err != nil {
+			err = fmt.Errorf("Write: %v", err)
+			return
+		}
+	}
+
+	if fi.Type == fs.TypeDirectory {
+		err = browse.Walk(ctx, blobStore, relPath, fi, visit)
+		if err != nil {
+			err = fmt.Errorf("Walk: %v", err)
+			return
+		}
+	}
+
+	err = ew.Flush()
+	if err != nil {
+		err = fmt.Errorf("Flush: %v", err)
+		return
+	}
+
+	return
+}
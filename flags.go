@@ -0,0 +1,55 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+// A flag.Value that accumulates the values of a flag that may be repeated.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(s string) (err error) {
+	*f = append(*f, s)
+	return
+}
+
+// Parse the supplied arguments using the given flag set, allowing flags to
+// follow positional arguments as in `comeback find snapshot -name foo`.
+// Return the positional arguments.
+func parseInterspersed(
+	flags *flag.FlagSet,
+	args []string) (positional []string, err error) {
+	for {
+		err = flags.Parse(args)
+		if err != nil {
+			return
+		}
+
+		args = flags.Args()
+		if len(args) == 0 {
+			return
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/registry"
+	"github.com/jacobsa/comeback/internal/retention"
+)
+
+var cmdForget = &Command{
+	Name: "forget",
+}
+
+var fForgetDryRun = cmdForget.Flags.Bool(
+	"dry_run",
+	false,
+	"If set, show what would be kept and forgotten but change nothing.")
+
+func init() {
+	cmdForget.Run = runForget // Break flag-related dependency loop.
+}
+
+func runForget(ctx context.Context, args []string) (err error) {
+	cfg := getConfig()
+
+	// By default, apply every job's policy.
+	jobNames := args
+	if len(jobNames) == 0 {
+		for name, job := range cfg.Jobs {
+			if job.Retention != nil {
+				jobNames = append(jobNames, name)
+			}
+		}
+
+		if len(jobNames) == 0 {
+			err = fmt.Errorf("No jobs have a retention policy.")
+			return
+		}
+	}
+
+	sort.Strings(jobNames)
+
+	for _, name := range jobNames {
+		job, ok := cfg.Jobs[name]
+		if !ok {
+			err = fmt.Errorf("Unknown job: %q", name)
+			return
+		}
+
+		if job.Retention == nil {
+			err = fmt.Errorf("Job %q has no retention policy.", name)
+			return
+		}
+	}
+
+	// Decide what to keep.
+	reg := getRegistry(ctx)
+	all, err := reg.ListBackups(ctx)
+	if err != nil {
+		err = fmt.Errorf("ListBackups: %v", err)
+		return
+	}
+
+	byName := make(map[string][]registry.CompletedJob)
+	for _, j := range all {
+		byName[j.Name] = append(byName[j.Name], j)
+	}
+
+	var decisions []retention.Decision
+	for _, name := range jobNames {
+		decisions = append(
+			decisions,
+			retention.Apply(cfg.Jobs[name].Retention, byName[name], time.Local)...)
+	}
+
+	// Show the decisions.
+	const minwidth = 0
+	const tabwidth = 8
+	const padding = 4
+	const padchar = '\t'
+	const flags = 0
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Start time\tJob name\tScore\tAction\tReasons")
+
+	var forget []registry.CompletedJob
+	for _, d := range decisions {
+		action := "keep"
+		if !d.Keep {
+			action = "forget"
+			forget = append(forget, d.Job)
+		}
+
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\n",
+			d.Job.StartTime.Format(time.RFC3339),
+			d.Job.Name,
+			d.Job.Score.Hex(),
+			action,
+			strings.Join(d.Reasons, ", "))
+	}
+
+	w.Flush()
+	fmt.Println()
+
+	if *fForgetDryRun {
+		fmt.Printf(
+			"Would forget %d of %d backups. Nothing has been changed.\n",
+			len(forget),
+			len(decisions))
+		return
+	}
+
+	// Delete the registry entries.
+	err = reg.DeleteBackups(ctx, forget)
+	if err != nil {
+		err = fmt.Errorf("DeleteBackups: %v", err)
+		return
+	}
+
+	fmt.Printf("Forgot %d of %d backups.\n", len(forget), len(decisions))
+	if len(forget) != 0 {
+		fmt.Println("Their blobs remain until collected with verify and gc.")
+	}
+
+	return
+}
@@ -18,6 +18,9 @@
 // score that is in the bucket but not represented in the verify output is
 // cloned to a garbage/ prefix in the bucket, and deleted from the blobs/
 // prefix.
+//
+// gc holds an exclusive repository lock while it works, and refuses to run if
+// the verify output doesn't cover every registered backup.
 
 package main
 
@@ -33,6 +36,8 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/lock"
+	"github.com/jacobsa/comeback/internal/registry"
 	"github.com/jacobsa/comeback/internal/verify"
 	"github.com/jacobsa/comeback/internal/wiring"
 	"github.com/jacobsa/gcloud/gcs"
@@ -53,6 +58,10 @@ func init() {
 
 const garbagePrefix = "garbage/"
 
+// Lock creation times come from the holder's clock, which may disagree with
+// GCS's. Protect blobs this much older than an abandoned save's lock, too.
+const gcClockSkewAllowance = time.Hour
+
 ////////////////////////////////////////////////////////////////////////
 // Helpers
 ////////////////////////////////////////////////////////////////////////
@@ -92,6 +101,118 @@ func parseGCInput(
 	return
 }
 
+// Make sure that the verify output covers every backup in the registry. A
+// backup recorded after verify ran may refer to blobs that were unreachable
+// at the time, which we would otherwise collect.
+func checkVerifyCoverage(
+	jobs []registry.CompletedJob,
+	accessible []blob.Score) (err error) {
+	accessibleMap := make(map[blob.Score]struct{})
+	for _, score := range accessible {
+		accessibleMap[score] = struct{}{}
+	}
+
+	for _, j := range jobs {
+		if _, ok := accessibleMap[j.Score]; !ok {
+			err = fmt.Errorf(
+				"The %s backup started at %v (score %s) isn't in the verify output. "+
+					"Please run verify again.",
+				j.Name,
+				j.StartTime.Format(time.RFC3339),
+				j.Score.Hex())
+
+			return
+		}
+	}
+
+	return
+}
+
+// Break the locks left behind by saves that stopped refreshing them. Such a
+// save may have uploaded blobs that aren't yet referred to by any backup, so
+// return a cutoff time after which blobs must not be collected, or the zero
+// time if there are no such saves.
+func breakAbandonedSaves(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	self *lock.Lock) (abandoned []lock.Info, cutoff time.Time, err error) {
+	abandoned, err = lock.BreakAbandoned(ctx, bucket, self)
+
+	if err != nil {
+		err = fmt.Errorf("BreakAbandoned: %v", err)
+		return
+	}
+
+	for _, info := range abandoned {
+		t := info.Created.Add(-gcClockSkewAllowance)
+		if cutoff.IsZero() || t.Before(cutoff) {
+			cutoff = t
+		}
+	}
+
+	return
+}
+
+// List the scores of all blob objects in the bucket, except for those updated
+// at or after the cutoff time (if non-zero), which are instead counted in
+// protected.
+func listCollectableScores(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	cutoff time.Time,
+	scores chan<- blob.Score,
+	protected *uint64) (err error) {
+	eg, ctx := errgroup.WithContext(ctx)
+
+	// List object records into a channel.
+	objects := make(chan *gcs.Object, 100)
+	eg.Go(func() (err error) {
+		defer close(objects)
+		err = blob.ListBlobObjects(
+			ctx,
+			bucket,
+			wiring.BlobObjectNamePrefix,
+			objects)
+
+		if err != nil {
+			err = fmt.Errorf("ListBlobObjects: %v", err)
+			return
+		}
+
+		return
+	})
+
+	// Filter and parse.
+	eg.Go(func() (err error) {
+		for o := range objects {
+			if !cutoff.IsZero() && !o.Updated.Before(cutoff) {
+				atomic.AddUint64(protected, 1)
+				continue
+			}
+
+			var score blob.Score
+			score, err = blob.ParseObjectRecord(o, wiring.BlobObjectNamePrefix)
+			if err != nil {
+				err = fmt.Errorf("ParseObjectRecord: %v", err)
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+
+			case scores <- score:
+			}
+		}
+
+		return
+	})
+
+	err = eg.Wait()
+	return
+}
+
 // Filter out scores that are in the list of non-garbage accessible scores,
 // passing on only garbage.
 func filterToGarbage(
@@ -230,15 +351,69 @@ func runGC(ctx context.Context, args []string) (err error) {
 		return
 	}
 
+	// Make sure no save is running while we work.
+	lk, ctx, releaseLock, err := holdLock(ctx, lock.Exclusive)
+	if err != nil {
+		err = fmt.Errorf("holdLock: %v", err)
+		return
+	}
+
+	defer releaseLock()
+
+	// Refuse to work from a verify run that predates some backups.
+	jobs, err := getRegistry(ctx).ListBackups(ctx)
+	if err != nil {
+		err = fmt.Errorf("ListBackups: %v", err)
+		return
+	}
+
+	err = checkVerifyCoverage(jobs, accessibleScores)
+	if err != nil {
+		return
+	}
+
+	// Make sure that abandoned saves can't register backups if they wake up,
+	// and don't touch anything that they may have uploaded. Do this before
+	// removing anything, so that such a save can't record a backup that refers
+	// to blobs we are in the middle of collecting.
+	abandoned, cutoff, err := breakAbandonedSaves(ctx, bucket, lk)
+	if err != nil {
+		err = fmt.Errorf("breakAbandonedSaves: %v", err)
+		return
+	}
+
+	if !cutoff.IsZero() {
+		log.Printf(
+			"Broke %d abandoned save locks; not collecting blobs newer than %v.",
+			len(abandoned),
+			cutoff.Format(time.RFC3339))
+	}
+
+	// Invalidate clients' caches of existing scores before removing anything,
+	// in case we are interrupted part way through. This also tells saves that
+	// still hold their locks that they must not register backups.
+	_, err = lock.BumpGCEpoch(ctx, bucket)
+	if err != nil {
+		err = fmt.Errorf("BumpGCEpoch: %v", err)
+		return
+	}
+
 	eg, ctx := errgroup.WithContext(ctx)
 
-	// List all extant scores into a channel.
+	// List all extant scores that are old enough to collect into a channel.
+	var protectedCount uint64
 	allScores := make(chan blob.Score, 100)
 	eg.Go(func() (err error) {
 		defer close(allScores)
-		err = blob.ListScores(ctx, bucket, wiring.BlobObjectNamePrefix, allScores)
+		err = listCollectableScores(
+			ctx,
+			bucket,
+			cutoff,
+			allScores,
+			&protectedCount)
+
 		if err != nil {
-			err = fmt.Errorf("ListScores: %v", err)
+			err = fmt.Errorf("listCollectableScores: %v", err)
 			return
 		}
 
@@ -349,11 +524,34 @@ func runGC(ctx context.Context, args []string) (err error) {
 		return
 	}
 
+	// Bump the epoch again, so that clients that listed while we worked also
+	// throw away their caches.
+	epoch, err := lock.BumpGCEpoch(ctx, bucket)
+	if err != nil {
+		err = fmt.Errorf("BumpGCEpoch: %v", err)
+		return
+	}
+
+	// Make sure we held the lock throughout.
+	err = lk.Refresh(ctx)
+	if err != nil {
+		err = fmt.Errorf("Refresh: %v", err)
+		return
+	}
+
 	// Print a summary.
 	log.Printf(
 		"Moved %d objects to garbage/, out of %d total.",
 		garbageScoresCount,
 		allScoresCount)
 
+	log.Printf("GC epoch is now %d.", epoch)
+
+	if protectedCount != 0 {
+		log.Printf(
+			"Skipped %d objects that abandoned saves may be using.",
+			protectedCount)
+	}
+
 	return
 }
@@ -0,0 +1,196 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/browse"
+	"github.com/jacobsa/comeback/internal/registry"
+)
+
+var cmdHistory = &Command{
+	Name: "history",
+}
+
+var fHistoryRestoreVersion = cmdHistory.Flags.Int(
+	"restore-version",
+	0,
+	"If set, write out the contents of this version (as numbered in the "+
+		"output) to the file given by --to instead of listing versions.")
+
+var fHistoryTo = cmdHistory.Flags.String(
+	"to",
+	"",
+	"The file to which --restore-version writes. It must not already exist.")
+
+func init() {
+	cmdHistory.Run = runHistory // Break flag-related dependency loop.
+}
+
+func runHistory(ctx context.Context, args []string) (err error) {
+	// Extract and parse arguments.
+	args, err = parseInterspersed(&cmdHistory.Flags, args)
+	if err != nil {
+		return
+	}
+
+	if len(args) != 2 {
+		err = fmt.Errorf(
+			"Usage: %s history [--restore-version N --to file] job path",
+			os.Args[0])
+		return
+	}
+
+	jobName := args[0]
+	relPath := args[1]
+
+	if (*fHistoryRestoreVersion != 0) != (*fHistoryTo != "") {
+		err = fmt.Errorf("--restore-version and --to must be used together")
+		return
+	}
+
+	// Find the job's backups.
+	allJobs, err := getRegistry(ctx).ListBackups(ctx)
+	if err != nil {
+		err = fmt.Errorf("ListBackups: %v", err)
+		return
+	}
+
+	var jobs []registry.CompletedJob
+	for _, j := range allJobs {
+		if j.Name == jobName {
+			jobs = append(jobs, j)
+		}
+	}
+
+	if len(jobs) == 0 {
+		err = fmt.Errorf("No backups found for job %q", jobName)
+		return
+	}
+
+	// Find the versions.
+	blobStore := getBlobStore(ctx)
+	versions, err := browse.History(ctx, blobStore, jobs, relPath)
+	if err != nil {
+		err = fmt.Errorf("History: %v", err)
+		return
+	}
+
+	if len(versions) == 0 {
+		err = fmt.Errorf(
+			"%q doesn't exist in any of the %d backups of job %q",
+			relPath,
+			len(jobs),
+			jobName)
+		return
+	}
+
+	// Write out a version if requested.
+	if n := *fHistoryRestoreVersion; n != 0 {
+		if n < 1 || n > len(versions) {
+			err = fmt.Errorf(
+				"Version %d doesn't exist; there are %d versions",
+				n,
+				len(versions))
+			return
+		}
+
+		err = restoreVersion(ctx, versions[n-1], *fHistoryTo)
+		if err != nil {
+			err = fmt.Errorf("restoreVersion: %v", err)
+			return
+		}
+
+		return
+	}
+
+	// Otherwise list them.
+	const minwidth = 0
+	const tabwidth = 8
+	const padding = 4
+	const padchar = '\t'
+	const flags = 0
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Version\tFirst seen\tLast seen\tBackups\tSize\tMTime\tScores")
+
+	for i, v := range versions {
+		var scores []string
+		for _, s := range v.Info.Scores {
+			scores = append(scores, s.Hex())
+		}
+
+		fmt.Fprintf(
+			w,
+			"%d\t%s\t%s\t%d\t%d\t%s\t%s\n",
+			i+1,
+			v.FirstSeen.StartTime.Format(time.RFC3339),
+			v.LastSeen.StartTime.Format(time.RFC3339),
+			v.Count,
+			v.Info.Size,
+			v.Info.MTime.Format(time.RFC3339),
+			strings.Join(scores, ","),
+		)
+	}
+
+	w.Flush()
+
+	return
+}
+
+// Write the contents of the supplied version to a new file at dst.
+func restoreVersion(
+	ctx context.Context,
+	v browse.Version,
+	dst string) (err error) {
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		err = fmt.Errorf("OpenFile: %v", err)
+		return
+	}
+
+	err = browse.WriteContents(ctx, getBlobStore(ctx), v.Info, f)
+	if err != nil {
+		f.Close()
+		os.Remove(dst)
+		err = fmt.Errorf("WriteContents: %v", err)
+		return
+	}
+
+	err = f.Close()
+	if err != nil {
+		err = fmt.Errorf("Close: %v", err)
+		return
+	}
+
+	// Match the backed up mtime, as restore does.
+	err = os.Chtimes(dst, time.Now(), v.Info.MTime)
+	if err != nil {
+		err = fmt.Errorf("Chtimes: %v", err)
+		return
+	}
+
+	return
+}
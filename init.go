@@ -0,0 +1,115 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jacobsa/comeback/internal/registry"
+	"github.com/jacobsa/comeback/internal/wiring"
+)
+
+var cmdInit = &Command{
+	Name: "init",
+}
+
+var fInitKDFName = cmdInit.Flags.String(
+	"kdf",
+	registry.DefaultKDF.Name,
+	"The key derivation function to use: "+strings.Join(kdfNames(), ", ")+".")
+
+var fInitNewPassword = registerNewPasswordFlags(&cmdInit.Flags)
+
+func init() {
+	cmdInit.Run = runInit // Break flag-related dependency loop.
+}
+
+// Find the password for a new repository. If a source other than prompting is
+// configured we use it, so that later commands find the same password;
+// otherwise we read a new one as directed by the flags.
+func readInitPassword(f *newPasswordFlags) (p string, err error) {
+	p, ok, err := readConfiguredPassword()
+	if err != nil {
+		return
+	}
+
+	if !ok {
+		p, err = readNewPassword(f)
+		return
+	}
+
+	if f.set() {
+		err = fmt.Errorf(
+			"--new_password_file and --new_password_fd can't be used when the " +
+				"password is configured elsewhere.")
+		return
+	}
+
+	if len(p) == 0 {
+		err = fmt.Errorf("You must enter a password.")
+		return
+	}
+
+	return
+}
+
+func runInit(ctx context.Context, args []string) (err error) {
+	if len(args) != 0 {
+		err = fmt.Errorf(
+			"Usage: %s init [--kdf=name] "+
+				"[--new_password_file=path | --new_password_fd=n]",
+			os.Args[0])
+		return
+	}
+
+	kdf, ok := registry.DefaultKDFs[*fInitKDFName]
+	if !ok {
+		err = fmt.Errorf("Unknown KDF %q", *fInitKDFName)
+		return
+	}
+
+	bucket := getBucket(ctx)
+	password, err := readInitPassword(fInitNewPassword)
+	if err != nil {
+		return
+	}
+
+	envelope := getConfig().EnvelopeEncryption
+	_, _, err = wiring.InitRegistryAndCrypter(
+		ctx,
+		password,
+		bucket,
+		kdf,
+		envelope)
+
+	if err != nil {
+		err = fmt.Errorf("InitRegistryAndCrypter: %v", err)
+		return
+	}
+
+	fmt.Printf(
+		"Created a repository in bucket %q, protected using %v.\n",
+		bucket.Name(),
+		kdf)
+
+	if envelope {
+		fmt.Println("Write-only key slots may be added with `add_key --write_only`.")
+	}
+
+	return
+}
@@ -38,14 +38,15 @@ func (s *checkingStore) Save(
 
 func (s *checkingStore) Load(
 	ctx context.Context,
-	score Score) (blob []byte, err error) {
+	score Score,
+	kind Kind) (blob []byte, err error) {
 	// Call the wrapped store.
-	if blob, err = s.wrapped.Load(ctx, score); err != nil {
+	if blob, err = s.wrapped.Load(ctx, score, kind); err != nil {
 		return
 	}
 
-	// Check its result.
-	actual := ComputeScore(blob)
+	// Check its result, using the algorithm with which the score was computed.
+	actual := ComputeScoreWithAlgorithm(score.Algorithm(), blob)
 	if actual != score {
 		return nil, fmt.Errorf(
 			"Incorrect data returned for blob; requested score is %s actual is %s.",
@@ -59,22 +59,22 @@ func (t *CheckingStore_LoadTest) CallsWrapped() {
 	score := blob.ComputeScore([]byte{0xde, 0xad})
 
 	// Wrapped
-	ExpectCall(t.wrapped, "Load")(Any(), DeepEquals(score)).
+	ExpectCall(t.wrapped, "Load")(Any(), DeepEquals(score), blob.Kind_Dir).
 		WillOnce(oglemock.Return(nil, errors.New("")))
 
 	// Call
-	t.store.Load(t.ctx, score)
+	t.store.Load(t.ctx, score, blob.Kind_Dir)
 }
 
 func (t *CheckingStore_LoadTest) WrappedReturnsError() {
 	score := blob.ComputeScore([]byte{})
 
 	// Wrapped
-	ExpectCall(t.wrapped, "Load")(Any(), Any()).
+	ExpectCall(t.wrapped, "Load")(Any(), Any(), Any()).
 		WillOnce(oglemock.Return(nil, errors.New("taco")))
 
 	// Call
-	_, err := t.store.Load(t.ctx, score)
+	_, err := t.store.Load(t.ctx, score, blob.Kind_Dir)
 
 	ExpectThat(err, Error(Equals("taco")))
 }
@@ -85,11 +85,11 @@ func (t *CheckingStore_LoadTest) WrappedReturnsIncorrectData() {
 	score := blob.ComputeScore(correctData)
 
 	// Wrapped
-	ExpectCall(t.wrapped, "Load")(Any(), Any()).
+	ExpectCall(t.wrapped, "Load")(Any(), Any(), Any()).
 		WillOnce(oglemock.Return(incorrectData, nil))
 
 	// Call
-	_, err := t.store.Load(t.ctx, score)
+	_, err := t.store.Load(t.ctx, score, blob.Kind_Dir)
 
 	ExpectThat(err, Error(HasSubstr("Incorrect")))
 	ExpectThat(err, Error(HasSubstr("data")))
@@ -101,11 +101,26 @@ func (t *CheckingStore_LoadTest) WrappedReturnsCorrectData() {
 	score := blob.ComputeScore(correctData)
 
 	// Wrapped
-	ExpectCall(t.wrapped, "Load")(Any(), Any()).
+	ExpectCall(t.wrapped, "Load")(Any(), Any(), Any()).
 		WillOnce(oglemock.Return(correctData, nil))
 
 	// Call
-	data, err := t.store.Load(t.ctx, score)
+	data, err := t.store.Load(t.ctx, score, blob.Kind_Dir)
+
+	AssertEq(nil, err)
+	ExpectThat(data, DeepEquals(correctData))
+}
+
+func (t *CheckingStore_LoadTest) LegacySHA1Score() {
+	correctData := []byte{0xde, 0xad}
+	score := blob.ComputeScoreWithAlgorithm(blob.Algorithm_SHA1, correctData)
+
+	// Wrapped
+	ExpectCall(t.wrapped, "Load")(Any(), Any(), Any()).
+		WillOnce(oglemock.Return(correctData, nil))
+
+	// Call
+	data, err := t.store.Load(t.ctx, score, blob.Kind_Dir)
 
 	AssertEq(nil, err)
 	ExpectThat(data, DeepEquals(correctData))
@@ -16,6 +16,7 @@
 package blob
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 
@@ -23,8 +24,8 @@ import (
 )
 
 // Return a blob store that wraps the supplied one, encrypting and decrypting
-// data as it passes through. The supplied crypter should have deterministic
-// output.
+// data as it passes through (see Encrypt and Decrypt). The supplied crypter
+// should have deterministic output.
 func NewEncryptingStore(crypter crypto.Crypter, wrapped Store) Store {
 	return &encryptingStore{crypter, wrapped}
 }
@@ -34,11 +35,71 @@ type encryptingStore struct {
 	wrapped Store
 }
 
+// Encrypted blobs consist of a header followed by the ciphertext. The header
+// is also used as associated data for the crypter, binding the blob's kind
+// into the ciphertext. The crypter returned by the registry additionally binds
+// the repository ID (see crypto.BindRepository).
+//
+// Older versions of comeback wrote the ciphertext alone, with no associated
+// data; such blobs are still read.
+var blobMagic = []byte{0xcb, 'b'}
+
+const blobFormatVersion = 2
+
+func blobHeader(kind Kind) []byte {
+	return append(append([]byte{}, blobMagic...), blobFormatVersion, byte(kind))
+}
+
+// Encrypt a blob of the given kind in the format used by NewEncryptingStore.
+// Encryption is deterministic if the crypter is, so this can be used to
+// compute the score that the store would assign to the blob.
+func Encrypt(
+	crypter crypto.Crypter,
+	kind Kind,
+	plaintext []byte) (ciphertext []byte, err error) {
+	header := blobHeader(kind)
+	ciphertext, err = crypter.Encrypt(header, plaintext, [][]byte{header})
+	return
+}
+
+// Encrypt a blob in the legacy format written by older versions of comeback,
+// with no header or associated data. Nothing writes this format any more, but
+// it is needed to compute the scores of blobs saved by those versions.
+func EncryptLegacy(
+	crypter crypto.Crypter,
+	plaintext []byte) (ciphertext []byte, err error) {
+	ciphertext, err = crypter.Encrypt(nil, plaintext, nil)
+	return
+}
+
+// Decrypt a blob written by Encrypt, or by older versions of comeback. For the
+// former, the blob must be of the given kind.
+func Decrypt(
+	crypter crypto.Crypter,
+	kind Kind,
+	ciphertext []byte) (plaintext []byte, err error) {
+	header := blobHeader(kind)
+	if bytes.HasPrefix(ciphertext, header) {
+		plaintext, err = crypter.Decrypt(
+			ciphertext[len(header):],
+			[][]byte{header})
+
+		// A legacy ciphertext may begin with the header by coincidence, so fall
+		// through if authentication fails.
+		if _, ok := err.(*crypto.NotAuthenticError); !ok {
+			return
+		}
+	}
+
+	plaintext, err = crypter.Decrypt(ciphertext, nil)
+	return
+}
+
 func (s *encryptingStore) Save(
 	ctx context.Context,
 	req *SaveRequest) (score Score, err error) {
 	// Encrypt the plaintext blob.
-	ciphertext, err := s.crypter.Encrypt(nil, req.Blob)
+	ciphertext, err := Encrypt(s.crypter, req.Kind, req.Blob)
 	if err != nil {
 		err = fmt.Errorf("Encrypt: %v", err)
 		return
@@ -53,15 +114,16 @@ func (s *encryptingStore) Save(
 
 func (s *encryptingStore) Load(
 	ctx context.Context,
-	score Score) ([]byte, error) {
+	score Score,
+	kind Kind) ([]byte, error) {
 	// Load the encrypted blob.
-	ciphertext, err := s.wrapped.Load(ctx, score)
+	ciphertext, err := s.wrapped.Load(ctx, score, kind)
 	if err != nil {
 		return nil, err
 	}
 
 	// Decrypt the ciphertext.
-	plaintext, err := s.crypter.Decrypt(ciphertext)
+	plaintext, err := Decrypt(s.crypter, kind, ciphertext)
 	if err != nil {
 		return nil, fmt.Errorf("Decrypt: %v", err)
 	}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/jacobsa/comeback/internal/blob"
 	"github.com/jacobsa/comeback/internal/blob/mock"
+	"github.com/jacobsa/comeback/internal/crypto"
 	"github.com/jacobsa/comeback/internal/crypto/mock"
 	. "github.com/jacobsa/oglematchers"
 	"github.com/jacobsa/oglemock"
@@ -62,7 +63,7 @@ func (t *EncryptingStore_StoreTest) CallsCrypter() {
 	b := []byte{0xde, 0xad}
 
 	// Crypter
-	ExpectCall(t.crypter, "Encrypt")(Any(), DeepEquals(b)).
+	ExpectCall(t.crypter, "Encrypt")(Any(), DeepEquals(b), Any()).
 		WillOnce(oglemock.Return(nil, errors.New("")))
 
 	// Call
@@ -71,7 +72,7 @@ func (t *EncryptingStore_StoreTest) CallsCrypter() {
 
 func (t *EncryptingStore_StoreTest) CrypterReturnsError() {
 	// Crypter
-	ExpectCall(t.crypter, "Encrypt")(Any(), Any()).
+	ExpectCall(t.crypter, "Encrypt")(Any(), Any(), Any()).
 		WillOnce(oglemock.Return(nil, errors.New("taco")))
 
 	// Call
@@ -85,7 +86,7 @@ func (t *EncryptingStore_StoreTest) CallsWrapped() {
 	// Crypter
 	encryptedBlob := []byte{0xde, 0xad}
 
-	ExpectCall(t.crypter, "Encrypt")(Any(), Any()).
+	ExpectCall(t.crypter, "Encrypt")(Any(), Any(), Any()).
 		WillOnce(oglemock.Return(encryptedBlob, nil))
 
 	// Wrapped
@@ -104,7 +105,7 @@ func (t *EncryptingStore_StoreTest) CallsWrapped() {
 
 func (t *EncryptingStore_StoreTest) WrappedReturnsError() {
 	// Crypter
-	ExpectCall(t.crypter, "Encrypt")(Any(), Any()).
+	ExpectCall(t.crypter, "Encrypt")(Any(), Any(), Any()).
 		WillOnce(oglemock.Return([]byte{}, nil))
 
 	// Wrapped
@@ -119,7 +120,7 @@ func (t *EncryptingStore_StoreTest) WrappedReturnsError() {
 
 func (t *EncryptingStore_StoreTest) WrappedSucceeds() {
 	// Crypter
-	ExpectCall(t.crypter, "Encrypt")(Any(), Any()).
+	ExpectCall(t.crypter, "Encrypt")(Any(), Any(), Any()).
 		WillOnce(oglemock.Return([]byte{}, nil))
 
 	// Wrapped
@@ -149,20 +150,20 @@ func (t *EncryptingStore_LoadTest) CallsWrapped() {
 	score := blob.ComputeScore([]byte("taco"))
 
 	// Wrapped
-	ExpectCall(t.wrapped, "Load")(Any(), DeepEquals(score)).
+	ExpectCall(t.wrapped, "Load")(Any(), DeepEquals(score), blob.Kind_File).
 		WillOnce(oglemock.Return(nil, errors.New("")))
 
 	// Call
-	t.store.Load(t.ctx, score)
+	t.store.Load(t.ctx, score, blob.Kind_File)
 }
 
 func (t *EncryptingStore_LoadTest) WrappedReturnsError() {
 	// Wrapped
-	ExpectCall(t.wrapped, "Load")(Any(), Any()).
+	ExpectCall(t.wrapped, "Load")(Any(), Any(), Any()).
 		WillOnce(oglemock.Return(nil, errors.New("taco")))
 
 	// Call
-	_, err := t.store.Load(t.ctx, blob.ComputeScore([]byte{}))
+	_, err := t.store.Load(t.ctx, blob.ComputeScore([]byte{}), blob.Kind_File)
 
 	ExpectThat(err, Error(Equals("taco")))
 }
@@ -171,28 +172,28 @@ func (t *EncryptingStore_LoadTest) CallsCrypter() {
 	// Wrapped
 	ciphertext := []byte{0xde, 0xad}
 
-	ExpectCall(t.wrapped, "Load")(Any(), Any()).
+	ExpectCall(t.wrapped, "Load")(Any(), Any(), Any()).
 		WillOnce(oglemock.Return(ciphertext, nil))
 
 	// Crypter
-	ExpectCall(t.crypter, "Decrypt")(DeepEquals(ciphertext)).
+	ExpectCall(t.crypter, "Decrypt")(DeepEquals(ciphertext), Any()).
 		WillOnce(oglemock.Return(nil, errors.New("")))
 
 	// Call
-	t.store.Load(t.ctx, blob.ComputeScore([]byte{}))
+	t.store.Load(t.ctx, blob.ComputeScore([]byte{}), blob.Kind_File)
 }
 
 func (t *EncryptingStore_LoadTest) CrypterReturnsError() {
 	// Wrapped
-	ExpectCall(t.wrapped, "Load")(Any(), Any()).
+	ExpectCall(t.wrapped, "Load")(Any(), Any(), Any()).
 		WillOnce(oglemock.Return([]byte{}, nil))
 
 	// Crypter
-	ExpectCall(t.crypter, "Decrypt")(Any()).
+	ExpectCall(t.crypter, "Decrypt")(Any(), Any()).
 		WillOnce(oglemock.Return(nil, errors.New("taco")))
 
 	// Call
-	_, err := t.store.Load(t.ctx, blob.ComputeScore([]byte{}))
+	_, err := t.store.Load(t.ctx, blob.ComputeScore([]byte{}), blob.Kind_File)
 
 	ExpectThat(err, Error(HasSubstr("Decrypt")))
 	ExpectThat(err, Error(HasSubstr("taco")))
@@ -200,18 +201,78 @@ func (t *EncryptingStore_LoadTest) CrypterReturnsError() {
 
 func (t *EncryptingStore_LoadTest) CrypterSucceeds() {
 	// Wrapped
-	ExpectCall(t.wrapped, "Load")(Any(), Any()).
+	ExpectCall(t.wrapped, "Load")(Any(), Any(), Any()).
 		WillOnce(oglemock.Return([]byte{}, nil))
 
 	// Crypter
 	expected := []byte{0xde, 0xad}
 
-	ExpectCall(t.crypter, "Decrypt")(Any()).
+	ExpectCall(t.crypter, "Decrypt")(Any(), Any()).
 		WillOnce(oglemock.Return(expected, nil))
 
 	// Call
-	blob, err := t.store.Load(t.ctx, blob.ComputeScore([]byte{}))
+	blob, err := t.store.Load(t.ctx, blob.ComputeScore([]byte{}), blob.Kind_File)
 	AssertEq(nil, err)
 
 	ExpectThat(blob, DeepEquals(expected))
 }
+
+////////////////////////////////////////////////////////////////////////
+// Format
+////////////////////////////////////////////////////////////////////////
+
+type EncryptingStore_FormatTest struct {
+	crypter crypto.Crypter
+}
+
+func init() { RegisterTestSuite(&EncryptingStore_FormatTest{}) }
+
+func (t *EncryptingStore_FormatTest) SetUp(ti *TestInfo) {
+	var err error
+	t.crypter, err = crypto.NewCrypter(make([]byte, 32))
+	AssertEq(nil, err)
+}
+
+func (t *EncryptingStore_FormatTest) RoundTrip() {
+	ciphertext, err := blob.Encrypt(t.crypter, blob.Kind_Dir, []byte("taco"))
+	AssertEq(nil, err)
+
+	plaintext, err := blob.Decrypt(t.crypter, blob.Kind_Dir, ciphertext)
+	AssertEq(nil, err)
+	ExpectEq("taco", string(plaintext))
+}
+
+func (t *EncryptingStore_FormatTest) WrongKind() {
+	ciphertext, err := blob.Encrypt(t.crypter, blob.Kind_File, []byte("taco"))
+	AssertEq(nil, err)
+
+	_, err = blob.Decrypt(t.crypter, blob.Kind_Dir, ciphertext)
+	_, ok := err.(*crypto.NotAuthenticError)
+	ExpectTrue(ok, "Error: %v", err)
+}
+
+func (t *EncryptingStore_FormatTest) WrongRepository() {
+	c0 := crypto.BindRepository(t.crypter, []byte("taco"))
+	c1 := crypto.BindRepository(t.crypter, []byte("burrito"))
+
+	ciphertext, err := blob.Encrypt(c0, blob.Kind_File, []byte("enchilada"))
+	AssertEq(nil, err)
+
+	_, err = blob.Decrypt(c1, blob.Kind_File, ciphertext)
+	_, ok := err.(*crypto.NotAuthenticError)
+	ExpectTrue(ok, "Error: %v", err)
+
+	plaintext, err := blob.Decrypt(c0, blob.Kind_File, ciphertext)
+	AssertEq(nil, err)
+	ExpectEq("enchilada", string(plaintext))
+}
+
+func (t *EncryptingStore_FormatTest) LegacyBlob() {
+	// Older versions encrypted with no header or associated data.
+	ciphertext, err := blob.EncryptLegacy(t.crypter, []byte("taco"))
+	AssertEq(nil, err)
+
+	plaintext, err := blob.Decrypt(t.crypter, blob.Kind_File, ciphertext)
+	AssertEq(nil, err)
+	ExpectEq("taco", string(plaintext))
+}
@@ -67,7 +67,8 @@ func (bs *existingScoresStore) Save(
 
 func (bs *existingScoresStore) Load(
 	ctx context.Context,
-	s Score) (blob []byte, err error) {
-	blob, err = bs.wrapped.Load(ctx, s)
+	s Score,
+	kind Kind) (blob []byte, err error) {
+	blob, err = bs.wrapped.Load(ctx, s, kind)
 	return
 }
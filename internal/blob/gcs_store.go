@@ -31,11 +31,29 @@ import (
 	"github.com/jacobsa/gcloud/gcs/gcsutil"
 )
 
-// A key placed in GCS object metadata by gcsStore containing the hex SHA-1
-// expected for the object contents. This is of course redundant with the
-// object name; we use it as a paranoid check against GCS returning the
-// metadata or contents for the wrong object.
-const metadataKey_SHA1 = "comeback_sha1"
+// Keys placed in GCS object metadata by gcsStore containing the hex hash
+// expected for the object contents, using the algorithm of the object's
+// score. This is of course redundant with the object name; we use it as a
+// paranoid check against GCS returning the metadata or contents for the wrong
+// object.
+const (
+	metadataKey_SHA1   = "comeback_sha1"
+	metadataKey_SHA256 = "comeback_sha256"
+)
+
+// Return the metadata key holding the hex hash for scores using the supplied
+// algorithm.
+func hashMetadataKey(alg Algorithm) string {
+	switch alg {
+	case Algorithm_SHA1:
+		return metadataKey_SHA1
+
+	case Algorithm_SHA256:
+		return metadataKey_SHA256
+	}
+
+	panic(fmt.Sprintf("Unknown algorithm: %d", alg))
+}
 
 // A key placed in GCS object metadata by gcsStore containing the CRC32C
 // checksum expected for the object contents. If GCS reports a different
@@ -109,21 +127,24 @@ func ParseObjectRecord(
 		return
 	}
 
-	// We expect the hex score to match the hex SHA-1 in the metadata.
-	hexSHA1, ok := o.Metadata[metadataKey_SHA1]
+	// We expect the score's hash to match the hex hash in the metadata for the
+	// score's algorithm.
+	hashKey := hashMetadataKey(score.Algorithm())
+	hexHash, ok := o.Metadata[hashKey]
 	if !ok {
 		err = fmt.Errorf(
 			"Object %q is missing metadata key %q",
 			o.Name,
-			metadataKey_SHA1)
+			hashKey)
 		return
 	}
 
-	if hexSHA1 != hexScore {
+	if hexHash != hex.EncodeToString(score.Hash()) {
 		err = fmt.Errorf(
-			"Score/SHA-1 metadata mismatch for object %q: %q",
+			"Score/%v metadata mismatch for object %q: %q",
+			score.Algorithm(),
 			o.Name,
-			hexSHA1)
+			hexHash)
 		return
 	}
 
@@ -213,14 +234,17 @@ func ListBlobObjects(
 	objects chan<- *gcs.Object) (err error) {
 	eg, ctx := errgroup.WithContext(ctx)
 
-	// GCS object listing is slow. Parallelize sixteen ways.
+	// GCS object listing is slow. Parallelize sixteen ways for each kind of
+	// score name: plain hex for SHA-1, and prefixed hex for the others.
 	const hexDigits = "0123456789abcdef"
-	for i := 0; i < len(hexDigits); i++ {
-		digit := hexDigits[i]
-		eg.Go(func() (err error) {
-			err = gcsutil.ListPrefix(ctx, bucket, namePrefix+string(digit), objects)
-			return
-		})
+	for _, algPrefix := range []string{"", Algorithm_SHA256.hexPrefix()} {
+		for i := 0; i < len(hexDigits); i++ {
+			prefix := namePrefix + algPrefix + string(hexDigits[i])
+			eg.Go(func() (err error) {
+				err = gcsutil.ListPrefix(ctx, bucket, prefix, objects)
+				return
+			})
+		}
 	}
 
 	err = eg.Wait()
@@ -300,9 +324,10 @@ func (s *gcsStore) Save(
 	score = req.score
 	name := s.makeName(score)
 
-	// Optimization: we know that the score is the SHA-1 hash of the blob, so
-	// don't need to compute it again.
-	var sha1 []byte = score[:]
+	// Optimization: we know that the score is the hash of the blob, so don't
+	// need to compute it again.
+	hashKey := hashMetadataKey(score.Algorithm())
+	hash := score.Hash()
 
 	// Create the object.
 	crc32c := *gcsutil.CRC32C(blob)
@@ -315,7 +340,7 @@ func (s *gcsStore) Save(
 		MD5:      &md5,
 
 		Metadata: map[string]string{
-			metadataKey_SHA1:   hex.EncodeToString(sha1),
+			hashKey:            hex.EncodeToString(hash),
 			metadataKey_CRC32C: fmt.Sprintf("%#08x", crc32c),
 			metadataKey_MD5:    hex.EncodeToString(md5[:]),
 		},
@@ -353,7 +378,8 @@ func (s *gcsStore) Save(
 
 func (s *gcsStore) Load(
 	ctx context.Context,
-	score Score) (blob []byte, err error) {
+	score Score,
+	kind Kind) (blob []byte, err error) {
 	// Create a ReadCloser.
 	req := &gcs.ReadObjectRequest{
 		Name: s.makeName(score),
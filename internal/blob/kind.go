@@ -0,0 +1,44 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+// The kind of data held by a blob. Stores that encrypt bind the kind into each
+// blob, so that a blob of one kind can't be passed off as another.
+//
+// The values are the magic bytes that package repr appends to each kind of
+// blob.
+type Kind byte
+
+const (
+	// A chunk of a file's contents.
+	Kind_File Kind = 'f'
+
+	// A directory listing.
+	Kind_Dir Kind = 'd'
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Kind_File:
+		return "file"
+
+	case Kind_Dir:
+		return "dir"
+
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jacobsa/comeback/internal/crypto"
+	"github.com/jacobsa/comeback/internal/util"
+)
+
+// Create a blob store that wraps one created with NewEncryptingStore, reusing
+// blobs saved in the legacy format by older versions of comeback rather than
+// saving their content again. Without this, the first backup after upgrading
+// would upload everything anew, since the current format and DefaultAlgorithm
+// give every blob a new score.
+//
+// For each call to Save, the store computes the score that an older version
+// would have assigned to the plaintext. If that score is in existingScores,
+// it is returned and the wrapped store is not called. This costs an extra
+// encryption and hash per blob.
+//
+// existingScores must be a subset of the scores contained by the bucket, in
+// hex form.
+func NewLegacyReusingStore(
+	crypter crypto.Crypter,
+	existingScores util.StringSet,
+	wrapped Store) (store Store) {
+	store = &legacyReusingStore{
+		crypter: crypter,
+		scores:  existingScores,
+		wrapped: wrapped,
+	}
+
+	return
+}
+
+type legacyReusingStore struct {
+	crypter crypto.Crypter
+	scores  util.StringSet
+	wrapped Store
+}
+
+func (bs *legacyReusingStore) Save(
+	ctx context.Context,
+	req *SaveRequest) (s Score, err error) {
+	// Did an older version save this content?
+	ciphertext, err := EncryptLegacy(bs.crypter, req.Blob)
+	if err != nil {
+		err = fmt.Errorf("EncryptLegacy: %v", err)
+		return
+	}
+
+	s = ComputeScoreWithAlgorithm(Algorithm_SHA1, ciphertext)
+	if bs.scores.Contains(s.Hex()) {
+		return
+	}
+
+	// Save it in the current format.
+	s, err = bs.wrapped.Save(ctx, req)
+	return
+}
+
+func (bs *legacyReusingStore) Load(
+	ctx context.Context,
+	s Score,
+	kind Kind) (blob []byte, err error) {
+	blob, err = bs.wrapped.Load(ctx, s, kind)
+	return
+}
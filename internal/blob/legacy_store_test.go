@@ -0,0 +1,121 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/blob/mock"
+	"github.com/jacobsa/comeback/internal/crypto"
+	"github.com/jacobsa/comeback/internal/util"
+	. "github.com/jacobsa/oglematchers"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+)
+
+func TestLegacyReusing(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type LegacyReusingStoreTest struct {
+	ctx            context.Context
+	crypter        crypto.Crypter
+	existingScores util.StringSet
+	wrapped        mock_blob.MockStore
+	store          blob.Store
+}
+
+func init() { RegisterTestSuite(&LegacyReusingStoreTest{}) }
+
+func (t *LegacyReusingStoreTest) SetUp(ti *TestInfo) {
+	t.ctx = ti.Ctx
+
+	c, err := crypto.NewCrypter(make([]byte, 32))
+	AssertEq(nil, err)
+	t.crypter = crypto.BindRepository(c, []byte("some_repo"))
+
+	t.existingScores = util.NewStringSet()
+	t.wrapped = mock_blob.NewMockStore(ti.MockController, "wrapped")
+	t.store = blob.NewLegacyReusingStore(t.crypter, t.existingScores, t.wrapped)
+}
+
+// Return the score that an older version of comeback would have assigned to
+// the supplied plaintext.
+func (t *LegacyReusingStoreTest) legacyScore(b []byte) blob.Score {
+	ciphertext, err := blob.EncryptLegacy(t.crypter, b)
+	AssertEq(nil, err)
+
+	return blob.ComputeScoreWithAlgorithm(blob.Algorithm_SHA1, ciphertext)
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *LegacyReusingStoreTest) LegacyBlobExists() {
+	b := []byte("taco")
+	expected := t.legacyScore(b)
+	t.existingScores.Add(expected.Hex())
+
+	// The wrapped store should not be called.
+	s, err := t.store.Save(t.ctx, &blob.SaveRequest{Blob: b, Kind: blob.Kind_File})
+	AssertEq(nil, err)
+	ExpectEq(expected, s)
+	ExpectEq(blob.Algorithm_SHA1, s.Algorithm())
+}
+
+func (t *LegacyReusingStoreTest) OtherLegacyBlobExists() {
+	t.existingScores.Add(t.legacyScore([]byte("burrito")).Hex())
+
+	b := []byte("taco")
+	expected := blob.ComputeScore([]byte("enchilada"))
+
+	// Wrapped
+	var req *blob.SaveRequest
+	ExpectCall(t.wrapped, "Save")(Any(), Any()).
+		WillOnce(oglemock.DoAll(
+			oglemock.SaveArg(1, &req),
+			oglemock.Return(expected, nil)))
+
+	// Call
+	s, err := t.store.Save(t.ctx, &blob.SaveRequest{Blob: b, Kind: blob.Kind_File})
+	AssertEq(nil, err)
+	ExpectEq(expected, s)
+
+	AssertNe(nil, req)
+	ExpectEq("taco", string(req.Blob))
+	ExpectEq(blob.Kind_File, req.Kind)
+}
+
+func (t *LegacyReusingStoreTest) CurrentFormatBlobExists() {
+	// A blob already saved in the current format is the wrapped store's
+	// business.
+	b := []byte("taco")
+	ciphertext, err := blob.Encrypt(t.crypter, blob.Kind_File, b)
+	AssertEq(nil, err)
+	t.existingScores.Add(blob.ComputeScore(ciphertext).Hex())
+
+	ExpectCall(t.wrapped, "Save")(Any(), Any()).
+		WillOnce(oglemock.Return(blob.ComputeScore(ciphertext), nil))
+
+	s, err := t.store.Save(t.ctx, &blob.SaveRequest{Blob: b, Kind: blob.Kind_File})
+	AssertEq(nil, err)
+	ExpectEq(blob.DefaultAlgorithm, s.Algorithm())
+}
@@ -43,7 +43,7 @@ func (m *mockStore) Oglemock_Description() string {
 	return m.description
 }
 
-func (m *mockStore) Load(p0 context.Context, p1 blob.Score) (o0 []uint8, o1 error) {
+func (m *mockStore) Load(p0 context.Context, p1 blob.Score, p2 blob.Kind) (o0 []uint8, o1 error) {
 	// Get a file name and line number for the caller.
 	_, file, line, _ := runtime.Caller(1)
 
@@ -53,7 +53,7 @@ func (m *mockStore) Load(p0 context.Context, p1 blob.Score) (o0 []uint8, o1 erro
 		"Load",
 		file,
 		line,
-		[]interface{}{p0, p1})
+		[]interface{}{p0, p1, p2})
 
 	if len(retVals) != 2 {
 		panic(fmt.Sprintf("mockStore.Load: invalid return values: %v", retVals))
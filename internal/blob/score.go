@@ -16,42 +16,180 @@
 package blob
 
 import (
+	"bytes"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"regexp"
+	"strings"
 )
 
+// The hash algorithm with which a score was computed.
+//
+// Repositories written by older versions of comeback contain only SHA-1
+// scores. New blobs are addressed by DefaultAlgorithm, but SHA-1 scores remain
+// valid forever: directory listings, backup records, and verify logs that
+// refer to them continue to work, and the two kinds of score coexist in the
+// same bucket. Because the score covers the ciphertext, a blob saved with a
+// new algorithm is simply a new blob; nothing is ever rewritten in place.
+type Algorithm uint8
+
+const (
+	// The zero value, so that the zero Score is the all-zero SHA-1 score it was
+	// before scores were versioned.
+	Algorithm_SHA1 Algorithm = 0
+
+	Algorithm_SHA256 Algorithm = 1
+)
+
+// The algorithm used for scores of newly saved blobs. Content already saved
+// with a SHA-1 score is not saved again (see NewLegacyReusingStore).
+const DefaultAlgorithm = Algorithm_SHA256
+
+// The lengths of the hashes produced by each algorithm.
+const (
+	SHA1ScoreLength   = sha1.Size
+	SHA256ScoreLength = sha256.Size
+)
+
+// The length of the longest hash produced by any algorithm.
+const maxHashLength = SHA256ScoreLength
+
+// Return the length of hashes produced by the algorithm, or zero if it is
+// unknown.
+func (a Algorithm) Size() int {
+	switch a {
+	case Algorithm_SHA1:
+		return SHA1ScoreLength
+
+	case Algorithm_SHA256:
+		return SHA256ScoreLength
+	}
+
+	return 0
+}
+
+func (a Algorithm) newHash() hash.Hash {
+	switch a {
+	case Algorithm_SHA1:
+		return sha1.New()
+
+	case Algorithm_SHA256:
+		return sha256.New()
+	}
+
+	panic(fmt.Sprintf("Unknown algorithm: %d", a))
+}
+
+// The prefix used for the algorithm in the output of Score.Hex. SHA-1 scores
+// have no prefix, so that their names are unchanged from before scores were
+// versioned.
+func (a Algorithm) hexPrefix() string {
+	switch a {
+	case Algorithm_SHA1:
+		return ""
+
+	case Algorithm_SHA256:
+		return "sha256-"
+	}
+
+	panic(fmt.Sprintf("Unknown algorithm: %d", a))
+}
+
+func (a Algorithm) String() string {
+	switch a {
+	case Algorithm_SHA1:
+		return "SHA-1"
+
+	case Algorithm_SHA256:
+		return "SHA-256"
+	}
+
+	return fmt.Sprintf("Algorithm(%d)", uint8(a))
+}
+
 // A Score is the identifier for a blob previously stored by a blob store. It
-// consists of a 20-byte SHA-1 hash of the blob's contents, so that with high
+// consists of a cryptographic hash of the blob's contents, so that with high
 // probability two blobs have the same contents if and only if they have the
-// same score.
-const ScoreLength = 20
+// same score, along with the algorithm used to compute the hash.
+//
+// Scores are comparable, and may be used as map keys. The zero value is the
+// all-zero SHA-1 score. The layout of the array is an implementation detail:
+// use Hex or Bytes for stable external forms.
+type Score [1 + maxHashLength]byte
 
-type Score [ScoreLength]byte
+// Score[0] holds the algorithm, and the hash follows, padded with zeroes.
+func (s *Score) hash() []byte {
+	return s[1 : 1+s.Algorithm().Size()]
+}
 
-// Compute the score for the supplied blob. This is primarily intended for use
-// by blob store implementations; most users should obtain scores through calls
-// to a blob store's Store method.
+// Compute the score for the supplied blob using DefaultAlgorithm. This is
+// primarily intended for use by blob store implementations; most users should
+// obtain scores through calls to a blob store's Store method.
 func ComputeScore(b []byte) (s Score) {
-	h := sha1.New()
+	s = ComputeScoreWithAlgorithm(DefaultAlgorithm, b)
+	return
+}
+
+// Compute the score for the supplied blob using the given algorithm.
+func ComputeScoreWithAlgorithm(alg Algorithm, b []byte) (s Score) {
+	h := alg.newHash()
 	h.Write(b)
 
 	slice := h.Sum(nil)
-	if len(slice) != ScoreLength {
+	if len(slice) != alg.Size() {
 		panic(
 			fmt.Sprintf(
-				"Expected %d bytes for SHA-1; got %d",
-				ScoreLength,
+				"Expected %d bytes for %v; got %d",
+				alg.Size(),
+				alg,
 				len(slice)))
 	}
 
-	copy(s[:], slice)
+	s[0] = byte(alg)
+	copy(s.hash(), slice)
 	return
 }
 
+// Create a score from the supplied algorithm and raw hash.
+func NewScore(alg Algorithm, h []byte) (s Score, err error) {
+	size := alg.Size()
+	if size == 0 {
+		err = fmt.Errorf("Unknown score algorithm: %d", alg)
+		return
+	}
+
+	if len(h) != size {
+		err = fmt.Errorf(
+			"Expected %d bytes for %v score; got %d",
+			size,
+			alg,
+			len(h))
+		return
+	}
+
+	s[0] = byte(alg)
+	copy(s.hash(), h)
+	return
+}
+
+// Return the algorithm with which the score was computed.
+func (s Score) Algorithm() Algorithm {
+	return Algorithm(s[0])
+}
+
+// Return the raw hash contained in the score.
+func (s Score) Hash() []byte {
+	return append([]byte(nil), s.hash()...)
+}
+
 var hexScoreRegexp = regexp.MustCompile(
-	fmt.Sprintf("^[0-9a-f]{%d}$", hex.EncodedLen(ScoreLength)))
+	fmt.Sprintf(
+		"^(?:[0-9a-f]{%d}|sha256-[0-9a-f]{%d})$",
+		hex.EncodedLen(SHA1ScoreLength),
+		hex.EncodedLen(SHA256ScoreLength)))
 
 // Parse the output of Score.Hex.
 func ParseHexScore(hexScore string) (s Score, err error) {
@@ -61,9 +199,17 @@ func ParseHexScore(hexScore string) (s Score, err error) {
 		return
 	}
 
+	// Find the algorithm.
+	alg := Algorithm_SHA1
+	if strings.HasPrefix(hexScore, Algorithm_SHA256.hexPrefix()) {
+		alg = Algorithm_SHA256
+		hexScore = strings.TrimPrefix(hexScore, alg.hexPrefix())
+	}
+
 	// Decode.
-	n, err := hex.Decode(s[:], []byte(hexScore))
-	if n != ScoreLength || err != nil {
+	s[0] = byte(alg)
+	n, err := hex.Decode(s.hash(), []byte(hexScore))
+	if n != alg.Size() || err != nil {
 		err = fmt.Errorf("Unexpected hex.Decode output: %v %v", n, err)
 		return
 	}
@@ -72,7 +218,54 @@ func ParseHexScore(hexScore string) (s Score, err error) {
 }
 
 // Return a fixed-width hex version of the score's hash, suitable for using
-// e.g. as a filename.
+// e.g. as a filename. SHA-1 scores are plain hex; scores using other
+// algorithms carry a prefix naming the algorithm, e.g. "sha256-".
 func (s Score) Hex() string {
-	return fmt.Sprintf("%x", s)
+	return s.Algorithm().hexPrefix() + hex.EncodeToString(s.hash())
+}
+
+// Return a compact binary version of the score, suitable for embedding in
+// other data structures. SHA-1 scores are the raw 20-byte hash, as they were
+// before scores were versioned; other scores are a one-byte algorithm tag
+// followed by the hash.
+func (s Score) Bytes() (b []byte) {
+	if s.Algorithm() != Algorithm_SHA1 {
+		b = append(b, s[0])
+	}
+
+	b = append(b, s.hash()...)
+	return
+}
+
+// Parse the output of Score.Bytes.
+func ParseScoreBytes(b []byte) (s Score, err error) {
+	if len(b) == SHA1ScoreLength {
+		s, err = NewScore(Algorithm_SHA1, b)
+		return
+	}
+
+	if len(b) == 0 || Algorithm(b[0]) == Algorithm_SHA1 {
+		err = fmt.Errorf("Unexpected hash length: %d", len(b))
+		return
+	}
+
+	alg := Algorithm(b[0])
+	if alg.Size() == 0 {
+		err = fmt.Errorf("Unknown score algorithm: %d", alg)
+		return
+	}
+
+	if len(b) != 1+alg.Size() {
+		err = fmt.Errorf("Unexpected hash length for %v: %d", alg, len(b))
+		return
+	}
+
+	s, err = NewScore(alg, b[1:])
+	return
+}
+
+// Compare two scores, returning -1, 0, or 1. Scores are ordered first by
+// algorithm and then by hash.
+func (s Score) Compare(other Score) int {
+	return bytes.Compare(s[:], other[:])
 }
@@ -31,14 +31,14 @@ func TestRegister(t *testing.T) { RunTests(t) }
 // Helpers
 ////////////////////////////////////////////////////////////////////////
 
-func fromHex(h string) (s Score) {
+func fromHex(alg Algorithm, h string) (s Score) {
 	b, err := hex.DecodeString(h)
 	if err != nil {
 		panic(fmt.Sprintf("Invalid hex string: %s", h))
 	}
 
-	AssertEq(ScoreLength, len(b))
-	copy(s[:], b)
+	s, err = NewScore(alg, b)
+	AssertEq(nil, err)
 
 	return
 }
@@ -55,11 +55,11 @@ func (t *ScoreTest) EmptySlice() {
 	data := []byte{}
 	golden := "da39a3ee5e6b4b0d3255bfef95601890afd80709"
 
-	score := ComputeScore(data)
-	AssertEq(20, len(score))
+	score := ComputeScoreWithAlgorithm(Algorithm_SHA1, data)
+	AssertEq(20, len(score.Hash()))
 
 	ExpectEq(golden, score.Hex())
-	ExpectThat(score, DeepEquals(fromHex(golden)))
+	ExpectThat(score, DeepEquals(fromHex(Algorithm_SHA1, golden)))
 
 	parsed, err := ParseHexScore(golden)
 	AssertEq(nil, err)
@@ -70,11 +70,11 @@ func (t *ScoreTest) HashStartsWithZero() {
 	data := []byte("hello_5")
 	golden := "086766b9ba6a30e3792c05b00c5fb0e85a18a040"
 
-	score := ComputeScore(data)
-	AssertEq(20, len(score))
+	score := ComputeScoreWithAlgorithm(Algorithm_SHA1, data)
+	AssertEq(20, len(score.Hash()))
 
 	ExpectEq(golden, score.Hex())
-	ExpectThat(score, DeepEquals(fromHex(golden)))
+	ExpectThat(score, DeepEquals(fromHex(Algorithm_SHA1, golden)))
 
 	parsed, err := ParseHexScore(golden)
 	AssertEq(nil, err)
@@ -85,11 +85,11 @@ func (t *ScoreTest) HexHashStartsWithNonZeroNumber() {
 	data := []byte("hello_0")
 	golden := "3966a6c98206d4cda8fd000656ed4f279a35726b"
 
-	score := ComputeScore(data)
-	AssertEq(20, len(score))
+	score := ComputeScoreWithAlgorithm(Algorithm_SHA1, data)
+	AssertEq(20, len(score.Hash()))
 
 	ExpectEq(golden, score.Hex())
-	ExpectThat(score, DeepEquals(fromHex(golden)))
+	ExpectThat(score, DeepEquals(fromHex(Algorithm_SHA1, golden)))
 
 	parsed, err := ParseHexScore(golden)
 	AssertEq(nil, err)
@@ -100,11 +100,11 @@ func (t *ScoreTest) HexHashStartsWithLetter() {
 	data := []byte("foo_barbazqux")
 	golden := "ccf73cc0bfe964b652934764f847699e4005205e"
 
-	score := ComputeScore(data)
-	AssertEq(20, len(score))
+	score := ComputeScoreWithAlgorithm(Algorithm_SHA1, data)
+	AssertEq(20, len(score.Hash()))
 
 	ExpectEq(golden, score.Hex())
-	ExpectThat(score, DeepEquals(fromHex(golden)))
+	ExpectThat(score, DeepEquals(fromHex(Algorithm_SHA1, golden)))
 
 	parsed, err := ParseHexScore(golden)
 	AssertEq(nil, err)
@@ -115,11 +115,11 @@ func (t *ScoreTest) DataContainsNonUtf8() {
 	data := []byte{0x4a, 0x80, 0x81, 0x82, 0x4b}
 	golden := "2feba26855d9f4e8b76d36c34dc385c8afe622c8"
 
-	score := ComputeScore(data)
-	AssertEq(20, len(score))
+	score := ComputeScoreWithAlgorithm(Algorithm_SHA1, data)
+	AssertEq(20, len(score.Hash()))
 
 	ExpectEq(golden, score.Hex())
-	ExpectThat(score, DeepEquals(fromHex(golden)))
+	ExpectThat(score, DeepEquals(fromHex(Algorithm_SHA1, golden)))
 
 	parsed, err := ParseHexScore(golden)
 	AssertEq(nil, err)
@@ -157,3 +157,106 @@ func (t *ScoreTest) ParseError_NonHexCharacter() {
 	ExpectThat(err, Error(HasSubstr(in)))
 	ExpectThat(err, Error(HasSubstr("legal hex score")))
 }
+
+func (t *ScoreTest) ParseError_SHA256Short() {
+	in := "sha256-" + strings.Repeat("0", 63)
+	_, err := ParseHexScore(in)
+
+	ExpectThat(err, Error(HasSubstr(in)))
+	ExpectThat(err, Error(HasSubstr("legal hex score")))
+}
+
+func (t *ScoreTest) ParseError_UnknownAlgorithm() {
+	in := "sha512-" + strings.Repeat("0", 64)
+	_, err := ParseHexScore(in)
+
+	ExpectThat(err, Error(HasSubstr(in)))
+	ExpectThat(err, Error(HasSubstr("legal hex score")))
+}
+
+func (t *ScoreTest) SHA256() {
+	data := []byte("hello_0")
+	golden := "sha256-" +
+		"80676a4119ab0fe9df1f10a4fbdc92237f82a7818a1d4b64ab4805d964b870ca"
+
+	score := ComputeScoreWithAlgorithm(Algorithm_SHA256, data)
+	ExpectEq(Algorithm_SHA256, score.Algorithm())
+	AssertEq(32, len(score.Hash()))
+
+	ExpectEq(golden, score.Hex())
+
+	parsed, err := ParseHexScore(golden)
+	AssertEq(nil, err)
+	ExpectThat(parsed, DeepEquals(score))
+}
+
+func (t *ScoreTest) DefaultAlgorithm() {
+	score := ComputeScore([]byte("taco"))
+	ExpectEq(Algorithm_SHA256, score.Algorithm())
+	ExpectThat(
+		score,
+		DeepEquals(ComputeScoreWithAlgorithm(Algorithm_SHA256, []byte("taco"))))
+}
+
+func (t *ScoreTest) DifferentAlgorithmsAreDifferentScores() {
+	a := ComputeScoreWithAlgorithm(Algorithm_SHA1, []byte("taco"))
+	b := ComputeScoreWithAlgorithm(Algorithm_SHA256, []byte("taco"))
+
+	ExpectNe(a, b)
+	ExpectEq(-1, a.Compare(b))
+	ExpectEq(1, b.Compare(a))
+	ExpectEq(0, a.Compare(a))
+}
+
+func (t *ScoreTest) ZeroValue() {
+	var s Score
+	ExpectEq(Algorithm_SHA1, s.Algorithm())
+	ExpectEq(strings.Repeat("0", 40), s.Hex())
+}
+
+func (t *ScoreTest) Bytes_SHA1() {
+	score := ComputeScoreWithAlgorithm(Algorithm_SHA1, []byte("taco"))
+
+	// SHA-1 scores are the raw hash, as they always were.
+	b := score.Bytes()
+	ExpectThat(b, DeepEquals(score.Hash()))
+
+	parsed, err := ParseScoreBytes(b)
+	AssertEq(nil, err)
+	ExpectEq(score, parsed)
+}
+
+func (t *ScoreTest) Bytes_SHA256() {
+	score := ComputeScoreWithAlgorithm(Algorithm_SHA256, []byte("taco"))
+
+	b := score.Bytes()
+	AssertEq(33, len(b))
+	ExpectEq(byte(Algorithm_SHA256), b[0])
+	ExpectThat(b[1:], DeepEquals(score.Hash()))
+
+	parsed, err := ParseScoreBytes(b)
+	AssertEq(nil, err)
+	ExpectEq(score, parsed)
+}
+
+func (t *ScoreTest) ParseScoreBytes_Errors() {
+	var err error
+
+	// Empty
+	_, err = ParseScoreBytes(nil)
+	ExpectThat(err, Error(HasSubstr("length")))
+
+	// Wrong length for SHA-1
+	_, err = ParseScoreBytes(make([]byte, 19))
+	ExpectThat(err, Error(HasSubstr("length")))
+
+	// Wrong length for SHA-256
+	b := ComputeScoreWithAlgorithm(Algorithm_SHA256, nil).Bytes()
+	_, err = ParseScoreBytes(b[:len(b)-1])
+	ExpectThat(err, Error(HasSubstr("length")))
+
+	// Unknown algorithm
+	b[0] = 17
+	_, err = ParseScoreBytes(b)
+	ExpectThat(err, Error(HasSubstr("algorithm")))
+}
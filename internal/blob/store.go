@@ -24,14 +24,17 @@ type Store interface {
 		ctx context.Context,
 		req *SaveRequest) (s Score, err error)
 
-	// Load a previously-stored blob.
-	Load(ctx context.Context, s Score) (blob []byte, err error)
+	// Load a previously-stored blob of the given kind.
+	Load(ctx context.Context, s Score, kind Kind) (blob []byte, err error)
 }
 
 type SaveRequest struct {
 	// The blob data to be stored.
 	Blob []byte
 
+	// The kind of data in the blob.
+	Kind Kind
+
 	// The score of the blob, used in a conspiracy between existingScoresStore
 	// and downstream stores to avoid recomputing scores.
 	score Score
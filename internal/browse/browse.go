@@ -0,0 +1,169 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package browse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/repr"
+)
+
+// NotFoundError is returned by LookUp when the requested path doesn't exist
+// within the backup.
+type NotFoundError struct {
+	Path string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("No such file or directory in backup: %q", e.Path)
+}
+
+// Load the listing for the directory with the supplied score, sorted by name.
+func ReadDir(
+	ctx context.Context,
+	blobStore blob.Store,
+	score blob.Score) (entries []*fs.FileInfo, err error) {
+	// Load the blob.
+	contents, err := blobStore.Load(ctx, score, blob.Kind_Dir)
+	if err != nil {
+		err = fmt.Errorf("Load(%s): %v", score.Hex(), err)
+		return
+	}
+
+	// Parse it.
+	entries, err = repr.UnmarshalDir(contents)
+	if err != nil {
+		err = fmt.Errorf("UnmarshalDir(%s): %v", score.Hex(), err)
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return
+}
+
+// Return an entry for the root directory of the backup with the supplied
+// score. Backups don't record information about their root directories
+// beyond their contents, so only the type and score are filled in.
+func RootEntry(root blob.Score) (fi *fs.FileInfo) {
+	fi = &fs.FileInfo{
+		Type:   fs.TypeDirectory,
+		Scores: []blob.Score{root},
+	}
+
+	return
+}
+
+// Find the entry for the supplied slash-separated path relative to the root
+// of the backup with the given score. The empty path refers to the root
+// itself, for which RootEntry is returned. If any component of the path
+// doesn't exist, the error is a *NotFoundError.
+func LookUp(
+	ctx context.Context,
+	blobStore blob.Store,
+	root blob.Score,
+	relPath string) (fi *fs.FileInfo, err error) {
+	fi = RootEntry(root)
+
+	relPath = strings.Trim(path.Clean("/"+relPath), "/")
+	if relPath == "" {
+		return
+	}
+
+	for _, name := range strings.Split(relPath, "/") {
+		if fi.Type != fs.TypeDirectory {
+			err = &NotFoundError{Path: relPath}
+			return
+		}
+
+		var entries []*fs.FileInfo
+		entries, err = ReadDir(ctx, blobStore, fi.Scores[0])
+		if err != nil {
+			err = fmt.Errorf("ReadDir: %v", err)
+			return
+		}
+
+		i := sort.Search(len(entries), func(i int) bool {
+			return entries[i].Name >= name
+		})
+
+		if i == len(entries) || entries[i].Name != name {
+			err = &NotFoundError{Path: relPath}
+			return
+		}
+
+		fi = entries[i]
+	}
+
+	return
+}
+
+// SkipDir may be returned by a WalkFunc to prevent Walk from descending into
+// the directory with which it was called.
+var SkipDir = errors.New("skip this directory")
+
+// A WalkFunc is called by Walk for each entry it visits, with the entry's
+// path relative to the root of the backup.
+type WalkFunc func(relPath string, fi *fs.FileInfo) error
+
+// Call the supplied function for each entry within the directory with the
+// supplied entry and relative path, recursively, in depth-first lexical
+// order. Any error returned by the function other than SkipDir ends the walk
+// and is returned.
+func Walk(
+	ctx context.Context,
+	blobStore blob.Store,
+	relPath string,
+	dir *fs.FileInfo,
+	fn WalkFunc) (err error) {
+	entries, err := ReadDir(ctx, blobStore, dir.Scores[0])
+	if err != nil {
+		err = fmt.Errorf("ReadDir(%q): %v", relPath, err)
+		return
+	}
+
+	for _, e := range entries {
+		childPath := path.Join(relPath, e.Name)
+
+		err = fn(childPath, e)
+		if err == SkipDir {
+			err = nil
+			continue
+		}
+
+		if err != nil {
+			return
+		}
+
+		if e.Type == fs.TypeDirectory {
+			err = Walk(ctx, blobStore, childPath, e, fn)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
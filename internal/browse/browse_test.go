@@ -0,0 +1,263 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package browse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/repr"
+	"github.com/jacobsa/comeback/internal/util"
+	"github.com/jacobsa/gcloud/gcs/gcsfake"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+	"github.com/jacobsa/timeutil"
+)
+
+func TestBrowse(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type BrowseTest struct {
+	ctx       context.Context
+	blobStore blob.Store
+
+	// The root of a backup with the following structure:
+	//
+	//	a.txt       (3 bytes, modified 2026-01-10)
+	//	link -> a.txt (modified 2026-01-09)
+	//	photos/
+	//	    x.jpg   (2048 bytes, modified 2026-01-01)
+	//	    y.png   (10 bytes, modified 2026-01-05)
+	root blob.Score
+}
+
+var _ SetUpInterface = &BrowseTest{}
+
+func init() { RegisterTestSuite(&BrowseTest{}) }
+
+func (t *BrowseTest) SetUp(ti *TestInfo) {
+	t.ctx = ti.Ctx
+
+	bucket := gcsfake.NewFakeBucket(timeutil.RealClock(), "some_bucket")
+	t.blobStore = blob.NewExistingScoresStore(
+		util.NewStringSet(),
+		blob.NewGCSStore(bucket, "blobs/"))
+
+	photos := t.storeDir(
+		&fs.FileInfo{
+			Type:  fs.TypeFile,
+			Name:  "y.png",
+			Size:  10,
+			MTime: t.date(5),
+		},
+		&fs.FileInfo{
+			Type:  fs.TypeFile,
+			Name:  "x.jpg",
+			Size:  2048,
+			MTime: t.date(1),
+		})
+
+	t.root = t.storeDir(
+		&fs.FileInfo{
+			Type:   fs.TypeDirectory,
+			Name:   "photos",
+			MTime:  t.date(5),
+			Scores: []blob.Score{photos},
+		},
+		&fs.FileInfo{
+			Type:  fs.TypeFile,
+			Name:  "a.txt",
+			Size:  3,
+			MTime: t.date(10),
+		},
+		&fs.FileInfo{
+			Type:   fs.TypeSymlink,
+			Name:   "link",
+			MTime:  t.date(9),
+			Target: "a.txt",
+		})
+}
+
+func (t *BrowseTest) date(day int) time.Time {
+	return time.Date(2026, time.January, day, 0, 0, 0, 0, time.UTC)
+}
+
+func (t *BrowseTest) storeDir(entries ...*fs.FileInfo) (s blob.Score) {
+	b, err := repr.MarshalDir(entries)
+	AssertEq(nil, err)
+
+	s, err = t.blobStore.Save(t.ctx, &blob.SaveRequest{Blob: b, Kind: blob.Kind_Dir})
+	AssertEq(nil, err)
+
+	return
+}
+
+// Walk the whole backup, returning the paths accepted by the predicate.
+func (t *BrowseTest) find(p Predicate) (paths []string) {
+	err := Walk(
+		t.ctx,
+		t.blobStore,
+		"",
+		RootEntry(t.root),
+		func(relPath string, fi *fs.FileInfo) (err error) {
+			if p(relPath, fi) {
+				paths = append(paths, relPath)
+			}
+
+			return
+		})
+
+	AssertEq(nil, err)
+	return
+}
+
+func (t *BrowseTest) mustPredicate(p Predicate, err error) Predicate {
+	AssertEq(nil, err)
+	return p
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *BrowseTest) ReadDir_Sorted() {
+	entries, err := ReadDir(t.ctx, t.blobStore, t.root)
+	AssertEq(nil, err)
+
+	AssertEq(3, len(entries))
+	ExpectEq("a.txt", entries[0].Name)
+	ExpectEq("link", entries[1].Name)
+	ExpectEq("photos", entries[2].Name)
+}
+
+func (t *BrowseTest) LookUp_Root() {
+	fi, err := LookUp(t.ctx, t.blobStore, t.root, "")
+	AssertEq(nil, err)
+
+	ExpectEq(fs.TypeDirectory, fi.Type)
+	ExpectThat(fi.Scores, ElementsAre(t.root))
+}
+
+func (t *BrowseTest) LookUp_Nested() {
+	fi, err := LookUp(t.ctx, t.blobStore, t.root, "/photos//x.jpg")
+	AssertEq(nil, err)
+
+	ExpectEq("x.jpg", fi.Name)
+	ExpectEq(2048, fi.Size)
+}
+
+func (t *BrowseTest) LookUp_Missing() {
+	_, err := LookUp(t.ctx, t.blobStore, t.root, "photos/z.jpg")
+
+	_, ok := err.(*NotFoundError)
+	ExpectTrue(ok, "err: %v", err)
+}
+
+func (t *BrowseTest) LookUp_ThroughFile() {
+	_, err := LookUp(t.ctx, t.blobStore, t.root, "a.txt/foo")
+
+	_, ok := err.(*NotFoundError)
+	ExpectTrue(ok, "err: %v", err)
+}
+
+func (t *BrowseTest) Walk_Everything() {
+	paths := t.find(All())
+	ExpectThat(
+		paths,
+		ElementsAre("a.txt", "link", "photos", "photos/x.jpg", "photos/y.png"))
+}
+
+func (t *BrowseTest) Walk_SkipDir() {
+	var paths []string
+	err := Walk(
+		t.ctx,
+		t.blobStore,
+		"",
+		RootEntry(t.root),
+		func(relPath string, fi *fs.FileInfo) (err error) {
+			paths = append(paths, relPath)
+			if fi.Type == fs.TypeDirectory {
+				err = SkipDir
+			}
+
+			return
+		})
+
+	AssertEq(nil, err)
+	ExpectThat(paths, ElementsAre("a.txt", "link", "photos"))
+}
+
+func (t *BrowseTest) Find_Name() {
+	p := t.mustPredicate(NameMatches("*.jpg"))
+	ExpectThat(t.find(p), ElementsAre("photos/x.jpg"))
+}
+
+func (t *BrowseTest) Find_InvalidName() {
+	_, err := NameMatches("[")
+	ExpectThat(err, Error(HasSubstr("Invalid pattern")))
+}
+
+func (t *BrowseTest) Find_Type() {
+	p := t.mustPredicate(TypeIs("d"))
+	ExpectThat(t.find(p), ElementsAre("photos"))
+
+	p = t.mustPredicate(TypeIs("l"))
+	ExpectThat(t.find(p), ElementsAre("link"))
+
+	_, err := TypeIs("x")
+	ExpectThat(err, Error(HasSubstr("Unknown type")))
+}
+
+func (t *BrowseTest) Find_Size() {
+	p := t.mustPredicate(SizeMatches("+1k"))
+	ExpectThat(t.find(p), ElementsAre("photos/x.jpg"))
+
+	p = t.mustPredicate(SizeMatches("-10"))
+	ExpectThat(t.find(p), ElementsAre("a.txt"))
+
+	p = t.mustPredicate(SizeMatches("10"))
+	ExpectThat(t.find(p), ElementsAre("photos/y.png"))
+
+	_, err := SizeMatches("+taco")
+	ExpectThat(err, Error(HasSubstr("Invalid size")))
+}
+
+func (t *BrowseTest) Find_MTime() {
+	now := t.date(11).Add(time.Hour)
+
+	p := t.mustPredicate(MTimeMatches("-2", now))
+	ExpectThat(t.find(p), ElementsAre("a.txt"))
+
+	p = t.mustPredicate(MTimeMatches("+7", now))
+	ExpectThat(t.find(p), ElementsAre("photos/x.jpg"))
+
+	p = t.mustPredicate(MTimeMatches("6", now))
+	ExpectThat(t.find(p), ElementsAre("photos", "photos/y.png"))
+}
+
+func (t *BrowseTest) Find_All() {
+	p := All(
+		t.mustPredicate(TypeIs("f")),
+		t.mustPredicate(NameMatches("*.png")))
+
+	ExpectThat(t.find(p), ElementsAre("photos/y.png"))
+}
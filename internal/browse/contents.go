@@ -0,0 +1,134 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package browse
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/repr"
+)
+
+// Write the contents of the supplied regular file to w.
+//
+// Several chunks are loaded at once so that throughput isn't limited by the
+// latency of a single request, though they are written strictly in order.
+func WriteContents(
+	ctx context.Context,
+	blobStore blob.Store,
+	fi *fs.FileInfo,
+	w io.Writer) (err error) {
+	if fi.Type != fs.TypeFile {
+		err = fmt.Errorf("%q is not a regular file", fi.Name)
+		return
+	}
+
+	if fi.HardLinkTarget != nil {
+		err = fmt.Errorf("%q is a hard link, which is not supported", fi.Name)
+		return
+	}
+
+	// The maximum number of chunks loaded but not yet written, or being loaded.
+	const prefetchDepth = 8
+
+	eg, ctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, prefetchDepth)
+
+	// Each chunk is delivered on its own channel, so that they can be written
+	// in order.
+	chunks := make([]chan []byte, len(fi.Scores))
+	for i := range chunks {
+		chunks[i] = make(chan []byte, 1)
+	}
+
+	// Start loading chunks in order, leaving room for writing to keep up.
+	eg.Go(func() (err error) {
+		for i, s := range fi.Scores {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			}
+
+			i, s := i, s
+			eg.Go(func() (err error) {
+				chunk, err := loadChunk(ctx, blobStore, s)
+				if err != nil {
+					return
+				}
+
+				chunks[i] <- chunk
+				return
+			})
+		}
+
+		return
+	})
+
+	// Write them out in order.
+	eg.Go(func() (err error) {
+		for i := range chunks {
+			var chunk []byte
+			select {
+			case chunk = <-chunks[i]:
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			}
+
+			<-sem
+
+			_, err = w.Write(chunk)
+			if err != nil {
+				err = fmt.Errorf("Write: %v", err)
+				return
+			}
+		}
+
+		return
+	})
+
+	err = eg.Wait()
+	return
+}
+
+// Load and unmarshal the file chunk with the supplied score.
+func loadChunk(
+	ctx context.Context,
+	blobStore blob.Store,
+	s blob.Score) (chunk []byte, err error) {
+	// Load.
+	chunk, err = blobStore.Load(ctx, s, blob.Kind_File)
+	if err != nil {
+		err = fmt.Errorf("Load(%s): %v", s.Hex(), err)
+		return
+	}
+
+	// Unmarshal.
+	chunk, err = repr.UnmarshalFile(chunk)
+	if err != nil {
+		err = fmt.Errorf("UnmarshalFile(%s): %v", s.Hex(), err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package browse
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/repr"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+)
+
+// Store the supplied contents as a file chunk, returning its score.
+func (t *BrowseTest) storeChunk(contents string) (s blob.Score) {
+	b, err := repr.MarshalFile([]byte(contents))
+	AssertEq(nil, err)
+
+	s, err = t.blobStore.Save(t.ctx, &blob.SaveRequest{Blob: b, Kind: blob.Kind_File})
+	AssertEq(nil, err)
+
+	return
+}
+
+func (t *BrowseTest) WriteContents() {
+	fi := &fs.FileInfo{
+		Type:   fs.TypeFile,
+		Name:   "foo",
+		Scores: []blob.Score{t.storeChunk("taco"), t.storeChunk("burrito")},
+	}
+
+	var buf bytes.Buffer
+	err := WriteContents(t.ctx, t.blobStore, fi, &buf)
+	AssertEq(nil, err)
+	ExpectEq("tacoburrito", buf.String())
+}
+
+func (t *BrowseTest) WriteContents_NotAFile() {
+	var buf bytes.Buffer
+	err := WriteContents(t.ctx, t.blobStore, RootEntry(t.root), &buf)
+	ExpectThat(err, Error(HasSubstr("not a regular file")))
+}
+
+func (t *BrowseTest) WriteContents_ManyChunks() {
+	fi := &fs.FileInfo{
+		Type: fs.TypeFile,
+		Name: "foo",
+	}
+
+	var expected string
+	for i := 0; i < 50; i++ {
+		contents := fmt.Sprintf("%d,", i)
+		fi.Scores = append(fi.Scores, t.storeChunk(contents))
+		expected += contents
+	}
+
+	var buf bytes.Buffer
+	err := WriteContents(t.ctx, t.blobStore, fi, &buf)
+	AssertEq(nil, err)
+	ExpectEq(expected, buf.String())
+}
+
+func (t *BrowseTest) WriteContents_MissingChunk() {
+	missing := blob.ComputeScore([]byte("enchilada"))
+	fi := &fs.FileInfo{
+		Type: fs.TypeFile,
+		Name: "foo",
+		Scores: []blob.Score{
+			t.storeChunk("taco"),
+			missing,
+			t.storeChunk("burrito"),
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteContents(t.ctx, t.blobStore, fi, &buf)
+	ExpectThat(err, Error(HasSubstr(missing.Hex())))
+}
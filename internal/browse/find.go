@@ -0,0 +1,177 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package browse
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/fs"
+)
+
+// A Predicate decides whether an entry visited by Walk should be reported.
+type Predicate func(relPath string, fi *fs.FileInfo) bool
+
+// Return a predicate that is satisfied only when all of the supplied
+// predicates are.
+func All(preds ...Predicate) Predicate {
+	return func(relPath string, fi *fs.FileInfo) bool {
+		for _, p := range preds {
+			if !p(relPath, fi) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Return a predicate matching entries whose names match the supplied glob
+// pattern, in the syntax of path.Match.
+func NameMatches(pattern string) (p Predicate, err error) {
+	// Check the pattern's syntax up front.
+	_, err = path.Match(pattern, "")
+	if err != nil {
+		err = fmt.Errorf("Invalid pattern %q: %v", pattern, err)
+		return
+	}
+
+	p = func(relPath string, fi *fs.FileInfo) bool {
+		matched, _ := path.Match(pattern, path.Base(relPath))
+		return matched
+	}
+
+	return
+}
+
+var typeLetters = map[string]fs.Type{
+	"f": fs.TypeFile,
+	"d": fs.TypeDirectory,
+	"l": fs.TypeSymlink,
+	"b": fs.TypeBlockDevice,
+	"c": fs.TypeCharDevice,
+	"p": fs.TypeNamedPipe,
+	"s": fs.TypeSocket,
+}
+
+// Return a predicate matching entries of the type given by a letter as
+// accepted by find(1)'s -type: one of f, d, l, b, c, p, or s.
+func TypeIs(letter string) (p Predicate, err error) {
+	t, ok := typeLetters[letter]
+	if !ok {
+		err = fmt.Errorf("Unknown type %q", letter)
+		return
+	}
+
+	p = func(relPath string, fi *fs.FileInfo) bool {
+		return fi.Type == t
+	}
+
+	return
+}
+
+// Parse a size comparison in the style of find(1)'s -size: a number of bytes
+// with an optional k, M, or G suffix (powers of 1024), optionally preceded by
+// '+' meaning "more than" or '-' meaning "less than". Without a sign the size
+// must match exactly. Only regular files match.
+func SizeMatches(spec string) (p Predicate, err error) {
+	sign, rest := splitSign(spec)
+
+	multiplier := uint64(1)
+	if rest != "" {
+		switch rest[len(rest)-1] {
+		case 'k':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		}
+
+		if multiplier != 1 {
+			rest = rest[:len(rest)-1]
+		}
+	}
+
+	n, err := strconv.ParseUint(rest, 10, 64)
+	if err != nil {
+		err = fmt.Errorf("Invalid size %q", spec)
+		return
+	}
+
+	size := n * multiplier
+	p = func(relPath string, fi *fs.FileInfo) bool {
+		if fi.Type != fs.TypeFile {
+			return false
+		}
+
+		switch sign {
+		case '+':
+			return fi.Size > size
+		case '-':
+			return fi.Size < size
+		default:
+			return fi.Size == size
+		}
+	}
+
+	return
+}
+
+// Parse a modification time comparison in the style of find(1)'s -mtime: a
+// number of days, optionally preceded by '+' meaning "longer ago than" or '-'
+// meaning "more recently than". Without a sign the age in whole days must
+// match exactly. Ages are measured from the supplied time.
+func MTimeMatches(spec string, now time.Time) (p Predicate, err error) {
+	sign, rest := splitSign(spec)
+
+	days, err := strconv.ParseUint(rest, 10, 32)
+	if err != nil {
+		err = fmt.Errorf("Invalid number of days %q", spec)
+		return
+	}
+
+	const day = 24 * time.Hour
+	limit := time.Duration(days) * day
+
+	p = func(relPath string, fi *fs.FileInfo) bool {
+		age := now.Sub(fi.MTime)
+
+		switch sign {
+		case '+':
+			return age > limit
+		case '-':
+			return age < limit
+		default:
+			return age >= limit && age < limit+day
+		}
+	}
+
+	return
+}
+
+func splitSign(spec string) (sign byte, rest string) {
+	rest = spec
+	if strings.HasPrefix(rest, "+") || strings.HasPrefix(rest, "-") {
+		sign = rest[0]
+		rest = rest[1:]
+	}
+
+	return
+}
@@ -0,0 +1,180 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package browse
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/registry"
+)
+
+// A Version is a distinct version of a path, as seen in a run of consecutive
+// backups.
+type Version struct {
+	// The oldest and newest backups in the run.
+	FirstSeen registry.CompletedJob
+	LastSeen  registry.CompletedJob
+
+	// The number of backups in the run.
+	Count int
+
+	// The entry for the path, as it appears in the newest backup in the run.
+	Info *fs.FileInfo
+}
+
+// Find the distinct versions of the supplied path among the given backups,
+// oldest first. Consecutive backups in which the entry has the same type,
+// scores, and symlink target are collapsed into a single version, and
+// backups in which the path doesn't exist separate versions.
+//
+// Directory listings are loaded at most once, so backups that share
+// unchanged directories along the path cost little.
+func History(
+	ctx context.Context,
+	blobStore blob.Store,
+	jobs []registry.CompletedJob,
+	relPath string) (versions []Version, err error) {
+	// Process backups in order.
+	jobs = append([]registry.CompletedJob(nil), jobs...)
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].StartTime.Before(jobs[j].StartTime)
+	})
+
+	relPath = strings.Trim(path.Clean("/"+relPath), "/")
+	if relPath == "" {
+		err = fmt.Errorf("History requires a path within the backup")
+		return
+	}
+
+	l := &memoizedLookUp{
+		ctx:       ctx,
+		blobStore: blobStore,
+		listings:  make(map[blob.Score][]*fs.FileInfo),
+	}
+
+	var current *Version
+	for _, j := range jobs {
+		var fi *fs.FileInfo
+		fi, err = l.LookUp(j.Score, relPath)
+		if err != nil {
+			err = fmt.Errorf("LookUp in backup %s: %v", j.Score.Hex(), err)
+			return
+		}
+
+		// A gap ends the current version.
+		if fi == nil {
+			current = nil
+			continue
+		}
+
+		// Extend the current version if nothing has changed.
+		if current != nil && sameVersion(current.Info, fi) {
+			current.LastSeen = j
+			current.Count++
+			current.Info = fi
+			continue
+		}
+
+		versions = append(versions, Version{
+			FirstSeen: j,
+			LastSeen:  j,
+			Count:     1,
+			Info:      fi,
+		})
+
+		current = &versions[len(versions)-1]
+	}
+
+	return
+}
+
+func sameVersion(a *fs.FileInfo, b *fs.FileInfo) bool {
+	if a.Type != b.Type || a.Target != b.Target || len(a.Scores) != len(b.Scores) {
+		return false
+	}
+
+	for i := range a.Scores {
+		if a.Scores[i] != b.Scores[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Looks up paths, remembering the directory listings it has loaded.
+type memoizedLookUp struct {
+	ctx       context.Context
+	blobStore blob.Store
+	listings  map[blob.Score][]*fs.FileInfo
+}
+
+// Return the entry for the supplied cleaned, non-empty path within the backup
+// with the given root, or nil if it doesn't exist.
+func (l *memoizedLookUp) LookUp(
+	root blob.Score,
+	relPath string) (fi *fs.FileInfo, err error) {
+	dir := RootEntry(root)
+	for _, name := range strings.Split(relPath, "/") {
+		if dir.Type != fs.TypeDirectory {
+			fi = nil
+			return
+		}
+
+		var entries []*fs.FileInfo
+		entries, err = l.readDir(dir.Scores[0])
+		if err != nil {
+			return
+		}
+
+		i := sort.Search(len(entries), func(i int) bool {
+			return entries[i].Name >= name
+		})
+
+		if i == len(entries) || entries[i].Name != name {
+			fi = nil
+			return
+		}
+
+		fi = entries[i]
+		dir = fi
+	}
+
+	return
+}
+
+func (l *memoizedLookUp) readDir(
+	score blob.Score) (entries []*fs.FileInfo, err error) {
+	entries, ok := l.listings[score]
+	if ok {
+		return
+	}
+
+	entries, err = ReadDir(l.ctx, l.blobStore, score)
+	if err != nil {
+		err = fmt.Errorf("ReadDir: %v", err)
+		return
+	}
+
+	l.listings[score] = entries
+	return
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package browse
+
+import (
+	"time"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/registry"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+)
+
+// Return a backup of job "j" started on the given day of January 2026 whose
+// root contains "etc/config.yaml" with the given contents, or nothing if the
+// contents are empty.
+func (t *BrowseTest) configBackup(day int, contents string) registry.CompletedJob {
+	var entries []*fs.FileInfo
+	if contents != "" {
+		entries = append(entries, &fs.FileInfo{
+			Type:   fs.TypeFile,
+			Name:   "config.yaml",
+			Size:   uint64(len(contents)),
+			MTime:  t.date(day),
+			Scores: []blob.Score{t.storeChunk(contents)},
+		})
+	}
+
+	etc := t.storeDir(entries...)
+	root := t.storeDir(&fs.FileInfo{
+		Type:   fs.TypeDirectory,
+		Name:   "etc",
+		Scores: []blob.Score{etc},
+	})
+
+	return registry.CompletedJob{
+		Name:      "j",
+		StartTime: t.date(day).Add(time.Hour),
+		Score:     root,
+	}
+}
+
+func (t *BrowseTest) History_CollapsesAndSplits() {
+	jobs := []registry.CompletedJob{
+		// Deliberately out of order.
+		t.configBackup(3, "a"),
+		t.configBackup(1, "a"),
+		t.configBackup(2, "a"),
+		t.configBackup(4, "b"),
+		t.configBackup(5, ""),
+		t.configBackup(6, "b"),
+		t.configBackup(7, "a"),
+	}
+
+	versions, err := History(t.ctx, t.blobStore, jobs, "/etc/config.yaml")
+	AssertEq(nil, err)
+	AssertEq(4, len(versions))
+
+	ExpectThat(versions[0].FirstSeen.StartTime, DeepEquals(jobs[1].StartTime))
+	ExpectThat(versions[0].LastSeen.StartTime, DeepEquals(jobs[0].StartTime))
+	ExpectEq(3, versions[0].Count)
+	ExpectEq(1, versions[0].Info.Size)
+
+	ExpectEq(jobs[3].Score, versions[1].FirstSeen.Score)
+	ExpectEq(jobs[3].Score, versions[1].LastSeen.Score)
+	ExpectEq(1, versions[1].Count)
+
+	// The gap separates two versions with the same contents.
+	ExpectEq(jobs[5].Score, versions[2].FirstSeen.Score)
+	ExpectEq(jobs[6].Score, versions[3].FirstSeen.Score)
+}
+
+func (t *BrowseTest) History_NeverPresent() {
+	jobs := []registry.CompletedJob{t.configBackup(1, "")}
+
+	versions, err := History(t.ctx, t.blobStore, jobs, "etc/config.yaml")
+	AssertEq(nil, err)
+	ExpectThat(versions, ElementsAre())
+}
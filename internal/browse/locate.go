@@ -0,0 +1,131 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package browse
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/fs"
+)
+
+// A Locator finds regular files with particular contents within backups.
+//
+// It remembers the results for each directory it has searched, keyed by the
+// directory's score. Since successive backups usually share most of their
+// directories, searching many backups with the same Locator loads little more
+// than searching one.
+type Locator struct {
+	blobStore blob.Store
+
+	// Alternative lists of scores for the file being sought, one for each
+	// format in which its contents may have been saved.
+	candidates [][]blob.Score
+
+	// The paths of matching files within each directory searched, relative to
+	// that directory.
+	memo map[blob.Score][]string
+}
+
+// Create a Locator that looks for files whose contents have exactly one of
+// the supplied lists of scores, as computed by save.ScoreFileInFormat.
+func NewLocator(
+	blobStore blob.Store,
+	candidates [][]blob.Score) (l *Locator) {
+	l = &Locator{
+		blobStore:  blobStore,
+		candidates: candidates,
+		memo:       make(map[blob.Score][]string),
+	}
+
+	return
+}
+
+// Return the paths of the matching files within the backup with the supplied
+// root score.
+func (l *Locator) Locate(
+	ctx context.Context,
+	root blob.Score) (paths []string, err error) {
+	paths, err = l.search(ctx, root)
+	return
+}
+
+func (l *Locator) search(
+	ctx context.Context,
+	dir blob.Score) (paths []string, err error) {
+	// Have we already been here?
+	paths, ok := l.memo[dir]
+	if ok {
+		return
+	}
+
+	entries, err := ReadDir(ctx, l.blobStore, dir)
+	if err != nil {
+		err = fmt.Errorf("ReadDir: %v", err)
+		return
+	}
+
+	for _, e := range entries {
+		switch {
+		case e.Type == fs.TypeFile && l.matches(e):
+			paths = append(paths, e.Name)
+
+		case e.Type == fs.TypeDirectory:
+			var children []string
+			children, err = l.search(ctx, e.Scores[0])
+			if err != nil {
+				return
+			}
+
+			for _, c := range children {
+				paths = append(paths, path.Join(e.Name, c))
+			}
+		}
+	}
+
+	l.memo[dir] = paths
+	return
+}
+
+func (l *Locator) matches(fi *fs.FileInfo) bool {
+	if fi.HardLinkTarget != nil {
+		return false
+	}
+
+	for _, scores := range l.candidates {
+		if scoresEqual(fi.Scores, scores) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func scoresEqual(a, b []blob.Score) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
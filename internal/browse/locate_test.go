@@ -0,0 +1,107 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package browse
+
+import (
+	"context"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/fs"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+)
+
+// A blob store that counts the loads made through it.
+type loadCountingStore struct {
+	blob.Store
+	loads int
+}
+
+func (s *loadCountingStore) Load(
+	ctx context.Context,
+	score blob.Score,
+	kind blob.Kind) (b []byte, err error) {
+	s.loads++
+	b, err = s.Store.Load(ctx, score, kind)
+	return
+}
+
+func (t *BrowseTest) Locate() {
+	target := []blob.Score{t.storeChunk("taco"), t.storeChunk("burrito")}
+	file := func(name string, scores []blob.Score) *fs.FileInfo {
+		return &fs.FileInfo{Type: fs.TypeFile, Name: name, Scores: scores}
+	}
+
+	dir := func(name string, s blob.Score) *fs.FileInfo {
+		return &fs.FileInfo{
+			Type:   fs.TypeDirectory,
+			Name:   name,
+			Scores: []blob.Score{s},
+		}
+	}
+
+	// Two backups sharing a subdirectory.
+	shared := t.storeDir(
+		file("copy", target),
+		file("prefix", target[:1]),
+		file("other", []blob.Score{t.storeChunk("enchilada")}))
+
+	root0 := t.storeDir(dir("shared", shared), file("orig", target))
+	root1 := t.storeDir(dir("shared", shared), dir("moved", t.storeDir(file("orig", target))))
+
+	store := &loadCountingStore{Store: t.blobStore}
+	l := NewLocator(store, [][]blob.Score{target})
+
+	paths, err := l.Locate(t.ctx, root0)
+	AssertEq(nil, err)
+	ExpectThat(paths, ElementsAre("orig", "shared/copy"))
+
+	// The shared directory shouldn't be loaded again.
+	store.loads = 0
+	paths, err = l.Locate(t.ctx, root1)
+	AssertEq(nil, err)
+	ExpectThat(paths, ElementsAre("moved/orig", "shared/copy"))
+	ExpectEq(2, store.loads)
+
+	// Nor should anything, the second time around.
+	store.loads = 0
+	paths, err = l.Locate(t.ctx, root0)
+	AssertEq(nil, err)
+	ExpectThat(paths, ElementsAre("orig", "shared/copy"))
+	ExpectEq(0, store.loads)
+}
+
+func (t *BrowseTest) Locate_MultipleCandidates() {
+	// The same contents, saved by an older and a newer version.
+	older := []blob.Score{t.storeChunk("taco (older)")}
+	newer := []blob.Score{t.storeChunk("taco (newer)")}
+
+	file := func(name string, scores []blob.Score) *fs.FileInfo {
+		return &fs.FileInfo{Type: fs.TypeFile, Name: name, Scores: scores}
+	}
+
+	root := t.storeDir(
+		file("old", older),
+		file("new", newer),
+		file("mixed", []blob.Score{older[0], newer[0]}),
+		file("other", []blob.Score{t.storeChunk("enchilada")}))
+
+	l := NewLocator(t.blobStore, [][]blob.Score{newer, older})
+
+	paths, err := l.Locate(t.ctx, root)
+	AssertEq(nil, err)
+	ExpectThat(paths, ElementsAre("new", "old"))
+}
@@ -105,7 +105,7 @@ func (d *dirInode) ensureChildren(ctx context.Context) (err error) {
 	}
 
 	// Read the blob.
-	contents, err := d.blobStore.Load(ctx, d.score)
+	contents, err := d.blobStore.Load(ctx, d.score, blob.Kind_Dir)
 	if err != nil {
 		err = fmt.Errorf("blobStore.Load: %v", err)
 		return
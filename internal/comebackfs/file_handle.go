@@ -94,7 +94,7 @@ func (fh *fileHandle) ensureFile(ctx context.Context) (err error) {
 		var p []byte
 
 		// Load a chunk.
-		p, err = fh.blobStore.Load(ctx, s)
+		p, err = fh.blobStore.Load(ctx, s, blob.Kind_File)
 		if err != nil {
 			err = fmt.Errorf("Load(%s): %v", s.Hex(), err)
 			return
@@ -19,18 +19,76 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/retention"
 )
 
+type jsonRetention struct {
+	KeepLast    int      `json:"keep_last"`
+	KeepHourly  int      `json:"keep_hourly"`
+	KeepDaily   int      `json:"keep_daily"`
+	KeepWeekly  int      `json:"keep_weekly"`
+	KeepMonthly int      `json:"keep_monthly"`
+	KeepYearly  int      `json:"keep_yearly"`
+	KeepWithin  string   `json:"keep_within"`
+	KeepTags    []string `json:"keep_tags"`
+}
+
 type jsonJob struct {
-	BasePath string   `json:"base_path"`
-	Excludes []string `json:"excludes"`
+	BasePath  string         `json:"base_path"`
+	Excludes  []string       `json:"excludes"`
+	Retention *jsonRetention `json:"retention"`
+}
+
+// Parse a duration as accepted by time.ParseDuration, or a whole number of
+// days such as "30d".
+func parseDuration(s string) (d time.Duration, err error) {
+	if strings.HasSuffix(s, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err == nil {
+			d = time.Duration(days) * 24 * time.Hour
+			return
+		}
+	}
+
+	d, err = time.ParseDuration(s)
+	return
+}
+
+func convertRetention(jr *jsonRetention) (p *retention.Policy, err error) {
+	p = &retention.Policy{
+		Last:    jr.KeepLast,
+		Hourly:  jr.KeepHourly,
+		Daily:   jr.KeepDaily,
+		Weekly:  jr.KeepWeekly,
+		Monthly: jr.KeepMonthly,
+		Yearly:  jr.KeepYearly,
+		Tags:    jr.KeepTags,
+	}
+
+	if jr.KeepWithin != "" {
+		p.Within, err = parseDuration(jr.KeepWithin)
+		if err != nil {
+			err = fmt.Errorf("Parsing keep_within: %v", err)
+			return
+		}
+	}
+
+	return
 }
 
 type jsonConfig struct {
-	Jobs       map[string]*jsonJob `json:"jobs"`
-	KeyFile    string              `json:"key_file"`
-	BucketName string              `json:"bucket"`
-	StateFile  string              `json:"state_file"`
+	Jobs               map[string]*jsonJob `json:"jobs"`
+	KeyFile            string              `json:"key_file"`
+	BucketName         string              `json:"bucket"`
+	StateFile          string              `json:"state_file"`
+	EnvelopeEncryption bool                `json:"envelope_encryption"`
+	PasswordFile       string              `json:"password_file"`
+	PasswordCommand    []string            `json:"password_command"`
 }
 
 // Parse the supplied JSON configuration data.
@@ -47,6 +105,10 @@ func Parse(data []byte) (*Config, error) {
 		KeyFile:    jCfg.KeyFile,
 		BucketName: jCfg.BucketName,
 		StateFile:  jCfg.StateFile,
+
+		EnvelopeEncryption: jCfg.EnvelopeEncryption,
+		PasswordFile:       jCfg.PasswordFile,
+		PasswordCommand:    jCfg.PasswordCommand,
 	}
 
 	for name, jJob := range jCfg.Jobs {
@@ -62,6 +124,14 @@ func Parse(data []byte) (*Config, error) {
 			job.Excludes = append(job.Excludes, re)
 		}
 
+		if jJob.Retention != nil {
+			var err error
+			job.Retention, err = convertRetention(jJob.Retention)
+			if err != nil {
+				return nil, fmt.Errorf("Job %s: %v", name, err)
+			}
+		}
+
 		cfg.Jobs[name] = job
 	}
 
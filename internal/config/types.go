@@ -15,7 +15,11 @@
 
 package config
 
-import "regexp"
+import (
+	"regexp"
+
+	"github.com/jacobsa/comeback/internal/retention"
+)
 
 type Job struct {
 	// The path on the file system that should be backed up.
@@ -26,6 +30,10 @@ type Job struct {
 	// these, it will be excluded from the backup. If the path represents a
 	// directory, its contents will also be excluded.
 	Excludes []*regexp.Regexp
+
+	// The rules used by `comeback forget` to decide which of the job's backups
+	// to keep. If nil, the job's backups are never forgotten.
+	Retention *retention.Policy
 }
 
 type Config struct {
@@ -39,6 +47,34 @@ type Config struct {
 	// information.
 	BucketName string
 
-	// A file on the local machine where state can be saved between runs.
+	// A file on the local machine where state can be saved between runs. It is
+	// encrypted with a key derived from the bucket's key, and rebuilt if it
+	// can't be read with that key.
 	StateFile string
+
+	// If set when the repository is created with `comeback init`, encrypt
+	// backups to a public key so that write-only key slots can be added with
+	// `comeback add_key --write_only`. Has no effect on existing repositories.
+	EnvelopeEncryption bool
+
+	// A file containing the crypto password, used instead of prompting for it.
+	// A single trailing newline is ignored.
+	//
+	// The password is taken from the first of these sources that is set:
+	//
+	//  *  The environment variable COMEBACK_PASSWORD_FD, naming an open file
+	//     descriptor from which the password is read until EOF, as in
+	//     `COMEBACK_PASSWORD_FD=3 comeback save 3<pw`. A single trailing
+	//     newline is ignored, and the descriptor is closed afterward.
+	//  *  The environment variable COMEBACK_PASSWORD.
+	//  *  PasswordCommand.
+	//  *  PasswordFile.
+	//
+	// If none is set, the user is prompted.
+	PasswordFile string
+
+	// A program and its arguments that print the crypto password to stdout,
+	// for use with password managers and keyrings. A single trailing newline
+	// is ignored. Mutually exclusive with PasswordFile.
+	PasswordCommand []string
 }
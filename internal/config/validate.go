@@ -32,6 +32,23 @@ func validateJob(j Job) error {
 		return fmt.Errorf("Base paths must absolute.")
 	}
 
+	// Retention policies must keep something.
+	if p := j.Retention; p != nil {
+		if p.Last < 0 ||
+			p.Hourly < 0 ||
+			p.Daily < 0 ||
+			p.Weekly < 0 ||
+			p.Monthly < 0 ||
+			p.Yearly < 0 ||
+			p.Within < 0 {
+			return fmt.Errorf("Retention counts and durations must be non-negative.")
+		}
+
+		if p.Empty() {
+			return fmt.Errorf("Retention policies must have at least one rule.")
+		}
+	}
+
 	return nil
 }
 
@@ -65,5 +82,15 @@ func Validate(c *Config) error {
 		return fmt.Errorf("You must specify a state file path.")
 	}
 
+	// Validate password sources.
+	if c.PasswordFile != "" && len(c.PasswordCommand) != 0 {
+		return fmt.Errorf(
+			"password_file and password_command are mutually exclusive.")
+	}
+
+	if len(c.PasswordCommand) != 0 && c.PasswordCommand[0] == "" {
+		return fmt.Errorf("password_command must start with a program name.")
+	}
+
 	return nil
 }
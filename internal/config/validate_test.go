@@ -0,0 +1,89 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"testing"
+
+	"github.com/jacobsa/comeback/internal/config"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+)
+
+func TestValidate(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type ValidateTest struct {
+	// A config that is valid, for tests to modify.
+	cfg config.Config
+}
+
+var _ SetUpInterface = &ValidateTest{}
+
+func init() { RegisterTestSuite(&ValidateTest{}) }
+
+func (t *ValidateTest) SetUp(ti *TestInfo) {
+	t.cfg = config.Config{
+		KeyFile:    "/some/key.json",
+		BucketName: "some_bucket",
+		StateFile:  "/some/state",
+	}
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *ValidateTest) NoPasswordSource() {
+	ExpectEq(nil, config.Validate(&t.cfg))
+}
+
+func (t *ValidateTest) PasswordFile() {
+	t.cfg.PasswordFile = "/some/password"
+	ExpectEq(nil, config.Validate(&t.cfg))
+}
+
+func (t *ValidateTest) PasswordCommand() {
+	t.cfg.PasswordCommand = []string{"pass", "show", "comeback"}
+	ExpectEq(nil, config.Validate(&t.cfg))
+}
+
+func (t *ValidateTest) PasswordFileAndCommand() {
+	t.cfg.PasswordFile = "/some/password"
+	t.cfg.PasswordCommand = []string{"pass", "show", "comeback"}
+
+	err := config.Validate(&t.cfg)
+	ExpectThat(err, Error(HasSubstr("password_file")))
+	ExpectThat(err, Error(HasSubstr("password_command")))
+	ExpectThat(err, Error(HasSubstr("mutually exclusive")))
+}
+
+func (t *ValidateTest) PasswordCommandWithoutProgram() {
+	t.cfg.PasswordCommand = []string{"", "show", "comeback"}
+
+	err := config.Validate(&t.cfg)
+	ExpectThat(err, Error(HasSubstr("password_command")))
+	ExpectThat(err, Error(HasSubstr("program name")))
+}
+
+func (t *ValidateTest) EmptyPasswordCommand() {
+	// An empty list is the same as not setting the option.
+	t.cfg.PasswordCommand = []string{}
+	ExpectEq(nil, config.Validate(&t.cfg))
+}
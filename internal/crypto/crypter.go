@@ -16,6 +16,8 @@
 package crypto
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"fmt"
 
 	"github.com/jacobsa/crypto/siv"
@@ -23,10 +25,22 @@ import (
 
 // A Crypter knows how to encrypt and decrypt arbitrary byte strings.
 type Crypter interface {
-	// Append to dst and return updated slice.
-	Encrypt(dst, plaintext []byte) (result []byte, err error)
+	// Append to dst and return updated slice. The associated data, which may be
+	// nil, is authenticated but not encrypted; Decrypt succeeds only if given
+	// the same associated data.
+	Encrypt(
+		dst []byte,
+		plaintext []byte,
+		associated [][]byte) (result []byte, err error)
 
-	Decrypt(ciphertext []byte) (plaintext []byte, err error)
+	Decrypt(
+		ciphertext []byte,
+		associated [][]byte) (plaintext []byte, err error)
+
+	// Return a 32-byte key for some other purpose, derived from the crypter's
+	// secret and the supplied label. Every crypter for the same repository that
+	// is able to encrypt, including write-only ones, derives the same key.
+	DeriveSubkey(label string) []byte
 }
 
 // *NotAuthenticError may be returned by Crypter.Decrypt if the input is
@@ -57,15 +71,81 @@ type sivCrypter struct {
 	key []byte
 }
 
-func (c *sivCrypter) Encrypt(dst, plaintext []byte) ([]byte, error) {
-	return siv.Encrypt(dst, c.key, plaintext, nil)
+func (c *sivCrypter) Encrypt(
+	dst []byte,
+	plaintext []byte,
+	associated [][]byte) ([]byte, error) {
+	return siv.Encrypt(dst, c.key, plaintext, associated)
 }
 
-func (c *sivCrypter) Decrypt(ciphertext []byte) ([]byte, error) {
-	plaintext, err := siv.Decrypt(c.key, ciphertext, nil)
+func (c *sivCrypter) Decrypt(
+	ciphertext []byte,
+	associated [][]byte) ([]byte, error) {
+	plaintext, err := siv.Decrypt(c.key, ciphertext, associated)
 	if _, ok := err.(*siv.NotAuthenticError); ok {
 		err = &NotAuthenticError{err.Error()}
 	}
 
 	return plaintext, err
 }
+
+func (c *sivCrypter) DeriveSubkey(label string) []byte {
+	return subkeyMAC(c.key, label)
+}
+
+// Compute HMAC-SHA256 over the supplied label, for use by DeriveSubkey.
+func subkeyMAC(key []byte, label string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("comeback subkey"))
+	mac.Write([]byte{0})
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// Return a crypter that wraps the supplied one, adding the given repository ID
+// to the front of the associated data for each call that has any. Calls
+// without associated data are passed through unchanged, so that data written
+// in formats that predate repository IDs can still be read.
+func BindRepository(wrapped Crypter, repositoryID []byte) Crypter {
+	return &repositoryCrypter{wrapped, repositoryID}
+}
+
+// Return the repository ID bound into the supplied crypter by
+// BindRepository, or nil if there is none.
+func RepositoryID(c Crypter) []byte {
+	if rc, ok := c.(*repositoryCrypter); ok {
+		return rc.repositoryID
+	}
+
+	return nil
+}
+
+type repositoryCrypter struct {
+	wrapped      Crypter
+	repositoryID []byte
+}
+
+func (c *repositoryCrypter) bind(associated [][]byte) [][]byte {
+	if associated == nil {
+		return nil
+	}
+
+	return append([][]byte{c.repositoryID}, associated...)
+}
+
+func (c *repositoryCrypter) Encrypt(
+	dst []byte,
+	plaintext []byte,
+	associated [][]byte) ([]byte, error) {
+	return c.wrapped.Encrypt(dst, plaintext, c.bind(associated))
+}
+
+func (c *repositoryCrypter) Decrypt(
+	ciphertext []byte,
+	associated [][]byte) ([]byte, error) {
+	return c.wrapped.Decrypt(ciphertext, c.bind(associated))
+}
+
+func (c *repositoryCrypter) DeriveSubkey(label string) []byte {
+	return c.wrapped.DeriveSubkey(label)
+}
@@ -16,6 +16,7 @@
 package crypto_test
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/jacobsa/comeback/internal/crypto"
@@ -66,11 +67,11 @@ func (t *CrypterTest) RoundTrip() {
 	msg := []byte{0xde, 0xad, 0xbe, 0xef}
 
 	// Encrypt
-	ciphertext, err := crypter.Encrypt(nil, msg)
+	ciphertext, err := crypter.Encrypt(nil, msg, nil)
 	AssertEq(nil, err)
 
 	// Decrypt
-	plaintext, err := crypter.Decrypt(ciphertext)
+	plaintext, err := crypter.Decrypt(ciphertext, nil)
 	AssertEq(nil, err)
 
 	ExpectThat(plaintext, DeepEquals(msg))
@@ -84,16 +85,49 @@ func (t *CrypterTest) CorruptedCiphertext() {
 	msg := []byte{0xde, 0xad, 0xbe, 0xef}
 
 	// Encrypt
-	ciphertext, err := crypter.Encrypt(nil, msg)
+	ciphertext, err := crypter.Encrypt(nil, msg, nil)
 	AssertEq(nil, err)
 
 	AssertGt(len(ciphertext), 2)
 	ciphertext[2]++
 
 	// Decrypt
-	_, err = crypter.Decrypt(ciphertext)
+	_, err = crypter.Decrypt(ciphertext, nil)
 	AssertNe(nil, err)
 
 	_, ok := err.(*crypto.NotAuthenticError)
 	ExpectTrue(ok)
 }
+
+func (t *CrypterTest) DeriveSubkey() {
+	c0, err := crypto.NewCrypter(make([]byte, 32))
+	AssertEq(nil, err)
+
+	c1, err := crypto.NewCrypter(make([]byte, 32))
+	AssertEq(nil, err)
+
+	other, err := crypto.NewCrypter(bytes.Repeat([]byte{0x17}, 32))
+	AssertEq(nil, err)
+
+	k := c0.DeriveSubkey("taco")
+	ExpectEq(32, len(k))
+
+	// The same key and label give the same subkey; anything else differs.
+	ExpectThat(c1.DeriveSubkey("taco"), DeepEquals(k))
+	ExpectThat(c0.DeriveSubkey("burrito"), Not(DeepEquals(k)))
+	ExpectThat(other.DeriveSubkey("taco"), Not(DeepEquals(k)))
+
+	// Binding a repository doesn't change subkeys.
+	bound := crypto.BindRepository(c0, []byte("repo"))
+	ExpectThat(bound.DeriveSubkey("taco"), DeepEquals(k))
+}
+
+func (t *CrypterTest) RepositoryID() {
+	c, err := crypto.NewCrypter(make([]byte, 32))
+	AssertEq(nil, err)
+
+	ExpectEq(0, len(crypto.RepositoryID(c)))
+
+	bound := crypto.BindRepository(c, []byte("repo"))
+	ExpectEq("repo", string(crypto.RepositoryID(bound)))
+}
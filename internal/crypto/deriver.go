@@ -16,9 +16,12 @@
 package crypto
 
 import (
+	"fmt"
 	"hash"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
 )
 
 // An object that knows how to derive a crypto key, given a user password and a
@@ -48,3 +51,76 @@ func (d *pbkdf2KeyDeriver) DeriveKey(password string, salt []byte) []byte {
 		d.h,
 	)
 }
+
+// Create a key deriver that uses the memory-hard scrypt function of RFC 7914,
+// with CPU/memory cost n (a power of two), block size r, and parallelism p.
+func NewScryptKeyDeriver(n, r, p, keyLen int) (KeyDeriver, error) {
+	// Check the parameters the same way scrypt.Key does, so that DeriveKey
+	// can't fail.
+	if n <= 1 || n&(n-1) != 0 {
+		return nil, fmt.Errorf("scrypt N must be a power of two greater than one.")
+	}
+
+	if r <= 0 || p <= 0 || uint64(r)*uint64(p) >= 1<<30 {
+		return nil, fmt.Errorf("Invalid scrypt parameters r=%d, p=%d.", r, p)
+	}
+
+	const maxInt = int(^uint(0) >> 1)
+	if r > maxInt/128/p || r > maxInt/256 || n > maxInt/128/r {
+		return nil, fmt.Errorf("scrypt parameters are too large.")
+	}
+
+	return &scryptKeyDeriver{n, r, p, keyLen}, nil
+}
+
+type scryptKeyDeriver struct {
+	n      int
+	r      int
+	p      int
+	keyLen int
+}
+
+func (d *scryptKeyDeriver) DeriveKey(password string, salt []byte) []byte {
+	key, err := scrypt.Key([]byte(password), salt, d.n, d.r, d.p, d.keyLen)
+	if err != nil {
+		panic(fmt.Sprintf("scrypt.Key: %v", err))
+	}
+
+	return key
+}
+
+// Create a key deriver that uses the memory-hard Argon2id function of
+// RFC 9106, making the given number of passes over the given amount of memory
+// (in KiB) with the given number of threads.
+func NewArgon2idKeyDeriver(
+	time uint32,
+	memory uint32,
+	threads uint8,
+	keyLen int) (KeyDeriver, error) {
+	if time < 1 {
+		return nil, fmt.Errorf("Argon2id time must be at least one.")
+	}
+
+	if threads < 1 {
+		return nil, fmt.Errorf("Argon2id threads must be at least one.")
+	}
+
+	return &argon2idKeyDeriver{time, memory, threads, keyLen}, nil
+}
+
+type argon2idKeyDeriver struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	keyLen  int
+}
+
+func (d *argon2idKeyDeriver) DeriveKey(password string, salt []byte) []byte {
+	return argon2.IDKey(
+		[]byte(password),
+		salt,
+		d.time,
+		d.memory,
+		d.threads,
+		uint32(d.keyLen))
+}
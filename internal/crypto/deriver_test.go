@@ -0,0 +1,97 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/jacobsa/comeback/internal/crypto"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+)
+
+func TestDeriver(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type DeriverTest struct{}
+
+func init() { RegisterTestSuite(&DeriverTest{}) }
+
+// Make sure the deriver is deterministic and depends on its inputs.
+func (t *DeriverTest) checkBehavior(d crypto.KeyDeriver, keyLen int) {
+	derive := func(password string, salt string) string {
+		return hex.EncodeToString(d.DeriveKey(password, []byte(salt)))
+	}
+
+	key := derive("taco", "saltsalt")
+	ExpectEq(2*keyLen, len(key))
+	ExpectEq(key, derive("taco", "saltsalt"))
+	ExpectNe(key, derive("burrito", "saltsalt"))
+	ExpectNe(key, derive("taco", "pepperpepper"))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *DeriverTest) Pbkdf2() {
+	d := crypto.NewPbkdf2KeyDeriver(2, 32, sha256.New)
+	t.checkBehavior(d, 32)
+}
+
+func (t *DeriverTest) Scrypt() {
+	d, err := crypto.NewScryptKeyDeriver(16, 1, 1, 32)
+	AssertEq(nil, err)
+	t.checkBehavior(d, 32)
+}
+
+func (t *DeriverTest) Scrypt_TestVector() {
+	// RFC 7914, section 12.
+	d, err := crypto.NewScryptKeyDeriver(1024, 8, 16, 64)
+	AssertEq(nil, err)
+
+	ExpectEq(
+		"fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b373162"+
+			"2eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640",
+		hex.EncodeToString(d.DeriveKey("password", []byte("NaCl"))))
+}
+
+func (t *DeriverTest) Scrypt_InvalidParameters() {
+	_, err := crypto.NewScryptKeyDeriver(1000, 8, 1, 32)
+	ExpectThat(err, Error(HasSubstr("power of two")))
+
+	_, err = crypto.NewScryptKeyDeriver(1024, 0, 1, 32)
+	ExpectThat(err, Error(HasSubstr("Invalid scrypt parameters")))
+}
+
+func (t *DeriverTest) Argon2id() {
+	d, err := crypto.NewArgon2idKeyDeriver(1, 64, 1, 32)
+	AssertEq(nil, err)
+	t.checkBehavior(d, 32)
+}
+
+func (t *DeriverTest) Argon2id_InvalidParameters() {
+	_, err := crypto.NewArgon2idKeyDeriver(0, 64, 1, 32)
+	ExpectThat(err, Error(HasSubstr("time")))
+
+	_, err = crypto.NewArgon2idKeyDeriver(1, 64, 0, 32)
+	ExpectThat(err, Error(HasSubstr("threads")))
+}
@@ -0,0 +1,191 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/jacobsa/crypto/siv"
+)
+
+// The length of the private, public, and write keys used by envelope crypters.
+const EnvelopeKeyLen = 32
+
+// Labels that separate the various uses of HMAC-SHA256 below.
+const (
+	envelopeLabel_WriteKey     = "comeback envelope write key"
+	envelopeLabel_EphemeralKey = "comeback envelope ephemeral key"
+	envelopeLabel_BlobKey      = "comeback envelope blob key"
+)
+
+// Return a crypter that encrypts to the supplied X25519 public key, so that
+// only the holder of the matching private key can decrypt. privateKey may be
+// nil, in which case Decrypt always fails; this allows handing a machine the
+// ability to write backups without the ability to read them.
+//
+// Like the crypter returned by NewCrypter, encryption is deterministic so that
+// identical blobs can be deduplicated by their ciphertext. To achieve this
+// the ephemeral key for each ciphertext is derived from the plaintext using
+// writeKey, a secret shared by all writers; without it, an attacker who can
+// guess a plaintext can't confirm the guess by encrypting it. Each ciphertext
+// consists of the ephemeral public key followed by the plaintext encrypted
+// with AES-SIV under a key derived from the X25519 shared secret.
+func NewEnvelopeCrypter(
+	publicKey []byte,
+	writeKey []byte,
+	privateKey []byte) (Crypter, error) {
+	pub, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("NewPublicKey: %v", err)
+	}
+
+	if len(writeKey) != EnvelopeKeyLen {
+		return nil, fmt.Errorf(
+			"NewEnvelopeCrypter requires a %d-byte write key.",
+			EnvelopeKeyLen)
+	}
+
+	c := &envelopeCrypter{
+		publicKey: pub,
+		writeKey:  writeKey,
+	}
+
+	if privateKey != nil {
+		c.privateKey, err = ecdh.X25519().NewPrivateKey(privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("NewPrivateKey: %v", err)
+		}
+
+		if !c.privateKey.PublicKey().Equal(pub) {
+			return nil, fmt.Errorf("The private key doesn't match the public key.")
+		}
+	}
+
+	return c, nil
+}
+
+// Derive the public key and write key that go along with the supplied
+// X25519 private key, for use with NewEnvelopeCrypter.
+func DeriveEnvelopeKeys(
+	privateKey []byte) (publicKey []byte, writeKey []byte, err error) {
+	priv, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		err = fmt.Errorf("NewPrivateKey: %v", err)
+		return
+	}
+
+	publicKey = priv.PublicKey().Bytes()
+	writeKey = envelopeMAC(privateKey, envelopeLabel_WriteKey)
+	return
+}
+
+type envelopeCrypter struct {
+	publicKey *ecdh.PublicKey
+	writeKey  []byte
+
+	// Nil if this crypter can only encrypt.
+	privateKey *ecdh.PrivateKey
+}
+
+// Compute HMAC-SHA256 over the label and the supplied data.
+func envelopeMAC(key []byte, label string, data ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(label))
+	mac.Write([]byte{0})
+	for _, d := range data {
+		mac.Write(d)
+	}
+
+	return mac.Sum(nil)
+}
+
+// Derive the AES-SIV key for a ciphertext from the X25519 shared secret and
+// the public keys involved.
+func (c *envelopeCrypter) blobKey(shared []byte, ephemeral []byte) []byte {
+	return envelopeMAC(
+		shared,
+		envelopeLabel_BlobKey,
+		ephemeral,
+		c.publicKey.Bytes())
+}
+
+func (c *envelopeCrypter) Encrypt(
+	dst []byte,
+	plaintext []byte,
+	associated [][]byte) ([]byte, error) {
+	// Derive the ephemeral key pair from the plaintext.
+	eph, err := ecdh.X25519().NewPrivateKey(
+		envelopeMAC(c.writeKey, envelopeLabel_EphemeralKey, plaintext))
+
+	if err != nil {
+		return nil, fmt.Errorf("NewPrivateKey: %v", err)
+	}
+
+	shared, err := eph.ECDH(c.publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("ECDH: %v", err)
+	}
+
+	ephPub := eph.PublicKey().Bytes()
+	dst = append(dst, ephPub...)
+
+	return siv.Encrypt(dst, c.blobKey(shared, ephPub), plaintext, associated)
+}
+
+func (c *envelopeCrypter) Decrypt(
+	ciphertext []byte,
+	associated [][]byte) ([]byte, error) {
+	if c.privateKey == nil {
+		return nil, fmt.Errorf(
+			"Decrypting requires the private key, which this client doesn't " +
+				"have because its key slot is write-only.")
+	}
+
+	if len(ciphertext) < EnvelopeKeyLen {
+		return nil, fmt.Errorf("Ciphertext is too short.")
+	}
+
+	ephPub := ciphertext[:EnvelopeKeyLen]
+	eph, err := ecdh.X25519().NewPublicKey(ephPub)
+	if err != nil {
+		return nil, fmt.Errorf("NewPublicKey: %v", err)
+	}
+
+	// A low-order ephemeral key can only come from tampering.
+	shared, err := c.privateKey.ECDH(eph)
+	if err != nil {
+		return nil, &NotAuthenticError{err.Error()}
+	}
+
+	plaintext, err := siv.Decrypt(
+		c.blobKey(shared, ephPub),
+		ciphertext[EnvelopeKeyLen:],
+		associated)
+
+	if _, ok := err.(*siv.NotAuthenticError); ok {
+		err = &NotAuthenticError{err.Error()}
+	}
+
+	return plaintext, err
+}
+
+// Subkeys are derived from the write key, which write-only clients hold too.
+func (c *envelopeCrypter) DeriveSubkey(label string) []byte {
+	return subkeyMAC(c.writeKey, label)
+}
@@ -0,0 +1,159 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jacobsa/comeback/internal/crypto"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+)
+
+func TestEnvelope(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type EnvelopeTest struct {
+	privateKey []byte
+	publicKey  []byte
+	writeKey   []byte
+
+	full      crypto.Crypter
+	writeOnly crypto.Crypter
+}
+
+func init() { RegisterTestSuite(&EnvelopeTest{}) }
+
+func (t *EnvelopeTest) SetUp(ti *TestInfo) {
+	var err error
+
+	t.privateKey = bytes.Repeat([]byte{0x17}, crypto.EnvelopeKeyLen)
+	t.publicKey, t.writeKey, err = crypto.DeriveEnvelopeKeys(t.privateKey)
+	AssertEq(nil, err)
+
+	t.full, err = crypto.NewEnvelopeCrypter(
+		t.publicKey,
+		t.writeKey,
+		t.privateKey)
+
+	AssertEq(nil, err)
+
+	t.writeOnly, err = crypto.NewEnvelopeCrypter(t.publicKey, t.writeKey, nil)
+	AssertEq(nil, err)
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *EnvelopeTest) ShortWriteKey() {
+	_, err := crypto.NewEnvelopeCrypter(t.publicKey, t.writeKey[:31], nil)
+	ExpectThat(err, Error(HasSubstr("32-byte write key")))
+}
+
+func (t *EnvelopeTest) MismatchedPrivateKey() {
+	other := bytes.Repeat([]byte{0x19}, crypto.EnvelopeKeyLen)
+	_, err := crypto.NewEnvelopeCrypter(t.publicKey, t.writeKey, other)
+	ExpectThat(err, Error(HasSubstr("doesn't match")))
+}
+
+func (t *EnvelopeTest) RoundTrip() {
+	msg := []byte("taco")
+
+	// Encrypt without the private key, appending to an existing slice.
+	ciphertext, err := t.writeOnly.Encrypt([]byte("prefix"), msg, nil)
+	AssertEq(nil, err)
+	AssertTrue(bytes.HasPrefix(ciphertext, []byte("prefix")))
+
+	// Decrypt with it.
+	plaintext, err := t.full.Decrypt(ciphertext[len("prefix"):], nil)
+	AssertEq(nil, err)
+	ExpectThat(plaintext, DeepEquals(msg))
+}
+
+func (t *EnvelopeTest) Deterministic() {
+	c0, err := t.full.Encrypt(nil, []byte("taco"), nil)
+	AssertEq(nil, err)
+
+	c1, err := t.writeOnly.Encrypt(nil, []byte("taco"), nil)
+	AssertEq(nil, err)
+
+	c2, err := t.writeOnly.Encrypt(nil, []byte("burrito"), nil)
+	AssertEq(nil, err)
+
+	ExpectThat(c1, DeepEquals(c0))
+	ExpectThat(c2, Not(DeepEquals(c0)))
+}
+
+func (t *EnvelopeTest) DifferentWriteKeys() {
+	otherWriteKey := bytes.Repeat([]byte{0x23}, crypto.EnvelopeKeyLen)
+	other, err := crypto.NewEnvelopeCrypter(t.publicKey, otherWriteKey, nil)
+	AssertEq(nil, err)
+
+	c0, err := t.writeOnly.Encrypt(nil, []byte("taco"), nil)
+	AssertEq(nil, err)
+
+	c1, err := other.Encrypt(nil, []byte("taco"), nil)
+	AssertEq(nil, err)
+
+	// The ciphertexts differ, but both can be decrypted.
+	ExpectThat(c1, Not(DeepEquals(c0)))
+
+	plaintext, err := t.full.Decrypt(c1, nil)
+	AssertEq(nil, err)
+	ExpectEq("taco", string(plaintext))
+}
+
+func (t *EnvelopeTest) WriteOnlyCannotDecrypt() {
+	ciphertext, err := t.full.Encrypt(nil, []byte("taco"), nil)
+	AssertEq(nil, err)
+
+	_, err = t.writeOnly.Decrypt(ciphertext, nil)
+	ExpectThat(err, Error(HasSubstr("write-only")))
+}
+
+func (t *EnvelopeTest) ShortCiphertext() {
+	_, err := t.full.Decrypt(make([]byte, crypto.EnvelopeKeyLen-1), nil)
+	ExpectThat(err, Error(HasSubstr("too short")))
+}
+
+func (t *EnvelopeTest) CorruptedCiphertext() {
+	ciphertext, err := t.full.Encrypt(nil, []byte("taco"), nil)
+	AssertEq(nil, err)
+
+	// Corrupt both the ephemeral key and the body.
+	for _, i := range []int{0, len(ciphertext) - 1} {
+		corrupted := append([]byte{}, ciphertext...)
+		corrupted[i] ^= 0x01
+
+		_, err = t.full.Decrypt(corrupted, nil)
+		_, ok := err.(*crypto.NotAuthenticError)
+		ExpectTrue(ok, "Index %d, error: %v", i, err)
+	}
+}
+
+func (t *EnvelopeTest) DeriveSubkey() {
+	k := t.full.DeriveSubkey("taco")
+	ExpectEq(32, len(k))
+
+	// Write-only clients derive the same subkeys as full ones.
+	ExpectThat(t.writeOnly.DeriveSubkey("taco"), DeepEquals(k))
+	ExpectThat(t.writeOnly.DeriveSubkey("burrito"), Not(DeepEquals(k)))
+}
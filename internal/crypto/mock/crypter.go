@@ -42,7 +42,7 @@ func (m *mockCrypter) Oglemock_Description() string {
 	return m.description
 }
 
-func (m *mockCrypter) Decrypt(p0 []uint8) (o0 []uint8, o1 error) {
+func (m *mockCrypter) Decrypt(p0 []uint8, p1 [][]uint8) (o0 []uint8, o1 error) {
 	// Get a file name and line number for the caller.
 	_, file, line, _ := runtime.Caller(1)
 
@@ -52,7 +52,7 @@ func (m *mockCrypter) Decrypt(p0 []uint8) (o0 []uint8, o1 error) {
 		"Decrypt",
 		file,
 		line,
-		[]interface{}{p0})
+		[]interface{}{p0, p1})
 
 	if len(retVals) != 2 {
 		panic(fmt.Sprintf("mockCrypter.Decrypt: invalid return values: %v", retVals))
@@ -71,7 +71,31 @@ func (m *mockCrypter) Decrypt(p0 []uint8) (o0 []uint8, o1 error) {
 	return
 }
 
-func (m *mockCrypter) Encrypt(p0 []uint8, p1 []uint8) (o0 []uint8, o1 error) {
+func (m *mockCrypter) DeriveSubkey(p0 string) (o0 []uint8) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"DeriveSubkey",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockCrypter.DeriveSubkey: invalid return values: %v", retVals))
+	}
+
+	// o0 []uint8
+	if retVals[0] != nil {
+		o0 = retVals[0].([]uint8)
+	}
+
+	return
+}
+
+func (m *mockCrypter) Encrypt(p0 []uint8, p1 []uint8, p2 [][]uint8) (o0 []uint8, o1 error) {
 	// Get a file name and line number for the caller.
 	_, file, line, _ := runtime.Caller(1)
 
@@ -81,7 +105,7 @@ func (m *mockCrypter) Encrypt(p0 []uint8, p1 []uint8) (o0 []uint8, o1 error) {
 		"Encrypt",
 		file,
 		line,
-		[]interface{}{p0, p1})
+		[]interface{}{p0, p1, p2})
 
 	if len(retVals) != 2 {
 		panic(fmt.Sprintf("mockCrypter.Encrypt: invalid return values: %v", retVals))
@@ -0,0 +1,347 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/browse"
+	"github.com/jacobsa/comeback/internal/fs"
+)
+
+// Kind says how an entry differs between two backups.
+type Kind int
+
+const (
+	// The entry exists only in the new backup.
+	Added Kind = iota
+
+	// The entry exists only in the old backup.
+	Removed
+
+	// The entry exists in both backups with the same type, but its content or
+	// metadata differ.
+	Modified
+
+	// The entry exists in both backups with different types. Any contents of a
+	// directory on either side are reported as added or removed.
+	TypeChanged
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	case TypeChanged:
+		return "type_changed"
+	}
+
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// The names of metadata fields that may appear in Change.Metadata.
+const (
+	FieldMode  = "mode"
+	FieldOwner = "owner"
+	FieldMTime = "mtime"
+)
+
+// A Change describes a single entry that differs between two backups.
+type Change struct {
+	// The path of the entry relative to the root of the backups.
+	Path string
+
+	Kind Kind
+
+	// For Modified entries, whether the content differs: the scores of a file,
+	// or the target of a symlink. Directories whose contents differ are not
+	// themselves reported as having different content; the differences within
+	// them are reported instead.
+	Content bool
+
+	// For Modified entries, the names of the metadata fields that differ, from
+	// among the Field* constants.
+	Metadata []string
+
+	// The entry in each backup, or nil if it doesn't exist there.
+	Old *fs.FileInfo
+	New *fs.FileInfo
+}
+
+// Compare the entries at the supplied path within the backups with the given
+// root scores, calling fn for each difference in depth-first lexical order.
+// Subtrees with equal scores are not loaded. If fn returns an error, the
+// comparison stops and the error is returned.
+func Diff(
+	ctx context.Context,
+	blobStore blob.Store,
+	oldRoot blob.Score,
+	newRoot blob.Score,
+	relPath string,
+	fn func(c *Change) error) (err error) {
+	d := &differ{
+		ctx:       ctx,
+		blobStore: blobStore,
+		fn:        fn,
+	}
+
+	relPath = strings.Trim(path.Clean("/"+relPath), "/")
+
+	// Find the starting points.
+	oldFI, err := lookUp(ctx, blobStore, oldRoot, relPath)
+	if err != nil {
+		err = fmt.Errorf("Looking up in old backup: %v", err)
+		return
+	}
+
+	newFI, err := lookUp(ctx, blobStore, newRoot, relPath)
+	if err != nil {
+		err = fmt.Errorf("Looking up in new backup: %v", err)
+		return
+	}
+
+	if oldFI == nil && newFI == nil {
+		err = &browse.NotFoundError{Path: relPath}
+		return
+	}
+
+	// The roots have no metadata to compare.
+	if relPath == "" {
+		err = d.diffDirs(relPath, oldRoot, newRoot)
+		return
+	}
+
+	err = d.diffEntries(relPath, oldFI, newFI)
+	return
+}
+
+// Like browse.LookUp, but return nil rather than an error when the path
+// doesn't exist.
+func lookUp(
+	ctx context.Context,
+	blobStore blob.Store,
+	root blob.Score,
+	relPath string) (fi *fs.FileInfo, err error) {
+	fi, err = browse.LookUp(ctx, blobStore, root, relPath)
+	if _, ok := err.(*browse.NotFoundError); ok {
+		fi = nil
+		err = nil
+	}
+
+	return
+}
+
+type differ struct {
+	ctx       context.Context
+	blobStore blob.Store
+	fn        func(c *Change) error
+}
+
+// Compare the directories at the supplied path with the given scores.
+func (d *differ) diffDirs(
+	relPath string,
+	oldScore blob.Score,
+	newScore blob.Score) (err error) {
+	// Is there anything to do?
+	if oldScore == newScore {
+		return
+	}
+
+	oldEntries, err := browse.ReadDir(d.ctx, d.blobStore, oldScore)
+	if err != nil {
+		err = fmt.Errorf("ReadDir(%q): %v", relPath, err)
+		return
+	}
+
+	newEntries, err := browse.ReadDir(d.ctx, d.blobStore, newScore)
+	if err != nil {
+		err = fmt.Errorf("ReadDir(%q): %v", relPath, err)
+		return
+	}
+
+	// Merge the sorted listings.
+	for len(oldEntries) > 0 || len(newEntries) > 0 {
+		var o, n *fs.FileInfo
+		switch {
+		case len(newEntries) == 0 ||
+			(len(oldEntries) > 0 && oldEntries[0].Name < newEntries[0].Name):
+			o = oldEntries[0]
+			oldEntries = oldEntries[1:]
+
+		case len(oldEntries) == 0 || newEntries[0].Name < oldEntries[0].Name:
+			n = newEntries[0]
+			newEntries = newEntries[1:]
+
+		default:
+			o = oldEntries[0]
+			n = newEntries[0]
+			oldEntries = oldEntries[1:]
+			newEntries = newEntries[1:]
+		}
+
+		name := n
+		if name == nil {
+			name = o
+		}
+
+		err = d.diffEntries(path.Join(relPath, name.Name), o, n)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Compare the entries at the supplied path, either of which may be nil.
+func (d *differ) diffEntries(
+	relPath string,
+	o *fs.FileInfo,
+	n *fs.FileInfo) (err error) {
+	switch {
+	case o == nil:
+		err = d.report(relPath, Added, o, n, n)
+
+	case n == nil:
+		err = d.report(relPath, Removed, o, n, o)
+
+	case o.Type != n.Type:
+		err = d.fn(&Change{Path: relPath, Kind: TypeChanged, Old: o, New: n})
+		if err != nil {
+			return
+		}
+
+		err = d.reportContents(relPath, Removed, o)
+		if err != nil {
+			return
+		}
+
+		err = d.reportContents(relPath, Added, n)
+
+	default:
+		c := &Change{
+			Path:     relPath,
+			Kind:     Modified,
+			Content:  n.Type != fs.TypeDirectory && contentDiffers(o, n),
+			Metadata: metadataDiffers(o, n),
+			Old:      o,
+			New:      n,
+		}
+
+		if c.Content || len(c.Metadata) > 0 {
+			err = d.fn(c)
+			if err != nil {
+				return
+			}
+		}
+
+		if n.Type == fs.TypeDirectory {
+			err = d.diffDirs(relPath, o.Scores[0], n.Scores[0])
+		}
+	}
+
+	return
+}
+
+// Report an entry that exists on only one side, along with everything
+// beneath it.
+func (d *differ) report(
+	relPath string,
+	kind Kind,
+	o *fs.FileInfo,
+	n *fs.FileInfo,
+	fi *fs.FileInfo) (err error) {
+	err = d.fn(&Change{Path: relPath, Kind: kind, Old: o, New: n})
+	if err != nil {
+		return
+	}
+
+	err = d.reportContents(relPath, kind, fi)
+	return
+}
+
+// If the supplied entry is a directory, report everything beneath it as added
+// or removed.
+func (d *differ) reportContents(
+	relPath string,
+	kind Kind,
+	fi *fs.FileInfo) (err error) {
+	if fi.Type != fs.TypeDirectory {
+		return
+	}
+
+	err = browse.Walk(
+		d.ctx,
+		d.blobStore,
+		relPath,
+		fi,
+		func(p string, child *fs.FileInfo) (err error) {
+			c := &Change{Path: p, Kind: kind}
+			if kind == Added {
+				c.New = child
+			} else {
+				c.Old = child
+			}
+
+			err = d.fn(c)
+			return
+		})
+
+	return
+}
+
+func contentDiffers(o *fs.FileInfo, n *fs.FileInfo) bool {
+	if len(o.Scores) != len(n.Scores) {
+		return true
+	}
+
+	for i := range o.Scores {
+		if o.Scores[i] != n.Scores[i] {
+			return true
+		}
+	}
+
+	if (o.HardLinkTarget == nil) != (n.HardLinkTarget == nil) ||
+		(o.HardLinkTarget != nil && *o.HardLinkTarget != *n.HardLinkTarget) {
+		return true
+	}
+
+	return o.Target != n.Target || o.DeviceNumber != n.DeviceNumber
+}
+
+func metadataDiffers(o *fs.FileInfo, n *fs.FileInfo) (fields []string) {
+	if o.Permissions != n.Permissions {
+		fields = append(fields, FieldMode)
+	}
+
+	if o.Uid != n.Uid || o.Gid != n.Gid {
+		fields = append(fields, FieldOwner)
+	}
+
+	if !o.MTime.Equal(n.MTime) {
+		fields = append(fields, FieldMTime)
+	}
+
+	return
+}
@@ -0,0 +1,287 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/repr"
+	"github.com/jacobsa/comeback/internal/util"
+	"github.com/jacobsa/gcloud/gcs/gcsfake"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+	"github.com/jacobsa/timeutil"
+)
+
+func TestDiff(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+// A blob store that records the scores loaded through it.
+type loadRecordingStore struct {
+	blob.Store
+	loaded []blob.Score
+}
+
+func (s *loadRecordingStore) Load(
+	ctx context.Context,
+	score blob.Score,
+	kind blob.Kind) (b []byte, err error) {
+	s.loaded = append(s.loaded, score)
+	b, err = s.Store.Load(ctx, score, kind)
+	return
+}
+
+func file(name string, size uint64, content string) *fs.FileInfo {
+	return &fs.FileInfo{
+		Type:        fs.TypeFile,
+		Name:        name,
+		Permissions: 0644,
+		Size:        size,
+		MTime:       time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Scores:      []blob.Score{blob.ComputeScore([]byte(content))},
+	}
+}
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type DiffTest struct {
+	ctx       context.Context
+	blobStore *loadRecordingStore
+}
+
+var _ SetUpInterface = &DiffTest{}
+
+func init() { RegisterTestSuite(&DiffTest{}) }
+
+func (t *DiffTest) SetUp(ti *TestInfo) {
+	t.ctx = ti.Ctx
+
+	bucket := gcsfake.NewFakeBucket(timeutil.RealClock(), "some_bucket")
+	t.blobStore = &loadRecordingStore{
+		Store: blob.NewExistingScoresStore(
+			util.NewStringSet(),
+			blob.NewGCSStore(bucket, "blobs/")),
+	}
+}
+
+// Store a directory listing, returning an entry for it.
+func (t *DiffTest) dir(name string, entries ...*fs.FileInfo) *fs.FileInfo {
+	b, err := repr.MarshalDir(entries)
+	AssertEq(nil, err)
+
+	s, err := t.blobStore.Save(t.ctx, &blob.SaveRequest{Blob: b, Kind: blob.Kind_Dir})
+	AssertEq(nil, err)
+
+	return &fs.FileInfo{
+		Type:        fs.TypeDirectory,
+		Name:        name,
+		Permissions: 0755,
+		Scores:      []blob.Score{s},
+	}
+}
+
+// Diff the supplied roots, returning a summary of each change and the totals.
+func (t *DiffTest) diff(
+	oldRoot *fs.FileInfo,
+	newRoot *fs.FileInfo,
+	relPath string) (changes []string, summary Summary, err error) {
+	err = Diff(
+		t.ctx,
+		t.blobStore,
+		oldRoot.Scores[0],
+		newRoot.Scores[0],
+		relPath,
+		func(c *Change) (err error) {
+			desc := fmt.Sprintf("%s %s", c.Kind, c.Path)
+			if c.Content {
+				desc += " content"
+			}
+
+			for _, f := range c.Metadata {
+				desc += " " + f
+			}
+
+			changes = append(changes, desc)
+			summary.Add(c)
+			return
+		})
+
+	return
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *DiffTest) Identical() {
+	root := t.dir("", file("foo", 1, "a"), t.dir("bar", file("baz", 2, "b")))
+
+	t.blobStore.loaded = nil
+	changes, _, err := t.diff(root, root, "")
+	AssertEq(nil, err)
+
+	ExpectThat(changes, ElementsAre())
+	ExpectThat(t.blobStore.loaded, ElementsAre())
+}
+
+func (t *DiffTest) UnchangedSubtreesArePruned() {
+	unchanged := t.dir("unchanged", file("a", 1, "a"), file("b", 1, "b"))
+	oldRoot := t.dir("", unchanged, file("foo", 1, "x"))
+	newRoot := t.dir("", unchanged, file("foo", 2, "y"))
+
+	t.blobStore.loaded = nil
+	changes, _, err := t.diff(oldRoot, newRoot, "")
+	AssertEq(nil, err)
+
+	ExpectThat(changes, ElementsAre("modified foo content"))
+
+	// Only the two roots should have been loaded.
+	ExpectThat(
+		t.blobStore.loaded,
+		ElementsAre(oldRoot.Scores[0], newRoot.Scores[0]))
+}
+
+func (t *DiffTest) AddedAndRemoved() {
+	oldRoot := t.dir(
+		"",
+		file("a", 1, "a"),
+		t.dir("gone", file("x", 10, "x"), file("y", 20, "y")))
+
+	newRoot := t.dir(
+		"",
+		file("a", 1, "a"),
+		file("b", 5, "b"))
+
+	changes, summary, err := t.diff(oldRoot, newRoot, "")
+	AssertEq(nil, err)
+
+	ExpectThat(
+		changes,
+		ElementsAre(
+			"added b",
+			"removed gone",
+			"removed gone/x",
+			"removed gone/y"))
+
+	ExpectEq(1, summary.Added.Entries)
+	ExpectEq(5, summary.Added.Bytes)
+	ExpectEq(3, summary.Removed.Entries)
+	ExpectEq(30, summary.Removed.Bytes)
+}
+
+func (t *DiffTest) Metadata() {
+	oldFile := file("a", 1, "a")
+	newFile := file("a", 1, "a")
+	newFile.Permissions = 0600
+	newFile.Uid = 17
+	newFile.MTime = newFile.MTime.Add(time.Second)
+
+	oldRoot := t.dir("", oldFile)
+	newRoot := t.dir("", newFile)
+
+	changes, summary, err := t.diff(oldRoot, newRoot, "")
+	AssertEq(nil, err)
+
+	ExpectThat(changes, ElementsAre("modified a mode owner mtime"))
+	ExpectEq(0, summary.ContentChanged.Entries)
+	ExpectEq(1, summary.MetadataChanged.Entries)
+	ExpectEq(1, summary.MetadataChanged.Bytes)
+}
+
+func (t *DiffTest) TypeChange() {
+	oldRoot := t.dir("", file("a", 7, "a"))
+	newRoot := t.dir("", t.dir("a", file("b", 3, "b")))
+
+	changes, summary, err := t.diff(oldRoot, newRoot, "")
+	AssertEq(nil, err)
+
+	ExpectThat(changes, ElementsAre("type_changed a", "added a/b"))
+	ExpectEq(1, summary.TypeChanged.Entries)
+	ExpectEq(1, summary.Added.Entries)
+	ExpectEq(3, summary.Added.Bytes)
+}
+
+func (t *DiffTest) Symlink() {
+	oldLink := &fs.FileInfo{Type: fs.TypeSymlink, Name: "l", Target: "foo"}
+	newLink := &fs.FileInfo{Type: fs.TypeSymlink, Name: "l", Target: "bar"}
+
+	changes, _, err := t.diff(t.dir("", oldLink), t.dir("", newLink), "")
+	AssertEq(nil, err)
+
+	ExpectThat(changes, ElementsAre("modified l content"))
+}
+
+func (t *DiffTest) Nested() {
+	oldRoot := t.dir(
+		"",
+		t.dir("a", t.dir("b", file("c", 1, "c"), file("d", 1, "d"))),
+		file("z", 1, "z"))
+
+	newRoot := t.dir(
+		"",
+		t.dir("a", t.dir("b", file("c", 2, "cc"), file("e", 1, "e"))),
+		file("z", 1, "zz"))
+
+	changes, _, err := t.diff(oldRoot, newRoot, "")
+	AssertEq(nil, err)
+
+	ExpectThat(
+		changes,
+		ElementsAre(
+			"modified a/b/c content",
+			"removed a/b/d",
+			"added a/b/e",
+			"modified z content"))
+}
+
+func (t *DiffTest) SubPath() {
+	oldRoot := t.dir("", t.dir("a", file("b", 1, "b")), file("z", 1, "z"))
+	newRoot := t.dir("", t.dir("a", file("b", 1, "bb")), file("z", 1, "zz"))
+
+	changes, _, err := t.diff(oldRoot, newRoot, "/a/")
+	AssertEq(nil, err)
+
+	ExpectThat(changes, ElementsAre("modified a/b content"))
+}
+
+func (t *DiffTest) SubPath_OnlyInOne() {
+	oldRoot := t.dir("")
+	newRoot := t.dir("", t.dir("a", file("b", 1, "b")))
+
+	changes, _, err := t.diff(oldRoot, newRoot, "a")
+	AssertEq(nil, err)
+
+	sort.Strings(changes)
+	ExpectThat(changes, ElementsAre("added a", "added a/b"))
+}
+
+func (t *DiffTest) SubPath_InNeither() {
+	root := t.dir("")
+
+	_, _, err := t.diff(root, root, "a")
+	ExpectThat(err, Error(HasSubstr("No such file")))
+}
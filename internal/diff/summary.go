@@ -0,0 +1,66 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import "github.com/jacobsa/comeback/internal/fs"
+
+// Totals counts the entries in a category of changes, and the bytes in the
+// regular files among them.
+type Totals struct {
+	Entries int    `json:"entries"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+func (t *Totals) add(fi *fs.FileInfo) {
+	t.Entries++
+	if fi.Type == fs.TypeFile {
+		t.Bytes += fi.Size
+	}
+}
+
+// A Summary accumulates totals for the changes found by Diff. Byte counts
+// use the size in the new backup, except for removed entries.
+type Summary struct {
+	Added           Totals `json:"added"`
+	Removed         Totals `json:"removed"`
+	ContentChanged  Totals `json:"content_changed"`
+	MetadataChanged Totals `json:"metadata_changed"`
+	TypeChanged     Totals `json:"type_changed"`
+}
+
+// Update the totals to account for the supplied change. A modified entry
+// counts toward both ContentChanged and MetadataChanged if both differ.
+func (s *Summary) Add(c *Change) {
+	switch c.Kind {
+	case Added:
+		s.Added.add(c.New)
+
+	case Removed:
+		s.Removed.add(c.Old)
+
+	case TypeChanged:
+		s.TypeChanged.add(c.New)
+
+	case Modified:
+		if c.Content {
+			s.ContentChanged.add(c.New)
+		}
+
+		if len(c.Metadata) > 0 {
+			s.MetadataChanged.add(c.New)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Coordination between processes that modify the same bucket.
+//
+// Locks are objects under the locks/ prefix, created and refreshed with
+// generation preconditions. Saves take shared locks, and gc takes an exclusive
+// one so that it never runs while a save is in flight. A lock whose object
+// hasn't been refreshed within StaleAfter, judged by GCS's clock rather than
+// the local one, is considered abandoned and is ignored by others.
+//
+// The bucket also records a GC epoch, incremented by each gc run, which lets
+// clients tell when their cached view of the existing blobs is out of date.
+package lock
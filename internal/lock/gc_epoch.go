@@ -0,0 +1,125 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/jacobsa/gcloud/gcs"
+)
+
+// The name of the object recording how many times gc has removed blobs from
+// the bucket. Clients that cache the set of existing blobs compare this with
+// the value they saw when building the cache, and must throw the cache away
+// when it has changed.
+const GCEpochObjectName = "gc_epoch"
+
+// Read the GC epoch, along with the generation of the object that stores it.
+// A bucket that has never been collected has epoch zero and generation zero.
+func readGCEpoch(
+	ctx context.Context,
+	bucket gcs.Bucket) (epoch uint64, generation int64, err error) {
+	o, err := bucket.StatObject(
+		ctx,
+		&gcs.StatObjectRequest{Name: GCEpochObjectName})
+
+	if _, ok := err.(*gcs.NotFoundError); ok {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("StatObject: %v", err)
+		return
+	}
+
+	rc, err := bucket.NewReader(
+		ctx,
+		&gcs.ReadObjectRequest{
+			Name:       o.Name,
+			Generation: o.Generation,
+		})
+
+	if err != nil {
+		err = fmt.Errorf("NewReader: %v", err)
+		return
+	}
+
+	contents, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		err = fmt.Errorf("ReadAll: %v", err)
+		return
+	}
+
+	epoch, err = strconv.ParseUint(strings.TrimSpace(string(contents)), 10, 64)
+	if err != nil {
+		err = fmt.Errorf("ParseUint: %v", err)
+		return
+	}
+
+	generation = o.Generation
+	return
+}
+
+// Return the current GC epoch for the bucket.
+func ReadGCEpoch(
+	ctx context.Context,
+	bucket gcs.Bucket) (epoch uint64, err error) {
+	epoch, _, err = readGCEpoch(ctx, bucket)
+	return
+}
+
+// Increment the GC epoch, returning the new value. Concurrent increments are
+// all reflected, thanks to a generation precondition.
+func BumpGCEpoch(
+	ctx context.Context,
+	bucket gcs.Bucket) (epoch uint64, err error) {
+	for {
+		var generation int64
+		epoch, generation, err = readGCEpoch(ctx, bucket)
+		if err != nil {
+			err = fmt.Errorf("readGCEpoch: %v", err)
+			return
+		}
+
+		epoch++
+		contents := fmt.Sprintf("%d\n", epoch)
+		_, err = bucket.CreateObject(
+			ctx,
+			&gcs.CreateObjectRequest{
+				Name:                   GCEpochObjectName,
+				Contents:               strings.NewReader(contents),
+				GenerationPrecondition: &generation,
+			})
+
+		// Try again if someone else got there first.
+		if _, ok := err.(*gcs.PreconditionError); ok {
+			continue
+		}
+
+		if err != nil {
+			err = fmt.Errorf("CreateObject: %v", err)
+			return
+		}
+
+		return
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jacobsa/gcloud/gcs"
+	"github.com/jacobsa/gcloud/gcs/gcsfake"
+	"github.com/jacobsa/gcloud/gcs/gcsutil"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+	"github.com/jacobsa/timeutil"
+)
+
+func TestGCEpoch(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type GCEpochTest struct {
+	ctx    context.Context
+	bucket gcs.Bucket
+}
+
+func init() { RegisterTestSuite(&GCEpochTest{}) }
+
+func (t *GCEpochTest) SetUp(ti *TestInfo) {
+	t.ctx = ti.Ctx
+	t.bucket = gcsfake.NewFakeBucket(timeutil.RealClock(), "some_bucket")
+}
+
+func (t *GCEpochTest) read() (epoch uint64) {
+	epoch, err := ReadGCEpoch(t.ctx, t.bucket)
+	AssertEq(nil, err)
+	return
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *GCEpochTest) NeverCollected() {
+	ExpectEq(0, t.read())
+}
+
+func (t *GCEpochTest) Bump() {
+	for i := uint64(1); i <= 3; i++ {
+		epoch, err := BumpGCEpoch(t.ctx, t.bucket)
+		AssertEq(nil, err)
+		ExpectEq(i, epoch)
+		ExpectEq(i, t.read())
+	}
+}
+
+func (t *GCEpochTest) ConcurrentBumps() {
+	const n = 8
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := BumpGCEpoch(t.ctx, t.bucket)
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		AssertEq(nil, <-errs)
+	}
+
+	ExpectEq(n, t.read())
+}
+
+func (t *GCEpochTest) CorruptObject() {
+	_, err := gcsutil.CreateObject(
+		t.ctx,
+		t.bucket,
+		GCEpochObjectName,
+		[]byte("taco"))
+
+	AssertEq(nil, err)
+
+	_, err = ReadGCEpoch(t.ctx, t.bucket)
+	ExpectThat(err, Error(HasSubstr("ParseUint")))
+}
@@ -0,0 +1,549 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jacobsa/gcloud/gcs"
+	"github.com/jacobsa/gcloud/gcs/gcsutil"
+	"github.com/jacobsa/timeutil"
+)
+
+const (
+	// The prefix under which lock objects live.
+	NamePrefix = "locks/"
+
+	// A lock whose object hasn't been updated for this long is assumed to
+	// belong to a process that has died or lost its connection, and no longer
+	// conflicts with anything.
+	StaleAfter = 15 * time.Minute
+
+	// How often a holder rewrites its lock object to show that it is alive.
+	RefreshInterval = time.Minute
+)
+
+type Kind int
+
+const (
+	Shared Kind = iota
+	Exclusive
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Shared:
+		return "shared"
+
+	case Exclusive:
+		return "exclusive"
+	}
+
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Does a lock of kind k prevent a lock of kind other from being held at the
+// same time?
+func (k Kind) conflictsWith(other Kind) bool {
+	return k == Exclusive || other == Exclusive
+}
+
+// Information about a lock object in the bucket.
+type Info struct {
+	Name    string
+	Kind    Kind
+	Created time.Time
+
+	// The last time the holder refreshed the lock, according to GCS.
+	Updated time.Time
+
+	// The generation of the object, for use in preconditions.
+	Generation int64
+}
+
+// Has the holder of the lock stopped refreshing it? now must come from GCS's
+// clock rather than ours, which may run fast or slow, so callers use the
+// Updated time of a lock object that they have just written.
+func (i *Info) Stale(now time.Time) bool {
+	return now.Sub(i.Updated) > StaleAfter
+}
+
+// The contents of a lock object.
+type jsonLock struct {
+	Kind    string    `json:"kind"`
+	Created time.Time `json:"created"`
+}
+
+func parseKind(s string) (k Kind, err error) {
+	switch s {
+	case "shared":
+		k = Shared
+
+	case "exclusive":
+		k = Exclusive
+
+	default:
+		err = fmt.Errorf("Unknown lock kind %q", s)
+	}
+
+	return
+}
+
+// List all lock objects in the bucket, including stale ones.
+func List(
+	ctx context.Context,
+	bucket gcs.Bucket) (locks []Info, err error) {
+	objects, _, err := gcsutil.ListAll(
+		ctx,
+		bucket,
+		&gcs.ListObjectsRequest{Prefix: NamePrefix})
+
+	if err != nil {
+		err = fmt.Errorf("ListAll: %v", err)
+		return
+	}
+
+	for _, o := range objects {
+		var info Info
+		info, err = readInfo(ctx, bucket, o)
+
+		// The holder may have released the lock since we listed.
+		if _, ok := err.(*gcs.NotFoundError); ok {
+			err = nil
+			continue
+		}
+
+		if err != nil {
+			err = fmt.Errorf("readInfo(%q): %v", o.Name, err)
+			return
+		}
+
+		locks = append(locks, info)
+	}
+
+	return
+}
+
+func readInfo(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	o *gcs.Object) (info Info, err error) {
+	rc, err := bucket.NewReader(
+		ctx,
+		&gcs.ReadObjectRequest{
+			Name:       o.Name,
+			Generation: o.Generation,
+		})
+
+	if err != nil {
+		return
+	}
+
+	contents, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		err = fmt.Errorf("ReadAll: %v", err)
+		return
+	}
+
+	var jl jsonLock
+	err = json.Unmarshal(contents, &jl)
+	if err != nil {
+		err = fmt.Errorf("Unmarshal: %v", err)
+		return
+	}
+
+	info = Info{
+		Name:       o.Name,
+		Created:    jl.Created,
+		Updated:    o.Updated,
+		Generation: o.Generation,
+	}
+
+	info.Kind, err = parseKind(jl.Kind)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Delete a lock left behind by another process. Refreshing a lock creates a
+// new generation, so if the holder has refreshed it since it was listed this
+// leaves it alone and returns an error. Otherwise the holder will find out the
+// next time it attempts to refresh.
+func Break(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	info Info) (err error) {
+	err = bucket.DeleteObject(
+		ctx,
+		&gcs.DeleteObjectRequest{
+			Name:       info.Name,
+			Generation: info.Generation,
+		})
+
+	if err != nil {
+		err = fmt.Errorf("DeleteObject: %v", err)
+		return
+	}
+
+	// Deleting a generation that no longer exists silently does nothing, so
+	// check whether the holder got there first.
+	o, err := bucket.StatObject(
+		ctx,
+		&gcs.StatObjectRequest{Name: info.Name})
+
+	if _, ok := err.(*gcs.NotFoundError); ok {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("StatObject: %v", err)
+		return
+	}
+
+	err = fmt.Errorf(
+		"Lock %s was refreshed by its holder (generation %d, expected %d)",
+		info.Name,
+		o.Generation,
+		info.Generation)
+
+	return
+}
+
+// Break the locks of any saves that have stopped refreshing them, returning
+// information about the locks broken. The caller must hold the exclusive lock
+// self, which is refreshed first both to make sure that we still hold it and
+// to learn the current time according to GCS. If a save wakes up after this returns it will fail to refresh its
+// lock, and so won't register a backup that refers to blobs collected in the
+// meantime. Returns an error without breaking the rest if any of the holders
+// turns out to be alive after all.
+func BreakAbandoned(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	self *Lock) (abandoned []Info, err error) {
+	err = self.Refresh(ctx)
+	if err != nil {
+		err = fmt.Errorf("Refresh: %v", err)
+		return
+	}
+
+	locks, err := List(ctx, bucket)
+	if err != nil {
+		err = fmt.Errorf("List: %v", err)
+		return
+	}
+
+	now := self.lastWritten()
+	for _, info := range locks {
+		if info.Name == self.Name() || info.Kind != Shared || !info.Stale(now) {
+			continue
+		}
+
+		err = Break(ctx, bucket, info)
+		if err != nil {
+			err = fmt.Errorf("Break(%q): %v", info.Name, err)
+			return
+		}
+
+		abandoned = append(abandoned, info)
+	}
+
+	return
+}
+
+// An error returned by Acquire when other processes hold conflicting locks.
+type ConflictError struct {
+	Holders []Info
+}
+
+func (e *ConflictError) Error() string {
+	var descs []string
+	for _, h := range e.Holders {
+		descs = append(
+			descs,
+			fmt.Sprintf(
+				"%s lock %s (created %v, refreshed %v)",
+				h.Kind,
+				strings.TrimPrefix(h.Name, NamePrefix),
+				h.Created.Format(time.RFC3339),
+				h.Updated.Format(time.RFC3339)))
+	}
+
+	return "Repository is locked: " + strings.Join(descs, "; ")
+}
+
+// Return the live locks in the list that conflict with a lock of the given
+// kind, ignoring the one with the given name.
+func conflicts(
+	locks []Info,
+	kind Kind,
+	self string,
+	now time.Time) (holders []Info) {
+	for _, l := range locks {
+		if l.Name == self || l.Stale(now) || !kind.conflictsWith(l.Kind) {
+			continue
+		}
+
+		holders = append(holders, l)
+	}
+
+	return
+}
+
+// A lock held by this process. The caller must arrange for Refresh to be
+// called more often than StaleAfter, for example by running KeepAlive.
+type Lock struct {
+	bucket  gcs.Bucket
+	kind    Kind
+	name    string
+	created time.Time
+
+	mu sync.Mutex
+
+	// The generation of our lock object, or zero if the lock has been released
+	// or broken.
+	//
+	// GUARDED_BY(mu)
+	generation int64
+
+	// The time at which GCS says it last wrote our lock object.
+	//
+	// GUARDED_BY(mu)
+	written time.Time
+}
+
+// Acquire a lock of the given kind, failing with a *ConflictError if a live
+// conflicting lock is held by someone else. The clock supplies the creation
+// time recorded in the lock object.
+//
+// We write our lock object and then check for conflicts, so two processes
+// racing for conflicting locks may both fail, but never both succeed. Writing
+// first also tells us the current time according to GCS, which is what
+// staleness is measured against.
+func Acquire(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	clock timeutil.Clock,
+	kind Kind) (l *Lock, err error) {
+	// Create our lock object.
+	var id [16]byte
+	_, err = io.ReadFull(rand.Reader, id[:])
+	if err != nil {
+		err = fmt.Errorf("ReadFull: %v", err)
+		return
+	}
+
+	l = &Lock{
+		bucket:  bucket,
+		kind:    kind,
+		name:    NamePrefix + hex.EncodeToString(id[:]),
+		created: clock.Now(),
+	}
+
+	err = l.write(ctx)
+	if err != nil {
+		err = fmt.Errorf("write: %v", err)
+		l = nil
+		return
+	}
+
+	// Look for conflicts, now that anybody else will be able to see us.
+	existing, err := List(ctx, bucket)
+	if err != nil {
+		err = fmt.Errorf("List: %v", err)
+		l.Release(ctx)
+		l = nil
+		return
+	}
+
+	if holders := conflicts(existing, kind, l.name, l.written); holders != nil {
+		err = &ConflictError{Holders: holders}
+		l.Release(ctx)
+		l = nil
+		return
+	}
+
+	return
+}
+
+// Create or overwrite the lock object, insisting that it is still at the
+// generation we last wrote.
+//
+// LOCKS_REQUIRED(l.mu)
+func (l *Lock) write(ctx context.Context) (err error) {
+	contents, err := json.Marshal(&jsonLock{
+		Kind:    l.kind.String(),
+		Created: l.created,
+	})
+
+	if err != nil {
+		err = fmt.Errorf("Marshal: %v", err)
+		return
+	}
+
+	precond := l.generation
+	o, err := l.bucket.CreateObject(
+		ctx,
+		&gcs.CreateObjectRequest{
+			Name:                   l.name,
+			Contents:               bytes.NewReader(contents),
+			GenerationPrecondition: &precond,
+		})
+
+	if err != nil {
+		return
+	}
+
+	l.generation = o.Generation
+	l.written = o.Updated
+	return
+}
+
+// The name of the lock object.
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// The time at which GCS says it last wrote the lock object.
+func (l *Lock) lastWritten() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.written
+}
+
+// The time at which the lock was acquired.
+func (l *Lock) Created() time.Time {
+	return l.created
+}
+
+// Rewrite the lock object to show that we're still alive. Return an error if
+// the lock has been broken by another process, in which case the caller no
+// longer holds it and must not act as if it does.
+func (l *Lock) Refresh(ctx context.Context) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.generation == 0 {
+		err = fmt.Errorf("Lock %s has been released", l.name)
+		return
+	}
+
+	err = l.write(ctx)
+	if _, ok := err.(*gcs.PreconditionError); ok {
+		l.generation = 0
+		err = fmt.Errorf("Lock %s was broken by another process", l.name)
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("write: %v", err)
+		return
+	}
+
+	return
+}
+
+// Make sure that we still hold the lock and that gc has not started
+// collecting garbage since the GC epoch was the given value. Callers should
+// do this immediately before recording anything that refers to blobs they
+// believed to exist.
+func (l *Lock) Confirm(ctx context.Context, epoch uint64) (err error) {
+	err = l.Refresh(ctx)
+	if err != nil {
+		err = fmt.Errorf("Refresh: %v", err)
+		return
+	}
+
+	// gc bumps the epoch before removing anything, after breaking any locks it
+	// considers abandoned. So if it hasn't changed, nothing has been removed.
+	current, err := ReadGCEpoch(ctx, l.bucket)
+	if err != nil {
+		err = fmt.Errorf("ReadGCEpoch: %v", err)
+		return
+	}
+
+	if current != epoch {
+		err = fmt.Errorf(
+			"Garbage was collected while we worked (GC epoch %d, expected %d)",
+			current,
+			epoch)
+
+		return
+	}
+
+	return
+}
+
+// Refresh the lock every RefreshInterval until the context is cancelled,
+// returning early with an error if it can't be refreshed.
+func (l *Lock) KeepAlive(ctx context.Context) (err error) {
+	ticker := time.NewTicker(RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			err = l.Refresh(ctx)
+			if err != nil {
+				err = fmt.Errorf("Refresh: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// Delete the lock object. It is not an error if the lock has already been
+// broken.
+func (l *Lock) Release(ctx context.Context) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.generation == 0 {
+		return
+	}
+
+	err = l.bucket.DeleteObject(
+		ctx,
+		&gcs.DeleteObjectRequest{
+			Name:       l.name,
+			Generation: l.generation,
+		})
+
+	l.generation = 0
+	if err != nil {
+		err = fmt.Errorf("DeleteObject: %v", err)
+		return
+	}
+
+	return
+}
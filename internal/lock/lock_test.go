@@ -0,0 +1,304 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jacobsa/gcloud/gcs"
+	"github.com/jacobsa/gcloud/gcs/gcsfake"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+	"github.com/jacobsa/timeutil"
+)
+
+func TestLock(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type LockTest struct {
+	ctx    context.Context
+	clock  timeutil.SimulatedClock
+	bucket gcs.Bucket
+}
+
+func init() { RegisterTestSuite(&LockTest{}) }
+
+func (t *LockTest) SetUp(ti *TestInfo) {
+	t.ctx = ti.Ctx
+	t.clock.SetTime(time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC))
+	t.bucket = gcsfake.NewFakeBucket(&t.clock, "some_bucket")
+}
+
+func (t *LockTest) acquire(kind Kind) (l *Lock) {
+	l, err := Acquire(t.ctx, t.bucket, &t.clock, kind)
+	AssertEq(nil, err)
+	return
+}
+
+func (t *LockTest) expectConflict(kind Kind, holders ...*Lock) {
+	_, err := Acquire(t.ctx, t.bucket, &t.clock, kind)
+	AssertNe(nil, err)
+
+	conflict, ok := err.(*ConflictError)
+	AssertTrue(ok, "%v", err)
+
+	var names []string
+	for _, h := range conflict.Holders {
+		names = append(names, h.Name)
+	}
+
+	var expected []interface{}
+	for _, h := range holders {
+		expected = append(expected, h.Name())
+	}
+
+	ExpectThat(names, ElementsAre(expected...))
+}
+
+func (t *LockTest) list() (locks []Info) {
+	locks, err := List(t.ctx, t.bucket)
+	AssertEq(nil, err)
+	return
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *LockTest) EmptyBucket() {
+	ExpectThat(t.list(), ElementsAre())
+}
+
+func (t *LockTest) SharedLocksCoexist() {
+	l0 := t.acquire(Shared)
+	t.clock.AdvanceTime(time.Second)
+	l1 := t.acquire(Shared)
+
+	locks := t.list()
+	AssertEq(2, len(locks))
+	for _, info := range locks {
+		ExpectEq(Shared, info.Kind)
+		ExpectThat(info.Name, AnyOf(l0.Name(), l1.Name()))
+		ExpectTrue(strings.HasPrefix(info.Name, NamePrefix), "%s", info.Name)
+	}
+}
+
+func (t *LockTest) ExclusiveConflictsWithShared() {
+	l := t.acquire(Shared)
+	t.expectConflict(Exclusive, l)
+
+	// The failed attempt should have left nothing behind.
+	ExpectEq(1, len(t.list()))
+}
+
+func (t *LockTest) SharedConflictsWithExclusive() {
+	l := t.acquire(Exclusive)
+	t.expectConflict(Shared, l)
+	t.expectConflict(Exclusive, l)
+}
+
+func (t *LockTest) ConflictErrorMessage() {
+	t.acquire(Exclusive)
+
+	_, err := Acquire(t.ctx, t.bucket, &t.clock, Shared)
+	ExpectThat(err, Error(HasSubstr("Repository is locked")))
+	ExpectThat(err, Error(HasSubstr("exclusive lock")))
+}
+
+func (t *LockTest) Release() {
+	l := t.acquire(Exclusive)
+
+	AssertEq(nil, l.Release(t.ctx))
+	ExpectThat(t.list(), ElementsAre())
+
+	// We can now take a shared lock, and releasing again is harmless.
+	t.acquire(Shared)
+	ExpectEq(nil, l.Release(t.ctx))
+	ExpectEq(1, len(t.list()))
+
+	// Refreshing a released lock fails.
+	ExpectThat(l.Refresh(t.ctx), Error(HasSubstr("released")))
+}
+
+func (t *LockTest) StaleLocksAreIgnored() {
+	t.acquire(Exclusive)
+	t.clock.AdvanceTime(StaleAfter + time.Second)
+
+	t.acquire(Shared)
+	ExpectEq(2, len(t.list()))
+}
+
+func (t *LockTest) LocalClockRunsFast() {
+	var fast timeutil.SimulatedClock
+	fast.SetTime(t.clock.Now().Add(StaleAfter + time.Hour))
+
+	// Staleness is judged by GCS's clock, so a live lock still conflicts.
+	l := t.acquire(Shared)
+	_, err := Acquire(t.ctx, t.bucket, &fast, Exclusive)
+	_, ok := err.(*ConflictError)
+	ExpectTrue(ok, "%v", err)
+	ExpectEq(1, len(t.list()))
+
+	// Once the lock goes stale gc may take its own, but if the save wakes up
+	// and refreshes before gc gets round to breaking its lock, it is left
+	// alone.
+	t.clock.AdvanceTime(StaleAfter + time.Second)
+	fast.AdvanceTime(StaleAfter + time.Second)
+
+	self, err := Acquire(t.ctx, t.bucket, &fast, Exclusive)
+	AssertEq(nil, err)
+	AssertEq(nil, l.Refresh(t.ctx))
+
+	abandoned, err := BreakAbandoned(t.ctx, t.bucket, self)
+	AssertEq(nil, err)
+	ExpectThat(abandoned, ElementsAre())
+	ExpectEq(nil, l.Refresh(t.ctx))
+}
+
+func (t *LockTest) LocalClockRunsSlow() {
+	var slow timeutil.SimulatedClock
+	slow.SetTime(t.clock.Now().Add(-time.Hour))
+
+	// A stale lock doesn't conflict, even though it looks recent to us.
+	t.acquire(Exclusive)
+	t.clock.AdvanceTime(StaleAfter + time.Second)
+	slow.AdvanceTime(StaleAfter + time.Second)
+
+	_, err := Acquire(t.ctx, t.bucket, &slow, Shared)
+	ExpectEq(nil, err)
+}
+
+func (t *LockTest) RefreshKeepsLockLive() {
+	l := t.acquire(Exclusive)
+
+	for i := 0; i < 3; i++ {
+		t.clock.AdvanceTime(StaleAfter / 2)
+		AssertEq(nil, l.Refresh(t.ctx))
+	}
+
+	t.expectConflict(Shared, l)
+
+	locks := t.list()
+	AssertEq(1, len(locks))
+	ExpectThat(locks[0].Created, timeutil.TimeEq(l.Created()))
+	ExpectThat(locks[0].Updated, timeutil.TimeEq(t.clock.Now()))
+	ExpectFalse(locks[0].Stale(t.clock.Now()))
+}
+
+func (t *LockTest) BreakStaleLock() {
+	l := t.acquire(Shared)
+	t.clock.AdvanceTime(StaleAfter + time.Second)
+
+	locks := t.list()
+	AssertEq(1, len(locks))
+	AssertTrue(locks[0].Stale(t.clock.Now()))
+
+	AssertEq(nil, Break(t.ctx, t.bucket, locks[0]))
+	ExpectThat(t.list(), ElementsAre())
+
+	// The holder finds out when it next refreshes.
+	err := l.Refresh(t.ctx)
+	ExpectThat(err, Error(HasSubstr("broken")))
+	ExpectThat(t.list(), ElementsAre())
+}
+
+func (t *LockTest) BreakAfterRefresh() {
+	l := t.acquire(Shared)
+	locks := t.list()
+	AssertEq(1, len(locks))
+
+	// If the holder refreshes after we listed, breaking fails and does nothing.
+	t.clock.AdvanceTime(time.Minute)
+	AssertEq(nil, l.Refresh(t.ctx))
+
+	err := Break(t.ctx, t.bucket, locks[0])
+	ExpectThat(err, Error(HasSubstr("refreshed by its holder")))
+	ExpectEq(1, len(t.list()))
+	ExpectEq(nil, l.Refresh(t.ctx))
+}
+
+func (t *LockTest) BreakAbandoned() {
+	// An old exclusive lock, and a shared lock taken after it went stale.
+	exclusive := t.acquire(Exclusive)
+	t.clock.AdvanceTime(StaleAfter + time.Second)
+	shared := t.acquire(Shared)
+	t.clock.AdvanceTime(StaleAfter + time.Second)
+
+	// Only the shared lock belongs to an abandoned save.
+	self := t.acquire(Exclusive)
+	abandoned, err := BreakAbandoned(t.ctx, t.bucket, self)
+	AssertEq(nil, err)
+	AssertEq(1, len(abandoned))
+	ExpectEq(shared.Name(), abandoned[0].Name)
+
+	var names []string
+	for _, info := range t.list() {
+		names = append(names, info.Name)
+	}
+
+	ExpectThat(
+		names,
+		ElementsAre(
+			AnyOf(exclusive.Name(), self.Name()),
+			AnyOf(exclusive.Name(), self.Name())))
+}
+
+func (t *LockTest) StaleSaveWakesDuringCollection() {
+	// A save takes its lock and notes the GC epoch, then goes to sleep for
+	// long enough that its lock becomes stale.
+	save := t.acquire(Shared)
+	epoch, err := ReadGCEpoch(t.ctx, t.bucket)
+	AssertEq(nil, err)
+
+	t.clock.AdvanceTime(StaleAfter + time.Second)
+
+	// gc takes its lock, breaks the save's, and bumps the epoch before
+	// removing anything.
+	gc := t.acquire(Exclusive)
+	abandoned, err := BreakAbandoned(t.ctx, t.bucket, gc)
+	AssertEq(nil, err)
+	AssertEq(1, len(abandoned))
+
+	_, err = BumpGCEpoch(t.ctx, t.bucket)
+	AssertEq(nil, err)
+
+	// When the save wakes up part way through collection, it must not register
+	// its backup.
+	err = save.Confirm(t.ctx, epoch)
+	ExpectThat(err, Error(HasSubstr("broken")))
+}
+
+func (t *LockTest) ConfirmNoticesGC() {
+	l := t.acquire(Shared)
+	epoch, err := ReadGCEpoch(t.ctx, t.bucket)
+	AssertEq(nil, err)
+
+	AssertEq(nil, l.Confirm(t.ctx, epoch))
+
+	// If the epoch changes while we still hold the lock, something has gone
+	// wrong, and we must not rely on the blobs we saw.
+	_, err = BumpGCEpoch(t.ctx, t.bucket)
+	AssertEq(nil, err)
+
+	err = l.Confirm(t.ctx, epoch)
+	ExpectThat(err, Error(HasSubstr("Garbage was collected")))
+}
@@ -16,92 +16,171 @@
 package registry
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
-	"encoding/base64"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
+	"golang.org/x/sync/errgroup"
+
 	"github.com/jacobsa/comeback/internal/blob"
 	"github.com/jacobsa/comeback/internal/crypto"
 	"github.com/jacobsa/gcloud/gcs"
 	"github.com/jacobsa/gcloud/gcs/gcsutil"
 )
 
-// Create a registry that stores data in the supplied GCS bucket, deriving a
-// crypto key from the supplied password and ensuring that the bucket may not
-// in the future be used with any other key and has not in the past, either.
-// Return a crypter configured to use the key.
-func NewGCSRegistry(
+// Options for a repository created by InitGCSRegistry.
+type NewBucketOptions struct {
+	// The function used to protect the bucket's first key slot.
+	KDF KDF
+
+	// If set, encrypt the bucket's contents to a public key, so that write-only
+	// key slots can be added later. This can't be changed once the repository
+	// has been created.
+	Envelope bool
+}
+
+// Create a repository in the supplied GCS bucket, generating a new key as
+// configured by opts and protecting it with the supplied password, and return
+// a registry and crypter for it as NewGCSRegistry would. It is an error if the
+// bucket already contains a repository.
+func InitGCSRegistry(
 	ctx context.Context,
 	bucket gcs.Bucket,
 	cryptoPassword string,
-	deriver crypto.KeyDeriver) (r Registry, crypter crypto.Crypter, err error) {
-	return newGCSRegistry(
+	opts NewBucketOptions) (r Registry, crypter crypto.Crypter, err error) {
+	return initGCSRegistry(
 		ctx,
 		bucket,
 		cryptoPassword,
-		deriver,
+		opts,
 		crypto.NewCrypter,
 		rand.Reader)
 }
 
+// Create a registry that stores data in the supplied GCS bucket, unlocking the
+// bucket's crypto key with the supplied password. Return a crypter configured
+// to use the key. If the password unlocks a write-only key slot, the crypter
+// can't decrypt and the registry can only record new backups.
+//
+// The bucket must already contain a repository created by InitGCSRegistry (or
+// by an older version of comeback), so that a mistyped bucket name is an
+// error rather than a new, empty repository.
+func NewGCSRegistry(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	cryptoPassword string) (r Registry, crypter crypto.Crypter, err error) {
+	return newGCSRegistry(
+		ctx,
+		bucket,
+		cryptoPassword,
+		crypto.NewCrypter)
+}
+
 const (
+	gcsRecordPrefix      = "records/"
 	gcsJobKeyPrefix      = "jobs/"
 	gcsMetadataKey_Name  = "job_name"
 	gcsMetadataKey_Score = "hex_score"
 
-	// Constants related to the "marker" object, used to ensure that the user has
-	// the right password. See notes on gcsRegistry.
-	markerObjectName                = "marker"
-	markerObjectMetadata_Salt       = "base64_salt"
-	markerObjectMetadata_Ciphertext = "base64_ciphertext"
+	// The number of records to read concurrently in ListBackups.
+	readRecordParallelism = 16
 )
 
 // A registry that stores job records in a GCS bucket. Object names are of the
 // form
 //
-//     <gcsJobKeyPrefix><time>
+//	<gcsRecordPrefix><hash>
+//
+// where <hash> is derived from the object's contents, which are a record
+// encrypted with the bucket's crypter (see marshalRecord). Names therefore
+// reveal nothing about the backups, not even their start times.
+//
+// Older versions of comeback used names of the form
+//
+//	<gcsJobKeyPrefix><time>
 //
 // where <time> is a time.Time with UTC location formatted according to
-// time.RFC3339. Additional information is stored as object metadata fields
-// keyed by the constants above. Metadata fields are used in preference to
-// object content so that they are accessible on a ListObjects request.
+// time.RFC3339, with either an encrypted record as contents or the job name
+// and score in plaintext metadata fields keyed by the constants above. These
+// are still read, and may be rewritten by MigrateLegacyRecords.
 //
-// The bucket additionally contains a "marker" object (named by the constant
-// markerObjectName) with metadata keys specifying a salt and a ciphertext for
-// some random plaintext, generated ant written the first time the bucket is
-// used. This marker allows us to verify that the user-provided crypto password
-// is correct by deriving a key using the password and the salt and making sure
-// that the ciphertext can be decrypted using that key.
+// The bucket additionally contains a "marker" object holding the key used to
+// encrypt everything else, protected by the user's password. See marker.go.
 type gcsRegistry struct {
-	bucket gcs.Bucket
+	bucket  gcs.Bucket
+	crypter crypto.Crypter
 }
 
-func (r *gcsRegistry) RecordBackup(
+// Return the name of the object holding the supplied encrypted record.
+func recordObjectName(contents []byte) string {
+	h := sha256.Sum256(contents)
+	return gcsRecordPrefix + hex.EncodeToString(h[:16])
+}
+
+// Write an object containing the supplied job's record, returning its name.
+// If the object already exists, return a *gcs.PreconditionError.
+func (r *gcsRegistry) writeRecord(
 	ctx context.Context,
-	j CompletedJob) (err error) {
-	// Write an object to the bucket. On the small change that the time collides
-	// (or we've done something dumb like use the zero time), use a generation
-	// precondition to ensure we don't overwrite anything.
+	j CompletedJob) (name string, err error) {
+	contents, err := marshalRecord(r.crypter, j)
+	if err != nil {
+		err = fmt.Errorf("marshalRecord: %v", err)
+		return
+	}
+
+	// Encryption is deterministic, so an identical record would have the same
+	// name. Use a generation precondition to ensure we don't overwrite it.
 	var precond int64
-	formattedTime := j.StartTime.UTC().Format(time.RFC3339)
+	name = recordObjectName(contents)
 	req := &gcs.CreateObjectRequest{
-		Name:                   gcsJobKeyPrefix + formattedTime,
-		Contents:               strings.NewReader(""),
+		Name:                   name,
+		Contents:               bytes.NewReader(contents),
 		GenerationPrecondition: &precond,
-
-		Metadata: map[string]string{
-			gcsMetadataKey_Name:  j.Name,
-			gcsMetadataKey_Score: j.Score.Hex(),
-		},
 	}
 
 	_, err = r.bucket.CreateObject(ctx, req)
+	return
+}
+
+func (r *gcsRegistry) RecordBackup(
+	ctx context.Context,
+	j CompletedJob) (err error) {
+	_, err = r.writeRecord(ctx, j)
+	if err != nil {
+		err = fmt.Errorf("writeRecord: %v", err)
+		return
+	}
+
+	return
+}
+
+// Is the supplied object a record with plaintext metadata, in the format
+// written by older versions of comeback?
+func isPlaintextRecord(o *gcs.Object) bool {
+	_, ok := o.Metadata[gcsMetadataKey_Name]
+	return ok
+}
+
+// Read and decrypt the record in the supplied object.
+func (r *gcsRegistry) readRecord(
+	ctx context.Context,
+	o *gcs.Object) (j CompletedJob, err error) {
+	contents, err := gcsutil.ReadObject(ctx, r.bucket, o.Name)
+	if err != nil {
+		err = fmt.Errorf("ReadObject: %v", err)
+		return
+	}
+
+	j, err = unmarshalRecord(r.crypter, contents)
 	if err != nil {
-		err = fmt.Errorf("CreateObject: %v", err)
+		err = fmt.Errorf("unmarshalRecord(%q): %v", o.Name, err)
 		return
 	}
 
@@ -159,203 +238,341 @@ func parseObjectAsJob(o *gcs.Object) (j CompletedJob, err error) {
 	return
 }
 
-func (r *gcsRegistry) ListBackups(
-	ctx context.Context) (jobs []CompletedJob, err error) {
-	// List all of the objects with the appropriate name prefix.
+// List the objects with the supplied name prefix, skipping the object whose
+// name is exactly the prefix, if any. This lets us tolerate a "directory
+// marker" with gcsfuse.
+func (r *gcsRegistry) listRecordObjects(
+	ctx context.Context,
+	prefix string) (objects []*gcs.Object, err error) {
 	req := &gcs.ListObjectsRequest{
-		Prefix: gcsJobKeyPrefix,
+		Prefix: prefix,
 	}
 
-	objects, _, err := gcsutil.ListAll(ctx, r.bucket, req)
+	all, _, err := gcsutil.ListAll(ctx, r.bucket, req)
 	if err != nil {
 		err = fmt.Errorf("gcsutil.ListAll: %v", err)
 		return
 	}
 
-	// Process each object.
-	for _, o := range objects {
-		// Skip the object whose name is exactly the prefix, if any. This lets us
-		// tolerate a "directory marker" with gcsfuse.
-		if o.Name == gcsJobKeyPrefix {
+	for _, o := range all {
+		if o.Name != prefix {
+			objects = append(objects, o)
+		}
+	}
+
+	return
+}
+
+// Parse the records in the supplied objects, in the same order. Records with
+// plaintext metadata are parsed from it; others must be read, which we do in
+// parallel.
+func (r *gcsRegistry) parseRecords(
+	ctx context.Context,
+	objects []*gcs.Object) (jobs []CompletedJob, err error) {
+	jobs = make([]CompletedJob, len(objects))
+	eg, ctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, readRecordParallelism)
+
+	for i, o := range objects {
+		if isPlaintextRecord(o) {
+			jobs[i], err = parseObjectAsJob(o)
+			if err != nil {
+				err = fmt.Errorf("parseObjectAsJob: %v", err)
+				return
+			}
+
 			continue
 		}
 
-		// Parse.
-		var j CompletedJob
-		j, err = parseObjectAsJob(o)
-		if err != nil {
-			err = fmt.Errorf("parseObjectAsJob: %v", err)
+		i := i
+		o := o
+		eg.Go(func() (err error) {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			}
+
+			defer func() { <-sem }()
+
+			jobs[i], err = r.readRecord(ctx, o)
 			return
-		}
+		})
+	}
 
-		jobs = append(jobs, j)
+	err = eg.Wait()
+	if err != nil {
+		jobs = nil
+		return
 	}
 
 	return
 }
 
-// Like NewGCSRegistry, but with more injected.
-func newGCSRegistry(
-	ctx context.Context,
-	bucket gcs.Bucket,
-	cryptoPassword string,
-	deriver crypto.KeyDeriver,
-	createCrypter func(key []byte) (crypto.Crypter, error),
-	cryptoRandSrc io.Reader) (r Registry, crypter crypto.Crypter, err error) {
-	// Find the previously-written encrypted marker object, if any.
-	statReq := &gcs.StatObjectRequest{Name: markerObjectName}
-	o, err := bucket.StatObject(ctx, statReq)
+// The identity of a backup, for the purpose of recognizing a legacy record
+// that has already been migrated.
+type jobKey struct {
+	startTime time.Time
+	name      string
+	score     blob.Score
+}
+
+func makeJobKey(j *CompletedJob) jobKey {
+	return jobKey{j.StartTime.UTC(), j.Name, j.Score}
+}
+
+func (r *gcsRegistry) ListBackups(
+	ctx context.Context) (jobs []CompletedJob, err error) {
+	// Read records in the current format.
+	objects, err := r.listRecordObjects(ctx, gcsRecordPrefix)
+	if err != nil {
+		return
+	}
+
+	jobs, err = r.parseRecords(ctx, objects)
+	if err != nil {
+		return
+	}
 
-	if _, ok := err.(*gcs.NotFoundError); ok {
-		err = nil
-		o = nil
+	// Read legacy records, skipping any that an interrupted migration has
+	// already rewritten.
+	objects, err = r.listRecordObjects(ctx, gcsJobKeyPrefix)
+	if err != nil {
+		return
 	}
 
+	legacy, err := r.parseRecords(ctx, objects)
 	if err != nil {
-		err = fmt.Errorf("StatObject: %v", err)
 		return
 	}
 
-	// If the object was found, we must verify it is compatible.
-	if o != nil {
-		crypter, err = verifyCompatibleAndSetUpCrypter(
-			o,
-			cryptoPassword,
-			deriver,
-			createCrypter,
-		)
+	seen := make(map[jobKey]bool)
+	for i := range jobs {
+		seen[makeJobKey(&jobs[i])] = true
+	}
 
+	for i := range legacy {
+		if !seen[makeJobKey(&legacy[i])] {
+			jobs = append(jobs, legacy[i])
+		}
+	}
+
+	// Object names no longer give an order, so impose one.
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].StartTime.Before(jobs[j].StartTime)
+	})
+
+	return
+}
+
+func (r *gcsRegistry) DeleteBackups(
+	ctx context.Context,
+	jobs []CompletedJob) (err error) {
+	// Find the objects holding each record, listing and parsing them all once
+	// rather than once per backup. An interrupted migration may have left
+	// records in both formats, so look at both.
+	objects := make(map[jobKey][]*gcs.Object)
+	for _, prefix := range []string{gcsRecordPrefix, gcsJobKeyPrefix} {
+		var listed []*gcs.Object
+		listed, err = r.listRecordObjects(ctx, prefix)
 		if err != nil {
 			return
 		}
 
-		// All is good.
-		r = &gcsRegistry{
-			bucket: bucket,
+		var parsed []CompletedJob
+		parsed, err = r.parseRecords(ctx, listed)
+		if err != nil {
+			return
 		}
 
-		return
+		for i := range parsed {
+			k := makeJobKey(&parsed[i])
+			objects[k] = append(objects[k], listed[i])
+		}
 	}
 
-	// Otherwise, we want to claim this bucket. Encrypt some random data, base64
-	// encode it, then write it out. Make sure to use a precondition to defeat
-	// the race condition where another machine is doing the same simultaneously.
-
-	// Generate a random salt.
-	salt := make([]byte, 8)
-	if _, err = io.ReadAtLeast(cryptoRandSrc, salt, len(salt)); err != nil {
-		err = fmt.Errorf("Reading random bytes for salt: %v", err)
-		return
+	// Make sure that every backup has a record before deleting anything.
+	for i := range jobs {
+		if len(objects[makeJobKey(&jobs[i])]) == 0 {
+			err = fmt.Errorf(
+				"No record found for the %s backup started at %v",
+				jobs[i].Name,
+				jobs[i].StartTime)
+			return
+		}
 	}
 
-	// Derive a crypto key and create the crypter.
-	cryptoKey := deriver.DeriveKey(cryptoPassword, salt)
-	crypter, err = createCrypter(cryptoKey)
-	if err != nil {
-		err = fmt.Errorf("createCrypter: %v", err)
-		return
+	for i := range jobs {
+		k := makeJobKey(&jobs[i])
+		for _, o := range objects[k] {
+			err = r.bucket.DeleteObject(
+				ctx,
+				&gcs.DeleteObjectRequest{
+					Name:       o.Name,
+					Generation: o.Generation,
+				})
+
+			if err != nil {
+				err = fmt.Errorf("DeleteObject(%q): %v", o.Name, err)
+				return
+			}
+		}
+
+		// The same backup may have been supplied twice.
+		delete(objects, k)
 	}
 
-	// Create some plaintext.
-	plaintext := make([]byte, 8)
-	_, err = io.ReadAtLeast(cryptoRandSrc, plaintext, len(plaintext))
+	return
+}
+
+func (r *gcsRegistry) MigrateLegacyRecords(
+	ctx context.Context) (migrated int, err error) {
+	objects, err := r.listRecordObjects(ctx, gcsJobKeyPrefix)
 	if err != nil {
-		err = fmt.Errorf("Reading random bytes for plaintext: %v", err)
 		return
 	}
 
-	// Encrypt the plaintext.
-	ciphertext, err := crypter.Encrypt(nil, plaintext)
+	jobs, err := r.parseRecords(ctx, objects)
 	if err != nil {
-		err = fmt.Errorf("Encrypt: %v", err)
 		return
 	}
 
-	// Base-64 encode.
-	encodedCiphertext := base64.StdEncoding.EncodeToString(ciphertext)
-	encodedSalt := base64.StdEncoding.EncodeToString(salt)
+	for i, o := range objects {
+		// Write the new record. If it already exists, a previous migration was
+		// interrupted before deleting the legacy record.
+		_, err = r.writeRecord(ctx, jobs[i])
+		if _, ok := err.(*gcs.PreconditionError); ok {
+			err = nil
+		}
 
-	// Write out the marker object.
-	var precond int64
-	createReq := &gcs.CreateObjectRequest{
-		Name: markerObjectName,
-		Metadata: map[string]string{
-			markerObjectMetadata_Salt:       encodedSalt,
-			markerObjectMetadata_Ciphertext: encodedCiphertext,
-		},
-		Contents:               strings.NewReader(""),
-		GenerationPrecondition: &precond,
+		if err != nil {
+			err = fmt.Errorf("writeRecord(%q): %v", o.Name, err)
+			return
+		}
+
+		// Delete the legacy record, taking care not to delete a different
+		// generation than the one we read.
+		err = r.bucket.DeleteObject(
+			ctx,
+			&gcs.DeleteObjectRequest{
+				Name:       o.Name,
+				Generation: o.Generation,
+			})
+
+		if err != nil {
+			err = fmt.Errorf("DeleteObject(%q): %v", o.Name, err)
+			return
+		}
+
+		migrated++
 	}
 
-	_, err = bucket.CreateObject(ctx, createReq)
+	return
+}
+
+// Like InitGCSRegistry, but with more injected.
+func initGCSRegistry(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	cryptoPassword string,
+	opts NewBucketOptions,
+	createCrypter func(key []byte) (crypto.Crypter, error),
+	cryptoRandSrc io.Reader) (r Registry, crypter crypto.Crypter, err error) {
+	// Refuse to touch a bucket that's already in use. claimBucket's
+	// precondition catches a race with another machine doing the same.
+	m, err := readMarker(ctx, bucket)
 	if err != nil {
-		err = fmt.Errorf("CreateObject: %v", err)
+		err = fmt.Errorf("readMarker: %v", err)
 		return
 	}
 
-	// All is good.
-	r = &gcsRegistry{
-		bucket: bucket,
+	if m != nil {
+		err = fmt.Errorf("Bucket %q already contains a repository.", bucket.Name())
+		return
 	}
 
+	contents, masterKey, err := claimBucket(
+		ctx,
+		bucket,
+		cryptoPassword,
+		opts,
+		createCrypter,
+		cryptoRandSrc)
+
+	if err != nil {
+		err = fmt.Errorf("claimBucket: %v", err)
+		return
+	}
+
+	r, crypter, err = makeGCSRegistry(bucket, contents, masterKey, 0, createCrypter)
 	return
 }
 
-func verifyCompatibleAndSetUpCrypter(
-	markerObject *gcs.Object,
+// Like NewGCSRegistry, but with more injected.
+func newGCSRegistry(
+	ctx context.Context,
+	bucket gcs.Bucket,
 	cryptoPassword string,
-	deriver crypto.KeyDeriver,
 	createCrypter func(key []byte) (crypto.Crypter, error)) (
+	r Registry,
 	crypter crypto.Crypter,
 	err error) {
-	o := markerObject
-
-	// Find the metadata keys.
-	var passwordSaltBase64 string
-	var ciphertextBase64 string
-	var ok bool
-
-	if passwordSaltBase64, ok = o.Metadata[markerObjectMetadata_Salt]; !ok {
-		err = fmt.Errorf("Missing salt metadata key.")
+	// Find the previously-written marker object, which we must use to find the
+	// key.
+	m, err := readMarker(ctx, bucket)
+	if err != nil {
+		err = fmt.Errorf("readMarker: %v", err)
 		return
 	}
 
-	if ciphertextBase64, ok = o.Metadata[markerObjectMetadata_Ciphertext]; !ok {
-		err = fmt.Errorf("Missing ciphertext metadata key.")
+	if m == nil {
+		err = fmt.Errorf(
+			"Bucket %q has no marker object, so doesn't contain a repository; "+
+				"use `comeback init` to create one.",
+			bucket.Name())
 		return
 	}
 
-	// Base64-decode.
-	passwordSalt, err := base64.StdEncoding.DecodeString(passwordSaltBase64)
+	masterKey, slotIndex, err := m.masterKey(cryptoPassword, createCrypter)
 	if err != nil {
-		err = fmt.Errorf("Decoding password salt: %v", err)
 		return
 	}
 
-	ciphertext, err := base64.StdEncoding.DecodeString(ciphertextBase64)
-	if err != nil {
-		err = fmt.Errorf("Decoding ciphertext: %v", err)
-		return
-	}
+	r, crypter, err = makeGCSRegistry(
+		bucket,
+		&m.contents,
+		masterKey,
+		slotIndex,
+		createCrypter)
+
+	return
+}
 
-	// Derive a key and create a crypter.
-	cryptoKey := deriver.DeriveKey(cryptoPassword, passwordSalt)
-	if crypter, err = createCrypter(cryptoKey); err != nil {
-		err = fmt.Errorf("createCrypter: %v", err)
+// Set up a registry for a bucket with the supplied marker contents, given the
+// key unwrapped from the slot with the given index (or -1 for a legacy
+// marker).
+func makeGCSRegistry(
+	bucket gcs.Bucket,
+	contents *jsonMarker,
+	masterKey []byte,
+	slotIndex int,
+	createCrypter func(key []byte) (crypto.Crypter, error)) (
+	r Registry,
+	crypter crypto.Crypter,
+	err error) {
+	crypter, err = contentsCrypter(contents, masterKey, slotIndex, createCrypter)
+	if err != nil {
+		err = fmt.Errorf("contentsCrypter: %v", err)
 		return
 	}
 
-	// Attempt to decrypt the ciphertext.
-	if _, err = crypter.Decrypt(ciphertext); err != nil {
-		// Special case: Did the crypter signal that the key was wrong?
-		if _, ok := err.(*crypto.NotAuthenticError); ok {
-			err = fmt.Errorf("The supplied password is incorrect.")
-			return
-		}
+	crypter = crypto.BindRepository(crypter, contents.RepositoryID)
 
-		// Generic error.
-		err = fmt.Errorf("Decrypt: %v", err)
-		return
+	// All is good.
+	r = &gcsRegistry{
+		bucket:  bucket,
+		crypter: crypter,
 	}
 
 	return
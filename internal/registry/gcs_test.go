@@ -0,0 +1,484 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/crypto"
+	"github.com/jacobsa/gcloud/gcs"
+	"github.com/jacobsa/gcloud/gcs/gcsfake"
+	"github.com/jacobsa/gcloud/gcs/gcsutil"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+	"github.com/jacobsa/timeutil"
+)
+
+func TestGCS(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type GCSRegistryTest struct {
+	ctx      context.Context
+	bucket   gcs.Bucket
+	registry Registry
+	crypter  crypto.Crypter
+}
+
+func init() { RegisterTestSuite(&GCSRegistryTest{}) }
+
+func (t *GCSRegistryTest) SetUp(ti *TestInfo) {
+	var err error
+
+	t.ctx = ti.Ctx
+	t.bucket = gcsfake.NewFakeBucket(timeutil.RealClock(), "some_bucket")
+	t.registry, t.crypter, err = initGCSRegistry(
+		t.ctx,
+		t.bucket,
+		"some password",
+		NewBucketOptions{KDF: KDF{Name: KDF_Pbkdf2, Iterations: 1}},
+		crypto.NewCrypter,
+		rand.Reader)
+
+	AssertEq(nil, err)
+}
+
+func (t *GCSRegistryTest) listObjects(prefix string) (objects []*gcs.Object) {
+	objects, _, err := gcsutil.ListAll(
+		t.ctx,
+		t.bucket,
+		&gcs.ListObjectsRequest{Prefix: prefix})
+
+	AssertEq(nil, err)
+	return
+}
+
+// Write a record in the format used by the oldest versions of comeback.
+func (t *GCSRegistryTest) createPlaintextRecord(j CompletedJob) {
+	_, err := t.bucket.CreateObject(
+		t.ctx,
+		&gcs.CreateObjectRequest{
+			Name:     gcsJobKeyPrefix + j.StartTime.UTC().Format(time.RFC3339),
+			Contents: strings.NewReader(""),
+			Metadata: map[string]string{
+				gcsMetadataKey_Name:  j.Name,
+				gcsMetadataKey_Score: j.Score.Hex(),
+			},
+		})
+
+	AssertEq(nil, err)
+}
+
+// Write an encrypted record under a name containing its start time, as
+// comeback once did.
+func (t *GCSRegistryTest) createTimeNamedRecord(j CompletedJob) {
+	contents, err := marshalRecord(t.crypter, j)
+	AssertEq(nil, err)
+
+	_, err = t.bucket.CreateObject(
+		t.ctx,
+		&gcs.CreateObjectRequest{
+			Name:     gcsJobKeyPrefix + j.StartTime.UTC().Format(time.RFC3339),
+			Contents: strings.NewReader(string(contents)),
+		})
+
+	AssertEq(nil, err)
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *GCSRegistryTest) RoundTrip() {
+	start := time.Date(2026, time.March, 1, 2, 3, 4, 5, time.UTC)
+	j0 := CompletedJob{
+		StartTime:     start,
+		Name:          "taco",
+		Score:         blob.ComputeScore([]byte("taco")),
+		EndTime:       start.Add(time.Hour),
+		Hostname:      "kitchen",
+		BasePath:      "/home/taco",
+		Version:       "v1.2.3",
+		FilesScanned:  1,
+		DirsScanned:   2,
+		BytesScanned:  3,
+		BlobsUploaded: 4,
+		BytesUploaded: 5,
+		Warnings:      6,
+		Tags:          []string{"nightly", "spicy"},
+	}
+
+	j1 := CompletedJob{
+		StartTime: start.Add(24 * time.Hour),
+		Name:      "burrito",
+		Score:     blob.ComputeScore([]byte("burrito")),
+	}
+
+	AssertEq(nil, t.registry.RecordBackup(t.ctx, j0))
+	AssertEq(nil, t.registry.RecordBackup(t.ctx, j1))
+
+	jobs, err := t.registry.ListBackups(t.ctx)
+	AssertEq(nil, err)
+	AssertEq(2, len(jobs))
+
+	ExpectThat(jobs[0], DeepEquals(j0))
+	ExpectThat(jobs[1], DeepEquals(j1))
+	ExpectEq(time.Hour, jobs[0].Duration())
+	ExpectEq(0, jobs[1].Duration())
+}
+
+func (t *GCSRegistryTest) RecordsAreEncrypted() {
+	j := CompletedJob{
+		StartTime: time.Date(2026, time.March, 1, 2, 3, 4, 0, time.UTC),
+		Name:      "enchilada",
+		Score:     blob.ComputeScore([]byte("taco")),
+	}
+
+	AssertEq(nil, t.registry.RecordBackup(t.ctx, j))
+
+	objects := t.listObjects(gcsRecordPrefix)
+	AssertEq(1, len(objects))
+	ExpectEq(0, len(objects[0].Metadata))
+	ExpectFalse(strings.Contains(objects[0].Name, "2026"), "%s", objects[0].Name)
+	ExpectEq(0, len(t.listObjects(gcsJobKeyPrefix)))
+
+	contents, err := gcsutil.ReadObject(t.ctx, t.bucket, objects[0].Name)
+	AssertEq(nil, err)
+	ExpectFalse(strings.Contains(string(contents), j.Name))
+	ExpectFalse(strings.Contains(string(contents), j.Score.Hex()))
+}
+
+func (t *GCSRegistryTest) LegacyRecords() {
+	j0 := CompletedJob{
+		StartTime: time.Date(2015, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "taco",
+		Score:     blob.ComputeScore([]byte("taco")),
+	}
+
+	j1 := CompletedJob{
+		StartTime: time.Date(2016, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "burrito",
+		Score:     blob.ComputeScore([]byte("burrito")),
+		Hostname:  "kitchen",
+	}
+
+	j2 := CompletedJob{
+		StartTime: time.Date(2017, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "enchilada",
+		Score:     blob.ComputeScore([]byte("enchilada")),
+	}
+
+	t.createTimeNamedRecord(j1)
+	t.createPlaintextRecord(j0)
+	AssertEq(nil, t.registry.RecordBackup(t.ctx, j2))
+
+	jobs, err := t.registry.ListBackups(t.ctx)
+	AssertEq(nil, err)
+	AssertEq(3, len(jobs))
+
+	ExpectThat(jobs[0], DeepEquals(j0))
+	ExpectThat(jobs[1], DeepEquals(j1))
+	ExpectThat(jobs[2], DeepEquals(j2))
+}
+
+func (t *GCSRegistryTest) MigrateLegacyRecords() {
+	j0 := CompletedJob{
+		StartTime: time.Date(2015, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "taco",
+		Score:     blob.ComputeScore([]byte("taco")),
+	}
+
+	j1 := CompletedJob{
+		StartTime: time.Date(2016, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "burrito",
+		Score:     blob.ComputeScore([]byte("burrito")),
+		Tags:      []string{"spicy"},
+	}
+
+	t.createPlaintextRecord(j0)
+	t.createTimeNamedRecord(j1)
+
+	// Migrate.
+	migrated, err := t.registry.MigrateLegacyRecords(t.ctx)
+	AssertEq(nil, err)
+	ExpectEq(2, migrated)
+
+	ExpectEq(0, len(t.listObjects(gcsJobKeyPrefix)))
+	ExpectEq(2, len(t.listObjects(gcsRecordPrefix)))
+
+	jobs, err := t.registry.ListBackups(t.ctx)
+	AssertEq(nil, err)
+	AssertEq(2, len(jobs))
+	ExpectThat(jobs[0], DeepEquals(j0))
+	ExpectThat(jobs[1], DeepEquals(j1))
+
+	// There's nothing more to do.
+	migrated, err = t.registry.MigrateLegacyRecords(t.ctx)
+	AssertEq(nil, err)
+	ExpectEq(0, migrated)
+}
+
+func (t *GCSRegistryTest) InterruptedMigration() {
+	j := CompletedJob{
+		StartTime: time.Date(2015, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "taco",
+		Score:     blob.ComputeScore([]byte("taco")),
+	}
+
+	// Simulate a migration that wrote the new record but didn't delete the
+	// old one.
+	t.createPlaintextRecord(j)
+	AssertEq(nil, t.registry.RecordBackup(t.ctx, j))
+
+	// The backup should be listed once.
+	jobs, err := t.registry.ListBackups(t.ctx)
+	AssertEq(nil, err)
+	AssertEq(1, len(jobs))
+	ExpectThat(jobs[0], DeepEquals(j))
+
+	// Migration should finish the job.
+	migrated, err := t.registry.MigrateLegacyRecords(t.ctx)
+	AssertEq(nil, err)
+	ExpectEq(1, migrated)
+
+	ExpectEq(0, len(t.listObjects(gcsJobKeyPrefix)))
+	ExpectEq(1, len(t.listObjects(gcsRecordPrefix)))
+}
+
+func (t *GCSRegistryTest) DuplicateRecord() {
+	j := CompletedJob{
+		StartTime: time.Date(2026, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "taco",
+		Score:     blob.ComputeScore([]byte("taco")),
+	}
+
+	AssertEq(nil, t.registry.RecordBackup(t.ctx, j))
+
+	err := t.registry.RecordBackup(t.ctx, j)
+	ExpectThat(err, Error(HasSubstr("Precondition")))
+}
+
+func (t *GCSRegistryTest) FutureRecordVersion() {
+	ciphertext, err := t.crypter.Encrypt(
+		nil,
+		[]byte(`{"version": 2, "name": "taco"}`),
+		nil)
+
+	AssertEq(nil, err)
+
+	_, err = t.bucket.CreateObject(
+		t.ctx,
+		&gcs.CreateObjectRequest{
+			Name:     gcsJobKeyPrefix + "2026-01-02T03:04:05Z",
+			Contents: strings.NewReader(string(ciphertext)),
+		})
+
+	AssertEq(nil, err)
+
+	_, err = t.registry.ListBackups(t.ctx)
+	ExpectThat(err, Error(HasSubstr("Unsupported record version 2")))
+}
+
+func (t *GCSRegistryTest) CorruptRecord() {
+	_, err := t.bucket.CreateObject(
+		t.ctx,
+		&gcs.CreateObjectRequest{
+			Name:     gcsJobKeyPrefix + "2026-01-02T03:04:05Z",
+			Contents: strings.NewReader("taco"),
+		})
+
+	AssertEq(nil, err)
+
+	_, err = t.registry.ListBackups(t.ctx)
+	ExpectThat(err, Error(HasSubstr("Decrypt")))
+}
+
+func (t *GCSRegistryTest) RecordFromAnotherRepository() {
+	// Two repositories sharing a key.
+	base, err := crypto.NewCrypter(make([]byte, 32))
+	AssertEq(nil, err)
+
+	c0 := crypto.BindRepository(base, []byte("repository 0"))
+	c1 := crypto.BindRepository(base, []byte("repository 1"))
+
+	j := CompletedJob{
+		StartTime: time.Date(2026, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "taco",
+		Score:     blob.ComputeScore([]byte("taco")),
+	}
+
+	b, err := marshalRecord(c0, j)
+	AssertEq(nil, err)
+
+	// The record can be read in its own repository, but not the other.
+	_, err = unmarshalRecord(c0, b)
+	ExpectEq(nil, err)
+
+	_, err = unmarshalRecord(c1, b)
+	ExpectThat(err, Error(HasSubstr("Decrypt")))
+}
+
+func (t *GCSRegistryTest) LegacyRecordFormat() {
+	// Older versions encrypted records with no associated data.
+	score := blob.ComputeScore([]byte("taco"))
+	ciphertext, err := t.crypter.Encrypt(
+		nil,
+		[]byte(`{"version": 1, "name": "taco", "score": "`+score.Hex()+`"}`),
+		nil)
+
+	AssertEq(nil, err)
+
+	j, err := unmarshalRecord(t.crypter, ciphertext)
+	AssertEq(nil, err)
+	ExpectEq("taco", j.Name)
+	ExpectEq(score, j.Score)
+}
+
+func (t *GCSRegistryTest) DeleteBackups() {
+	start := time.Date(2026, time.January, 2, 3, 4, 5, 6, time.UTC)
+	j0 := CompletedJob{
+		StartTime: start,
+		Name:      "taco",
+		Score:     blob.ComputeScore([]byte("taco")),
+		Tags:      []string{"spicy"},
+	}
+
+	j1 := CompletedJob{
+		StartTime: start.Add(time.Hour),
+		Name:      "taco",
+		Score:     blob.ComputeScore([]byte("burrito")),
+	}
+
+	AssertEq(nil, t.registry.RecordBackup(t.ctx, j0))
+	AssertEq(nil, t.registry.RecordBackup(t.ctx, j1))
+
+	// Delete a backup as listed.
+	jobs, err := t.registry.ListBackups(t.ctx)
+	AssertEq(nil, err)
+	AssertEq(2, len(jobs))
+
+	err = t.registry.DeleteBackups(t.ctx, jobs[:1])
+	AssertEq(nil, err)
+
+	jobs, err = t.registry.ListBackups(t.ctx)
+	AssertEq(nil, err)
+	AssertEq(1, len(jobs))
+	ExpectThat(jobs[0], DeepEquals(j1))
+
+	// Deleting it again should fail.
+	err = t.registry.DeleteBackups(t.ctx, []CompletedJob{j0})
+	ExpectThat(err, Error(HasSubstr("No record found")))
+}
+
+func (t *GCSRegistryTest) DeleteBackups_Legacy() {
+	j0 := CompletedJob{
+		StartTime: time.Date(2015, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "taco",
+		Score:     blob.ComputeScore([]byte("taco")),
+	}
+
+	j1 := CompletedJob{
+		StartTime: time.Date(2016, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "taco",
+		Score:     blob.ComputeScore([]byte("burrito")),
+	}
+
+	t.createPlaintextRecord(j0)
+	t.createTimeNamedRecord(j1)
+
+	// A backup with the same start time but a different score shouldn't match.
+	other := j0
+	other.Score = blob.ComputeScore([]byte("enchilada"))
+	err := t.registry.DeleteBackups(t.ctx, []CompletedJob{other})
+	ExpectThat(err, Error(HasSubstr("No record found")))
+
+	AssertEq(nil, t.registry.DeleteBackups(t.ctx, []CompletedJob{j0}))
+	AssertEq(nil, t.registry.DeleteBackups(t.ctx, []CompletedJob{j1}))
+
+	ExpectEq(0, len(t.listObjects(gcsJobKeyPrefix)))
+}
+
+func (t *GCSRegistryTest) DeleteBackups_InterruptedMigration() {
+	j := CompletedJob{
+		StartTime: time.Date(2015, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "taco",
+		Score:     blob.ComputeScore([]byte("taco")),
+	}
+
+	t.createPlaintextRecord(j)
+	AssertEq(nil, t.registry.RecordBackup(t.ctx, j))
+
+	AssertEq(nil, t.registry.DeleteBackups(t.ctx, []CompletedJob{j}))
+
+	ExpectEq(0, len(t.listObjects(gcsJobKeyPrefix)))
+	ExpectEq(0, len(t.listObjects(gcsRecordPrefix)))
+}
+
+func (t *GCSRegistryTest) DeleteBackups_Several() {
+	var jobs []CompletedJob
+	for i := 0; i < 4; i++ {
+		j := CompletedJob{
+			StartTime: time.Date(2015+i, time.January, 2, 3, 4, 5, 0, time.UTC),
+			Name:      "taco",
+			Score:     blob.ComputeScore([]byte(fmt.Sprintf("taco %d", i))),
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	t.createPlaintextRecord(jobs[0])
+	t.createTimeNamedRecord(jobs[1])
+	AssertEq(nil, t.registry.RecordBackup(t.ctx, jobs[2]))
+	AssertEq(nil, t.registry.RecordBackup(t.ctx, jobs[3]))
+
+	AssertEq(nil, t.registry.DeleteBackups(t.ctx, jobs[:3]))
+
+	listed, err := t.registry.ListBackups(t.ctx)
+	AssertEq(nil, err)
+	ExpectThat(listed, ElementsAre(DeepEquals(jobs[3])))
+}
+
+func (t *GCSRegistryTest) DeleteBackups_MissingRecord() {
+	j0 := CompletedJob{
+		StartTime: time.Date(2015, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "taco",
+		Score:     blob.ComputeScore([]byte("taco")),
+	}
+
+	j1 := CompletedJob{
+		StartTime: time.Date(2016, time.January, 2, 3, 4, 5, 0, time.UTC),
+		Name:      "taco",
+		Score:     blob.ComputeScore([]byte("burrito")),
+	}
+
+	AssertEq(nil, t.registry.RecordBackup(t.ctx, j0))
+
+	// Nothing should be deleted if any backup is missing its record.
+	err := t.registry.DeleteBackups(t.ctx, []CompletedJob{j0, j1})
+	ExpectThat(err, Error(HasSubstr("No record found")))
+
+	listed, err := t.registry.ListBackups(t.ctx)
+	AssertEq(nil, err)
+	ExpectThat(listed, ElementsAre(DeepEquals(j0)))
+}
@@ -0,0 +1,208 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/jacobsa/comeback/internal/crypto"
+)
+
+// A key derivation function and its parameters, recorded in each key slot so
+// that the cost of guessing passwords can be raised over time without
+// touching anything but the marker.
+type KDF struct {
+	// One of KDF_Pbkdf2, KDF_Scrypt, or KDF_Argon2id.
+	Name string `json:"name"`
+
+	// PBKDF2-HMAC-SHA256.
+	Iterations int `json:"iterations,omitempty"`
+
+	// scrypt.
+	N int `json:"n,omitempty"`
+	R int `json:"r,omitempty"`
+	P int `json:"p,omitempty"`
+
+	// Argon2id. Memory is in KiB.
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
+const (
+	KDF_Pbkdf2   = "pbkdf2-sha256"
+	KDF_Scrypt   = "scrypt"
+	KDF_Argon2id = "argon2id"
+
+	// The length of keys used to wrap the master key. This is the minimum for
+	// AES-SIV.
+	wrappingKeyLen = 32
+)
+
+// Upper bounds on KDF parameters, each well beyond the defaults below. Key
+// slots are read from the bucket, so without these a corrupt or tampered
+// marker could make every client allocate gigabytes of memory or spin for
+// hours before it could even report a wrong password.
+const (
+	maxPbkdf2Iterations = 10 * 1000 * 1000
+
+	// For scrypt, memory is 128*N*r bytes and time is proportional to N*r*p.
+	maxScryptMemory = 1 << 30
+	maxScryptCost   = 1 << 24
+
+	// For Argon2id, memory is in KiB.
+	maxArgon2idTime    = 64
+	maxArgon2idMemory  = 1 << 20
+	maxArgon2idThreads = 64
+)
+
+// The KDF used for new key slots by default: Argon2id with the second
+// recommended option of RFC 9106, section 4.
+var DefaultKDF = KDF{
+	Name:    KDF_Argon2id,
+	Time:    3,
+	Memory:  64 * 1024,
+	Threads: 4,
+}
+
+// Reasonable parameters for each KDF, for use when the user asks for one by
+// name.
+var DefaultKDFs = map[string]KDF{
+	KDF_Pbkdf2:   {Name: KDF_Pbkdf2, Iterations: 600000},
+	KDF_Scrypt:   {Name: KDF_Scrypt, N: 1 << 17, R: 8, P: 1},
+	KDF_Argon2id: DefaultKDF,
+}
+
+// The KDF used by older versions of comeback, which didn't record one: PBKDF2,
+// recommended for use by NIST Special Publication 800-132, with HMAC-SHA256
+// and a fixed 4096 iterations.
+var legacyKDF = KDF{Name: KDF_Pbkdf2, Iterations: 4096}
+
+// Create a key deriver for the KDF, returning an error if its parameters are
+// invalid.
+func (k *KDF) keyDeriver() (d crypto.KeyDeriver, err error) {
+	switch k.Name {
+	case KDF_Pbkdf2:
+		if k.Iterations < 1 {
+			err = fmt.Errorf("PBKDF2 iterations must be at least one.")
+			return
+		}
+
+		if k.Iterations > maxPbkdf2Iterations {
+			err = fmt.Errorf(
+				"PBKDF2 iterations must be at most %d.",
+				maxPbkdf2Iterations)
+			return
+		}
+
+		d = crypto.NewPbkdf2KeyDeriver(k.Iterations, wrappingKeyLen, sha256.New)
+
+	case KDF_Scrypt:
+		err = checkScryptLimits(int64(k.N), int64(k.R), int64(k.P))
+		if err != nil {
+			return
+		}
+
+		d, err = crypto.NewScryptKeyDeriver(k.N, k.R, k.P, wrappingKeyLen)
+
+	case KDF_Argon2id:
+		err = checkArgon2idLimits(k.Time, k.Memory, k.Threads)
+		if err != nil {
+			return
+		}
+
+		d, err = crypto.NewArgon2idKeyDeriver(
+			k.Time,
+			k.Memory,
+			k.Threads,
+			wrappingKeyLen)
+
+	default:
+		err = fmt.Errorf("Unknown KDF %q", k.Name)
+	}
+
+	return
+}
+
+// Reject scrypt parameters that would use too much memory or time. Smaller
+// values are left for the scrypt implementation to check.
+func checkScryptLimits(n, r, p int64) (err error) {
+	const maxNR = maxScryptMemory / 128
+	if n > maxNR || r > maxNR || n*r > maxNR {
+		err = fmt.Errorf(
+			"scrypt N*r must be at most %d (%d MiB of memory).",
+			maxNR,
+			maxScryptMemory>>20)
+		return
+	}
+
+	if p > maxScryptCost || n*r*p > maxScryptCost {
+		err = fmt.Errorf("scrypt N*r*p must be at most %d.", maxScryptCost)
+		return
+	}
+
+	return
+}
+
+// Reject Argon2id parameters that would use too much memory or time.
+func checkArgon2idLimits(
+	time uint32,
+	memory uint32,
+	threads uint8) (err error) {
+	switch {
+	case time > maxArgon2idTime:
+		err = fmt.Errorf("Argon2id time must be at most %d.", maxArgon2idTime)
+
+	case memory > maxArgon2idMemory:
+		err = fmt.Errorf(
+			"Argon2id memory must be at most %d KiB.",
+			maxArgon2idMemory)
+
+	case threads > maxArgon2idThreads:
+		err = fmt.Errorf(
+			"Argon2id threads must be at most %d.",
+			maxArgon2idThreads)
+	}
+
+	return
+}
+
+// Check that the KDF's parameters are valid.
+func (k KDF) Validate() (err error) {
+	_, err = k.keyDeriver()
+	return
+}
+
+func (k KDF) String() string {
+	switch k.Name {
+	case KDF_Pbkdf2:
+		return fmt.Sprintf("%s (%d iterations)", k.Name, k.Iterations)
+
+	case KDF_Scrypt:
+		return fmt.Sprintf("%s (N=%d, r=%d, p=%d)", k.Name, k.N, k.R, k.P)
+
+	case KDF_Argon2id:
+		return fmt.Sprintf(
+			"%s (t=%d, m=%d KiB, p=%d)",
+			k.Name,
+			k.Time,
+			k.Memory,
+			k.Threads)
+	}
+
+	return k.Name
+}
@@ -0,0 +1,509 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/jacobsa/comeback/internal/crypto"
+	"github.com/jacobsa/gcloud/gcs"
+)
+
+// Information about one of the key slots in the bucket's marker, each of
+// which independently unlocks the master key.
+type KeySlot struct {
+	Label string
+
+	// True if the slot holds a generated recovery key rather than a password.
+	Recovery bool
+
+	// True if the slot allows saving backups but not reading them.
+	WriteOnly bool
+
+	// The function used to derive a key from the slot's password.
+	KDF KDF
+}
+
+// List the bucket's key slots. This doesn't require a password, since slot
+// labels are stored in plaintext.
+func ListKeySlots(
+	ctx context.Context,
+	bucket gcs.Bucket) (slots []KeySlot, err error) {
+	m, err := readMarker(ctx, bucket)
+	if err != nil {
+		err = fmt.Errorf("readMarker: %v", err)
+		return
+	}
+
+	switch {
+	case m == nil:
+		err = fmt.Errorf(
+			"The bucket has no marker object; use `comeback init` to create " +
+				"a repository.")
+		return
+
+	// Legacy markers have a single password, which becomes the default slot
+	// when converted.
+	case m.legacy:
+		slots = []KeySlot{{Label: defaultSlotLabel, KDF: legacyKDF}}
+		return
+	}
+
+	for _, s := range m.contents.KeySlots {
+		kdf := legacyKDF
+		if s.KDF != nil {
+			kdf = *s.KDF
+		}
+
+		slots = append(slots, KeySlot{
+			Label:     s.Label,
+			Recovery:  s.Kind == slotKind_Recovery,
+			WriteOnly: s.Access == slotAccess_Write,
+			KDF:       kdf,
+		})
+	}
+
+	return
+}
+
+// Change the password for the key slot unlocked by oldPassword, protecting
+// it with the given KDF, without touching anything else in the bucket. If the
+// bucket was set up by an older version of comeback, this converts its marker
+// to the current format, keeping the key that the bucket already uses. Older
+// versions of comeback will no longer be able to open the bucket afterward.
+func ChangePassword(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	oldPassword string,
+	newPassword string,
+	kdf KDF) (err error) {
+	err = modifyMarker(
+		ctx,
+		bucket,
+		oldPassword,
+		kdf,
+		func(c *jsonMarker, masterKey []byte, slotIndex int) (err error) {
+			old := c.KeySlots[slotIndex]
+			if old.Kind == slotKind_Recovery {
+				err = fmt.Errorf(
+					"Slot %q holds a recovery key, which can't be changed. "+
+						"Add a new slot and revoke this one instead.",
+					old.Label)
+				return
+			}
+
+			c.KeySlots[slotIndex], err = wrapKey(
+				masterKey,
+				old.Label,
+				old.Kind,
+				old.Access,
+				newPassword,
+				kdf,
+				crypto.NewCrypter,
+				rand.Reader)
+
+			if err != nil {
+				err = fmt.Errorf("wrapKey: %v", err)
+				return
+			}
+
+			return
+		})
+
+	return
+}
+
+// Re-wrap the master key in the slot unlocked by password using the given
+// KDF, keeping the same password. Other slots are untouched, since we don't
+// know their passwords.
+func UpgradeKDF(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	password string,
+	kdf KDF) (err error) {
+	err = modifyMarker(
+		ctx,
+		bucket,
+		password,
+		kdf,
+		func(c *jsonMarker, masterKey []byte, slotIndex int) (err error) {
+			old := c.KeySlots[slotIndex]
+			c.KeySlots[slotIndex], err = wrapKey(
+				masterKey,
+				old.Label,
+				old.Kind,
+				old.Access,
+				password,
+				kdf,
+				crypto.NewCrypter,
+				rand.Reader)
+
+			if err != nil {
+				err = fmt.Errorf("wrapKey: %v", err)
+				return
+			}
+
+			return
+		})
+
+	return
+}
+
+// Add a key slot with the given label that allows newPassword to unlock the
+// bucket. password must unlock an existing slot.
+func AddKeySlot(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	password string,
+	label string,
+	newPassword string,
+	kdf KDF) (err error) {
+	err = addSlot(
+		ctx,
+		bucket,
+		password,
+		label,
+		slotKind_Password,
+		slotAccess_Full,
+		newPassword,
+		kdf)
+
+	return
+}
+
+// Add a write-only key slot with the given label, allowing newPassword to be
+// used to save backups but not to read them. The bucket must use envelope
+// encryption, and password must unlock an existing slot that isn't
+// write-only.
+func AddWriteOnlyKeySlot(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	password string,
+	label string,
+	newPassword string,
+	kdf KDF) (err error) {
+	err = addSlot(
+		ctx,
+		bucket,
+		password,
+		label,
+		slotKind_Password,
+		slotAccess_Write,
+		newPassword,
+		kdf)
+
+	return
+}
+
+// Generate a random recovery key and add a key slot with the given label for
+// it, returning the key. The key isn't stored anywhere else, so the caller
+// must make sure the user gets to see it.
+func AddRecoveryKey(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	password string,
+	label string,
+	kdf KDF) (recoveryKey string, err error) {
+	recoveryKey, err = newRecoveryKey(rand.Reader)
+	if err != nil {
+		err = fmt.Errorf("newRecoveryKey: %v", err)
+		return
+	}
+
+	err = addSlot(
+		ctx,
+		bucket,
+		password,
+		label,
+		slotKind_Recovery,
+		slotAccess_Full,
+		recoveryKey,
+		kdf)
+
+	return
+}
+
+// Remove the key slot with the given label. password must unlock some slot
+// that isn't write-only, which may be the one being revoked. The last slot
+// that isn't write-only can't be revoked.
+//
+// Note that this doesn't change the master key, so whoever held the slot's
+// password can still decrypt the bucket if they have kept the key or an old
+// copy of the marker.
+func RevokeKeySlot(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	password string,
+	label string,
+	kdf KDF) (err error) {
+	err = modifyMarker(
+		ctx,
+		bucket,
+		password,
+		kdf,
+		func(c *jsonMarker, masterKey []byte, slotIndex int) (err error) {
+			err = checkFullAccess(c, slotIndex)
+			if err != nil {
+				return
+			}
+
+			i := findSlot(c, label)
+			if i < 0 {
+				err = fmt.Errorf("There is no key slot labelled %q.", label)
+				return
+			}
+
+			full := 0
+			for _, s := range c.KeySlots {
+				if s.Access != slotAccess_Write {
+					full++
+				}
+			}
+
+			if full == 1 && c.KeySlots[i].Access != slotAccess_Write {
+				err = fmt.Errorf("Refusing to revoke the only key slot.")
+				return
+			}
+
+			c.KeySlots = append(c.KeySlots[:i], c.KeySlots[i+1:]...)
+			return
+		})
+
+	return
+}
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+// Return the index of the slot with the given label, or -1.
+func findSlot(c *jsonMarker, label string) int {
+	for i, s := range c.KeySlots {
+		if s.Label == label {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Return an error if the slot with the given index is write-only. Such slots
+// may not be used to manage others.
+func checkFullAccess(c *jsonMarker, slotIndex int) (err error) {
+	s := c.KeySlots[slotIndex]
+	if s.Access == slotAccess_Write {
+		err = fmt.Errorf(
+			"Slot %q is write-only, and can't be used to manage key slots.",
+			s.Label)
+		return
+	}
+
+	return
+}
+
+func addSlot(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	password string,
+	label string,
+	kind string,
+	access string,
+	newPassword string,
+	kdf KDF) (err error) {
+	if label == "" {
+		err = fmt.Errorf("Key slots must have a label.")
+		return
+	}
+
+	err = modifyMarker(
+		ctx,
+		bucket,
+		password,
+		kdf,
+		func(c *jsonMarker, masterKey []byte, slotIndex int) (err error) {
+			err = checkFullAccess(c, slotIndex)
+			if err != nil {
+				return
+			}
+
+			if findSlot(c, label) >= 0 {
+				err = fmt.Errorf("There is already a key slot labelled %q.", label)
+				return
+			}
+
+			// Write-only slots wrap the write key rather than the private key.
+			key := masterKey
+			if access == slotAccess_Write {
+				if c.Envelope == nil {
+					err = fmt.Errorf(
+						"Write-only key slots require a bucket that uses envelope " +
+							"encryption.")
+					return
+				}
+
+				_, key, err = crypto.DeriveEnvelopeKeys(masterKey)
+				if err != nil {
+					err = fmt.Errorf("DeriveEnvelopeKeys: %v", err)
+					return
+				}
+			}
+
+			slot, err := wrapKey(
+				key,
+				label,
+				kind,
+				access,
+				newPassword,
+				kdf,
+				crypto.NewCrypter,
+				rand.Reader)
+
+			if err != nil {
+				err = fmt.Errorf("wrapKey: %v", err)
+				return
+			}
+
+			c.KeySlots = append(c.KeySlots, slot)
+			return
+		})
+
+	return
+}
+
+// Read the marker and unlock it with the supplied password, converting it to
+// the current format (with a slot using the given KDF) if necessary. Call f
+// with the contents, the key unwrapped from the slot that the password
+// unlocked, and that slot's index, then write out the modified contents,
+// failing if anybody else has changed the marker since we read it.
+func modifyMarker(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	password string,
+	kdf KDF,
+	f func(c *jsonMarker, masterKey []byte, slotIndex int) error) (err error) {
+	m, err := readMarker(ctx, bucket)
+	if err != nil {
+		err = fmt.Errorf("readMarker: %v", err)
+		return
+	}
+
+	if m == nil {
+		err = fmt.Errorf(
+			"The bucket has no marker object; use `comeback init` to create " +
+				"a repository.")
+		return
+	}
+
+	masterKey, slotIndex, err := m.masterKey(password, crypto.NewCrypter)
+	if err != nil {
+		return
+	}
+
+	// Convert legacy markers to a single default slot, wrapping the key that
+	// the password derives.
+	c := m.contents
+	if m.legacy {
+		var slot jsonKeySlot
+		slot, err = wrapKey(
+			masterKey,
+			defaultSlotLabel,
+			slotKind_Password,
+			slotAccess_Full,
+			password,
+			kdf,
+			crypto.NewCrypter,
+			rand.Reader)
+
+		if err != nil {
+			err = fmt.Errorf("wrapKey: %v", err)
+			return
+		}
+
+		c = jsonMarker{
+			Version:  markerVersion,
+			KeySlots: []jsonKeySlot{slot},
+		}
+
+		slotIndex = 0
+	}
+
+	err = f(&c, masterKey, slotIndex)
+	if err != nil {
+		return
+	}
+
+	err = writeMarker(ctx, bucket, &c, m.generation)
+	if _, ok := err.(*gcs.PreconditionError); ok {
+		err = fmt.Errorf(
+			"The marker was modified concurrently; please try again.")
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("writeMarker: %v", err)
+		return
+	}
+
+	return
+}
+
+// Generate a random recovery key, formatted in dash-separated groups of
+// characters so that it is easy to write down.
+func newRecoveryKey(cryptoRandSrc io.Reader) (key string, err error) {
+	b := make([]byte, recoveryKeyLen)
+	_, err = io.ReadFull(cryptoRandSrc, b)
+	if err != nil {
+		err = fmt.Errorf("ReadFull: %v", err)
+		return
+	}
+
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+
+	const groupLen = 4
+	var groups []string
+	for len(encoded) > 0 {
+		n := groupLen
+		if n > len(encoded) {
+			n = len(encoded)
+		}
+
+		groups = append(groups, encoded[:n])
+		encoded = encoded[n:]
+	}
+
+	key = strings.Join(groups, "-")
+	return
+}
+
+// Strip the grouping and case from a recovery key, so that it can be typed
+// however the user likes.
+func normalizeRecoveryKey(s string) string {
+	return strings.Map(
+		func(r rune) rune {
+			if r == '-' || unicode.IsSpace(r) {
+				return -1
+			}
+
+			return unicode.ToUpper(r)
+		},
+		s)
+}
@@ -0,0 +1,566 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/jacobsa/comeback/internal/crypto"
+	"github.com/jacobsa/gcloud/gcs"
+)
+
+// The bucket contains a "marker" object, written when the repository is
+// created by InitGCSRegistry, which holds a random master key that encrypts
+// everything else, along with the format version and a repository ID. The
+// master key is stored in one or more labelled key slots, each of which wraps
+// it (encrypts it with AES-SIV, which was designed for the purpose) using a key
+// derived from a password or recovery key and a random salt using the KDF
+// recorded in the slot (see kdf.go). Unwrapping it
+// successfully also tells us that the password is correct. Changing passwords
+// and adding or revoking slots means rewriting only the marker; see
+// key_slots.go.
+//
+// Older versions of comeback instead used the password-derived key directly,
+// with a marker that had empty contents and metadata keys specifying the
+// salt and a ciphertext for some random plaintext, allowing the password to be
+// verified. ChangePassword converts such a marker to the current format,
+// wrapping the key that the bucket already uses.
+const (
+	markerObjectName                = "marker"
+	markerObjectMetadata_Salt       = "base64_salt"
+	markerObjectMetadata_Ciphertext = "base64_ciphertext"
+
+	markerVersion          = 2
+	markerVersion_Envelope = 3
+	saltLen                = 16
+
+	// The label given to the slot created along with the master key.
+	defaultSlotLabel = "default"
+
+	slotKind_Password = "password"
+	slotKind_Recovery = "recovery"
+
+	slotAccess_Full  = "full"
+	slotAccess_Write = "write"
+
+	// The number of random bytes in a recovery key.
+	recoveryKeyLen = 20
+
+	// The length of generated repository IDs.
+	repositoryIDLen = 16
+
+	// The length of generated master keys. This is the minimum for AES-SIV,
+	// and the length of an X25519 private key.
+	masterKeyLen = 32
+)
+
+// The contents of a current marker object.
+type jsonMarker struct {
+	Version int `json:"version"`
+
+	// Each slot independently wraps the same master key, or the write key for
+	// write-only slots.
+	KeySlots []jsonKeySlot `json:"key_slots"`
+
+	// Present only for buckets that use envelope encryption.
+	Envelope *jsonEnvelope `json:"envelope,omitempty"`
+
+	// A random ID bound into each blob's encryption (see blob.Encrypt), so
+	// that blobs can't be copied between buckets. Buckets claimed before this
+	// existed have none, and must never gain one since that would make their
+	// existing blobs unreadable.
+	RepositoryID []byte `json:"repository_id,omitempty"`
+}
+
+type jsonEnvelope struct {
+	PublicKey []byte `json:"public_key"`
+}
+
+type jsonKeySlot struct {
+	// A name for the slot, unique within the marker, used to revoke it.
+	Label string `json:"label"`
+
+	// Either "password" or "recovery". A recovery key is treated like a
+	// password, except that it is normalized before use so that case and
+	// grouping don't matter.
+	Kind string `json:"kind"`
+
+	// Either "full" or "write". Write-only slots exist only in buckets that
+	// use envelope encryption.
+	Access string `json:"access,omitempty"`
+
+	// The function used to derive a wrapping key from the password and salt.
+	// Slots written before this was recorded use legacyKDF.
+	KDF *KDF `json:"kdf,omitempty"`
+
+	Salt       []byte `json:"salt"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// A marker object read from the bucket.
+type marker struct {
+	// The generation of the object, for use in preconditions.
+	generation int64
+
+	// Set only for markers written by older versions of comeback.
+	legacy           bool
+	legacySalt       []byte
+	legacyCiphertext []byte
+
+	// Set only for current markers.
+	contents jsonMarker
+}
+
+// Read the bucket's marker object, returning nil if there is none.
+func readMarker(
+	ctx context.Context,
+	bucket gcs.Bucket) (m *marker, err error) {
+	o, err := bucket.StatObject(
+		ctx,
+		&gcs.StatObjectRequest{Name: markerObjectName})
+
+	if _, ok := err.(*gcs.NotFoundError); ok {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("StatObject: %v", err)
+		return
+	}
+
+	m = &marker{generation: o.Generation}
+
+	// Is this a legacy marker?
+	if _, ok := o.Metadata[markerObjectMetadata_Salt]; ok {
+		err = m.parseLegacy(o)
+		if err != nil {
+			err = fmt.Errorf("parseLegacy: %v", err)
+			return
+		}
+
+		return
+	}
+
+	// Read and parse the contents.
+	rc, err := bucket.NewReader(
+		ctx,
+		&gcs.ReadObjectRequest{
+			Name:       o.Name,
+			Generation: o.Generation,
+		})
+
+	if err != nil {
+		err = fmt.Errorf("NewReader: %v", err)
+		return
+	}
+
+	contents, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		err = fmt.Errorf("ReadAll: %v", err)
+		return
+	}
+
+	err = json.Unmarshal(contents, &m.contents)
+	if err != nil {
+		err = fmt.Errorf("Unmarshal: %v", err)
+		return
+	}
+
+	switch m.contents.Version {
+	case markerVersion:
+		if m.contents.Envelope != nil {
+			err = fmt.Errorf(
+				"Unexpected envelope in version %d marker.",
+				markerVersion)
+			return
+		}
+
+	case markerVersion_Envelope:
+		if m.contents.Envelope == nil {
+			err = fmt.Errorf("Envelope marker is missing its public key.")
+			return
+		}
+
+	default:
+		err = fmt.Errorf(
+			"Unsupported marker version %d; this version of comeback is too old.",
+			m.contents.Version)
+		return
+	}
+
+	// The first markers in this format had a single slot with no label or kind.
+	for i := range m.contents.KeySlots {
+		slot := &m.contents.KeySlots[i]
+		if slot.Label == "" {
+			slot.Label = defaultSlotLabel
+		}
+
+		if slot.Kind == "" {
+			slot.Kind = slotKind_Password
+		}
+
+		if slot.Access == "" {
+			slot.Access = slotAccess_Full
+		}
+
+		if slot.Access == slotAccess_Write && m.contents.Envelope == nil {
+			err = fmt.Errorf(
+				"Slot %q is write-only, but the bucket doesn't use envelope "+
+					"encryption.",
+				slot.Label)
+			return
+		}
+	}
+
+	return
+}
+
+func (m *marker) parseLegacy(o *gcs.Object) (err error) {
+	m.legacy = true
+
+	// Find the metadata keys.
+	var passwordSaltBase64 string
+	var ciphertextBase64 string
+	var ok bool
+
+	if passwordSaltBase64, ok = o.Metadata[markerObjectMetadata_Salt]; !ok {
+		err = fmt.Errorf("Missing salt metadata key.")
+		return
+	}
+
+	if ciphertextBase64, ok = o.Metadata[markerObjectMetadata_Ciphertext]; !ok {
+		err = fmt.Errorf("Missing ciphertext metadata key.")
+		return
+	}
+
+	// Base64-decode.
+	m.legacySalt, err = base64.StdEncoding.DecodeString(passwordSaltBase64)
+	if err != nil {
+		err = fmt.Errorf("Decoding password salt: %v", err)
+		return
+	}
+
+	m.legacyCiphertext, err = base64.StdEncoding.DecodeString(ciphertextBase64)
+	if err != nil {
+		err = fmt.Errorf("Decoding ciphertext: %v", err)
+		return
+	}
+
+	return
+}
+
+// Return the key that encrypts the bucket's contents (or the write key, for
+// write-only slots), given a password or recovery key for any slot, along with
+// the index of the slot that it unlocked. For legacy markers the index is -1.
+func (m *marker) masterKey(
+	cryptoPassword string,
+	createCrypter func(key []byte) (crypto.Crypter, error)) (
+	key []byte,
+	slotIndex int,
+	err error) {
+	if m.legacy {
+		slotIndex = -1
+		key, err = m.legacyKey(cryptoPassword, createCrypter)
+		return
+	}
+
+	for i, slot := range m.contents.KeySlots {
+		slotIndex = i
+		key, err = unwrapKey(slot, cryptoPassword, createCrypter)
+
+		// Try the next slot if the password doesn't fit this one.
+		if _, ok := err.(*crypto.NotAuthenticError); ok {
+			continue
+		}
+
+		if err != nil {
+			err = fmt.Errorf("unwrapKey(%q): %v", slot.Label, err)
+		}
+
+		return
+	}
+
+	err = fmt.Errorf("The supplied password is incorrect.")
+	return
+}
+
+// Return a crypter for the bucket's contents, given the key unwrapped from
+// the slot with the given index of the supplied marker contents (or -1 for a
+// legacy marker).
+func contentsCrypter(
+	c *jsonMarker,
+	key []byte,
+	slotIndex int,
+	createCrypter func(key []byte) (crypto.Crypter, error)) (
+	crypter crypto.Crypter,
+	err error) {
+	if c.Envelope == nil {
+		crypter, err = createCrypter(key)
+		if err != nil {
+			err = fmt.Errorf("createCrypter: %v", err)
+			return
+		}
+
+		return
+	}
+
+	// Write-only slots wrap the write key itself.
+	if c.KeySlots[slotIndex].Access == slotAccess_Write {
+		crypter, err = crypto.NewEnvelopeCrypter(c.Envelope.PublicKey, key, nil)
+		if err != nil {
+			err = fmt.Errorf("NewEnvelopeCrypter: %v", err)
+			return
+		}
+
+		return
+	}
+
+	// Others wrap the private key, from which everything else is derived.
+	publicKey, writeKey, err := crypto.DeriveEnvelopeKeys(key)
+	if err != nil {
+		err = fmt.Errorf("DeriveEnvelopeKeys: %v", err)
+		return
+	}
+
+	if !bytes.Equal(publicKey, c.Envelope.PublicKey) {
+		err = fmt.Errorf("The marker's public key doesn't match its private key.")
+		return
+	}
+
+	crypter, err = crypto.NewEnvelopeCrypter(publicKey, writeKey, key)
+	if err != nil {
+		err = fmt.Errorf("NewEnvelopeCrypter: %v", err)
+		return
+	}
+
+	return
+}
+
+func (m *marker) legacyKey(
+	cryptoPassword string,
+	createCrypter func(key []byte) (crypto.Crypter, error)) (
+	key []byte,
+	err error) {
+	deriver, err := legacyKDF.keyDeriver()
+	if err != nil {
+		err = fmt.Errorf("keyDeriver: %v", err)
+		return
+	}
+
+	// Derive a key and create a crypter.
+	key = deriver.DeriveKey(cryptoPassword, m.legacySalt)
+	crypter, err := createCrypter(key)
+	if err != nil {
+		err = fmt.Errorf("createCrypter: %v", err)
+		return
+	}
+
+	// Attempt to decrypt the ciphertext.
+	if _, err = crypter.Decrypt(m.legacyCiphertext, nil); err != nil {
+		// Special case: Did the crypter signal that the key was wrong?
+		if _, ok := err.(*crypto.NotAuthenticError); ok {
+			err = fmt.Errorf("The supplied password is incorrect.")
+			return
+		}
+
+		// Generic error.
+		err = fmt.Errorf("Decrypt: %v", err)
+		return
+	}
+
+	return
+}
+
+// Attempt to unwrap the key in the supplied slot. Return a
+// *crypto.NotAuthenticError if the password is wrong.
+func unwrapKey(
+	slot jsonKeySlot,
+	cryptoPassword string,
+	createCrypter func(key []byte) (crypto.Crypter, error)) (
+	key []byte,
+	err error) {
+	if slot.Kind == slotKind_Recovery {
+		cryptoPassword = normalizeRecoveryKey(cryptoPassword)
+	}
+
+	kdf := legacyKDF
+	if slot.KDF != nil {
+		kdf = *slot.KDF
+	}
+
+	deriver, err := kdf.keyDeriver()
+	if err != nil {
+		err = fmt.Errorf("keyDeriver: %v", err)
+		return
+	}
+
+	crypter, err := createCrypter(deriver.DeriveKey(cryptoPassword, slot.Salt))
+	if err != nil {
+		err = fmt.Errorf("createCrypter: %v", err)
+		return
+	}
+
+	key, err = crypter.Decrypt(slot.WrappedKey, nil)
+	return
+}
+
+// Create a key slot with the given label, kind, and access that allows the
+// supplied password or recovery key to unwrap the master key (or the write
+// key, for write-only slots), using the given KDF.
+func wrapKey(
+	masterKey []byte,
+	label string,
+	kind string,
+	access string,
+	cryptoPassword string,
+	kdf KDF,
+	createCrypter func(key []byte) (crypto.Crypter, error),
+	cryptoRandSrc io.Reader) (slot jsonKeySlot, err error) {
+	slot.Label = label
+	slot.Kind = kind
+	slot.Access = access
+	slot.KDF = &kdf
+	if kind == slotKind_Recovery {
+		cryptoPassword = normalizeRecoveryKey(cryptoPassword)
+	}
+
+	deriver, err := kdf.keyDeriver()
+	if err != nil {
+		err = fmt.Errorf("keyDeriver: %v", err)
+		return
+	}
+
+	slot.Salt = make([]byte, saltLen)
+	_, err = io.ReadFull(cryptoRandSrc, slot.Salt)
+	if err != nil {
+		err = fmt.Errorf("Reading random bytes for salt: %v", err)
+		return
+	}
+
+	crypter, err := createCrypter(deriver.DeriveKey(cryptoPassword, slot.Salt))
+	if err != nil {
+		err = fmt.Errorf("createCrypter: %v", err)
+		return
+	}
+
+	slot.WrappedKey, err = crypter.Encrypt(nil, masterKey, nil)
+	if err != nil {
+		err = fmt.Errorf("Encrypt: %v", err)
+		return
+	}
+
+	return
+}
+
+// Write out the supplied marker contents, failing with a
+// *gcs.PreconditionError if the existing marker's generation is not the one
+// given. Generation zero means that there must be no existing marker.
+func writeMarker(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	contents *jsonMarker,
+	generation int64) (err error) {
+	b, err := json.Marshal(contents)
+	if err != nil {
+		err = fmt.Errorf("Marshal: %v", err)
+		return
+	}
+
+	_, err = bucket.CreateObject(
+		ctx,
+		&gcs.CreateObjectRequest{
+			Name:                   markerObjectName,
+			Contents:               bytes.NewReader(b),
+			GenerationPrecondition: &generation,
+		})
+
+	return
+}
+
+// Generate a new master key and write a marker for it, returning the marker's
+// contents and the key. Use a precondition to defeat the race condition where
+// another machine is doing the same simultaneously.
+func claimBucket(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	cryptoPassword string,
+	opts NewBucketOptions,
+	createCrypter func(key []byte) (crypto.Crypter, error),
+	cryptoRandSrc io.Reader) (contents *jsonMarker, masterKey []byte, err error) {
+	masterKey = make([]byte, masterKeyLen)
+	_, err = io.ReadFull(cryptoRandSrc, masterKey)
+	if err != nil {
+		err = fmt.Errorf("Reading random bytes for master key: %v", err)
+		return
+	}
+
+	slot, err := wrapKey(
+		masterKey,
+		defaultSlotLabel,
+		slotKind_Password,
+		slotAccess_Full,
+		cryptoPassword,
+		opts.KDF,
+		createCrypter,
+		cryptoRandSrc)
+
+	if err != nil {
+		err = fmt.Errorf("wrapKey: %v", err)
+		return
+	}
+
+	contents = &jsonMarker{
+		Version:      markerVersion,
+		KeySlots:     []jsonKeySlot{slot},
+		RepositoryID: make([]byte, repositoryIDLen),
+	}
+
+	_, err = io.ReadFull(cryptoRandSrc, contents.RepositoryID)
+	if err != nil {
+		err = fmt.Errorf("Reading random bytes for repository ID: %v", err)
+		return
+	}
+
+	// For envelope encryption the master key is a private key; publish the
+	// matching public key.
+	if opts.Envelope {
+		var publicKey []byte
+		publicKey, _, err = crypto.DeriveEnvelopeKeys(masterKey)
+		if err != nil {
+			err = fmt.Errorf("DeriveEnvelopeKeys: %v", err)
+			return
+		}
+
+		contents.Version = markerVersion_Envelope
+		contents.Envelope = &jsonEnvelope{PublicKey: publicKey}
+	}
+
+	err = writeMarker(ctx, bucket, contents, 0)
+	if err != nil {
+		err = fmt.Errorf("writeMarker: %v", err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,782 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/jacobsa/comeback/internal/crypto"
+	"github.com/jacobsa/gcloud/gcs"
+	"github.com/jacobsa/gcloud/gcs/gcsfake"
+	"github.com/jacobsa/gcloud/gcs/gcsutil"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+	"github.com/jacobsa/timeutil"
+)
+
+func TestMarker(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type MarkerTest struct {
+	ctx    context.Context
+	bucket gcs.Bucket
+	kdf    KDF
+
+	// Whether newly claimed buckets use envelope encryption.
+	envelope bool
+}
+
+func init() { RegisterTestSuite(&MarkerTest{}) }
+
+func (t *MarkerTest) SetUp(ti *TestInfo) {
+	t.ctx = ti.Ctx
+	t.bucket = gcsfake.NewFakeBucket(timeutil.RealClock(), "some_bucket")
+	t.kdf = KDF{Name: KDF_Pbkdf2, Iterations: 1}
+}
+
+// Create a repository in the bucket.
+func (t *MarkerTest) init(password string) (crypter crypto.Crypter, err error) {
+	_, crypter, err = initGCSRegistry(
+		t.ctx,
+		t.bucket,
+		password,
+		NewBucketOptions{KDF: t.kdf, Envelope: t.envelope},
+		crypto.NewCrypter,
+		rand.Reader)
+
+	return
+}
+
+// Open the repository in the bucket.
+func (t *MarkerTest) open(password string) (crypter crypto.Crypter, err error) {
+	_, crypter, err = newGCSRegistry(t.ctx, t.bucket, password, crypto.NewCrypter)
+	return
+}
+
+func (t *MarkerTest) changePassword(oldPassword, newPassword string) error {
+	return ChangePassword(t.ctx, t.bucket, oldPassword, newPassword, t.kdf)
+}
+
+func (t *MarkerTest) addKeySlot(password, label, newPassword string) error {
+	return AddKeySlot(t.ctx, t.bucket, password, label, newPassword, t.kdf)
+}
+
+func (t *MarkerTest) addWriteOnlyKeySlot(
+	password, label, newPassword string) error {
+	return AddWriteOnlyKeySlot(
+		t.ctx,
+		t.bucket,
+		password,
+		label,
+		newPassword,
+		t.kdf)
+}
+
+func (t *MarkerTest) revokeKeySlot(password, label string) error {
+	return RevokeKeySlot(t.ctx, t.bucket, password, label, t.kdf)
+}
+
+func (t *MarkerTest) listKeySlots() (slots []KeySlot) {
+	slots, err := ListKeySlots(t.ctx, t.bucket)
+	AssertEq(nil, err)
+	return
+}
+
+// Make sure that the two crypters use the same key.
+func (t *MarkerTest) expectSameKey(c0, c1 crypto.Crypter) {
+	ciphertext, err := c0.Encrypt(nil, []byte("taco"), nil)
+	AssertEq(nil, err)
+
+	plaintext, err := c1.Decrypt(ciphertext, nil)
+	AssertEq(nil, err)
+	ExpectEq("taco", string(plaintext))
+}
+
+// Write a marker in the format used by older versions of comeback, returning
+// a crypter for the key derived from the password.
+func (t *MarkerTest) createLegacyMarker(password string) (c crypto.Crypter) {
+	salt := []byte("saltsalt")
+	deriver, err := legacyKDF.keyDeriver()
+	AssertEq(nil, err)
+
+	c, err = crypto.NewCrypter(deriver.DeriveKey(password, salt))
+	AssertEq(nil, err)
+
+	ciphertext, err := c.Encrypt(nil, []byte("some plaintext"), nil)
+	AssertEq(nil, err)
+
+	_, err = t.bucket.CreateObject(
+		t.ctx,
+		&gcs.CreateObjectRequest{
+			Name:     markerObjectName,
+			Contents: strings.NewReader(""),
+			Metadata: map[string]string{
+				markerObjectMetadata_Salt: base64.StdEncoding.EncodeToString(salt),
+				markerObjectMetadata_Ciphertext: base64.StdEncoding.EncodeToString(
+					ciphertext),
+			},
+		})
+
+	AssertEq(nil, err)
+	return
+}
+
+func (t *MarkerTest) readMarkerContents() (m jsonMarker) {
+	o, err := t.bucket.StatObject(
+		t.ctx,
+		&gcs.StatObjectRequest{Name: markerObjectName})
+
+	AssertEq(nil, err)
+	ExpectEq(0, len(o.Metadata))
+
+	rc, err := t.bucket.NewReader(
+		t.ctx,
+		&gcs.ReadObjectRequest{Name: markerObjectName})
+
+	AssertEq(nil, err)
+	defer rc.Close()
+
+	contents, err := ioutil.ReadAll(rc)
+	AssertEq(nil, err)
+	AssertEq(nil, json.Unmarshal(contents, &m))
+
+	return
+}
+
+// A bucket that lets a test interfere just before an object is created.
+type interferingBucket struct {
+	gcs.Bucket
+	beforeCreate func()
+}
+
+func (b *interferingBucket) CreateObject(
+	ctx context.Context,
+	req *gcs.CreateObjectRequest) (*gcs.Object, error) {
+	if f := b.beforeCreate; f != nil {
+		b.beforeCreate = nil
+		f()
+	}
+
+	return b.Bucket.CreateObject(ctx, req)
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *MarkerTest) NewBucket() {
+	c0, err := t.init("taco")
+	AssertEq(nil, err)
+
+	m := t.readMarkerContents()
+	ExpectEq(markerVersion, m.Version)
+	AssertEq(1, len(m.KeySlots))
+	ExpectEq(defaultSlotLabel, m.KeySlots[0].Label)
+	ExpectEq(slotKind_Password, m.KeySlots[0].Kind)
+	ExpectEq(saltLen, len(m.KeySlots[0].Salt))
+	ExpectEq(repositoryIDLen, len(m.RepositoryID))
+
+	// Opening again should get the same key.
+	c1, err := t.open("taco")
+	AssertEq(nil, err)
+	t.expectSameKey(c0, c1)
+}
+
+func (t *MarkerTest) NoMarker() {
+	_, err := t.open("taco")
+	ExpectThat(err, Error(HasSubstr("no marker")))
+	ExpectThat(err, Error(HasSubstr("comeback init")))
+
+	// Nothing should have been written.
+	_, err = t.bucket.StatObject(
+		t.ctx,
+		&gcs.StatObjectRequest{Name: markerObjectName})
+
+	_, ok := err.(*gcs.NotFoundError)
+	ExpectTrue(ok, "err: %v", err)
+}
+
+func (t *MarkerTest) InitTwice() {
+	c0, err := t.init("taco")
+	AssertEq(nil, err)
+
+	_, err = t.init("burrito")
+	ExpectThat(err, Error(HasSubstr("already contains a repository")))
+
+	// The original repository should be untouched.
+	c1, err := t.open("taco")
+	AssertEq(nil, err)
+	t.expectSameKey(c0, c1)
+}
+
+func (t *MarkerTest) InitOverLegacyMarker() {
+	t.createLegacyMarker("taco")
+
+	_, err := t.init("burrito")
+	ExpectThat(err, Error(HasSubstr("already contains a repository")))
+}
+
+func (t *MarkerTest) WrongPassword() {
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+
+	_, err = t.open("burrito")
+	ExpectThat(err, Error(HasSubstr("password is incorrect")))
+}
+
+func (t *MarkerTest) MasterKeyIsNotDerivedFromPassword() {
+	c, err := t.init("taco")
+	AssertEq(nil, err)
+
+	m := t.readMarkerContents()
+	AssertEq(1, len(m.KeySlots))
+
+	deriver, err := t.kdf.keyDeriver()
+	AssertEq(nil, err)
+
+	derived, err := crypto.NewCrypter(
+		deriver.DeriveKey("taco", m.KeySlots[0].Salt))
+	AssertEq(nil, err)
+
+	ciphertext, err := c.Encrypt(nil, []byte("taco"), nil)
+	AssertEq(nil, err)
+
+	_, err = derived.Decrypt(ciphertext, nil)
+	ExpectNe(nil, err)
+}
+
+func (t *MarkerTest) FutureMarkerVersion() {
+	_, err := gcsutil.CreateObject(
+		t.ctx,
+		t.bucket,
+		markerObjectName,
+		[]byte(`{"version": 17}`))
+
+	AssertEq(nil, err)
+
+	_, err = t.open("taco")
+	ExpectThat(err, Error(HasSubstr("Unsupported marker version 17")))
+}
+
+func (t *MarkerTest) LegacyMarker() {
+	legacy := t.createLegacyMarker("taco")
+
+	c, err := t.open("taco")
+	AssertEq(nil, err)
+	t.expectSameKey(legacy, c)
+
+	_, err = t.open("burrito")
+	ExpectThat(err, Error(HasSubstr("password is incorrect")))
+}
+
+func (t *MarkerTest) ChangePassword() {
+	c0, err := t.init("taco")
+	AssertEq(nil, err)
+
+	AssertEq(nil, t.changePassword("taco", "burrito"))
+
+	_, err = t.open("taco")
+	ExpectThat(err, Error(HasSubstr("password is incorrect")))
+
+	c1, err := t.open("burrito")
+	AssertEq(nil, err)
+	t.expectSameKey(c0, c1)
+}
+
+func (t *MarkerTest) ChangePassword_WrongOldPassword() {
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+
+	err = t.changePassword("enchilada", "burrito")
+	ExpectThat(err, Error(HasSubstr("password is incorrect")))
+
+	_, err = t.open("taco")
+	ExpectEq(nil, err)
+}
+
+func (t *MarkerTest) ChangePassword_NoMarker() {
+	err := t.changePassword("taco", "burrito")
+	ExpectThat(err, Error(HasSubstr("no marker")))
+}
+
+func (t *MarkerTest) ChangePassword_Legacy() {
+	legacy := t.createLegacyMarker("taco")
+
+	AssertEq(nil, t.changePassword("taco", "burrito"))
+
+	m := t.readMarkerContents()
+	ExpectEq(markerVersion, m.Version)
+	ExpectEq(1, len(m.KeySlots))
+
+	// Blobs have already been written without a repository ID, so it must not
+	// gain one.
+	ExpectEq(0, len(m.RepositoryID))
+
+	_, err := t.open("taco")
+	ExpectThat(err, Error(HasSubstr("password is incorrect")))
+
+	// The bucket keeps the key it already had.
+	c, err := t.open("burrito")
+	AssertEq(nil, err)
+	t.expectSameKey(legacy, c)
+}
+
+func (t *MarkerTest) ChangePassword_ConcurrentModification() {
+	c0, err := t.init("taco")
+	AssertEq(nil, err)
+
+	// Someone else changes the password after we read the marker.
+	underlying := t.bucket
+	b := &interferingBucket{Bucket: underlying}
+	b.beforeCreate = func() {
+		AssertEq(
+			nil,
+			ChangePassword(t.ctx, underlying, "taco", "queso", t.kdf))
+	}
+
+	err = ChangePassword(t.ctx, b, "taco", "burrito", t.kdf)
+	ExpectThat(err, Error(HasSubstr("modified concurrently")))
+
+	// Their change stands.
+	c1, err := t.open("queso")
+	AssertEq(nil, err)
+	t.expectSameKey(c0, c1)
+
+	_, err = t.open("burrito")
+	ExpectThat(err, Error(HasSubstr("password is incorrect")))
+}
+
+func (t *MarkerTest) AddKeySlot() {
+	c0, err := t.init("taco")
+	AssertEq(nil, err)
+
+	AssertEq(nil, t.addKeySlot("taco", "alice", "burrito"))
+
+	ExpectThat(
+		t.listKeySlots(),
+		DeepEquals([]KeySlot{
+			{Label: "default", KDF: t.kdf},
+			{Label: "alice", KDF: t.kdf},
+		}))
+
+	// Both passwords unlock the same key.
+	c1, err := t.open("taco")
+	AssertEq(nil, err)
+	t.expectSameKey(c0, c1)
+
+	c2, err := t.open("burrito")
+	AssertEq(nil, err)
+	t.expectSameKey(c0, c2)
+
+	// Either may be used to add another.
+	AssertEq(nil, t.addKeySlot("burrito", "bob", "queso"))
+	ExpectEq(3, len(t.listKeySlots()))
+}
+
+func (t *MarkerTest) AddKeySlot_WrongPassword() {
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+
+	err = t.addKeySlot("enchilada", "alice", "burrito")
+	ExpectThat(err, Error(HasSubstr("password is incorrect")))
+	ExpectEq(1, len(t.listKeySlots()))
+}
+
+func (t *MarkerTest) AddKeySlot_DuplicateLabel() {
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+
+	err = t.addKeySlot("taco", "default", "burrito")
+	ExpectThat(err, Error(HasSubstr("already a key slot labelled")))
+
+	err = t.addKeySlot("taco", "", "burrito")
+	ExpectThat(err, Error(HasSubstr("must have a label")))
+}
+
+func (t *MarkerTest) AddKeySlot_Legacy() {
+	legacy := t.createLegacyMarker("taco")
+	ExpectThat(
+		t.listKeySlots(),
+		DeepEquals([]KeySlot{{Label: "default", KDF: legacyKDF}}))
+
+	AssertEq(nil, t.addKeySlot("taco", "alice", "burrito"))
+
+	for _, p := range []string{"taco", "burrito"} {
+		c, err := t.open(p)
+		AssertEq(nil, err)
+		t.expectSameKey(legacy, c)
+	}
+}
+
+func (t *MarkerTest) UnlabelledSlot() {
+	t.kdf = legacyKDF
+	c0, err := t.init("taco")
+	AssertEq(nil, err)
+
+	// Strip the label, kind, and KDF, as in markers written before slots had
+	// them.
+	m := t.readMarkerContents()
+	m.KeySlots[0].Label = ""
+	m.KeySlots[0].Kind = ""
+	m.KeySlots[0].KDF = nil
+
+	contents, err := json.Marshal(m)
+	AssertEq(nil, err)
+
+	_, err = gcsutil.CreateObject(t.ctx, t.bucket, markerObjectName, contents)
+	AssertEq(nil, err)
+
+	ExpectThat(
+		t.listKeySlots(),
+		DeepEquals([]KeySlot{{Label: "default", KDF: legacyKDF}}))
+
+	c1, err := t.open("taco")
+	AssertEq(nil, err)
+	t.expectSameKey(c0, c1)
+}
+
+func (t *MarkerTest) AddRecoveryKey() {
+	c0, err := t.init("taco")
+	AssertEq(nil, err)
+
+	key, err := AddRecoveryKey(t.ctx, t.bucket, "taco", "escrow", t.kdf)
+	AssertEq(nil, err)
+	ExpectThat(key, MatchesRegexp(`^([A-Z2-7]{4}-){7}[A-Z2-7]{4}$`))
+
+	ExpectThat(
+		t.listKeySlots(),
+		DeepEquals([]KeySlot{
+			{Label: "default", KDF: t.kdf},
+			{Label: "escrow", Recovery: true, KDF: t.kdf},
+		}))
+
+	// The key works regardless of case and grouping.
+	variants := []string{
+		key,
+		strings.ToLower(key),
+		strings.Replace(key, "-", "", -1),
+		strings.Replace(key, "-", " ", -1),
+	}
+
+	for _, v := range variants {
+		c, err := t.open(v)
+		AssertEq(nil, err, "%q", v)
+		t.expectSameKey(c0, c)
+	}
+
+	// But its slot can't have its password changed.
+	err = t.changePassword(key, "burrito")
+	ExpectThat(err, Error(HasSubstr("recovery key")))
+}
+
+func (t *MarkerTest) ChangePassword_OnlyAffectsOneSlot() {
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+	AssertEq(nil, t.addKeySlot("taco", "alice", "burrito"))
+
+	AssertEq(nil, t.changePassword("burrito", "queso"))
+
+	m := t.readMarkerContents()
+	AssertEq(2, len(m.KeySlots))
+	ExpectEq("default", m.KeySlots[0].Label)
+	ExpectEq("alice", m.KeySlots[1].Label)
+
+	_, err = t.open("taco")
+	ExpectEq(nil, err)
+
+	_, err = t.open("queso")
+	ExpectEq(nil, err)
+
+	_, err = t.open("burrito")
+	ExpectThat(err, Error(HasSubstr("password is incorrect")))
+}
+
+func (t *MarkerTest) RevokeKeySlot() {
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+	AssertEq(nil, t.addKeySlot("taco", "alice", "burrito"))
+
+	AssertEq(nil, t.revokeKeySlot("taco", "alice"))
+	ExpectThat(
+		t.listKeySlots(),
+		DeepEquals([]KeySlot{{Label: "default", KDF: t.kdf}}))
+
+	_, err = t.open("burrito")
+	ExpectThat(err, Error(HasSubstr("password is incorrect")))
+
+	_, err = t.open("taco")
+	ExpectEq(nil, err)
+}
+
+func (t *MarkerTest) RevokeKeySlot_Own() {
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+	AssertEq(nil, t.addKeySlot("taco", "alice", "burrito"))
+
+	AssertEq(nil, t.revokeKeySlot("taco", "default"))
+	ExpectThat(
+		t.listKeySlots(),
+		DeepEquals([]KeySlot{{Label: "alice", KDF: t.kdf}}))
+
+	_, err = t.open("taco")
+	ExpectThat(err, Error(HasSubstr("password is incorrect")))
+}
+
+func (t *MarkerTest) RevokeKeySlot_Last() {
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+
+	err = t.revokeKeySlot("taco", "default")
+	ExpectThat(err, Error(HasSubstr("only key slot")))
+}
+
+func (t *MarkerTest) RevokeKeySlot_Unknown() {
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+
+	err = t.revokeKeySlot("taco", "alice")
+	ExpectThat(err, Error(HasSubstr("no key slot labelled")))
+}
+
+func (t *MarkerTest) KDFIsRecorded() {
+	t.kdf = KDF{Name: KDF_Scrypt, N: 16, R: 1, P: 1}
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+
+	m := t.readMarkerContents()
+	AssertEq(1, len(m.KeySlots))
+	AssertNe(nil, m.KeySlots[0].KDF)
+	ExpectThat(*m.KeySlots[0].KDF, DeepEquals(t.kdf))
+
+	// Opening doesn't depend on the KDF we would use for new slots.
+	t.kdf = KDF{Name: KDF_Argon2id, Time: 1, Memory: 64, Threads: 1}
+	_, err = t.open("taco")
+	ExpectEq(nil, err)
+}
+
+func (t *MarkerTest) InvalidKDF() {
+	t.kdf = KDF{Name: "rot13"}
+	_, err := t.init("taco")
+	ExpectThat(err, Error(HasSubstr("Unknown KDF")))
+
+	t.kdf = KDF{Name: KDF_Scrypt, N: 17, R: 1, P: 1}
+	_, err = t.init("taco")
+	ExpectThat(err, Error(HasSubstr("power of two")))
+}
+
+func (t *MarkerTest) KDFLimits() {
+	kdfs := []KDF{
+		{Name: KDF_Pbkdf2, Iterations: maxPbkdf2Iterations + 1},
+		{Name: KDF_Scrypt, N: 1 << 30, R: 8, P: 1},
+		{Name: KDF_Scrypt, N: 1 << 62, R: 1 << 62, P: 1},
+		{Name: KDF_Scrypt, N: 1 << 10, R: 8, P: 1 << 20},
+		{Name: KDF_Argon2id, Time: 1 << 31, Memory: 64, Threads: 1},
+		{Name: KDF_Argon2id, Time: 1, Memory: 1 << 31, Threads: 1},
+		{Name: KDF_Argon2id, Time: 1, Memory: 64 * 1024, Threads: 255},
+	}
+
+	for _, kdf := range kdfs {
+		ExpectThat(kdf.Validate(), Error(HasSubstr("at most")), "%v", kdf)
+	}
+
+	// The defaults are within the limits.
+	for _, kdf := range DefaultKDFs {
+		ExpectEq(nil, kdf.Validate(), "%v", kdf)
+	}
+}
+
+func (t *MarkerTest) TamperedKDF() {
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+
+	// Replace the slot's KDF with one that would take far too much memory.
+	m := t.readMarkerContents()
+	m.KeySlots[0].KDF = &KDF{
+		Name:    KDF_Argon2id,
+		Time:    1,
+		Memory:  1 << 31,
+		Threads: 1,
+	}
+
+	contents, err := json.Marshal(m)
+	AssertEq(nil, err)
+
+	_, err = gcsutil.CreateObject(t.ctx, t.bucket, markerObjectName, contents)
+	AssertEq(nil, err)
+
+	// Opening should fail promptly, without attempting to derive a key.
+	_, err = t.open("taco")
+	ExpectThat(err, Error(HasSubstr("Argon2id memory must be at most")))
+}
+
+func (t *MarkerTest) UpgradeKDF() {
+	c0, err := t.init("taco")
+	AssertEq(nil, err)
+	AssertEq(nil, t.addKeySlot("taco", "alice", "burrito"))
+
+	argon := KDF{Name: KDF_Argon2id, Time: 1, Memory: 64, Threads: 1}
+	AssertEq(nil, UpgradeKDF(t.ctx, t.bucket, "burrito", argon))
+
+	// Only the slot we unlocked is changed.
+	ExpectThat(
+		t.listKeySlots(),
+		DeepEquals([]KeySlot{
+			{Label: "default", KDF: t.kdf},
+			{Label: "alice", KDF: argon},
+		}))
+
+	for _, p := range []string{"taco", "burrito"} {
+		c, err := t.open(p)
+		AssertEq(nil, err)
+		t.expectSameKey(c0, c)
+	}
+}
+
+func (t *MarkerTest) UpgradeKDF_Legacy() {
+	legacy := t.createLegacyMarker("taco")
+
+	AssertEq(nil, UpgradeKDF(t.ctx, t.bucket, "taco", t.kdf))
+	ExpectThat(
+		t.listKeySlots(),
+		DeepEquals([]KeySlot{{Label: "default", KDF: t.kdf}}))
+
+	c, err := t.open("taco")
+	AssertEq(nil, err)
+	t.expectSameKey(legacy, c)
+}
+
+func (t *MarkerTest) UpgradeKDF_RecoveryKey() {
+	c0, err := t.init("taco")
+	AssertEq(nil, err)
+
+	key, err := AddRecoveryKey(t.ctx, t.bucket, "taco", "escrow", t.kdf)
+	AssertEq(nil, err)
+
+	scrypt := KDF{Name: KDF_Scrypt, N: 16, R: 1, P: 1}
+	AssertEq(
+		nil,
+		UpgradeKDF(t.ctx, t.bucket, strings.ToLower(key), scrypt))
+
+	c1, err := t.open(key)
+	AssertEq(nil, err)
+	t.expectSameKey(c0, c1)
+}
+
+func (t *MarkerTest) Envelope() {
+	t.envelope = true
+	c0, err := t.init("taco")
+	AssertEq(nil, err)
+
+	m := t.readMarkerContents()
+	ExpectEq(markerVersion_Envelope, m.Version)
+	AssertNe(nil, m.Envelope)
+	ExpectEq(32, len(m.Envelope.PublicKey))
+
+	// Reopening gives the same key, whatever the options say.
+	t.envelope = false
+	c1, err := t.open("taco")
+	AssertEq(nil, err)
+	t.expectSameKey(c0, c1)
+}
+
+func (t *MarkerTest) AddWriteOnlyKeySlot() {
+	t.envelope = true
+	c0, err := t.init("taco")
+	AssertEq(nil, err)
+	AssertEq(nil, t.addWriteOnlyKeySlot("taco", "laptop", "burrito"))
+
+	ExpectThat(
+		t.listKeySlots(),
+		DeepEquals([]KeySlot{
+			{Label: "default", KDF: t.kdf},
+			{Label: "laptop", WriteOnly: true, KDF: t.kdf},
+		}))
+
+	// The write-only crypter encrypts just like the full one, but can't
+	// decrypt.
+	c1, err := t.open("burrito")
+	AssertEq(nil, err)
+	t.expectSameKey(c1, c0)
+
+	ciphertext, err := c1.Encrypt(nil, []byte("taco"), nil)
+	AssertEq(nil, err)
+
+	expected, err := c0.Encrypt(nil, []byte("taco"), nil)
+	AssertEq(nil, err)
+	ExpectThat(ciphertext, DeepEquals(expected))
+
+	_, err = c1.Decrypt(ciphertext, nil)
+	ExpectThat(err, Error(HasSubstr("write-only")))
+}
+
+func (t *MarkerTest) AddWriteOnlyKeySlot_NotEnvelope() {
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+
+	err = t.addWriteOnlyKeySlot("taco", "laptop", "burrito")
+	ExpectThat(err, Error(HasSubstr("envelope")))
+}
+
+func (t *MarkerTest) WriteOnlyKeySlot_CannotManageSlots() {
+	t.envelope = true
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+	AssertEq(nil, t.addWriteOnlyKeySlot("taco", "laptop", "burrito"))
+
+	err = t.addKeySlot("burrito", "alice", "enchilada")
+	ExpectThat(err, Error(HasSubstr("write-only")))
+
+	err = t.addWriteOnlyKeySlot("burrito", "desktop", "enchilada")
+	ExpectThat(err, Error(HasSubstr("write-only")))
+
+	err = t.revokeKeySlot("burrito", "default")
+	ExpectThat(err, Error(HasSubstr("write-only")))
+
+	// The last full-access slot can't be revoked, but write-only ones can.
+	err = t.revokeKeySlot("taco", "default")
+	ExpectThat(err, Error(HasSubstr("only key slot")))
+
+	AssertEq(nil, t.revokeKeySlot("taco", "laptop"))
+	_, err = t.open("burrito")
+	ExpectThat(err, Error(HasSubstr("password is incorrect")))
+}
+
+func (t *MarkerTest) WriteOnlyKeySlot_ChangePassword() {
+	t.envelope = true
+	_, err := t.init("taco")
+	AssertEq(nil, err)
+	AssertEq(nil, t.addWriteOnlyKeySlot("taco", "laptop", "burrito"))
+
+	AssertEq(nil, t.changePassword("burrito", "enchilada"))
+
+	c, err := t.open("enchilada")
+	AssertEq(nil, err)
+
+	ciphertext, err := c.Encrypt(nil, []byte("taco"), nil)
+	AssertEq(nil, err)
+
+	_, err = c.Decrypt(ciphertext, nil)
+	ExpectThat(err, Error(HasSubstr("write-only")))
+}
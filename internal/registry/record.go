@@ -0,0 +1,160 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/crypto"
+)
+
+// The version of the record format written by marshalRecord. Records with
+// later versions were written by a newer version of comeback, and can't be
+// read.
+const recordVersion = 1
+
+// Records are encrypted with this associated data, so that they can't be
+// confused with other data encrypted with the same key. The crypter returned
+// by the registry additionally binds the repository ID (see
+// crypto.BindRepository), so that a record copied from another repository
+// sharing the key doesn't decrypt.
+//
+// Older versions of comeback encrypted records with no associated data; such
+// records are still read.
+var recordAssociatedData = []byte("comeback registry record")
+
+// The plaintext form of a record, encoded as JSON.
+type jsonRecord struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	Score     string    `json:"score"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+
+	Hostname string `json:"hostname,omitempty"`
+	BasePath string `json:"base_path,omitempty"`
+	Comeback string `json:"comeback_version,omitempty"`
+
+	FilesScanned  uint64 `json:"files_scanned"`
+	DirsScanned   uint64 `json:"dirs_scanned"`
+	BytesScanned  uint64 `json:"bytes_scanned"`
+	BlobsUploaded uint64 `json:"blobs_uploaded"`
+	BytesUploaded uint64 `json:"bytes_uploaded"`
+	Warnings      uint64 `json:"warnings"`
+
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Encode the supplied job as an encrypted record.
+func marshalRecord(
+	crypter crypto.Crypter,
+	j CompletedJob) (b []byte, err error) {
+	r := jsonRecord{
+		Version:       recordVersion,
+		Name:          j.Name,
+		Score:         j.Score.Hex(),
+		StartTime:     j.StartTime,
+		EndTime:       j.EndTime,
+		Hostname:      j.Hostname,
+		BasePath:      j.BasePath,
+		Comeback:      j.Version,
+		FilesScanned:  j.FilesScanned,
+		DirsScanned:   j.DirsScanned,
+		BytesScanned:  j.BytesScanned,
+		BlobsUploaded: j.BlobsUploaded,
+		BytesUploaded: j.BytesUploaded,
+		Warnings:      j.Warnings,
+		Tags:          j.Tags,
+	}
+
+	plaintext, err := json.Marshal(r)
+	if err != nil {
+		err = fmt.Errorf("Marshal: %v", err)
+		return
+	}
+
+	b, err = crypter.Encrypt(
+		nil,
+		plaintext,
+		[][]byte{recordAssociatedData})
+
+	if err != nil {
+		err = fmt.Errorf("Encrypt: %v", err)
+		return
+	}
+
+	return
+}
+
+// Decode a record written by marshalRecord.
+func unmarshalRecord(
+	crypter crypto.Crypter,
+	b []byte) (j CompletedJob, err error) {
+	plaintext, err := crypter.Decrypt(b, [][]byte{recordAssociatedData})
+
+	// Fall back to the legacy format if authentication fails.
+	if _, ok := err.(*crypto.NotAuthenticError); ok {
+		plaintext, err = crypter.Decrypt(b, nil)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("Decrypt: %v", err)
+		return
+	}
+
+	var r jsonRecord
+	err = json.Unmarshal(plaintext, &r)
+	if err != nil {
+		err = fmt.Errorf("Unmarshal: %v", err)
+		return
+	}
+
+	if r.Version < 1 || r.Version > recordVersion {
+		err = fmt.Errorf(
+			"Unsupported record version %d; this version of comeback reads "+
+				"versions up to %d",
+			r.Version,
+			recordVersion)
+		return
+	}
+
+	j = CompletedJob{
+		StartTime:     r.StartTime,
+		Name:          r.Name,
+		EndTime:       r.EndTime,
+		Hostname:      r.Hostname,
+		BasePath:      r.BasePath,
+		Version:       r.Comeback,
+		FilesScanned:  r.FilesScanned,
+		DirsScanned:   r.DirsScanned,
+		BytesScanned:  r.BytesScanned,
+		BlobsUploaded: r.BlobsUploaded,
+		BytesUploaded: r.BytesUploaded,
+		Warnings:      r.Warnings,
+		Tags:          r.Tags,
+	}
+
+	j.Score, err = blob.ParseHexScore(r.Score)
+	if err != nil {
+		err = fmt.Errorf("Parsing hex score %q: %v", r.Score, err)
+		return
+	}
+
+	return
+}
@@ -26,8 +26,17 @@ type Registry interface {
 	// Record that the named backup job has completed.
 	RecordBackup(ctx context.Context, j CompletedJob) (err error)
 
-	// Return a list of all completed backups.
+	// Return a list of all completed backups, oldest first.
 	ListBackups(ctx context.Context) (jobs []CompletedJob, err error)
+
+	// Remove the records of the supplied backups, as returned by ListBackups.
+	// Nothing is removed if any of them has no record. The backups' blobs are
+	// left alone, to be collected as garbage if nothing else references them.
+	DeleteBackups(ctx context.Context, jobs []CompletedJob) (err error)
+
+	// Rewrite any records stored in formats written by older versions of
+	// comeback into the current format, returning the number rewritten.
+	MigrateLegacyRecords(ctx context.Context) (migrated int, err error)
 }
 
 // A record in the backup registry describing a successful backup job.
@@ -40,4 +49,42 @@ type CompletedJob struct {
 
 	// The score representing the contents of the backup.
 	Score blob.Score
+
+	// The remaining fields are absent from records written by older versions
+	// of comeback, in which case they have their zero values.
+
+	// The time at which the backup finished.
+	EndTime time.Time
+
+	// The machine on which the backup was made, the path that was backed up,
+	// and the version of comeback that did it.
+	Hostname string
+	BasePath string
+	Version  string
+
+	// The number of regular files and directories found, and the total size
+	// of the files.
+	FilesScanned uint64
+	DirsScanned  uint64
+	BytesScanned uint64
+
+	// The number of blobs that weren't already in the bucket, and their total
+	// size as stored.
+	BlobsUploaded uint64
+	BytesUploaded uint64
+
+	// The number of problems encountered that didn't cause the backup to fail.
+	Warnings uint64
+
+	// Arbitrary labels supplied by the user.
+	Tags []string
+}
+
+// Return the time the backup took, or zero if unknown.
+func (j *CompletedJob) Duration() time.Duration {
+	if j.EndTime.IsZero() {
+		return 0
+	}
+
+	return j.EndTime.Sub(j.StartTime)
 }
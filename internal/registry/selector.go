@@ -0,0 +1,317 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/blob"
+)
+
+// Find the backup described by the supplied snapshot selector among the given
+// completed jobs, as returned by Registry.ListBackups. The following forms are
+// accepted:
+//
+//   - A hex score, or a prefix of one at least four characters long that
+//     matches exactly one backup. A complete score needn't match any backup.
+//     The algorithm prefix of a non-SHA-1 score (e.g. "sha256-") may be
+//     omitted from a prefix.
+//   - "job:latest", the newest backup of the named job.
+//   - "job@time", the newest backup of the named job started at or before the
+//     given time. The time may be RFC 3339, or of the form "2006-01-02",
+//     "2006-01-02 15:04", or "2006-01-02T15:04:05" in local time. A date
+//     alone refers to the end of that day.
+//   - "job~N", the Nth backup of the named job counting back from the newest,
+//     which is "job~0".
+//   - "latest", the newest backup, so long as there is only one job.
+//
+// In the case of a bare score that matches no backup, only j.Score is set.
+func ResolveSelector(
+	jobs []CompletedJob,
+	sel string) (j CompletedJob, err error) {
+	// Handle scores first. Job names look like scores only in perverse cases.
+	if hexPrefixRegexp.MatchString(sel) {
+		j, err = resolveScorePrefix(jobs, sel)
+		return
+	}
+
+	if sel == "latest" {
+		names := jobNames(jobs)
+		switch len(names) {
+		case 0:
+			err = fmt.Errorf("No backups found")
+			return
+
+		case 1:
+			j, err = resolveRelative(jobs, names[0], 0)
+			return
+
+		default:
+			err = fmt.Errorf(
+				"%q is ambiguous because there are backups for multiple jobs (%s); "+
+					"use e.g. %q instead",
+				sel,
+				strings.Join(names, ", "),
+				names[0]+":latest")
+			return
+		}
+	}
+
+	if strings.HasSuffix(sel, ":latest") {
+		j, err = resolveRelative(jobs, strings.TrimSuffix(sel, ":latest"), 0)
+		return
+	}
+
+	if i := strings.LastIndex(sel, "@"); i >= 0 {
+		var t time.Time
+		t, err = parseSelectorTime(sel[i+1:])
+		if err != nil {
+			err = fmt.Errorf("Selector %q: %v", sel, err)
+			return
+		}
+
+		j, err = resolveTime(jobs, sel[:i], t)
+		return
+	}
+
+	if i := strings.LastIndex(sel, "~"); i >= 0 {
+		var n int
+		n, err = strconv.Atoi(sel[i+1:])
+		if err != nil || n < 0 {
+			err = fmt.Errorf("Selector %q: invalid count %q", sel, sel[i+1:])
+			return
+		}
+
+		j, err = resolveRelative(jobs, sel[:i], n)
+		return
+	}
+
+	err = fmt.Errorf(
+		"Unrecognized snapshot selector %q; expected a score, \"job:latest\", "+
+			"\"job@time\", or \"job~N\"",
+		sel)
+
+	return
+}
+
+var hexPrefixRegexp = regexp.MustCompile(`^(sha256-)?[0-9a-f]{4,64}$`)
+
+func resolveScorePrefix(
+	jobs []CompletedJob,
+	prefix string) (j CompletedJob, err error) {
+	// Find the distinct scores with the prefix. More than one job may have the
+	// same score, in which case we choose the newest.
+	matches := make(map[blob.Score]CompletedJob)
+	for _, candidate := range jobs {
+		if !strings.HasPrefix(candidate.Score.Hex(), prefix) &&
+			!strings.HasPrefix(hex.EncodeToString(candidate.Score.Hash()), prefix) {
+			continue
+		}
+
+		existing, ok := matches[candidate.Score]
+		if !ok || existing.StartTime.Before(candidate.StartTime) {
+			matches[candidate.Score] = candidate
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		// A complete score is fine even if no backup has it.
+		var s blob.Score
+		s, err = blob.ParseHexScore(prefix)
+		if err != nil {
+			err = fmt.Errorf("No backup has a score beginning with %q", prefix)
+			return
+		}
+
+		j.Score = s
+
+	case 1:
+		for _, match := range matches {
+			j = match
+		}
+
+	default:
+		var hexScores []string
+		for s := range matches {
+			hexScores = append(hexScores, s.Hex())
+		}
+
+		sort.Strings(hexScores)
+		err = fmt.Errorf(
+			"Score prefix %q is ambiguous; it matches %s",
+			prefix,
+			strings.Join(hexScores, ", "))
+	}
+
+	return
+}
+
+// Return the backups of the named job, newest first.
+func backupsOf(
+	jobs []CompletedJob,
+	name string) (matches []CompletedJob, err error) {
+	for _, j := range jobs {
+		if j.Name == name {
+			matches = append(matches, j)
+		}
+	}
+
+	if len(matches) == 0 {
+		names := jobNames(jobs)
+		err = fmt.Errorf(
+			"No backups found for job %q (known jobs: %s)",
+			name,
+			strings.Join(names, ", "))
+		return
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[j].StartTime.Before(matches[i].StartTime)
+	})
+
+	return
+}
+
+func resolveRelative(
+	jobs []CompletedJob,
+	name string,
+	n int) (j CompletedJob, err error) {
+	matches, err := backupsOf(jobs, name)
+	if err != nil {
+		return
+	}
+
+	if n >= len(matches) {
+		err = fmt.Errorf(
+			"Job %q has only %d backups, so there is no %s~%d",
+			name,
+			len(matches),
+			name,
+			n)
+		return
+	}
+
+	j = matches[n]
+	return
+}
+
+func resolveTime(
+	jobs []CompletedJob,
+	name string,
+	t time.Time) (j CompletedJob, err error) {
+	matches, err := backupsOf(jobs, name)
+	if err != nil {
+		return
+	}
+
+	for _, candidate := range matches {
+		if !candidate.StartTime.After(t) {
+			j = candidate
+			return
+		}
+	}
+
+	err = fmt.Errorf(
+		"Job %q has no backups started at or before %s; the oldest is from %s",
+		name,
+		t.Format(time.RFC3339),
+		matches[len(matches)-1].StartTime.Format(time.RFC3339))
+
+	return
+}
+
+// Parse the time in a "job@time" selector.
+func parseSelectorTime(s string) (t time.Time, err error) {
+	t, err = time.Parse(time.RFC3339Nano, s)
+	if err == nil {
+		return
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04"} {
+		t, err = time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return
+		}
+	}
+
+	// A date alone means the end of that day.
+	t, err = time.ParseInLocation("2006-01-02", s, time.Local)
+	if err == nil {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		return
+	}
+
+	err = fmt.Errorf("Unrecognized time %q", s)
+	return
+}
+
+// Return the sorted distinct job names among the supplied jobs.
+func jobNames(jobs []CompletedJob) (names []string) {
+	seen := make(map[string]bool)
+	for _, j := range jobs {
+		if !seen[j.Name] {
+			seen[j.Name] = true
+			names = append(names, j.Name)
+		}
+	}
+
+	sort.Strings(names)
+	return
+}
+
+// Like ResolveSelector, but for arguments of the form "selector:path" naming a
+// path within a backup. The path is optional. Since selectors may themselves
+// contain colons, the longest prefix that is a valid selector wins.
+func ResolveSelectorAndPath(
+	jobs []CompletedJob,
+	arg string) (j CompletedJob, relPath string, err error) {
+	// Try the whole argument, then successively shorter prefixes.
+	j, err = ResolveSelector(jobs, arg)
+	if err == nil {
+		return
+	}
+
+	// If there's no valid prefix, the error for the longest prefix that leaves
+	// a path is likely the most helpful.
+	wholeErr := err
+	var prefixErr error
+
+	for i := strings.LastIndex(arg, ":"); i >= 0; i = strings.LastIndex(arg[:i], ":") {
+		j, err = ResolveSelector(jobs, arg[:i])
+		if err == nil {
+			relPath = arg[i+1:]
+			return
+		}
+
+		if prefixErr == nil {
+			prefixErr = err
+		}
+	}
+
+	err = wholeErr
+	if prefixErr != nil {
+		err = prefixErr
+	}
+
+	return
+}
@@ -0,0 +1,254 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+)
+
+func TestSelector(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type SelectorTest struct {
+	jobs []CompletedJob
+}
+
+func init() { RegisterTestSuite(&SelectorTest{}) }
+
+func (t *SelectorTest) SetUp(ti *TestInfo) {
+	add := func(name string, startTime time.Time, score string) {
+		s, err := blob.ParseHexScore(score)
+		AssertEq(nil, err)
+
+		t.jobs = append(t.jobs, CompletedJob{
+			Name:      name,
+			StartTime: startTime,
+			Score:     s,
+		})
+	}
+
+	// Deliberately out of order.
+	add("home", t.localTime(2026, 1, 2, 3), "aaaa000000000000000000000000000000000002")
+	add("home", t.localTime(2026, 1, 1, 3), "aaaa000000000000000000000000000000000001")
+	add("home", t.localTime(2026, 1, 3, 3), "bbbb000000000000000000000000000000000003")
+	add("photos", t.localTime(2026, 1, 2, 12), "cccc000000000000000000000000000000000004")
+}
+
+// Add a backup whose score uses SHA-256 rather than SHA-1.
+func (t *SelectorTest) addSHA256Backup() {
+	s, err := blob.ParseHexScore("sha256-eeee" + strings.Repeat("0", 59) + "5")
+	AssertEq(nil, err)
+
+	t.jobs = append(t.jobs, CompletedJob{
+		Name:      "music",
+		StartTime: t.localTime(2026, 1, 2, 18),
+		Score:     s,
+	})
+}
+
+func (t *SelectorTest) localTime(year, month, day, hour int) time.Time {
+	return time.Date(year, time.Month(month), day, hour, 0, 0, 0, time.Local)
+}
+
+func (t *SelectorTest) resolve(sel string) (j CompletedJob, err error) {
+	j, err = ResolveSelector(t.jobs, sel)
+	return
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *SelectorTest) FullScore() {
+	j, err := t.resolve("aaaa000000000000000000000000000000000001")
+	AssertEq(nil, err)
+	ExpectEq("home", j.Name)
+	ExpectEq("aaaa000000000000000000000000000000000001", j.Score.Hex())
+}
+
+func (t *SelectorTest) FullScore_NotInRegistry() {
+	j, err := t.resolve("dddd000000000000000000000000000000000000")
+	AssertEq(nil, err)
+	ExpectEq("", j.Name)
+	ExpectEq("dddd000000000000000000000000000000000000", j.Score.Hex())
+}
+
+func (t *SelectorTest) ScorePrefix() {
+	j, err := t.resolve("bbbb")
+	AssertEq(nil, err)
+	ExpectEq("bbbb000000000000000000000000000000000003", j.Score.Hex())
+}
+
+func (t *SelectorTest) ScorePrefix_Ambiguous() {
+	_, err := t.resolve("aaaa")
+	ExpectThat(err, Error(HasSubstr("ambiguous")))
+	ExpectThat(err, Error(HasSubstr("aaaa000000000000000000000000000000000001")))
+	ExpectThat(err, Error(HasSubstr("aaaa000000000000000000000000000000000002")))
+}
+
+func (t *SelectorTest) ScorePrefix_NoMatch() {
+	_, err := t.resolve("dddd")
+	ExpectThat(err, Error(HasSubstr("No backup")))
+}
+
+func (t *SelectorTest) ScorePrefix_SHA256() {
+	t.addSHA256Backup()
+
+	j, err := t.resolve("sha256-eeee")
+	AssertEq(nil, err)
+	ExpectEq("music", j.Name)
+	ExpectEq(blob.Algorithm_SHA256, j.Score.Algorithm())
+}
+
+func (t *SelectorTest) ScorePrefix_SHA256WithoutAlgorithm() {
+	t.addSHA256Backup()
+
+	j, err := t.resolve("eeee")
+	AssertEq(nil, err)
+	ExpectEq("music", j.Name)
+}
+
+func (t *SelectorTest) FullScore_SHA256_NotInRegistry() {
+	hexScore := "sha256-" + strings.Repeat("d", 64)
+
+	j, err := t.resolve(hexScore)
+	AssertEq(nil, err)
+	ExpectEq("", j.Name)
+	ExpectEq(hexScore, j.Score.Hex())
+}
+
+func (t *SelectorTest) JobLatest() {
+	j, err := t.resolve("home:latest")
+	AssertEq(nil, err)
+	ExpectEq("bbbb000000000000000000000000000000000003", j.Score.Hex())
+}
+
+func (t *SelectorTest) UnknownJob() {
+	_, err := t.resolve("taco:latest")
+	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, Error(HasSubstr("home, photos")))
+}
+
+func (t *SelectorTest) Relative() {
+	j, err := t.resolve("home~0")
+	AssertEq(nil, err)
+	ExpectEq("bbbb000000000000000000000000000000000003", j.Score.Hex())
+
+	j, err = t.resolve("home~2")
+	AssertEq(nil, err)
+	ExpectEq("aaaa000000000000000000000000000000000001", j.Score.Hex())
+}
+
+func (t *SelectorTest) Relative_TooFarBack() {
+	_, err := t.resolve("home~3")
+	ExpectThat(err, Error(HasSubstr("only 3 backups")))
+}
+
+func (t *SelectorTest) Relative_Invalid() {
+	_, err := t.resolve("home~taco")
+	ExpectThat(err, Error(HasSubstr("invalid count")))
+}
+
+func (t *SelectorTest) Time_Date() {
+	// A date refers to the end of the day.
+	j, err := t.resolve("home@2026-01-02")
+	AssertEq(nil, err)
+	ExpectEq("aaaa000000000000000000000000000000000002", j.Score.Hex())
+}
+
+func (t *SelectorTest) Time_Minutes() {
+	j, err := t.resolve("home@2026-01-02 02:59")
+	AssertEq(nil, err)
+	ExpectEq("aaaa000000000000000000000000000000000001", j.Score.Hex())
+
+	j, err = t.resolve("home@2026-01-02 03:00")
+	AssertEq(nil, err)
+	ExpectEq("aaaa000000000000000000000000000000000002", j.Score.Hex())
+}
+
+func (t *SelectorTest) Time_RFC3339() {
+	sel := "home@" + t.localTime(2026, 1, 3, 3).Format(time.RFC3339)
+
+	j, err := t.resolve(sel)
+	AssertEq(nil, err)
+	ExpectEq("bbbb000000000000000000000000000000000003", j.Score.Hex())
+}
+
+func (t *SelectorTest) Time_BeforeFirstBackup() {
+	_, err := t.resolve("home@2025-12-31")
+	ExpectThat(err, Error(HasSubstr("no backups started at or before")))
+}
+
+func (t *SelectorTest) Time_Invalid() {
+	_, err := t.resolve("home@yesterday")
+	ExpectThat(err, Error(HasSubstr("Unrecognized time")))
+}
+
+func (t *SelectorTest) Latest_MultipleJobs() {
+	_, err := t.resolve("latest")
+	ExpectThat(err, Error(HasSubstr("ambiguous")))
+	ExpectThat(err, Error(HasSubstr("home:latest")))
+}
+
+func (t *SelectorTest) Latest_SingleJob() {
+	t.jobs = t.jobs[:3]
+
+	j, err := t.resolve("latest")
+	AssertEq(nil, err)
+	ExpectEq("bbbb000000000000000000000000000000000003", j.Score.Hex())
+}
+
+func (t *SelectorTest) Unrecognized() {
+	_, err := t.resolve("home")
+	ExpectThat(err, Error(HasSubstr("Unrecognized snapshot selector")))
+}
+
+func (t *SelectorTest) WithPath_NoPath() {
+	j, relPath, err := ResolveSelectorAndPath(t.jobs, "home:latest")
+	AssertEq(nil, err)
+	ExpectEq("bbbb000000000000000000000000000000000003", j.Score.Hex())
+	ExpectEq("", relPath)
+}
+
+func (t *SelectorTest) WithPath_Score() {
+	j, relPath, err := ResolveSelectorAndPath(t.jobs, "cccc:foo/bar")
+	AssertEq(nil, err)
+	ExpectEq("cccc000000000000000000000000000000000004", j.Score.Hex())
+	ExpectEq("foo/bar", relPath)
+}
+
+func (t *SelectorTest) WithPath_ColonsInSelectorAndPath() {
+	sel := "home@" + t.localTime(2026, 1, 3, 3).Format(time.RFC3339)
+
+	j, relPath, err := ResolveSelectorAndPath(t.jobs, sel+":foo:bar")
+	AssertEq(nil, err)
+	ExpectEq("bbbb000000000000000000000000000000000003", j.Score.Hex())
+	ExpectEq("foo:bar", relPath)
+}
+
+func (t *SelectorTest) WithPath_Invalid() {
+	_, _, err := ResolveSelectorAndPath(t.jobs, "taco:latest:foo")
+	ExpectThat(err, Error(HasSubstr("No backups found for job \"taco\"")))
+}
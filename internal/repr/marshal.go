@@ -46,12 +46,8 @@ func convertType(t fs.Type) (repr_proto.FileInfoProto_Type, error) {
 func makeInfoProto(
 	entry *fs.FileInfo) (*repr_proto.FileInfoProto, error) {
 	blobs := []*repr_proto.BlobInfoProto{}
-	for i, _ := range entry.Scores {
-		// Make a copy of the score (a value type, not a reference type), for
-		// slicing below.
-		var score blob.Score = entry.Scores[i]
-
-		proto := &repr_proto.BlobInfoProto{Hash: score[:]}
+	for _, score := range entry.Scores {
+		proto := &repr_proto.BlobInfoProto{Hash: score.Bytes()}
 		blobs = append(blobs, proto)
 	}
 
@@ -93,9 +89,10 @@ func makeInfoProto(
 	return entryProto, nil
 }
 
+// The magic bytes double as the blobs' kinds.
 const (
-	magicByte_Dir  byte = 'd'
-	magicByte_File byte = 'f'
+	magicByte_Dir  = byte(blob.Kind_Dir)
+	magicByte_File = byte(blob.Kind_File)
 )
 
 // MarshalDir turns a directory listing into bytes that can later be used with
@@ -75,7 +75,9 @@ func (x *FileInfoProto_Type) UnmarshalJSON(data []byte) error {
 }
 
 type BlobInfoProto struct {
-	// The SHA-1 hash of the blob, in 20-byte 'raw' form.
+	// The score of the blob, in the form returned by blob.Score.Bytes: the
+	// 20-byte 'raw' SHA-1 hash for blobs saved by older versions, or a one-byte
+	// algorithm tag followed by the raw hash for newer blobs.
 	Hash             []byte `protobuf:"bytes,1,opt,name=hash" json:"hash,omitempty"`
 	XXX_unrecognized []byte `json:"-"`
 }
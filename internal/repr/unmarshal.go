@@ -54,12 +54,12 @@ func convertTimeProto(timeProto *repr_proto.TimeProto) (time.Time, error) {
 
 func convertBlobInfoProto(
 	p *repr_proto.BlobInfoProto) (s blob.Score, err error) {
-	if len(p.Hash) != blob.ScoreLength {
-		err = fmt.Errorf("Illegal hash length: %d", len(p.Hash))
+	s, err = blob.ParseScoreBytes(p.Hash)
+	if err != nil {
+		err = fmt.Errorf("Illegal hash: %v", err)
 		return
 	}
 
-	copy(s[:], p.Hash)
 	return
 }
 
@@ -40,7 +40,7 @@ const (
 
 func computeScoreSlice(b []byte) (score []byte) {
 	s := blob.ComputeScore(b)
-	return s[:]
+	return s.Bytes()
 }
 
 func makeLegalEntryProto() *repr_proto.FileInfoProto {
@@ -144,7 +144,7 @@ func (t *UnmarshalTest) HashIsTooShort() {
 	_, err = repr.UnmarshalDir(data)
 
 	ExpectThat(err, Error(HasSubstr("hash length")))
-	ExpectThat(err, Error(HasSubstr("19")))
+	ExpectThat(err, Error(HasSubstr("32")))
 }
 
 func (t *UnmarshalTest) HashIsTooLong() {
@@ -174,7 +174,37 @@ func (t *UnmarshalTest) HashIsTooLong() {
 	_, err = repr.UnmarshalDir(data)
 
 	ExpectThat(err, Error(HasSubstr("hash length")))
-	ExpectThat(err, Error(HasSubstr("21")))
+	ExpectThat(err, Error(HasSubstr("34")))
+}
+
+func (t *UnmarshalTest) LegacySHA1Hash() {
+	// Input
+	listingProto := &repr_proto.DirectoryListingProto{
+		Entry: []*repr_proto.FileInfoProto{
+			makeLegalEntryProto(),
+		},
+	}
+
+	legacy := blob.ComputeScoreWithAlgorithm(blob.Algorithm_SHA1, []byte("taco"))
+	current := blob.ComputeScore([]byte("burrito"))
+
+	listingProto.Entry[0].Blob = []*repr_proto.BlobInfoProto{
+		&repr_proto.BlobInfoProto{Hash: legacy.Hash()},
+		&repr_proto.BlobInfoProto{Hash: current.Bytes()},
+	}
+
+	data, err := proto.Marshal(listingProto)
+	AssertEq(nil, err)
+
+	// Call
+	data = append(data, magicByte_Dir)
+	entries, err := repr.UnmarshalDir(data)
+	AssertEq(nil, err)
+
+	AssertThat(entries, ElementsAre(Any()))
+	ExpectThat(entries[0].Scores, ElementsAre(legacy, current))
+	ExpectEq(blob.Algorithm_SHA1, entries[0].Scores[0].Algorithm())
+	ExpectEq(blob.Algorithm_SHA256, entries[0].Scores[1].Algorithm())
 }
 
 // Catch changes to the layout of os.FileMode, which we have hard-coded by
@@ -0,0 +1,74 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import "fmt"
+
+// A ConflictPolicy determines what Restore does when an entry that it would
+// write already exists within the destination directory. Regardless of
+// policy, existing directories are merged with the directories being
+// restored.
+type ConflictPolicy int
+
+const (
+	// Refuse to restore into a destination directory that isn't empty, and fail
+	// if an entry that would be written already exists.
+	ConflictFail ConflictPolicy = iota
+
+	// Leave existing entries alone.
+	ConflictSkip
+
+	// Replace existing entries.
+	ConflictOverwrite
+
+	// Leave existing entries alone if they are identical to the backed up
+	// version, and otherwise replace them.
+	ConflictOverwriteIfDifferent
+
+	// Leave existing entries alone if they are identical to the backed up
+	// version, and otherwise move them aside to a new name with an ".orig"
+	// suffix before writing the backed up version in their place.
+	ConflictRename
+)
+
+var conflictPolicyNames = map[ConflictPolicy]string{
+	ConflictFail:                 "fail",
+	ConflictSkip:                 "skip",
+	ConflictOverwrite:            "overwrite",
+	ConflictOverwriteIfDifferent: "overwrite_if_different",
+	ConflictRename:               "rename",
+}
+
+func (p ConflictPolicy) String() string {
+	if name, ok := conflictPolicyNames[p]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("ConflictPolicy(%d)", int(p))
+}
+
+// Parse the output of ConflictPolicy.String.
+func ParseConflictPolicy(s string) (p ConflictPolicy, err error) {
+	for candidate, name := range conflictPolicyNames {
+		if name == s {
+			p = candidate
+			return
+		}
+	}
+
+	err = fmt.Errorf("Unknown conflict policy: %q", s)
+	return
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/jacobsa/comeback/internal/dag"
 	"github.com/jacobsa/comeback/internal/fs"
 	"github.com/jacobsa/comeback/internal/repr"
+	"github.com/jacobsa/comeback/internal/util"
 )
 
 // Create a dag.DependencyResolver for *node.
@@ -33,21 +34,34 @@ import (
 // n.Info.Scores[0], which must exist and be the only score. No other nodes
 // have dependencies.
 //
-// Child nodes returned are filled into node.Children fields.
+// Child nodes returned are filled into node.Children fields. Only children
+// within or on the way to the subtrees selected by the supplied filter are
+// returned, so listings are loaded only for such directories. Those that are
+// merely on the way have their Skip fields set, as do directories that don't
+// match the filter's include patterns.
+//
+// Each selected path of the filter that is encountered is added to the
+// supplied set.
 func newDependencyResolver(
+	filter Filter,
+	foundPaths util.StringSet,
 	blobStore blob.Store,
 	logger *log.Logger) (dr dag.DependencyResolver) {
 	dr = &dependencyResolver{
-		blobStore: blobStore,
-		logger:    logger,
+		filter:     filter,
+		foundPaths: foundPaths,
+		blobStore:  blobStore,
+		logger:     logger,
 	}
 
 	return
 }
 
 type dependencyResolver struct {
-	blobStore blob.Store
-	logger    *log.Logger
+	filter     Filter
+	foundPaths util.StringSet
+	blobStore  blob.Store
+	logger     *log.Logger
 }
 
 func (dr *dependencyResolver) FindDependencies(
@@ -80,7 +94,7 @@ func (dr *dependencyResolver) FindDependencies(
 	// Load the listing blob.
 	dr.logger.Printf("Loading listing: %s", n.RelPath)
 
-	b, err := dr.blobStore.Load(ctx, score)
+	b, err := dr.blobStore.Load(ctx, score, blob.Kind_Dir)
 	if err != nil {
 		err = fmt.Errorf("Load(%s): %v", score.Hex(), err)
 		return
@@ -100,6 +114,36 @@ func (dr *dependencyResolver) FindDependencies(
 			Info:    *entry,
 		}
 
+		// Skip children outside of the selection.
+		switch dr.filter.classify(child.RelPath) {
+		case selectNone:
+			continue
+
+		case selectAncestor:
+			if entry.Type != fs.TypeDirectory {
+				continue
+			}
+
+			child.Skip = true
+
+		case selectSubtree:
+			if dr.filter.excluded(child.RelPath) {
+				continue
+			}
+
+			if !dr.filter.included(child.RelPath) {
+				if entry.Type != fs.TypeDirectory {
+					continue
+				}
+
+				child.Skip = true
+			}
+		}
+
+		if dr.filter.isSelectedPath(child.RelPath) {
+			dr.foundPaths.Add(child.RelPath)
+		}
+
 		n.Children = append(n.Children, child)
 		deps = append(deps, child)
 	}
@@ -24,9 +24,12 @@ import (
 	"time"
 
 	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/crypto"
 	"github.com/jacobsa/comeback/internal/dag"
 	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/registry"
 	"github.com/jacobsa/comeback/internal/repr"
+	"github.com/jacobsa/comeback/internal/util"
 	"github.com/jacobsa/comeback/internal/wiring"
 	"github.com/jacobsa/gcloud/gcs/gcsfake"
 	. "github.com/jacobsa/oglematchers"
@@ -44,14 +47,21 @@ func TestDependencyResolver(t *testing.T) { RunTests(t) }
 // Helpers
 ////////////////////////////////////////////////////////////////////////
 
-func newFakeBlobStore(ctx context.Context) (blobStore blob.Store, err error) {
+func newFakeBlobStore(
+	ctx context.Context) (blobStore blob.Store, crypter crypto.Crypter, err error) {
 	// Create a bucket.
 	bucket := gcsfake.NewFakeBucket(timeutil.RealClock(), "some_bucket")
 
 	// And a cryptoer.
-	_, crypter, err := wiring.MakeRegistryAndCrypter(ctx, "password", bucket)
+	_, crypter, err = wiring.InitRegistryAndCrypter(
+		ctx,
+		"password",
+		bucket,
+		registry.DefaultKDF,
+		false)
+
 	if err != nil {
-		err = fmt.Errorf("MakeRegistryAndCrypter: %v", err)
+		err = fmt.Errorf("InitRegistryAndCrypter: %v", err)
 		return
 	}
 
@@ -70,9 +80,10 @@ func newFakeBlobStore(ctx context.Context) (blobStore blob.Store, err error) {
 ////////////////////////////////////////////////////////////////////////
 
 type DependencyResolverTest struct {
-	ctx       context.Context
-	blobStore blob.Store
-	dr        dag.DependencyResolver
+	ctx        context.Context
+	blobStore  blob.Store
+	foundPaths util.StringSet
+	dr         dag.DependencyResolver
 }
 
 var _ SetUpInterface = &DependencyResolverTest{}
@@ -84,11 +95,20 @@ func (t *DependencyResolverTest) SetUp(ti *TestInfo) {
 	t.ctx = ti.Ctx
 
 	// Create the blob store.
-	t.blobStore, err = newFakeBlobStore(t.ctx)
+	t.blobStore, _, err = newFakeBlobStore(t.ctx)
 	AssertEq(nil, err)
 
 	// Create the dependency resolver.
-	t.dr = newDependencyResolver(t.blobStore, log.New(ioutil.Discard, "", 0))
+	t.setFilter(Filter{})
+}
+
+func (t *DependencyResolverTest) setFilter(f Filter) {
+	t.foundPaths = util.NewStringSet()
+	t.dr = newDependencyResolver(
+		f,
+		t.foundPaths,
+		t.blobStore,
+		log.New(ioutil.Discard, "", 0))
 }
 
 func (t *DependencyResolverTest) call(n *node) (deps []*node, err error) {
@@ -108,7 +128,7 @@ func (t *DependencyResolverTest) call(n *node) (deps []*node, err error) {
 func (t *DependencyResolverTest) store(b []byte) (s blob.Score, err error) {
 	s, err = t.blobStore.Save(
 		t.ctx,
-		&blob.SaveRequest{Blob: b})
+		&blob.SaveRequest{Blob: b, Kind: blob.Kind_Dir})
 
 	return
 }
@@ -263,3 +283,122 @@ func (t *DependencyResolverTest) SomeChildren() {
 	ExpectEq("taco/burrito/bar", child.RelPath)
 	ExpectThat(child.Info, DeepEquals(*listing[1]))
 }
+
+func (t *DependencyResolverTest) Filter_Paths() {
+	var err error
+
+	// Set up a listing.
+	listing := []*fs.FileInfo{
+		&fs.FileInfo{
+			Type: fs.TypeFile,
+			Name: "foo",
+		},
+		&fs.FileInfo{
+			Type:   fs.TypeDirectory,
+			Name:   "bar",
+			Scores: []blob.Score{blob.ComputeScore([]byte(""))},
+		},
+		&fs.FileInfo{
+			Type:   fs.TypeDirectory,
+			Name:   "baz",
+			Scores: []blob.Score{blob.ComputeScore([]byte(""))},
+		},
+		&fs.FileInfo{
+			Type: fs.TypeFile,
+			Name: "qux",
+		},
+	}
+
+	serialized, err := repr.MarshalDir(listing)
+	AssertEq(nil, err)
+
+	score, err := t.store(serialized)
+	AssertEq(nil, err)
+
+	// Select a path within one directory and the entirety of a file.
+	t.setFilter(Filter{Paths: []string{"taco/bar/enchilada", "/taco/qux"}})
+
+	n := &node{
+		RelPath: "taco",
+		Info: fs.FileInfo{
+			Type:   fs.TypeDirectory,
+			Scores: []blob.Score{score},
+		},
+	}
+
+	// Call
+	deps, err := t.call(n)
+
+	AssertEq(nil, err)
+	AssertEq(2, len(deps))
+
+	ExpectEq("taco/bar", deps[0].RelPath)
+	ExpectTrue(deps[0].Skip)
+
+	ExpectEq("taco/qux", deps[1].RelPath)
+	ExpectFalse(deps[1].Skip)
+
+	ExpectFalse(t.foundPaths.Contains("taco/bar/enchilada"))
+	ExpectTrue(t.foundPaths.Contains("taco/qux"))
+}
+
+func (t *DependencyResolverTest) Filter_Patterns() {
+	var err error
+
+	// Set up a listing.
+	listing := []*fs.FileInfo{
+		&fs.FileInfo{
+			Type: fs.TypeFile,
+			Name: "foo.jpg",
+		},
+		&fs.FileInfo{
+			Type: fs.TypeFile,
+			Name: "foo.txt",
+		},
+		&fs.FileInfo{
+			Type:   fs.TypeDirectory,
+			Name:   "tmp",
+			Scores: []blob.Score{blob.ComputeScore([]byte(""))},
+		},
+		&fs.FileInfo{
+			Type:   fs.TypeDirectory,
+			Name:   "photos",
+			Scores: []blob.Score{blob.ComputeScore([]byte(""))},
+		},
+		&fs.FileInfo{
+			Type: fs.TypeFile,
+			Name: "bar.jpg",
+		},
+	}
+
+	serialized, err := repr.MarshalDir(listing)
+	AssertEq(nil, err)
+
+	score, err := t.store(serialized)
+	AssertEq(nil, err)
+
+	t.setFilter(Filter{
+		Includes: []string{"*.jpg"},
+		Excludes: []string{"tmp", "taco/bar.*"},
+	})
+
+	n := &node{
+		RelPath: "taco",
+		Info: fs.FileInfo{
+			Type:   fs.TypeDirectory,
+			Scores: []blob.Score{score},
+		},
+	}
+
+	// Call
+	deps, err := t.call(n)
+
+	AssertEq(nil, err)
+	AssertEq(2, len(deps))
+
+	ExpectEq("taco/foo.jpg", deps[0].RelPath)
+	ExpectFalse(deps[0].Skip)
+
+	ExpectEq("taco/photos", deps[1].RelPath)
+	ExpectTrue(deps[1].Skip)
+}
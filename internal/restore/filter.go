@@ -0,0 +1,185 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// A Filter selects the portion of a backup to be restored. The zero value
+// selects the entire backup.
+type Filter struct {
+	// Paths relative to the root of the backup naming the subtrees to be
+	// restored. If empty, the entire backup is selected. Each selected subtree
+	// is restored at the same relative path within the destination directory.
+	Paths []string
+
+	// Glob patterns in the syntax accepted by path.Match. If Includes is
+	// non-empty, only entries within the selected subtrees that match at least
+	// one of its patterns are written, though directories that don't match are
+	// still searched for descendants that do. Entries matching any of Excludes
+	// are not written, and if they are directories neither are their contents.
+	// It is an error for Excludes to match a selected path or one of its
+	// ancestors.
+	//
+	// A pattern containing a slash is matched against the entry's path relative
+	// to the root of the backup. Otherwise it is matched against the entry's
+	// name.
+	Includes []string
+	Excludes []string
+}
+
+// The relationship of a path within the backup to the paths selected by a
+// filter.
+type selection int
+
+const (
+	// The path is neither within nor on the way to a selected subtree.
+	selectNone selection = iota
+
+	// The path is a directory that must be traversed to reach a selected
+	// subtree, but is not itself selected.
+	selectAncestor
+
+	// The path is within a selected subtree.
+	selectSubtree
+)
+
+// Return an error if the filter is malformed.
+func (f *Filter) check() (err error) {
+	patterns := append(append([]string{}, f.Includes...), f.Excludes...)
+	for _, pattern := range patterns {
+		if _, err = path.Match(pattern, ""); err != nil {
+			err = fmt.Errorf("Bad pattern %q: %v", pattern, err)
+			return
+		}
+	}
+
+	// A selected path that is excluded, or is within an excluded directory,
+	// would never be restored, so the user has probably made a mistake.
+	for _, p := range f.cleanPaths() {
+		for relPath := p; relPath != ""; relPath = parentPath(relPath) {
+			if pattern, ok := matchingPattern(f.Excludes, relPath); ok {
+				err = fmt.Errorf(
+					"Selected path %q is excluded by pattern %q",
+					p,
+					pattern)
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// Return the path of the directory containing the entry with the supplied
+// path within the backup, or the empty string for entries in the root.
+func parentPath(relPath string) string {
+	dir := path.Dir(relPath)
+	if dir == "." {
+		return ""
+	}
+
+	return dir
+}
+
+// Return the selected paths in the form used for relative paths within the
+// backup, where the root is the empty string.
+func (f *Filter) cleanPaths() (paths []string) {
+	for _, p := range f.Paths {
+		paths = append(paths, cleanPath(p))
+	}
+
+	return
+}
+
+// Clean the supplied path, treating it as relative to the root of the backup
+// regardless of leading slashes.
+func cleanPath(p string) (clean string) {
+	clean = path.Clean("/" + p)[1:]
+	return
+}
+
+func (f *Filter) classify(relPath string) (s selection) {
+	if len(f.Paths) == 0 {
+		s = selectSubtree
+		return
+	}
+
+	for _, p := range f.cleanPaths() {
+		switch {
+		case p == "" || relPath == p || strings.HasPrefix(relPath, p+"/"):
+			s = selectSubtree
+			return
+
+		case relPath == "" || strings.HasPrefix(p, relPath+"/"):
+			s = selectAncestor
+		}
+	}
+
+	return
+}
+
+// Is the supplied path within the backup one of the selected paths?
+func (f *Filter) isSelectedPath(relPath string) bool {
+	for _, p := range f.cleanPaths() {
+		if relPath == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *Filter) included(relPath string) bool {
+	if len(f.Includes) == 0 {
+		return true
+	}
+
+	return matchesAny(f.Includes, relPath)
+}
+
+func (f *Filter) excluded(relPath string) bool {
+	return matchesAny(f.Excludes, relPath)
+}
+
+func matchesAny(patterns []string, relPath string) bool {
+	_, ok := matchingPattern(patterns, relPath)
+	return ok
+}
+
+// Return the first of the patterns that matches the supplied path, if any.
+func matchingPattern(
+	patterns []string,
+	relPath string) (pattern string, ok bool) {
+	for _, pattern = range patterns {
+		name := relPath
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(relPath)
+		}
+
+		// Patterns have been checked by Filter.check, so there can be no error.
+		if matched, _ := path.Match(pattern, name); matched {
+			ok = true
+			return
+		}
+	}
+
+	pattern = ""
+	return
+}
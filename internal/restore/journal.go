@@ -0,0 +1,292 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jacobsa/comeback/internal/blob"
+)
+
+// A journal records the progress of a restore into a file, so that an
+// interrupted restore can be resumed. It also makes available the progress
+// recorded by an earlier attempt at the same restore. The file lives outside
+// the destination directory, so that an interrupted restore doesn't leave it
+// among the user's data.
+//
+// The file is append-only and consists of lines of text. The first line
+// identifies the restore:
+//
+//	comeback restore journal v1 <hex score> <conflict policy>
+//
+// Each following line is one of:
+//
+//	begin <quoted relative path>
+//	partial <chunk length> <chunk count> <quoted relative path>
+//	done <quoted relative path>
+//
+// where a begin record says that an entry is about to be created, after any
+// existing entry in its place has been dealt with according to the conflict
+// policy, a partial record says that the first <chunk count> chunks of a file
+// have been written, and a done record says that an entry has been restored
+// completely, including its permissions and times. A line torn by a crash is
+// ignored.
+type journal struct {
+	// The progress recorded by an earlier attempt, if any. Constant after
+	// creation.
+	begun   map[string]bool
+	done    map[string]bool
+	partial map[string]partialRecord
+
+	mu sync.Mutex
+
+	// GUARDED_BY(mu)
+	f *os.File
+}
+
+type partialRecord struct {
+	// The length of each chunk of the file but the last.
+	ChunkLen int64
+
+	// The number of leading chunks that have been written.
+	Chunks int
+}
+
+// Open the journal at the supplied path, creating it if it doesn't already
+// exist. If it does exist, it must be for the same score and policy.
+func openJournal(
+	p string,
+	score blob.Score,
+	policy ConflictPolicy) (j *journal, err error) {
+	j = &journal{
+		begun:   make(map[string]bool),
+		done:    make(map[string]bool),
+		partial: make(map[string]partialRecord),
+	}
+
+	header := fmt.Sprintf(
+		"comeback restore journal v1 %s %s",
+		score.Hex(),
+		policy)
+
+	// Read the existing journal, if any.
+	var resumed bool
+	f, err := os.Open(p)
+	switch {
+	case os.IsNotExist(err):
+		err = nil
+
+	case err != nil:
+		err = fmt.Errorf("Open: %v", err)
+		return
+
+	default:
+		resumed = true
+		err = j.load(f, header)
+		f.Close()
+		if err != nil {
+			err = fmt.Errorf("load(%q): %v", p, err)
+			return
+		}
+	}
+
+	// Open it for appending.
+	j.f, err = os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		err = fmt.Errorf("OpenFile: %v", err)
+		return
+	}
+
+	if !resumed {
+		err = j.append(header)
+		if err != nil {
+			j.f.Close()
+			err = fmt.Errorf("append: %v", err)
+			return
+		}
+	}
+
+	return
+}
+
+// Read the records in the supplied file, which must begin with the given
+// header.
+func (j *journal) load(r io.Reader, header string) (err error) {
+	scanner := bufio.NewScanner(r)
+
+	// Check the header.
+	if !scanner.Scan() {
+		err = scanner.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+
+		err = fmt.Errorf("Reading header: %v", err)
+		return
+	}
+
+	if scanner.Text() != header {
+		err = fmt.Errorf(
+			"Found a journal for a different restore (%q); remove it to start over",
+			scanner.Text())
+		return
+	}
+
+	// Process each record, ignoring any that can't be parsed. The last might
+	// have been torn by a crash.
+	for scanner.Scan() {
+		j.loadRecord(scanner.Text())
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		err = fmt.Errorf("Scan: %v", err)
+		return
+	}
+
+	return
+}
+
+func (j *journal) loadRecord(line string) {
+	switch {
+	case strings.HasPrefix(line, "begin "):
+		relPath, err := strconv.Unquote(strings.TrimPrefix(line, "begin "))
+		if err != nil {
+			return
+		}
+
+		j.begun[relPath] = true
+
+	case strings.HasPrefix(line, "done "):
+		relPath, err := strconv.Unquote(strings.TrimPrefix(line, "done "))
+		if err != nil {
+			return
+		}
+
+		j.done[relPath] = true
+		delete(j.partial, relPath)
+
+	case strings.HasPrefix(line, "partial "):
+		fields := strings.SplitN(line, " ", 4)
+		if len(fields) != 4 {
+			return
+		}
+
+		chunkLen, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || chunkLen <= 0 {
+			return
+		}
+
+		chunks, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return
+		}
+
+		relPath, err := strconv.Unquote(fields[3])
+		if err != nil {
+			return
+		}
+
+		j.partial[relPath] = partialRecord{ChunkLen: chunkLen, Chunks: chunks}
+	}
+}
+
+func (j *journal) append(line string) (err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, err = io.WriteString(j.f, line+"\n")
+	if err != nil {
+		err = fmt.Errorf("WriteString: %v", err)
+		return
+	}
+
+	return
+}
+
+// Did an earlier attempt record that it had begun creating the entry at the
+// supplied path? If so, anything now at that path was put there by the
+// restore, unless the entry was also recorded as done.
+func (j *journal) Begun(relPath string) bool {
+	return j.begun[relPath]
+}
+
+// Did an earlier attempt record the entry at the supplied path as done?
+func (j *journal) Done(relPath string) bool {
+	return j.done[relPath]
+}
+
+// Return the progress recorded by an earlier attempt for the partially
+// written file at the supplied path, if any.
+func (j *journal) Partial(relPath string) (r partialRecord, ok bool) {
+	r, ok = j.partial[relPath]
+	return
+}
+
+// Record that the entry at the supplied path is about to be created.
+func (j *journal) RecordBegin(relPath string) (err error) {
+	err = j.append("begin " + strconv.Quote(relPath))
+	return
+}
+
+// Record that the first r.Chunks chunks of the file at the supplied path have
+// been written.
+func (j *journal) RecordPartial(relPath string, r partialRecord) (err error) {
+	err = j.append(fmt.Sprintf(
+		"partial %d %d %s",
+		r.ChunkLen,
+		r.Chunks,
+		strconv.Quote(relPath)))
+
+	return
+}
+
+// Record that the entry at the supplied path has been completely restored.
+func (j *journal) RecordDone(relPath string) (err error) {
+	err = j.append("done " + strconv.Quote(relPath))
+	return
+}
+
+// Close the journal file, leaving it in place.
+func (j *journal) Close() (err error) {
+	err = j.f.Close()
+	return
+}
+
+// Close and delete the journal file, for use when the restore has finished.
+func (j *journal) Remove() (err error) {
+	name := j.f.Name()
+
+	err = j.f.Close()
+	if err != nil {
+		err = fmt.Errorf("Close: %v", err)
+		return
+	}
+
+	err = os.Remove(name)
+	if err != nil {
+		err = fmt.Errorf("Remove: %v", err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,182 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+)
+
+func TestJournal(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type JournalTest struct {
+	// A directory that is deleted when the test completes.
+	dir string
+
+	score blob.Score
+}
+
+var _ SetUpInterface = &JournalTest{}
+var _ TearDownInterface = &JournalTest{}
+
+func init() { RegisterTestSuite(&JournalTest{}) }
+
+func (t *JournalTest) SetUp(ti *TestInfo) {
+	var err error
+
+	t.dir, err = ioutil.TempDir("", "journal_test")
+	AssertEq(nil, err)
+
+	t.score = blob.ComputeScore([]byte("taco"))
+}
+
+func (t *JournalTest) journalPath() string {
+	return path.Join(t.dir, "journal")
+}
+
+func (t *JournalTest) TearDown() {
+	var err error
+
+	err = os.RemoveAll(t.dir)
+	AssertEq(nil, err)
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *JournalTest) NewJournal() {
+	j, err := openJournal(t.journalPath(), t.score, ConflictFail)
+	AssertEq(nil, err)
+	defer j.Close()
+
+	ExpectFalse(j.Done("foo"))
+	ExpectFalse(j.Begun("foo"))
+
+	_, ok := j.Partial("foo")
+	ExpectFalse(ok)
+}
+
+func (t *JournalTest) Reopen() {
+	var err error
+
+	// Record some progress.
+	j, err := openJournal(t.journalPath(), t.score, ConflictSkip)
+	AssertEq(nil, err)
+
+	AssertEq(nil, j.RecordPartial("foo", partialRecord{ChunkLen: 17, Chunks: 1}))
+	AssertEq(nil, j.RecordPartial("foo", partialRecord{ChunkLen: 17, Chunks: 2}))
+	AssertEq(nil, j.RecordPartial("bar baz", partialRecord{ChunkLen: 19, Chunks: 3}))
+	AssertEq(nil, j.RecordPartial("qux", partialRecord{ChunkLen: 19, Chunks: 1}))
+	AssertEq(nil, j.RecordDone("qux"))
+	AssertEq(nil, j.RecordDone("a \"quoted\"\nname"))
+	AssertEq(nil, j.RecordBegin("enchilada"))
+	AssertEq(nil, j.Close())
+
+	// Reopen.
+	j, err = openJournal(t.journalPath(), t.score, ConflictSkip)
+	AssertEq(nil, err)
+	defer j.Close()
+
+	r, ok := j.Partial("foo")
+	AssertTrue(ok)
+	ExpectEq(17, r.ChunkLen)
+	ExpectEq(2, r.Chunks)
+	ExpectFalse(j.Done("foo"))
+
+	r, ok = j.Partial("bar baz")
+	AssertTrue(ok)
+	ExpectEq(19, r.ChunkLen)
+	ExpectEq(3, r.Chunks)
+
+	_, ok = j.Partial("qux")
+	ExpectFalse(ok)
+	ExpectTrue(j.Done("qux"))
+	ExpectTrue(j.Done("a \"quoted\"\nname"))
+
+	ExpectTrue(j.Begun("enchilada"))
+	ExpectFalse(j.Done("enchilada"))
+	ExpectFalse(j.Begun("foo"))
+}
+
+func (t *JournalTest) TornRecord() {
+	var err error
+
+	j, err := openJournal(t.journalPath(), t.score, ConflictFail)
+	AssertEq(nil, err)
+	AssertEq(nil, j.RecordDone("foo"))
+	AssertEq(nil, j.Close())
+
+	// Simulate a crash partway through writing a record.
+	f, err := os.OpenFile(
+		t.journalPath(),
+		os.O_WRONLY|os.O_APPEND,
+		0)
+	AssertEq(nil, err)
+
+	_, err = f.Write([]byte(`done "ba`))
+	AssertEq(nil, err)
+	AssertEq(nil, f.Close())
+
+	// The torn record should be ignored.
+	j, err = openJournal(t.journalPath(), t.score, ConflictFail)
+	AssertEq(nil, err)
+	defer j.Close()
+
+	ExpectTrue(j.Done("foo"))
+	ExpectFalse(j.Done("ba"))
+	ExpectFalse(j.Done("bar"))
+}
+
+func (t *JournalTest) DifferentScore() {
+	j, err := openJournal(t.journalPath(), t.score, ConflictFail)
+	AssertEq(nil, err)
+	AssertEq(nil, j.Close())
+
+	_, err = openJournal(t.journalPath(), blob.ComputeScore([]byte("burrito")), ConflictFail)
+	ExpectThat(err, Error(HasSubstr("different restore")))
+}
+
+func (t *JournalTest) DifferentPolicy() {
+	j, err := openJournal(t.journalPath(), t.score, ConflictFail)
+	AssertEq(nil, err)
+	AssertEq(nil, j.Close())
+
+	_, err = openJournal(t.journalPath(), t.score, ConflictOverwrite)
+	ExpectThat(err, Error(HasSubstr("different restore")))
+}
+
+func (t *JournalTest) Remove() {
+	j, err := openJournal(t.journalPath(), t.score, ConflictFail)
+	AssertEq(nil, err)
+
+	err = j.Remove()
+	AssertEq(nil, err)
+
+	entries, err := ioutil.ReadDir(t.dir)
+	AssertEq(nil, err)
+	ExpectThat(entries, ElementsAre())
+}
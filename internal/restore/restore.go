@@ -18,6 +18,7 @@ package restore
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"syscall"
@@ -31,25 +32,81 @@ import (
 	"github.com/jacobsa/gcloud/gcs"
 )
 
-// Restore the backup rooted at the supplied score into the given directory,
-// which must already exist.
+// Restore the portion of the backup rooted at the supplied score selected by
+// the given filter into the given directory, which must already exist.
+//
+// Existing entries within the directory are dealt with according to the
+// supplied conflict policy. If the policy is ConflictFail, the directory must
+// be empty. See newVisitor for the meaning of compareContent.
+//
+// Progress is recorded in a journal file at journalPath, which must be outside
+// the directory and is removed when the restore succeeds. If the restore is
+// interrupted, calling Restore again with the same journal path, score, and
+// policy resumes where it left off. Entries that the journal shows the earlier
+// attempt began creating are replaced without consulting the conflict policy;
+// everything else is subject to it as usual.
 //
 // The supplied bucket is assumed to contain objects with the given name
 // prefix.
 func Restore(
 	ctx context.Context,
 	dir string,
+	journalPath string,
 	score blob.Score,
+	filter Filter,
+	policy ConflictPolicy,
+	compareContent bool,
 	bucket gcs.Bucket,
 	objectNamePrefix string,
 	crypter crypto.Crypter,
 	logger *log.Logger) (err error) {
 	// Hopefully enough parallelism to keep our CPUs saturated (for decryption,
-	// SHA-1 computation, etc.) or our NIC saturated (for GCS traffic), depending
+	// hash computation, etc.) or our NIC saturated (for GCS traffic), depending
 	// on which is the current bottleneck.
 	const resolverParallelism = 128
 	const visitorParallelism = 128
 
+	// Large files are loaded several chunks at a time. Put a bound on the total
+	// number of chunks in memory.
+	const chunkParallelism = 128
+
+	// Make sure the filter is usable before doing any work.
+	err = filter.check()
+	if err != nil {
+		err = fmt.Errorf("Invalid filter: %v", err)
+		return
+	}
+
+	// Has an earlier attempt left a journal behind?
+	_, err = os.Lstat(journalPath)
+	resuming := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		err = fmt.Errorf("Lstat: %v", err)
+		return
+	}
+
+	err = nil
+
+	// By default, refuse to touch a directory that already has something in it.
+	// If we're resuming, the directory was empty when we started, and the
+	// visitor applies the policy to anything that the earlier attempt didn't
+	// record putting there.
+	if policy == ConflictFail && !resuming {
+		var empty bool
+		empty, err = isEmptyDir(dir)
+		if err != nil {
+			err = fmt.Errorf("isEmptyDir: %v", err)
+			return
+		}
+
+		if !empty {
+			err = fmt.Errorf(
+				"Destination directory %q is not empty; choose a conflict policy",
+				dir)
+			return
+		}
+	}
+
 	// Manufacture a root node.
 	fi, err := os.Stat(dir)
 	if err != nil {
@@ -69,15 +126,45 @@ func Restore(
 		},
 	}
 
+	switch filter.classify(rootNode.RelPath) {
+	case selectAncestor:
+		rootNode.Skip = true
+
+	case selectSubtree:
+		rootNode.Skip = !filter.included(rootNode.RelPath)
+	}
+
+	// Open the journal, picking up the progress of any earlier attempt.
+	j, err := openJournal(journalPath, score, policy)
+	if err != nil {
+		err = fmt.Errorf("openJournal: %v", err)
+		return
+	}
+
+	defer func() {
+		if j != nil {
+			j.Close()
+		}
+	}()
+
 	// Create a blob store.
 	blobStore := newBlobStore(bucket, objectNamePrefix, crypter)
 
 	// Walk the graph.
+	foundPaths := util.NewStringSet()
 	err = dag.Visit(
 		ctx,
 		[]dag.Node{rootNode},
-		newDependencyResolver(blobStore, logger),
-		newVisitor(dir, blobStore, logger),
+		newDependencyResolver(filter, foundPaths, blobStore, logger),
+		newVisitor(
+			dir,
+			policy,
+			compareContent,
+			crypter,
+			j,
+			chunkParallelism,
+			blobStore,
+			logger),
 		resolverParallelism,
 		visitorParallelism)
 
@@ -86,6 +173,49 @@ func Restore(
 		return
 	}
 
+	// Complain about selected paths that don't exist, since the user probably
+	// made a mistake.
+	for _, p := range filter.cleanPaths() {
+		if p != "" && !foundPaths.Contains(p) {
+			err = fmt.Errorf("Path not found in backup: %q", p)
+			return
+		}
+	}
+
+	// We're done with the journal.
+	err = j.Remove()
+	j = nil
+	if err != nil {
+		err = fmt.Errorf("Removing journal: %v", err)
+		return
+	}
+
+	return
+}
+
+// Does the supplied directory contain no entries?
+func isEmptyDir(dir string) (empty bool, err error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		err = fmt.Errorf("Open: %v", err)
+		return
+	}
+
+	defer f.Close()
+
+	names, err := f.Readdirnames(1)
+	if err == io.EOF {
+		err = nil
+		empty = true
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("Readdirnames: %v", err)
+		return
+	}
+
+	empty = len(names) == 0
 	return
 }
 
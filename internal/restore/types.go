@@ -31,4 +31,10 @@ type node struct {
 	// The nodes comprising the children of this directory. Empty for
 	// non-directories.
 	Children []*node
+
+	// Set for directories that are traversed only in order to reach
+	// descendants selected by the restore's filter. Such nodes are not written
+	// themselves; their paths are created as necessary when writing their
+	// descendants.
+	Skip bool
 }
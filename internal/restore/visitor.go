@@ -18,25 +18,34 @@ package restore
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path"
+	"strings"
+	"sync"
 	"time"
 	"unsafe"
 
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/sys/unix"
 
 	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/crypto"
 	"github.com/jacobsa/comeback/internal/dag"
 	"github.com/jacobsa/comeback/internal/fs"
 	"github.com/jacobsa/comeback/internal/repr"
+	"github.com/jacobsa/comeback/internal/save"
 )
 
 // Create a dag.Visitor for *node.
 //
-// For each node n, the visitor does the following:
+// Nodes with the Skip field set are ignored. For each other node n, the
+// visitor does the following:
 //
 //  *  Ensure that the directory path.Dir(n.RelPath) exists.
+//  *  Deal with any existing entry at n.RelPath according to the supplied
+//     conflict policy, possibly deciding to leave it alone.
 //  *  <Perform type-specific action.>
 //  *  Set the appropriate permissions and times for n.RelPath.
 //
@@ -48,23 +57,57 @@ import (
 //  *  Directories: ensure that the directory n.RelPath exists.
 //  *  Symlinks: create a symlink pointing at n.Info.Target.
 //
+// When the conflict policy asks whether an existing file is identical to the
+// backed up version, the visitor compares size and mtime. If compareContent
+// is set, it instead compares the scores of the existing contents, computed
+// using the supplied crypter, with n.Info.Scores.
+//
+// The chunks of a file are loaded in parallel and written at their offsets,
+// with at most chunkParallelism chunks in flight across all files. Progress
+// is recorded in the supplied journal. Entries that an earlier attempt
+// recorded as done are left alone if they still look right, and files that
+// it partially wrote are resumed from the last chunk that can be verified,
+// in both cases without consulting the conflict policy. Entries that it began
+// creating but didn't finish are replaced. Everything else, including entries
+// recorded as done that have since changed, is subject to the policy.
 func newVisitor(
 	basePath string,
+	policy ConflictPolicy,
+	compareContent bool,
+	crypter crypto.Crypter,
+	j *journal,
+	chunkParallelism int,
 	blobStore blob.Store,
 	logger *log.Logger) (v dag.Visitor) {
 	v = &visitor{
-		basePath:  basePath,
-		blobStore: blobStore,
-		logger:    logger,
+		basePath:       basePath,
+		policy:         policy,
+		compareContent: compareContent,
+		crypter:        crypter,
+		journal:        j,
+		chunkSem:       make(chan struct{}, chunkParallelism),
+		blobStore:      blobStore,
+		logger:         logger,
 	}
 
 	return
 }
 
 type visitor struct {
-	basePath  string
-	blobStore blob.Store
-	logger    *log.Logger
+	basePath       string
+	policy         ConflictPolicy
+	compareContent bool
+	crypter        crypto.Crypter
+	journal        *journal
+	blobStore      blob.Store
+	logger         *log.Logger
+
+	// A semaphore limiting the number of file chunks in memory at once.
+	chunkSem chan struct{}
+
+	// Held while fixing up conflicts with the leading directories of a path, so
+	// that siblings don't race to do so.
+	parentsMu sync.Mutex
 }
 
 func (v *visitor) Visit(ctx context.Context, untyped dag.Node) (err error) {
@@ -75,21 +118,84 @@ func (v *visitor) Visit(ctx context.Context, untyped dag.Node) (err error) {
 		return
 	}
 
+	// Is there anything to do?
+	if n.Skip {
+		return
+	}
+
 	absPath := path.Join(v.basePath, n.RelPath)
 
+	// Has an earlier attempt at this restore already dealt with this node?
+	if v.journal.Done(n.RelPath) {
+		var intact bool
+		intact, err = v.stillRestored(ctx, absPath, n)
+		if err != nil {
+			err = fmt.Errorf("stillRestored: %v", err)
+			return
+		}
+
+		if intact {
+			return
+		}
+	}
+
 	// Make sure the leading directories exist so that we can write into them.
+	ok, err = v.prepareParents(n.RelPath)
+	if err != nil {
+		err = fmt.Errorf("prepareParents: %v", err)
+		return
+	}
+
+	if !ok {
+		v.logger.Printf("Skipping due to existing parent: %s", n.RelPath)
+		return
+	}
+
 	err = os.MkdirAll(path.Dir(absPath), 0700)
 	if err != nil {
 		err = fmt.Errorf("MkdirAll: %v", err)
 		return
 	}
 
+	// Deal with anything that is already in the way, unless it's a file that an
+	// earlier attempt partially wrote. Anything at a path that an earlier
+	// attempt began creating is its own work, so replace it whatever the
+	// policy.
+	_, resuming := v.journal.Partial(n.RelPath)
+	if !(resuming && n.Info.Type == fs.TypeFile) {
+		policy := v.policy
+		if v.journal.Begun(n.RelPath) && !v.journal.Done(n.RelPath) {
+			policy = ConflictOverwrite
+		}
+
+		ok, err = v.handleExisting(ctx, absPath, n, policy)
+		if err != nil {
+			err = fmt.Errorf("handleExisting: %v", err)
+			return
+		}
+
+		if !ok {
+			v.logger.Printf("Leaving existing entry alone: %s", n.RelPath)
+			return
+		}
+
+		// Let a later attempt know that whatever it finds here is our doing.
+		// Directories are merged with existing ones anyway.
+		if n.Info.Type != fs.TypeDirectory {
+			err = v.journal.RecordBegin(n.RelPath)
+			if err != nil {
+				err = fmt.Errorf("RecordBegin: %v", err)
+				return
+			}
+		}
+	}
+
 	// Perform type-specific logic.
 	switch n.Info.Type {
 	case fs.TypeFile:
 		v.logger.Printf("Loading contents: %s", n.RelPath)
 
-		err = v.writeFileContents(ctx, absPath, n.Info.Scores)
+		err = v.writeFileContents(ctx, n.RelPath, absPath, n.Info.Scores)
 		if err != nil {
 			err = fmt.Errorf("writeFileContents: %v", err)
 			return
@@ -128,19 +234,289 @@ func (v *visitor) Visit(ctx context.Context, untyped dag.Node) (err error) {
 		return
 	}
 
+	// Let a later attempt know that it needn't bother.
+	err = v.journal.RecordDone(n.RelPath)
+	if err != nil {
+		err = fmt.Errorf("RecordDone: %v", err)
+		return
+	}
+
+	return
+}
+
+// Does the entry at the supplied path, which an earlier attempt recorded as
+// done, still appear to match the backed up node?
+func (v *visitor) stillRestored(
+	ctx context.Context,
+	absPath string,
+	n *node) (intact bool, err error) {
+	fi, err := os.Lstat(absPath)
+	if os.IsNotExist(err) {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("Lstat: %v", err)
+		return
+	}
+
+	if n.Info.Type == fs.TypeDirectory {
+		intact = fi.IsDir()
+		return
+	}
+
+	intact, err = v.identical(ctx, absPath, fi, n)
+	return
+}
+
+// Ensure that each leading directory of the supplied relative path either
+// doesn't yet exist or is a directory, applying the conflict policy to
+// anything else. Return false if the policy says to leave such an entry
+// alone, in which case the path can't be written.
+func (v *visitor) prepareParents(relPath string) (ok bool, err error) {
+	// Handle the common case without locking.
+	parent := path.Dir(path.Join(v.basePath, relPath))
+	if fi, statErr := os.Lstat(parent); statErr == nil && fi.IsDir() {
+		ok = true
+		return
+	}
+
+	v.parentsMu.Lock()
+	defer v.parentsMu.Unlock()
+
+	// Walk down from the base path. Everything after a missing directory is
+	// missing too.
+	p := v.basePath
+	for _, name := range strings.Split(path.Dir(relPath), "/") {
+		if name == "." {
+			break
+		}
+
+		p = path.Join(p, name)
+
+		var fi os.FileInfo
+		fi, err = os.Lstat(p)
+		if os.IsNotExist(err) {
+			err = nil
+			break
+		}
+
+		if err != nil {
+			err = fmt.Errorf("Lstat: %v", err)
+			return
+		}
+
+		if fi.IsDir() {
+			continue
+		}
+
+		// The entry is not a directory, so it can't be identical to what we
+		// want to restore.
+		switch v.policy {
+		case ConflictFail:
+			err = fmt.Errorf("%q already exists and is not a directory", p)
+			return
+
+		case ConflictSkip:
+			return
+
+		case ConflictRename:
+			err = renameAside(p)
+			if err != nil {
+				err = fmt.Errorf("renameAside: %v", err)
+				return
+			}
+
+		default:
+			err = os.Remove(p)
+			if err != nil {
+				err = fmt.Errorf("Remove: %v", err)
+				return
+			}
+		}
+
+		break
+	}
+
+	ok = true
+	return
+}
+
+// Apply the supplied conflict policy to whatever already exists at the
+// supplied path, if anything. Return false if the node should not be written.
+func (v *visitor) handleExisting(
+	ctx context.Context,
+	absPath string,
+	n *node,
+	policy ConflictPolicy) (ok bool, err error) {
+	fi, err := os.Lstat(absPath)
+	if os.IsNotExist(err) {
+		err = nil
+		ok = true
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("Lstat: %v", err)
+		return
+	}
+
+	// Existing directories are merged with backed up ones. Note that this is
+	// also the usual case when restoring a directory's contents has already
+	// created it.
+	if n.Info.Type == fs.TypeDirectory && fi.IsDir() {
+		ok = true
+		return
+	}
+
+	// Is the existing entry the same as what we would write?
+	var identical bool
+	switch policy {
+	case ConflictOverwriteIfDifferent, ConflictRename:
+		identical, err = v.identical(ctx, absPath, fi, n)
+		if err != nil {
+			err = fmt.Errorf("identical: %v", err)
+			return
+		}
+	}
+
+	switch {
+	case policy == ConflictFail:
+		err = fmt.Errorf("%q already exists", absPath)
+		return
+
+	case policy == ConflictSkip || identical:
+		return
+
+	case policy == ConflictRename:
+		err = renameAside(absPath)
+		if err != nil {
+			err = fmt.Errorf("renameAside: %v", err)
+			return
+		}
+
+	default:
+		err = os.RemoveAll(absPath)
+		if err != nil {
+			err = fmt.Errorf("RemoveAll: %v", err)
+			return
+		}
+	}
+
+	ok = true
+	return
+}
+
+// Is the existing entry at the supplied path, which has the given stat info,
+// identical to the backed up node?
+func (v *visitor) identical(
+	ctx context.Context,
+	absPath string,
+	fi os.FileInfo,
+	n *node) (same bool, err error) {
+	switch n.Info.Type {
+	case fs.TypeFile:
+		if !fi.Mode().IsRegular() || uint64(fi.Size()) != n.Info.Size {
+			return
+		}
+
+		if !v.compareContent {
+			same = fi.ModTime().Equal(n.Info.MTime)
+			return
+		}
+
+		// Compute the scores for the existing contents.
+		var f *os.File
+		f, err = os.Open(absPath)
+		if err != nil {
+			err = fmt.Errorf("Open: %v", err)
+			return
+		}
+
+		defer f.Close()
+
+		same, err = save.FileMatches(f, v.crypter, n.Info.Scores)
+		if err != nil {
+			err = fmt.Errorf("FileMatches: %v", err)
+			return
+		}
+
+	case fs.TypeSymlink:
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return
+		}
+
+		var target string
+		target, err = os.Readlink(absPath)
+		if err != nil {
+			err = fmt.Errorf("Readlink: %v", err)
+			return
+		}
+
+		same = target == n.Info.Target
+	}
+
 	return
 }
 
+// Move the entry at the supplied path to an unused name formed by adding an
+// ".orig" suffix and, if necessary, a number.
+func renameAside(p string) (err error) {
+	for i := 0; ; i++ {
+		candidate := p + ".orig"
+		if i > 0 {
+			candidate = fmt.Sprintf("%s.%d", candidate, i)
+		}
+
+		_, err = os.Lstat(candidate)
+		if os.IsNotExist(err) {
+			err = os.Rename(p, candidate)
+			if err != nil {
+				err = fmt.Errorf("Rename: %v", err)
+				return
+			}
+
+			return
+		}
+
+		if err != nil {
+			err = fmt.Errorf("Lstat: %v", err)
+			return
+		}
+	}
+}
+
+// Write out the contents of the file with the supplied scores, resuming from
+// where an earlier attempt got to if possible.
+//
+// Every chunk but the last is the same length, which we learn from the first
+// chunk. This lets us load the remaining chunks in parallel and write each at
+// its offset.
 func (v *visitor) writeFileContents(
 	ctx context.Context,
+	relPath string,
 	absPath string,
 	scores []blob.Score) (err error) {
-	// Create the file.
-	f, err := os.OpenFile(
-		absPath,
-		os.O_WRONLY|os.O_CREATE|os.O_EXCL,
-		0400)
+	// Figure out how much of the file can be kept from an earlier attempt.
+	var chunkLen int64
+	var start int
+
+	if r, ok := v.journal.Partial(relPath); ok {
+		chunkLen = r.ChunkLen
+		start, err = v.verifyChunks(absPath, chunkLen, scores[:r.Chunks])
+		if err != nil {
+			err = fmt.Errorf("verifyChunks: %v", err)
+			return
+		}
 
+		if start > 0 {
+			v.logger.Printf("Resuming at chunk %d: %s", start, relPath)
+		}
+	}
+
+	// Open the file, discarding anything after the chunks we're keeping.
+	f, err := os.OpenFile(absPath, os.O_WRONLY|os.O_CREATE, 0600)
 	if err != nil {
 		err = fmt.Errorf("OpenFile: %v", err)
 		return
@@ -148,28 +524,36 @@ func (v *visitor) writeFileContents(
 
 	defer f.Close()
 
-	// Load and write out each chunk.
-	for _, s := range scores {
-		var chunk []byte
+	err = f.Truncate(int64(start) * chunkLen)
+	if err != nil {
+		err = fmt.Errorf("Truncate: %v", err)
+		return
+	}
 
-		// Load.
-		chunk, err = v.blobStore.Load(ctx, s)
+	// Learn the chunk length from the first chunk, if necessary.
+	if start == 0 && len(scores) > 0 {
+		var chunk []byte
+		chunk, err = v.loadChunk(ctx, scores[0])
 		if err != nil {
-			err = fmt.Errorf("Load(%s): %v", s.Hex(), err)
+			err = fmt.Errorf("loadChunk: %v", err)
 			return
 		}
 
-		// Unmarshal.
-		chunk, err = repr.UnmarshalFile(chunk)
+		chunkLen = int64(len(chunk))
+		_, err = f.WriteAt(chunk, 0)
 		if err != nil {
-			err = fmt.Errorf("UnmarshalFile(%s): %v", s.Hex(), err)
+			err = fmt.Errorf("WriteAt: %v", err)
 			return
 		}
 
-		// Write.
-		_, err = f.Write(chunk)
+		start = 1
+	}
+
+	// Load and write out the remaining chunks.
+	if start < len(scores) {
+		err = v.writeChunks(ctx, relPath, f, chunkLen, start, scores)
 		if err != nil {
-			err = fmt.Errorf("Write: %v", err)
+			err = fmt.Errorf("writeChunks: %v", err)
 			return
 		}
 	}
@@ -184,6 +568,200 @@ func (v *visitor) writeFileContents(
 	return
 }
 
+// Load the chunks of the supplied file with indices in [start, len(scores))
+// in parallel, writing each at its offset and journaling progress as the
+// prefix of chunks written grows.
+func (v *visitor) writeChunks(
+	ctx context.Context,
+	relPath string,
+	f *os.File,
+	chunkLen int64,
+	start int,
+	scores []blob.Score) (err error) {
+	eg, ctx := errgroup.WithContext(ctx)
+
+	// Feed indices to workers.
+	indices := make(chan int)
+	eg.Go(func() (err error) {
+		defer close(indices)
+		for i := start; i < len(scores); i++ {
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+				err = ctx.Err()
+				return
+			}
+		}
+
+		return
+	})
+
+	// Keep track of which chunks have been written, so we can tell how far a
+	// later attempt can skip ahead.
+	var mu sync.Mutex
+	written := make(map[int]bool)
+	prefix := start
+
+	recordWritten := func(i int) (err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		written[i] = true
+		if i != prefix {
+			return
+		}
+
+		for written[prefix] {
+			delete(written, prefix)
+			prefix++
+		}
+
+		err = v.journal.RecordPartial(
+			relPath,
+			partialRecord{ChunkLen: chunkLen, Chunks: prefix})
+
+		return
+	}
+
+	// Load and write chunks.
+	const perFileParallelism = 8
+	for w := 0; w < perFileParallelism; w++ {
+		eg.Go(func() (err error) {
+			for i := range indices {
+				err = v.writeChunk(ctx, f, chunkLen, i, scores)
+				if err != nil {
+					return
+				}
+
+				err = recordWritten(i)
+				if err != nil {
+					err = fmt.Errorf("RecordPartial: %v", err)
+					return
+				}
+			}
+
+			return
+		})
+	}
+
+	err = eg.Wait()
+	return
+}
+
+// Load the chunk of the supplied file with index i and write it at its
+// offset, checking that its length is consistent with chunkLen.
+func (v *visitor) writeChunk(
+	ctx context.Context,
+	f *os.File,
+	chunkLen int64,
+	i int,
+	scores []blob.Score) (err error) {
+	// Don't have too many chunks in memory at once.
+	select {
+	case v.chunkSem <- struct{}{}:
+	case <-ctx.Done():
+		err = ctx.Err()
+		return
+	}
+
+	defer func() { <-v.chunkSem }()
+
+	chunk, err := v.loadChunk(ctx, scores[i])
+	if err != nil {
+		err = fmt.Errorf("loadChunk: %v", err)
+		return
+	}
+
+	// Only the last chunk may be short.
+	l := int64(len(chunk))
+	if l > chunkLen || (l != chunkLen && i != len(scores)-1) {
+		err = fmt.Errorf(
+			"Chunk %s has length %d; expected %d",
+			scores[i].Hex(),
+			l,
+			chunkLen)
+		return
+	}
+
+	_, err = f.WriteAt(chunk, int64(i)*chunkLen)
+	if err != nil {
+		err = fmt.Errorf("WriteAt: %v", err)
+		return
+	}
+
+	return
+}
+
+// Load and unmarshal the file chunk with the supplied score.
+func (v *visitor) loadChunk(
+	ctx context.Context,
+	s blob.Score) (chunk []byte, err error) {
+	// Load.
+	chunk, err = v.blobStore.Load(ctx, s, blob.Kind_File)
+	if err != nil {
+		err = fmt.Errorf("Load(%s): %v", s.Hex(), err)
+		return
+	}
+
+	// Unmarshal.
+	chunk, err = repr.UnmarshalFile(chunk)
+	if err != nil {
+		err = fmt.Errorf("UnmarshalFile(%s): %v", s.Hex(), err)
+		return
+	}
+
+	return
+}
+
+// Return the number of leading chunks of the file at the supplied path, of
+// the given length, that match the supplied scores.
+func (v *visitor) verifyChunks(
+	absPath string,
+	chunkLen int64,
+	scores []blob.Score) (good int, err error) {
+	f, err := os.Open(absPath)
+	if os.IsNotExist(err) {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("Open: %v", err)
+		return
+	}
+
+	defer f.Close()
+
+	buf := make([]byte, chunkLen)
+	for ; good < len(scores); good++ {
+		// Read the chunk. A short read means it wasn't completely written.
+		_, err = io.ReadFull(f, buf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = nil
+			return
+		}
+
+		if err != nil {
+			err = fmt.Errorf("ReadFull: %v", err)
+			return
+		}
+
+		// Does it match?
+		var match bool
+		match, err = save.ChunkMatches(buf, v.crypter, scores[good])
+		if err != nil {
+			err = fmt.Errorf("ChunkMatches: %v", err)
+			return
+		}
+
+		if !match {
+			return
+		}
+	}
+
+	return
+}
+
 // Cf. os.syscallMode
 func syscallMode(i os.FileMode) (o uint32) {
 	o |= uint32(i.Perm())
@@ -261,26 +839,19 @@ func fchmodat(
 func use(p unsafe.Pointer)
 
 // Like os.Chtimes, but doesn't follow symlinks.
-// Cf. http://stackoverflow.com/a/10611073/1505451
 func chtimes(path string, atime time.Time, mtime time.Time) (err error) {
-	// Open the file without following symlinks.
-	fd, err := unix.Open(path, unix.O_SYMLINK, 0)
-	if err != nil {
-		return err
+	// Unlike futimes, which takes microseconds, this preserves the mtime
+	// exactly so that identical recognizes the file on a repeated restore.
+	ts := []unix.Timespec{
+		unix.NsecToTimespec(atime.UnixNano()),
+		unix.NsecToTimespec(mtime.UnixNano()),
 	}
 
-	defer unix.Close(fd)
-
-	// Call futimes.
-	var utimes [2]unix.Timeval
-	utimes[0] = unix.NsecToTimeval(atime.UnixNano())
-	utimes[1] = unix.NsecToTimeval(mtime.UnixNano())
-
-	err = unix.Futimes(fd, utimes[:])
+	err = unix.UtimesNanoAt(unix.AT_FDCWD, path, ts, unix.AT_SYMLINK_NOFOLLOW)
 	if err != nil {
-		err = fmt.Errorf("unix.Futimes: %v", err)
+		err = fmt.Errorf("unix.UtimesNanoAt: %v", err)
 		return
 	}
 
-	return nil
+	return
 }
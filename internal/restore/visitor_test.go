@@ -17,17 +17,22 @@ package restore
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/crypto"
 	"github.com/jacobsa/comeback/internal/dag"
 	"github.com/jacobsa/comeback/internal/fs"
 	"github.com/jacobsa/comeback/internal/repr"
+	"github.com/jacobsa/comeback/internal/save"
 	. "github.com/jacobsa/oglematchers"
 	. "github.com/jacobsa/ogletest"
 	"github.com/jacobsa/timeutil"
@@ -51,11 +56,18 @@ func marshalFileOrDie(content []byte) (chunk []byte) {
 
 type VisitorTest struct {
 	ctx       context.Context
+	crypter   crypto.Crypter
 	blobStore blob.Store
 
 	// A directory that is deleted when the test completes.
 	dir string
 
+	// A journal in a separate directory that is deleted when the test
+	// completes.
+	journalDir  string
+	journalPath string
+	journal     *journal
+
 	// A visitor configured with the above directory.
 	visitor dag.Visitor
 }
@@ -70,20 +82,71 @@ func (t *VisitorTest) SetUp(ti *TestInfo) {
 	t.ctx = ti.Ctx
 
 	// Create the blob store.
-	t.blobStore, err = newFakeBlobStore(t.ctx)
+	t.blobStore, t.crypter, err = newFakeBlobStore(t.ctx)
 	AssertEq(nil, err)
 
 	// Set up the directory.
 	t.dir, err = ioutil.TempDir("", "visitor_test")
 	AssertEq(nil, err)
 
+	// And the journal.
+	t.journalDir, err = ioutil.TempDir("", "visitor_test")
+	AssertEq(nil, err)
+
+	t.journalPath = path.Join(t.journalDir, "journal")
+	t.journal, err = openJournal(t.journalPath, blob.Score{}, ConflictFail)
+	AssertEq(nil, err)
+
 	// Create the visitor.
-	t.visitor = newVisitor(t.dir, t.blobStore, log.New(ioutil.Discard, "", 0))
+	t.setPolicy(ConflictFail, false)
+}
+
+func (t *VisitorTest) setPolicy(policy ConflictPolicy, compareContent bool) {
+	t.visitor = newVisitor(
+		t.dir,
+		policy,
+		compareContent,
+		t.crypter,
+		t.journal,
+		4,
+		t.blobStore,
+		log.New(ioutil.Discard, "", 0))
+}
+
+// Replace the journal with one containing the supplied records, as if left
+// by an earlier attempt.
+func (t *VisitorTest) setJournal(records ...string) {
+	var err error
+
+	err = t.journal.Close()
+	AssertEq(nil, err)
+
+	header := fmt.Sprintf(
+		"comeback restore journal v1 %s %s\n",
+		blob.Score{}.Hex(),
+		ConflictFail)
+
+	err = ioutil.WriteFile(
+		t.journalPath,
+		[]byte(header+strings.Join(records, "\n")+"\n"),
+		0600)
+	AssertEq(nil, err)
+
+	t.journal, err = openJournal(t.journalPath, blob.Score{}, ConflictFail)
+	AssertEq(nil, err)
+
+	t.setPolicy(ConflictFail, false)
 }
 
 func (t *VisitorTest) TearDown() {
 	var err error
 
+	err = t.journal.Close()
+	AssertEq(nil, err)
+
+	err = os.RemoveAll(t.journalDir)
+	AssertEq(nil, err)
+
 	err = os.RemoveAll(t.dir)
 	AssertEq(nil, err)
 }
@@ -96,8 +159,80 @@ func (t *VisitorTest) call(n *node) (err error) {
 func (t *VisitorTest) store(b []byte) (s blob.Score, err error) {
 	s, err = t.blobStore.Save(
 		t.ctx,
-		&blob.SaveRequest{Blob: b})
+		&blob.SaveRequest{Blob: b, Kind: blob.Kind_File})
+
+	return
+}
+
+// Store the supplied contents as a single chunk and return a node for a file
+// at the given path with those contents.
+func (t *VisitorTest) fileNode(relPath string, contents string) (n *node) {
+	score, err := t.store(marshalFileOrDie([]byte(contents)))
+	AssertEq(nil, err)
+
+	n = &node{
+		RelPath: relPath,
+		Info: fs.FileInfo{
+			Type:        fs.TypeFile,
+			Name:        path.Base(relPath),
+			Permissions: 0600,
+			Size:        uint64(len(contents)),
+			MTime:       time.Date(2012, time.August, 15, 12, 56, 00, 0, time.Local),
+			Scores:      []blob.Score{score},
+		},
+	}
+
+	return
+}
+
+// Like fileNode, but refer to the contents by a score computed in the given
+// format, as a backup made by an older version would. Nothing is stored.
+func (t *VisitorTest) fileNodeInFormat(
+	relPath string,
+	contents string,
+	format save.ChunkFormat) (n *node) {
+	score, err := save.ScoreChunkInFormat([]byte(contents), t.crypter, format)
+	AssertEq(nil, err)
+
+	n = t.fileNode(relPath, contents)
+	n.Info.Scores = []blob.Score{score}
+	return
+}
 
+// Write a file at the supplied path relative to t.dir with the given contents
+// and mtime, returning its inode number.
+func (t *VisitorTest) writeExisting(
+	relPath string,
+	contents string,
+	mtime time.Time) (inode uint64) {
+	p := path.Join(t.dir, relPath)
+
+	err := os.MkdirAll(path.Dir(p), 0700)
+	AssertEq(nil, err)
+
+	err = ioutil.WriteFile(p, []byte(contents), 0600)
+	AssertEq(nil, err)
+
+	err = os.Chtimes(p, mtime, mtime)
+	AssertEq(nil, err)
+
+	inode = t.inode(relPath)
+	return
+}
+
+func (t *VisitorTest) inode(relPath string) (inode uint64) {
+	fi, err := os.Lstat(path.Join(t.dir, relPath))
+	AssertEq(nil, err)
+
+	inode = uint64(fi.Sys().(*syscall.Stat_t).Ino)
+	return
+}
+
+func (t *VisitorTest) readFile(relPath string) (contents string) {
+	b, err := ioutil.ReadFile(path.Join(t.dir, relPath))
+	AssertEq(nil, err)
+
+	contents = string(b)
 	return
 }
 
@@ -116,7 +251,7 @@ func (t *VisitorTest) File_MissingBlob() {
 	chunk1 := marshalFileOrDie([]byte("burrito"))
 	score1 := blob.ComputeScore(chunk1)
 
-	chunk2 := marshalFileOrDie([]byte("enchilada"))
+	chunk2 := marshalFileOrDie([]byte("ench"))
 	score2, err := t.store(chunk2)
 	AssertEq(nil, err)
 
@@ -150,7 +285,7 @@ func (t *VisitorTest) File_CorruptBlob() {
 	score1, err := t.store(chunk1)
 	AssertEq(nil, err)
 
-	chunk2 := marshalFileOrDie([]byte("enchilada"))
+	chunk2 := marshalFileOrDie([]byte("ench"))
 	score2, err := t.store(chunk2)
 	AssertEq(nil, err)
 
@@ -205,10 +340,14 @@ func (t *VisitorTest) File_NonEmpty() {
 	score0, err := t.store(chunk0)
 	AssertEq(nil, err)
 
-	chunk1 := marshalFileOrDie([]byte("burrito"))
+	chunk1 := marshalFileOrDie([]byte("burr"))
 	score1, err := t.store(chunk1)
 	AssertEq(nil, err)
 
+	chunk2 := marshalFileOrDie([]byte("ito"))
+	score2, err := t.store(chunk2)
+	AssertEq(nil, err)
+
 	// Node
 	n := &node{
 		RelPath: "foo/bar/baz",
@@ -216,7 +355,7 @@ func (t *VisitorTest) File_NonEmpty() {
 			Type:        fs.TypeFile,
 			Name:        "baz",
 			Permissions: 0400,
-			Scores:      []blob.Score{score0, score1},
+			Scores:      []blob.Score{score0, score1, score2},
 		},
 	}
 
@@ -375,3 +514,504 @@ func (t *VisitorTest) ParentDirsAlreadyExist() {
 	AssertEq(nil, err)
 	ExpectEq("taco/burrito", target)
 }
+
+func (t *VisitorTest) Skip() {
+	var err error
+
+	n := &node{
+		RelPath: "foo/bar",
+		Info: fs.FileInfo{
+			Type:        fs.TypeDirectory,
+			Name:        "bar",
+			Permissions: 0741,
+		},
+		Skip: true,
+	}
+
+	// Call
+	err = t.call(n)
+	AssertEq(nil, err)
+
+	// Nothing should have been created.
+	entries, err := ioutil.ReadDir(t.dir)
+	AssertEq(nil, err)
+	ExpectThat(entries, ElementsAre())
+}
+
+func (t *VisitorTest) Conflict_Fail() {
+	n := t.fileNode("foo/bar", "taco")
+	t.writeExisting("foo/bar", "burrito", n.Info.MTime)
+
+	// Call
+	err := t.call(n)
+
+	ExpectThat(err, Error(HasSubstr("already exists")))
+	ExpectEq("burrito", t.readFile("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_Skip() {
+	t.setPolicy(ConflictSkip, false)
+
+	n := t.fileNode("foo/bar", "taco")
+	inode := t.writeExisting("foo/bar", "burrito", time.Now())
+
+	// Call
+	err := t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("burrito", t.readFile("foo/bar"))
+	ExpectEq(inode, t.inode("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_Overwrite() {
+	t.setPolicy(ConflictOverwrite, false)
+
+	n := t.fileNode("foo/bar", "taco")
+	t.writeExisting("foo/bar", "burrito", time.Now())
+
+	// Call
+	err := t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("taco", t.readFile("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_Overwrite_DifferentType() {
+	t.setPolicy(ConflictOverwrite, false)
+
+	n := t.fileNode("foo/bar", "taco")
+
+	err := os.MkdirAll(path.Join(t.dir, "foo/bar/baz"), 0700)
+	AssertEq(nil, err)
+
+	// Call
+	err = t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("taco", t.readFile("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_OverwriteIfDifferent_SameSizeAndMTime() {
+	t.setPolicy(ConflictOverwriteIfDifferent, false)
+
+	// The existing file has the same size and mtime, so it should be assumed to
+	// be identical and left alone.
+	n := t.fileNode("foo/bar", "taco")
+	inode := t.writeExisting("foo/bar", "pizz", n.Info.MTime)
+
+	// Call
+	err := t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("pizz", t.readFile("foo/bar"))
+	ExpectEq(inode, t.inode("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_OverwriteIfDifferent_RepeatedRestore() {
+	// A backed up mtime with sub-microsecond precision, as recorded on file
+	// systems that store nanoseconds.
+	n := t.fileNode("foo/bar", "taco")
+	n.Info.MTime = time.Date(2012, time.August, 15, 12, 56, 0, 123456789, time.Local)
+
+	// Restore the file once. Its mtime should be preserved exactly.
+	err := t.call(n)
+	AssertEq(nil, err)
+
+	fi, err := os.Lstat(path.Join(t.dir, "foo/bar"))
+	AssertEq(nil, err)
+	ExpectThat(fi.ModTime(), timeutil.TimeEq(n.Info.MTime))
+
+	// Restoring again in a fresh attempt should recognize the file as
+	// identical and leave it alone.
+	inode := t.inode("foo/bar")
+	t.setJournal()
+	t.setPolicy(ConflictOverwriteIfDifferent, false)
+
+	err = t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq(inode, t.inode("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_OverwriteIfDifferent_DifferentMTime() {
+	t.setPolicy(ConflictOverwriteIfDifferent, false)
+
+	n := t.fileNode("foo/bar", "taco")
+	t.writeExisting("foo/bar", "pizz", n.Info.MTime.Add(time.Second))
+
+	// Call
+	err := t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("taco", t.readFile("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_OverwriteIfDifferent_CompareContent() {
+	t.setPolicy(ConflictOverwriteIfDifferent, true)
+
+	n := t.fileNode("foo/bar", "taco")
+	t.writeExisting("foo/bar", "pizz", n.Info.MTime)
+
+	// The size and mtime match but the contents don't.
+	err := t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("taco", t.readFile("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_OverwriteIfDifferent_IdenticalContent() {
+	t.setPolicy(ConflictOverwriteIfDifferent, true)
+
+	// The contents match, so the mtime should be ignored.
+	n := t.fileNode("foo/bar", "taco")
+	inode := t.writeExisting("foo/bar", "taco", time.Now())
+
+	// Call
+	err := t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("taco", t.readFile("foo/bar"))
+	ExpectEq(inode, t.inode("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_OverwriteIfDifferent_IdenticalContent_SHA1() {
+	t.setPolicy(ConflictOverwriteIfDifferent, true)
+
+	// A file saved by a version that addressed blobs by SHA-1.
+	n := t.fileNodeInFormat(
+		"foo/bar",
+		"taco",
+		save.ChunkFormat{Algorithm: blob.Algorithm_SHA1})
+
+	inode := t.writeExisting("foo/bar", "taco", time.Now())
+
+	// Call
+	err := t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("taco", t.readFile("foo/bar"))
+	ExpectEq(inode, t.inode("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_OverwriteIfDifferent_IdenticalContent_Legacy() {
+	t.setPolicy(ConflictOverwriteIfDifferent, true)
+
+	// A file saved by a version that encrypted blobs without associated data.
+	n := t.fileNodeInFormat(
+		"foo/bar",
+		"taco",
+		save.ChunkFormat{Algorithm: blob.Algorithm_SHA1, Legacy: true})
+
+	inode := t.writeExisting("foo/bar", "taco", time.Now())
+
+	// Call
+	err := t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("taco", t.readFile("foo/bar"))
+	ExpectEq(inode, t.inode("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_OverwriteIfDifferent_DifferentContent_Legacy() {
+	t.setPolicy(ConflictOverwriteIfDifferent, true)
+
+	n := t.fileNodeInFormat(
+		"foo/bar",
+		"taco",
+		save.ChunkFormat{Algorithm: blob.Algorithm_SHA1, Legacy: true})
+
+	// The existing file is different, and so is replaced. The node refers to a
+	// blob that isn't stored, so replacing it fails.
+	t.writeExisting("foo/bar", "pizz", n.Info.MTime)
+
+	err := t.call(n)
+	ExpectThat(err, Error(HasSubstr("not found")))
+}
+
+func (t *VisitorTest) Conflict_Rename() {
+	t.setPolicy(ConflictRename, false)
+
+	n := t.fileNode("foo/bar", "taco")
+	t.writeExisting("foo/bar", "burrito", time.Now())
+	t.writeExisting("foo/bar.orig", "enchilada", time.Now())
+
+	// Call
+	err := t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("taco", t.readFile("foo/bar"))
+	ExpectEq("enchilada", t.readFile("foo/bar.orig"))
+	ExpectEq("burrito", t.readFile("foo/bar.orig.1"))
+}
+
+func (t *VisitorTest) Conflict_ParentIsFile_Fail() {
+	n := t.fileNode("foo/bar/baz", "taco")
+	t.writeExisting("foo/bar", "burrito", time.Now())
+
+	// Call
+	err := t.call(n)
+
+	ExpectThat(err, Error(HasSubstr("not a directory")))
+	ExpectEq("burrito", t.readFile("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_ParentIsFile_Skip() {
+	t.setPolicy(ConflictSkip, false)
+
+	n := t.fileNode("foo/bar/baz", "taco")
+	t.writeExisting("foo/bar", "burrito", time.Now())
+
+	// Call
+	err := t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("burrito", t.readFile("foo/bar"))
+}
+
+func (t *VisitorTest) Conflict_ParentIsFile_Rename() {
+	t.setPolicy(ConflictRename, false)
+
+	n := t.fileNode("foo/bar/baz", "taco")
+	t.writeExisting("foo/bar", "burrito", time.Now())
+
+	// Call
+	err := t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("taco", t.readFile("foo/bar/baz"))
+	ExpectEq("burrito", t.readFile("foo/bar.orig"))
+}
+
+func (t *VisitorTest) File_ManyChunks() {
+	var err error
+
+	// Blobs
+	var scores []blob.Score
+	var expected string
+	for i := 0; i < 20; i++ {
+		contents := fmt.Sprintf("%04d", i)
+		if i == 19 {
+			contents = "ab"
+		}
+
+		var s blob.Score
+		s, err = t.store(marshalFileOrDie([]byte(contents)))
+		AssertEq(nil, err)
+
+		scores = append(scores, s)
+		expected += contents
+	}
+
+	// Node
+	n := &node{
+		RelPath: "foo",
+		Info: fs.FileInfo{
+			Type:        fs.TypeFile,
+			Name:        "foo",
+			Permissions: 0400,
+			Scores:      scores,
+		},
+	}
+
+	// Call
+	err = t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq(expected, t.readFile("foo"))
+}
+
+func (t *VisitorTest) File_InconsistentChunkLength() {
+	var err error
+
+	// Blobs
+	score0, err := t.store(marshalFileOrDie([]byte("taco")))
+	AssertEq(nil, err)
+
+	score1, err := t.store(marshalFileOrDie([]byte("enchilada")))
+	AssertEq(nil, err)
+
+	score2, err := t.store(marshalFileOrDie([]byte("bu")))
+	AssertEq(nil, err)
+
+	// Node
+	n := &node{
+		RelPath: "foo",
+		Info: fs.FileInfo{
+			Type:        fs.TypeFile,
+			Name:        "foo",
+			Permissions: 0400,
+			Scores:      []blob.Score{score0, score1, score2},
+		},
+	}
+
+	// Call
+	err = t.call(n)
+
+	ExpectThat(err, Error(HasSubstr(score1.Hex())))
+	ExpectThat(err, Error(HasSubstr("length")))
+}
+
+func (t *VisitorTest) Journal_RecordsDone() {
+	var err error
+
+	n := t.fileNode("foo/bar", "taco")
+
+	// Call
+	err = t.call(n)
+	AssertEq(nil, err)
+
+	// The journal should say that the file was begun and then done.
+	contents, err := ioutil.ReadFile(t.journalPath)
+	AssertEq(nil, err)
+	ExpectThat(
+		string(contents),
+		HasSubstr("begin \"foo/bar\"\ndone \"foo/bar\"\n"))
+}
+
+func (t *VisitorTest) Journal_BegunIsReplaced() {
+	var err error
+
+	n := t.fileNode("foo/bar", "taco")
+	t.setJournal(`begin "foo/bar"`)
+
+	// An earlier attempt created the file but was interrupted before recording
+	// any progress. It should be replaced, even though the conflict policy
+	// says to fail.
+	t.writeExisting("foo/bar", "pi", time.Now())
+
+	err = t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("taco", t.readFile("foo/bar"))
+}
+
+func (t *VisitorTest) Journal_UnrecordedPathKeepsPolicy() {
+	var err error
+
+	// We are resuming, but the journal says nothing about this path, so the
+	// existing file isn't ours and the conflict policy applies.
+	n := t.fileNode("foo/bar", "taco")
+	t.setJournal(`begin "foo/baz"`, `done "foo/baz"`)
+	t.writeExisting("foo/bar", "pizz", time.Now())
+
+	err = t.call(n)
+	ExpectThat(err, Error(HasSubstr("already exists")))
+	ExpectEq("pizz", t.readFile("foo/bar"))
+}
+
+func (t *VisitorTest) Journal_DoneAndIntact() {
+	var err error
+
+	n := t.fileNode("foo/bar", "taco")
+	t.setJournal(`done "foo/bar"`)
+
+	// A file that still looks like the one we wrote should be left alone,
+	// even though the conflict policy says to fail.
+	inode := t.writeExisting("foo/bar", "pizz", n.Info.MTime)
+
+	err = t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("pizz", t.readFile("foo/bar"))
+	ExpectEq(inode, t.inode("foo/bar"))
+}
+
+func (t *VisitorTest) Journal_DoneButModified() {
+	var err error
+
+	n := t.fileNode("foo/bar", "taco")
+	t.setJournal(`done "foo/bar"`)
+
+	// A file that has since changed is subject to the conflict policy.
+	t.writeExisting("foo/bar", "burrito", n.Info.MTime)
+
+	err = t.call(n)
+	ExpectThat(err, Error(HasSubstr("already exists")))
+}
+
+func (t *VisitorTest) Journal_Partial() {
+	var err error
+
+	// Blobs. The first isn't stored, since it shouldn't be needed.
+	var scores []blob.Score
+	for i, contents := range []string{"taco", "burr", "ench", "ilad", "a"} {
+		var s blob.Score
+		if i == 0 {
+			s, err = save.ScoreChunk([]byte(contents), t.crypter)
+		} else {
+			s, err = t.store(marshalFileOrDie([]byte(contents)))
+		}
+
+		AssertEq(nil, err)
+		scores = append(scores, s)
+	}
+
+	// Node
+	n := &node{
+		RelPath: "foo",
+		Info: fs.FileInfo{
+			Type:        fs.TypeFile,
+			Name:        "foo",
+			Permissions: 0400,
+			Scores:      scores,
+		},
+	}
+
+	// An earlier attempt wrote three chunks, but the third was damaged
+	// afterward.
+	t.setJournal(`partial 4 3 "foo"`)
+	t.writeExisting("foo", "tacoburrXXXX", time.Now())
+
+	// Call
+	err = t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("tacoburrenchilada", t.readFile("foo"))
+}
+
+func (t *VisitorTest) Journal_Partial_SHA1() {
+	var err error
+
+	// The first three chunks were saved by versions that addressed blobs by
+	// SHA-1, one of them before blobs had associated data. They aren't stored,
+	// since they shouldn't be needed.
+	formats := []save.ChunkFormat{
+		{Algorithm: blob.Algorithm_SHA1, Legacy: true},
+		{Algorithm: blob.Algorithm_SHA1},
+		{Algorithm: blob.Algorithm_SHA1, Legacy: true},
+	}
+
+	var scores []blob.Score
+	for i, contents := range []string{"taco", "burr", "ench", "ilad", "a"} {
+		var s blob.Score
+		if i < len(formats) {
+			s, err = save.ScoreChunkInFormat([]byte(contents), t.crypter, formats[i])
+		} else {
+			s, err = t.store(marshalFileOrDie([]byte(contents)))
+		}
+
+		AssertEq(nil, err)
+		scores = append(scores, s)
+	}
+
+	// Node
+	n := &node{
+		RelPath: "foo",
+		Info: fs.FileInfo{
+			Type:        fs.TypeFile,
+			Name:        "foo",
+			Permissions: 0400,
+			Scores:      scores,
+		},
+	}
+
+	// An earlier attempt wrote three chunks intact.
+	t.setJournal(`partial 4 3 "foo"`)
+	t.writeExisting("foo", "tacoburrench", time.Now())
+
+	// Call
+	err = t.call(n)
+	AssertEq(nil, err)
+
+	ExpectEq("tacoburrenchilada", t.readFile("foo"))
+}
@@ -0,0 +1,172 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/registry"
+)
+
+// A Policy says which backups of a job to keep. A backup is kept if any rule
+// selects it, and all others are forgotten.
+type Policy struct {
+	// Keep the most recent Last backups.
+	Last int
+
+	// Keep the most recent backup in each of the most recent N hours, days,
+	// etc. that have backups. Weeks are ISO 8601 weeks.
+	Hourly  int
+	Daily   int
+	Weekly  int
+	Monthly int
+	Yearly  int
+
+	// Keep all backups started within this long before the most recent one.
+	Within time.Duration
+
+	// Keep all backups with any of these tags.
+	Tags []string
+}
+
+// Does the policy have no rules, and so keep nothing?
+func (p *Policy) Empty() bool {
+	return p.Last == 0 &&
+		p.Hourly == 0 &&
+		p.Daily == 0 &&
+		p.Weekly == 0 &&
+		p.Monthly == 0 &&
+		p.Yearly == 0 &&
+		p.Within == 0 &&
+		len(p.Tags) == 0
+}
+
+// A Decision records whether to keep a backup, and why.
+type Decision struct {
+	Job  registry.CompletedJob
+	Keep bool
+
+	// The rules that selected the backup, e.g. "last" or "tag pinned". Empty
+	// if Keep is false.
+	Reasons []string
+}
+
+// The time-based bucket rules, in the order in which reasons are listed.
+var bucketRules = []struct {
+	name  string
+	count func(p *Policy) int
+	key   func(t time.Time) string
+}{
+	{
+		"hourly",
+		func(p *Policy) int { return p.Hourly },
+		func(t time.Time) string { return t.Format("2006-01-02 15") },
+	},
+	{
+		"daily",
+		func(p *Policy) int { return p.Daily },
+		func(t time.Time) string { return t.Format("2006-01-02") },
+	},
+	{
+		"weekly",
+		func(p *Policy) int { return p.Weekly },
+		func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		},
+	},
+	{
+		"monthly",
+		func(p *Policy) int { return p.Monthly },
+		func(t time.Time) string { return t.Format("2006-01") },
+	},
+	{
+		"yearly",
+		func(p *Policy) int { return p.Yearly },
+		func(t time.Time) string { return t.Format("2006") },
+	},
+}
+
+// Apply the policy to the supplied backups, which should all be of the same
+// job. Hours, days, etc. are taken in the supplied location. Return a decision
+// for each backup, most recent first.
+func Apply(
+	p *Policy,
+	jobs []registry.CompletedJob,
+	loc *time.Location) (decisions []Decision) {
+	for _, j := range jobs {
+		decisions = append(decisions, Decision{Job: j})
+	}
+
+	sort.SliceStable(decisions, func(i, j int) bool {
+		return decisions[i].Job.StartTime.After(decisions[j].Job.StartTime)
+	})
+
+	keep := func(d *Decision, reason string) {
+		d.Keep = true
+		d.Reasons = append(d.Reasons, reason)
+	}
+
+	for i := range decisions {
+		d := &decisions[i]
+
+		if i < p.Last {
+			keep(d, "last")
+		}
+
+		if p.Within != 0 {
+			newest := decisions[0].Job.StartTime
+			if !d.Job.StartTime.Before(newest.Add(-p.Within)) {
+				keep(d, "within")
+			}
+		}
+
+		for _, want := range p.Tags {
+			for _, t := range d.Job.Tags {
+				if t == want {
+					keep(d, "tag "+t)
+					break
+				}
+			}
+		}
+	}
+
+	// Walking from newest to oldest, the first backup seen in each bucket is
+	// the most recent one in it.
+	for _, rule := range bucketRules {
+		remaining := rule.count(p)
+		var lastKey string
+		for i := range decisions {
+			if remaining == 0 {
+				break
+			}
+
+			d := &decisions[i]
+			key := rule.key(d.Job.StartTime.In(loc))
+			if key == lastKey {
+				continue
+			}
+
+			lastKey = key
+			remaining--
+			keep(d, rule.name)
+		}
+	}
+
+	return
+}
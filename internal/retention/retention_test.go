@@ -0,0 +1,201 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retention
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/registry"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+)
+
+func TestRetention(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type RetentionTest struct {
+	jobs []registry.CompletedJob
+}
+
+func init() { RegisterTestSuite(&RetentionTest{}) }
+
+// Add a backup at the given time, in the form "2006-01-02 15:04".
+func (t *RetentionTest) add(s string, tags ...string) {
+	st, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+	AssertEq(nil, err)
+
+	t.jobs = append(t.jobs, registry.CompletedJob{
+		Name:      "taco",
+		StartTime: st,
+		Tags:      tags,
+	})
+}
+
+// Apply the policy, returning a line for each backup of the form
+// "2006-01-02 15:04 reason,reason", with "-" for backups that are forgotten.
+func (t *RetentionTest) apply(p Policy) (lines []string) {
+	for _, d := range Apply(&p, t.jobs, time.UTC) {
+		reasons := "-"
+		if d.Keep {
+			reasons = strings.Join(d.Reasons, ",")
+		}
+
+		lines = append(
+			lines,
+			fmt.Sprintf("%s %s", d.Job.StartTime.Format("2006-01-02 15:04"), reasons))
+	}
+
+	return
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *RetentionTest) Empty() {
+	var p Policy
+	ExpectTrue(p.Empty())
+
+	p.Tags = []string{"pinned"}
+	ExpectFalse(p.Empty())
+}
+
+func (t *RetentionTest) NoBackups() {
+	ExpectThat(t.apply(Policy{Last: 3, Daily: 2}), ElementsAre())
+}
+
+func (t *RetentionTest) Last() {
+	// Deliberately out of order.
+	t.add("2026-01-02 00:00")
+	t.add("2026-01-03 00:00")
+	t.add("2026-01-01 00:00")
+
+	ExpectThat(
+		t.apply(Policy{Last: 2}),
+		ElementsAre(
+			"2026-01-03 00:00 last",
+			"2026-01-02 00:00 last",
+			"2026-01-01 00:00 -",
+		))
+}
+
+func (t *RetentionTest) Daily() {
+	t.add("2026-01-01 09:00")
+	t.add("2026-01-01 18:00")
+	t.add("2026-01-02 09:00")
+	t.add("2026-01-04 09:00")
+	t.add("2026-01-04 18:00")
+
+	ExpectThat(
+		t.apply(Policy{Daily: 3}),
+		ElementsAre(
+			"2026-01-04 18:00 daily",
+			"2026-01-04 09:00 -",
+			"2026-01-02 09:00 daily",
+			"2026-01-01 18:00 daily",
+			"2026-01-01 09:00 -",
+		))
+}
+
+func (t *RetentionTest) Hourly() {
+	t.add("2026-01-01 09:00")
+	t.add("2026-01-01 09:30")
+	t.add("2026-01-01 10:15")
+
+	ExpectThat(
+		t.apply(Policy{Hourly: 5}),
+		ElementsAre(
+			"2026-01-01 10:15 hourly",
+			"2026-01-01 09:30 hourly",
+			"2026-01-01 09:00 -",
+		))
+}
+
+func (t *RetentionTest) WeeklyMonthlyYearly() {
+	t.add("2024-12-30 00:00") // ISO week 2025-W01
+	t.add("2025-01-05 00:00") // ISO week 2025-W01
+	t.add("2025-06-01 00:00")
+	t.add("2025-06-20 00:00")
+	t.add("2026-01-01 00:00")
+
+	ExpectThat(
+		t.apply(Policy{Weekly: 4}),
+		ElementsAre(
+			"2026-01-01 00:00 weekly",
+			"2025-06-20 00:00 weekly",
+			"2025-06-01 00:00 weekly",
+			"2025-01-05 00:00 weekly",
+			"2024-12-30 00:00 -",
+		))
+
+	ExpectThat(
+		t.apply(Policy{Monthly: 2, Yearly: 3}),
+		ElementsAre(
+			"2026-01-01 00:00 monthly,yearly",
+			"2025-06-20 00:00 monthly,yearly",
+			"2025-06-01 00:00 -",
+			"2025-01-05 00:00 -",
+			"2024-12-30 00:00 yearly",
+		))
+}
+
+func (t *RetentionTest) Within() {
+	t.add("2026-01-01 00:00")
+	t.add("2026-01-05 00:00")
+	t.add("2026-01-06 00:00")
+	t.add("2026-01-08 00:00")
+
+	// Relative to the most recent backup, not the current time.
+	ExpectThat(
+		t.apply(Policy{Within: 3 * 24 * time.Hour}),
+		ElementsAre(
+			"2026-01-08 00:00 within",
+			"2026-01-06 00:00 within",
+			"2026-01-05 00:00 within",
+			"2026-01-01 00:00 -",
+		))
+}
+
+func (t *RetentionTest) Tags() {
+	t.add("2026-01-01 00:00", "pinned")
+	t.add("2026-01-02 00:00", "other")
+	t.add("2026-01-03 00:00")
+
+	ExpectThat(
+		t.apply(Policy{Last: 1, Tags: []string{"pinned"}}),
+		ElementsAre(
+			"2026-01-03 00:00 last",
+			"2026-01-02 00:00 -",
+			"2026-01-01 00:00 tag pinned",
+		))
+}
+
+func (t *RetentionTest) Location() {
+	// In UTC these are on different days, but in UTC-5 they're on the same one.
+	t.add("2026-01-02 01:00")
+	t.add("2026-01-01 20:00")
+
+	decisions := Apply(&Policy{Daily: 5}, t.jobs, time.FixedZone("", -5*3600))
+	AssertEq(2, len(decisions))
+	ExpectTrue(decisions[0].Keep)
+	ExpectFalse(decisions[1].Keep)
+}
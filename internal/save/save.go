@@ -37,7 +37,8 @@ import (
 
 // Save a backup of the given directory, applying the supplied exclusions and
 // using the supplied score map to avoid reading file content when possible.
-// Return a score for the root of the backup.
+// Return a score for the root of the backup, along with counts of the work
+// done.
 //
 // The supplied bucket will be used to store objects with the given name
 // prefix. existingScores must contain only scores that are known to exist in
@@ -53,7 +54,7 @@ func Save(
 	existingScores util.StringSet,
 	scoreMap state.ScoreMap,
 	logger *log.Logger,
-	clock timeutil.Clock) (score blob.Score, err error) {
+	clock timeutil.Clock) (score blob.Score, stats Stats, err error) {
 	eg, ctx := errgroup.WithContext(ctx)
 
 	// Set up a semaphore that limits memory usage for read buffers. It's
@@ -76,7 +77,7 @@ func Save(
 		// in what otherwise would be LIFO processing of file system nodes.
 		const resolverParallelism = 1
 
-		// The visitor reads contents, computes SHA-1s, encrypts, and talks to GCS.
+		// The visitor reads contents, computes hashes, encrypts, and talks to GCS.
 		// Hopefully this is enough parallelism to keep our CPUs or NIC saturated,
 		// depending on which is the current bottleneck.
 		const visitorParallelism = 128
@@ -92,10 +93,12 @@ func Save(
 				existingScores,
 				readFromDiskSem,
 				encryptAndComputeScoresSem,
+				&stats,
 			),
 			readFromDiskSem,
 			clock,
 			logger,
+			&stats.Warnings,
 			processedNodes)
 
 		err = dag.Visit(
@@ -114,9 +117,9 @@ func Save(
 		return
 	})
 
-	// Find the root score.
+	// Find the root score, counting nodes along the way.
 	eg.Go(func() (err error) {
-		score, err = findRootScore(processedNodes)
+		score, err = findRootScore(processedNodes, &stats)
 		if err != nil {
 			err = fmt.Errorf("findRootScore: %v", err)
 			return
@@ -136,16 +139,22 @@ func Save(
 // bucket, in hex form. It will be updated as the blob store is used.
 //
 // It is assumed that readFromDiskSem is held upon calling Save, and
-// encryptAndComputeScoresSem is not.
+// encryptAndComputeScoresSem is not. Blobs written to GCS are counted in the
+// supplied stats.
 func newBlobStore(
 	bucket gcs.Bucket,
 	objectNamePrefix string,
 	crypter crypto.Crypter,
 	existingScores util.StringSet,
 	readFromDiskSem semaphore,
-	encryptAndComputeScoresSem semaphore) (bs blob.Store) {
+	encryptAndComputeScoresSem semaphore,
+	stats *Stats) (bs blob.Store) {
 	// Store blobs in GCS.
 	bs = blob.NewGCSStore(bucket, objectNamePrefix)
+	bs = &countingBlobStore{
+		Store: bs,
+		stats: stats,
+	}
 
 	// At this point in a Store call it's clear that we're going to have to go to
 	// the network. Release the semaphore to allow more encryption to happen so
@@ -165,6 +174,10 @@ func newBlobStore(
 	// Encrypt blob data before sending it off to GCS.
 	bs = blob.NewEncryptingStore(crypter, bs)
 
+	// Reuse blobs that older versions of comeback saved in the legacy format
+	// instead of uploading their content again.
+	bs = blob.NewLegacyReusingStore(crypter, existingScores, bs)
+
 	// Release the semaphore we held while loading data from disk. We do this
 	// under encryptAndComputeScoresSem in order to avoid a window of unbounded
 	// memory usage where we've released that semaphore but are blocking on
@@ -184,9 +197,13 @@ func newBlobStore(
 	return
 }
 
-func findRootScore(nodes <-chan *fsNode) (score blob.Score, err error) {
+func findRootScore(
+	nodes <-chan *fsNode,
+	stats *Stats) (score blob.Score, err error) {
 	found := false
 	for n := range nodes {
+		stats.addNode(n)
+
 		// Skip non-root nodes.
 		if n.RelPath != "" {
 			continue
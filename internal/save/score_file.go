@@ -0,0 +1,225 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package save
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/crypto"
+	"github.com/jacobsa/comeback/internal/repr"
+)
+
+// A way in which some version of comeback has stored file chunks as blobs.
+// Backups refer to chunks by score, so a file's contents can only be matched
+// against a backup by computing its scores in the same format the backup was
+// made with.
+type ChunkFormat struct {
+	// The algorithm with which the blob's score was computed.
+	Algorithm blob.Algorithm
+
+	// Was the blob encrypted in the legacy format, without a header or
+	// associated data? See blob.EncryptLegacy.
+	Legacy bool
+}
+
+// The format in which Save currently stores file chunks.
+var DefaultChunkFormat = ChunkFormat{Algorithm: blob.DefaultAlgorithm}
+
+// All formats that backups may refer to, newest first.
+var ChunkFormats = []ChunkFormat{
+	DefaultChunkFormat,
+	{Algorithm: blob.Algorithm_SHA1},
+	{Algorithm: blob.Algorithm_SHA1, Legacy: true},
+}
+
+// Compute the scores that Save would record for a file with the contents
+// supplied by the reader, chunking and encrypting with the given crypter
+// exactly as Save does, but without storing anything. The crypter must be
+// the one the backups were made with for the scores to be comparable.
+//
+// Guarantees non-nil result when successful, even for empty contents.
+func ScoreFile(
+	r io.Reader,
+	crypter crypto.Crypter) (scores []blob.Score, err error) {
+	scores, err = ScoreFileInFormat(r, crypter, DefaultChunkFormat)
+	return
+}
+
+// Like ScoreFile, but compute the scores that a version of comeback using the
+// given format would have recorded.
+func ScoreFileInFormat(
+	r io.Reader,
+	crypter crypto.Crypter,
+	format ChunkFormat) (scores []blob.Score, err error) {
+	scores = make([]blob.Score, 0, 1)
+	err = readChunks(r, func(chunk []byte) (err error) {
+		score, err := ScoreChunkInFormat(chunk, crypter, format)
+		if err != nil {
+			err = fmt.Errorf("ScoreChunkInFormat: %v", err)
+			return
+		}
+
+		scores = append(scores, score)
+		return
+	})
+
+	return
+}
+
+// Report whether the contents supplied by the reader are exactly those of a
+// file with the expected scores, as recorded by any version of comeback. Each
+// chunk is compared in the format of the score it is compared against.
+func FileMatches(
+	r io.Reader,
+	crypter crypto.Crypter,
+	expected []blob.Score) (match bool, err error) {
+	var n int
+	errMismatch := errors.New("mismatch")
+	err = readChunks(r, func(chunk []byte) (err error) {
+		if n == len(expected) {
+			err = errMismatch
+			return
+		}
+
+		ok, err := ChunkMatches(chunk, crypter, expected[n])
+		if err != nil {
+			err = fmt.Errorf("ChunkMatches: %v", err)
+			return
+		}
+
+		if !ok {
+			err = errMismatch
+			return
+		}
+
+		n++
+		return
+	})
+
+	if err == errMismatch {
+		err = nil
+		return
+	}
+
+	if err != nil {
+		return
+	}
+
+	match = n == len(expected)
+	return
+}
+
+// Call f for each chunk of the contents supplied by the reader, as Save
+// divides them.
+func readChunks(
+	r io.Reader,
+	f func(chunk []byte) error) (err error) {
+	buf := make([]byte, fileChunkSize)
+
+	for {
+		// Read a chunk of data.
+		var n int
+		n, err = io.ReadFull(r, buf)
+
+		switch {
+		case err == io.EOF:
+			// EOF means we're done.
+			err = nil
+			return
+
+		case err == io.ErrUnexpectedEOF:
+			// A short read is fine.
+			err = nil
+
+		case err != nil:
+			err = fmt.Errorf("Read: %v", err)
+			return
+		}
+
+		err = f(buf[:n])
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Compute the score that Save would record for a single chunk of file
+// contents, encapsulating and encrypting it as the blob store would.
+func ScoreChunk(
+	contents []byte,
+	crypter crypto.Crypter) (score blob.Score, err error) {
+	score, err = ScoreChunkInFormat(contents, crypter, DefaultChunkFormat)
+	return
+}
+
+// Like ScoreChunk, but compute the score that a version of comeback using the
+// given format would have recorded.
+func ScoreChunkInFormat(
+	contents []byte,
+	crypter crypto.Crypter,
+	format ChunkFormat) (score blob.Score, err error) {
+	chunk, err := repr.MarshalFile(contents)
+	if err != nil {
+		err = fmt.Errorf("MarshalFile: %v", err)
+		return
+	}
+
+	var ciphertext []byte
+	if format.Legacy {
+		ciphertext, err = blob.EncryptLegacy(crypter, chunk)
+	} else {
+		ciphertext, err = blob.Encrypt(crypter, blob.Kind_File, chunk)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("Encrypt: %v", err)
+		return
+	}
+
+	score = blob.ComputeScoreWithAlgorithm(format.Algorithm, ciphertext)
+	return
+}
+
+// Report whether a chunk of file contents is the one with the expected score,
+// as recorded by any version of comeback. A SHA-1 score may refer to a blob in
+// either the current or the legacy encryption format, so both are tried.
+func ChunkMatches(
+	contents []byte,
+	crypter crypto.Crypter,
+	expected blob.Score) (match bool, err error) {
+	for _, format := range ChunkFormats {
+		if format.Algorithm != expected.Algorithm() {
+			continue
+		}
+
+		var score blob.Score
+		score, err = ScoreChunkInFormat(contents, crypter, format)
+		if err != nil {
+			err = fmt.Errorf("ScoreChunkInFormat: %v", err)
+			return
+		}
+
+		if score == expected {
+			match = true
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,294 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package save
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/crypto"
+	"github.com/jacobsa/comeback/internal/repr"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+)
+
+func TestScoreFile(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type ScoreFileTest struct {
+	crypter crypto.Crypter
+}
+
+func init() { RegisterTestSuite(&ScoreFileTest{}) }
+
+func (t *ScoreFileTest) SetUp(ti *TestInfo) {
+	var err error
+
+	t.crypter, err = crypto.NewCrypter(make([]byte, 32))
+	AssertEq(nil, err)
+}
+
+// Compute the score of the blob that the blob store would be asked to save
+// for the supplied chunk of file contents.
+func (t *ScoreFileTest) chunkScore(contents []byte) (s blob.Score) {
+	s = t.chunkScoreWithAlgorithm(blob.DefaultAlgorithm, contents)
+	return
+}
+
+// Like chunkScore, but as computed by a version of comeback that addressed
+// blobs by the given algorithm.
+func (t *ScoreFileTest) chunkScoreWithAlgorithm(
+	alg blob.Algorithm,
+	contents []byte) (s blob.Score) {
+	chunk, err := repr.MarshalFile(append([]byte{}, contents...))
+	AssertEq(nil, err)
+
+	ciphertext, err := blob.Encrypt(t.crypter, blob.Kind_File, chunk)
+	AssertEq(nil, err)
+
+	s = blob.ComputeScoreWithAlgorithm(alg, ciphertext)
+	return
+}
+
+// Like chunkScore, but as computed by a version of comeback that encrypted
+// blobs in the legacy format and addressed them by SHA-1.
+func (t *ScoreFileTest) legacyChunkScore(contents []byte) (s blob.Score) {
+	chunk, err := repr.MarshalFile(append([]byte{}, contents...))
+	AssertEq(nil, err)
+
+	ciphertext, err := t.crypter.Encrypt(nil, chunk, nil)
+	AssertEq(nil, err)
+
+	s = blob.ComputeScoreWithAlgorithm(blob.Algorithm_SHA1, ciphertext)
+	return
+}
+
+func (t *ScoreFileTest) fileMatches(
+	contents []byte,
+	expected ...blob.Score) (match bool) {
+	match, err := FileMatches(bytes.NewReader(contents), t.crypter, expected)
+	AssertEq(nil, err)
+	return
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *ScoreFileTest) Empty() {
+	scores, err := ScoreFile(bytes.NewReader(nil), t.crypter)
+
+	AssertEq(nil, err)
+	ExpectNe(nil, scores)
+	ExpectThat(scores, ElementsAre())
+}
+
+func (t *ScoreFileTest) SingleChunk() {
+	contents := []byte("taco")
+
+	scores, err := ScoreFile(bytes.NewReader(contents), t.crypter)
+
+	AssertEq(nil, err)
+	ExpectThat(scores, ElementsAre(t.chunkScore(contents)))
+}
+
+func (t *ScoreFileTest) MultipleChunks() {
+	contents := bytes.Repeat([]byte("a"), fileChunkSize+3)
+
+	scores, err := ScoreFile(bytes.NewReader(contents), t.crypter)
+
+	AssertEq(nil, err)
+	ExpectThat(
+		scores,
+		ElementsAre(
+			t.chunkScore(contents[:fileChunkSize]),
+			t.chunkScore(contents[fileChunkSize:]),
+		))
+}
+
+func (t *ScoreFileTest) ReadError() {
+	r := &errReader{errors.New("taco")}
+
+	_, err := ScoreFile(r, t.crypter)
+
+	ExpectThat(err, Error(HasSubstr("Read")))
+	ExpectThat(err, Error(HasSubstr("taco")))
+}
+
+func (t *ScoreFileTest) SHA1Format() {
+	contents := bytes.Repeat([]byte("a"), fileChunkSize+3)
+
+	scores, err := ScoreFileInFormat(
+		bytes.NewReader(contents),
+		t.crypter,
+		ChunkFormat{Algorithm: blob.Algorithm_SHA1})
+
+	AssertEq(nil, err)
+	ExpectThat(
+		scores,
+		ElementsAre(
+			t.chunkScoreWithAlgorithm(blob.Algorithm_SHA1, contents[:fileChunkSize]),
+			t.chunkScoreWithAlgorithm(blob.Algorithm_SHA1, contents[fileChunkSize:]),
+		))
+}
+
+func (t *ScoreFileTest) ChunkMatches() {
+	contents := []byte("taco")
+	for _, alg := range []blob.Algorithm{
+		blob.Algorithm_SHA1,
+		blob.Algorithm_SHA256,
+	} {
+		match, err := ChunkMatches(
+			contents,
+			t.crypter,
+			t.chunkScoreWithAlgorithm(alg, contents))
+
+		AssertEq(nil, err)
+		ExpectTrue(match, "%v", alg)
+
+		match, err = ChunkMatches(
+			[]byte("burrito"),
+			t.crypter,
+			t.chunkScoreWithAlgorithm(alg, contents))
+
+		AssertEq(nil, err)
+		ExpectFalse(match, "%v", alg)
+	}
+}
+
+func (t *ScoreFileTest) FileMatches_Empty() {
+	ExpectTrue(t.fileMatches(nil))
+	ExpectFalse(t.fileMatches(nil, t.chunkScore(nil)))
+	ExpectFalse(t.fileMatches([]byte("taco")))
+}
+
+func (t *ScoreFileTest) FileMatches_SHA1() {
+	contents := bytes.Repeat([]byte("a"), fileChunkSize+3)
+	first := contents[:fileChunkSize]
+	second := contents[fileChunkSize:]
+
+	// A file saved by an older version.
+	ExpectTrue(
+		t.fileMatches(
+			contents,
+			t.chunkScoreWithAlgorithm(blob.Algorithm_SHA1, first),
+			t.chunkScoreWithAlgorithm(blob.Algorithm_SHA1, second)))
+
+	// Mismatched or missing chunks.
+	ExpectFalse(
+		t.fileMatches(
+			contents,
+			t.chunkScoreWithAlgorithm(blob.Algorithm_SHA1, first),
+			t.chunkScoreWithAlgorithm(blob.Algorithm_SHA1, first)))
+
+	ExpectFalse(
+		t.fileMatches(
+			contents,
+			t.chunkScoreWithAlgorithm(blob.Algorithm_SHA1, first)))
+
+	ExpectFalse(
+		t.fileMatches(
+			first,
+			t.chunkScoreWithAlgorithm(blob.Algorithm_SHA1, first),
+			t.chunkScoreWithAlgorithm(blob.Algorithm_SHA1, second)))
+}
+
+func (t *ScoreFileTest) FileMatches_MixedAlgorithms() {
+	contents := bytes.Repeat([]byte("a"), fileChunkSize+3)
+
+	ExpectTrue(
+		t.fileMatches(
+			contents,
+			t.chunkScore(contents[:fileChunkSize]),
+			t.chunkScoreWithAlgorithm(blob.Algorithm_SHA1, contents[fileChunkSize:])))
+}
+
+func (t *ScoreFileTest) LegacyFormat() {
+	contents := []byte("taco")
+
+	scores, err := ScoreFileInFormat(
+		bytes.NewReader(contents),
+		t.crypter,
+		ChunkFormat{Algorithm: blob.Algorithm_SHA1, Legacy: true})
+
+	AssertEq(nil, err)
+	ExpectThat(scores, ElementsAre(t.legacyChunkScore(contents)))
+
+	// The legacy and current formats give different scores.
+	ExpectNe(
+		t.legacyChunkScore(contents),
+		t.chunkScoreWithAlgorithm(blob.Algorithm_SHA1, contents))
+}
+
+func (t *ScoreFileTest) ChunkMatches_Legacy() {
+	match, err := ChunkMatches(
+		[]byte("taco"),
+		t.crypter,
+		t.legacyChunkScore([]byte("taco")))
+
+	AssertEq(nil, err)
+	ExpectTrue(match)
+
+	match, err = ChunkMatches(
+		[]byte("burrito"),
+		t.crypter,
+		t.legacyChunkScore([]byte("taco")))
+
+	AssertEq(nil, err)
+	ExpectFalse(match)
+}
+
+func (t *ScoreFileTest) FileMatches_MixedFormats() {
+	contents := bytes.Join(
+		[][]byte{
+			bytes.Repeat([]byte("a"), fileChunkSize),
+			bytes.Repeat([]byte("b"), fileChunkSize),
+			[]byte("ccc"),
+		},
+		nil)
+
+	first := contents[:fileChunkSize]
+	second := contents[fileChunkSize : 2*fileChunkSize]
+	third := contents[2*fileChunkSize:]
+
+	ExpectTrue(
+		t.fileMatches(
+			contents,
+			t.legacyChunkScore(first),
+			t.chunkScoreWithAlgorithm(blob.Algorithm_SHA1, second),
+			t.chunkScore(third)))
+
+	ExpectFalse(
+		t.fileMatches(
+			contents,
+			t.legacyChunkScore(first),
+			t.legacyChunkScore(first),
+			t.chunkScore(third)))
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (n int, err error) {
+	err = r.err
+	return
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package save
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/fs"
+)
+
+// Stats describes the work done by Save.
+type Stats struct {
+	// The number of regular files and directories found, and the total size of
+	// the files.
+	FilesScanned uint64
+	DirsScanned  uint64
+	BytesScanned uint64
+
+	// The number of blobs written to the bucket, and their total size as
+	// stored. Blobs that were already present aren't counted.
+	BlobsUploaded uint64
+	BytesUploaded uint64
+
+	// The number of problems encountered that didn't cause the backup to fail,
+	// each of which is logged.
+	Warnings uint64
+}
+
+// Account for a node that was saved.
+func (s *Stats) addNode(n *fsNode) {
+	switch n.Info.Type {
+	case fs.TypeFile:
+		s.FilesScanned++
+		s.BytesScanned += n.Info.Size
+
+	case fs.TypeDirectory:
+		s.DirsScanned++
+	}
+}
+
+// A blob store that counts the blobs saved through it. It should wrap the
+// store that talks to GCS, so that blobs that already exist are not counted.
+type countingBlobStore struct {
+	blob.Store
+	stats *Stats
+}
+
+func (bs *countingBlobStore) Save(
+	ctx context.Context,
+	req *blob.SaveRequest) (s blob.Score, err error) {
+	s, err = bs.Store.Save(ctx, req)
+	if err != nil {
+		return
+	}
+
+	atomic.AddUint64(&bs.stats.BlobsUploaded, 1)
+	atomic.AddUint64(&bs.stats.BytesUploaded, uint64(len(req.Blob)))
+
+	return
+}
@@ -22,6 +22,7 @@ import (
 	"log"
 	"os"
 	"path"
+	"sync/atomic"
 
 	"github.com/jacobsa/comeback/internal/blob"
 	"github.com/jacobsa/comeback/internal/dag"
@@ -44,6 +45,9 @@ const fileChunkSize = 1 << 24
 //  *  For directories, write a listing to blob store to obtain a list of
 //     scores.
 //
+//  *  Log a warning and increment the supplied counter for each file whose
+//     size changes while it is being read.
+//
 //  *  Write all nodes to the supplied channel.
 //
 func newVisitor(
@@ -54,6 +58,7 @@ func newVisitor(
 	readFromDiskSem semaphore,
 	clock timeutil.Clock,
 	logger *log.Logger,
+	warnings *uint64,
 	visitedNodes chan<- *fsNode) (v dag.Visitor) {
 	v = &visitor{
 		chunkSize:       chunkSize,
@@ -63,6 +68,7 @@ func newVisitor(
 		readFromDiskSem: readFromDiskSem,
 		clock:           clock,
 		logger:          logger,
+		warnings:        warnings,
 		visitedNodes:    visitedNodes,
 	}
 
@@ -77,6 +83,7 @@ type visitor struct {
 	readFromDiskSem semaphore
 	clock           timeutil.Clock
 	logger          *log.Logger
+	warnings        *uint64
 	visitedNodes    chan<- *fsNode
 }
 
@@ -173,9 +180,11 @@ func (v *visitor) saveFile(
 	defer f.Close()
 
 	// Process a chunk at a time.
+	var size uint64
 	for {
 		var s blob.Score
-		s, err = v.saveFileChunk(ctx, f)
+		var chunkLen int
+		s, chunkLen, err = v.saveFileChunk(ctx, f)
 
 		if err == io.EOF {
 			err = nil
@@ -187,6 +196,22 @@ func (v *visitor) saveFile(
 		}
 
 		scores = append(scores, s)
+		size += uint64(chunkLen)
+	}
+
+	// If the file changed size since we statted it, the backup holds whatever
+	// we happened to read. Record the size that goes with that, and don't
+	// remember the scores for next time.
+	if size != n.Info.Size {
+		v.logger.Printf(
+			"WARNING: %q changed while being read: expected %d bytes, read %d",
+			n.RelPath,
+			n.Info.Size,
+			size)
+
+		atomic.AddUint64(v.warnings, 1)
+		n.Info.Size = size
+		return
 	}
 
 	// Update the score map if the file is eligible.
@@ -197,10 +222,11 @@ func (v *visitor) saveFile(
 	return
 }
 
-// Returns io.EOF when the reader is exhausted.
+// Returns io.EOF when the reader is exhausted. Otherwise returns the number
+// of bytes of the file that the chunk contains.
 func (v *visitor) saveFileChunk(
 	ctx context.Context,
-	f *os.File) (s blob.Score, err error) {
+	f *os.File) (s blob.Score, size int, err error) {
 	// Wait for permission to allocate memory.
 	err = v.readFromDiskSem.Acquire(ctx)
 	if err != nil {
@@ -225,9 +251,8 @@ func (v *visitor) saveFileChunk(
 	}()
 
 	// Read a chunk of data from the file.
-	var n int
 	buf := make([]byte, v.chunkSize)
-	n, err = io.ReadFull(f, buf)
+	size, err = io.ReadFull(f, buf)
 
 	switch {
 	case err == io.EOF:
@@ -245,7 +270,7 @@ func (v *visitor) saveFileChunk(
 
 	// Encapsulate the data so it can be identified as a file chunk.
 	var chunk []byte
-	chunk, err = repr.MarshalFile(buf[:n])
+	chunk, err = repr.MarshalFile(buf[:size])
 	if err != nil {
 		err = fmt.Errorf("MarshalFile: %v", err)
 		return
@@ -254,6 +279,7 @@ func (v *visitor) saveFileChunk(
 	// Write out the blob.
 	saveReq := &blob.SaveRequest{
 		Blob: chunk,
+		Kind: blob.Kind_File,
 	}
 
 	s, err = v.blobStore.Save(ctx, saveReq)
@@ -307,6 +333,7 @@ func (v *visitor) saveDir(
 	// Write out the blob.
 	storeReq := &blob.SaveRequest{
 		Blob: b,
+		Kind: blob.Kind_Dir,
 	}
 
 	s, err := v.blobStore.Save(ctx, storeReq)
@@ -75,6 +75,7 @@ type VisitorTest struct {
 	scoreMap  state.ScoreMap
 	blobStore mock_blob.MockStore
 	clock     timeutil.SimulatedClock
+	warnings  uint64
 
 	node fsNode
 
@@ -117,6 +118,7 @@ func (t *VisitorTest) call() (err error) {
 		make(semaphore, 10),
 		&t.clock,
 		log.New(ioutil.Discard, "", 0),
+		&t.warnings,
 		make(chan *fsNode, 1))
 
 	err = visitor.Visit(t.ctx, &t.node)
@@ -394,6 +396,43 @@ func (t *VisitorTest) File_UpdatesScoreMap() {
 	ExpectThat(t.node.Info.Scores, ElementsAre())
 }
 
+func (t *VisitorTest) File_ChangedWhileReading() {
+	var err error
+
+	// Set up a file that has grown since it was statted.
+	t.node.RelPath = "foo"
+	t.node.Info.Type = fs.TypeFile
+	t.node.Info.Size = 3
+	p := path.Join(t.dir, t.node.RelPath)
+
+	contents := []byte("taco")
+	err = ioutil.WriteFile(p, contents, 0700)
+	AssertEq(nil, err)
+
+	t.node.Info.MTime = t.clock.Now().Add(-100 * time.Hour)
+	key := makeScoreMapKey(&t.node, &t.clock)
+	AssertNe(nil, key)
+
+	// Blob store
+	expected, err := repr.MarshalFile(contents)
+	AssertEq(nil, err)
+
+	score := blob.ComputeScore(expected)
+	ExpectCall(t.blobStore, "Save")(Any(), blobEquals(expected)).
+		WillOnce(Return(score, nil))
+
+	// Call
+	err = t.call()
+	AssertEq(nil, err)
+
+	ExpectThat(t.node.Info.Scores, ElementsAre(score))
+	ExpectEq(len(contents), t.node.Info.Size)
+	ExpectEq(1, t.warnings)
+
+	// The score map should not have been updated.
+	ExpectEq(nil, t.scoreMap.Get(*key))
+}
+
 func (t *VisitorTest) OtherType() {
 	var err error
 
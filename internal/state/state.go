@@ -16,10 +16,14 @@
 package state
 
 import (
+	"bytes"
 	"encoding/gob"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"time"
 
+	"github.com/jacobsa/comeback/internal/crypto"
 	"github.com/jacobsa/comeback/internal/util"
 )
 
@@ -34,20 +38,108 @@ type State struct {
 	// source.
 	RelistTime time.Time
 
+	// The bucket's GC epoch at the time ExistingScores was listed. If the epoch
+	// has since changed, gc may have removed some of the scores.
+	GCEpoch uint64
+
 	// A map from file system info to the scores that were seen for a given file
 	// last time. These scores may have been written to the blob store, but not
 	// flushed.
 	ScoresForFiles ScoreMap
 }
 
-func LoadState(r io.Reader) (state State, err error) {
-	decoder := gob.NewDecoder(r)
+// State files begin with this header, followed by the gob-encoded State
+// encrypted with the crypter returned by NewCrypter. The header is also
+// authenticated as associated data.
+var fileHeader = []byte("comeback encrypted state v1\n")
+
+// The label with which the state key is derived from the repository's key.
+const subkeyLabel = "state file"
+
+// Return a crypter for state files, using a key derived from the repository's
+// key. The state file reveals every backed-up path and the set of scores in the
+// bucket, so it is protected like the bucket's contents.
+func NewCrypter(repoCrypter crypto.Crypter) (c crypto.Crypter, err error) {
+	c, err = crypto.NewCrypter(repoCrypter.DeriveSubkey(subkeyLabel))
+	if err != nil {
+		err = fmt.Errorf("NewCrypter: %v", err)
+		return
+	}
+
+	return
+}
+
+// Load a state file written by SaveState with a crypter for the same
+// repository. An error is returned if the file can't be authenticated,
+// including when it was written for another repository or predates
+// encryption; since the state is only a cache, the caller may then start
+// afresh.
+func LoadState(
+	r io.Reader,
+	crypter crypto.Crypter) (state State, err error) {
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		err = fmt.Errorf("ReadAll: %v", err)
+		return
+	}
+
+	if !bytes.HasPrefix(contents, fileHeader) {
+		err = fmt.Errorf("Not an encrypted state file.")
+		return
+	}
+
+	plaintext, err := crypter.Decrypt(
+		contents[len(fileHeader):],
+		[][]byte{fileHeader})
+
+	if _, ok := err.(*crypto.NotAuthenticError); ok {
+		err = fmt.Errorf(
+			"The state file was written with another key or for another "+
+				"repository, or has been tampered with: %v",
+			err)
+		return
+	}
+
+	if err != nil {
+		err = fmt.Errorf("Decrypt: %v", err)
+		return
+	}
+
+	decoder := gob.NewDecoder(bytes.NewReader(plaintext))
 	err = decoder.Decode(&state)
+	if err != nil {
+		err = fmt.Errorf("Decode: %v", err)
+		return
+	}
+
 	return
 }
 
-func SaveState(w io.Writer, state State) (err error) {
-	encoder := gob.NewEncoder(w)
+// Write out the state, encrypted with the supplied crypter.
+func SaveState(
+	w io.Writer,
+	state State,
+	crypter crypto.Crypter) (err error) {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
 	err = encoder.Encode(state)
+	if err != nil {
+		err = fmt.Errorf("Encode: %v", err)
+		return
+	}
+
+	contents := append([]byte(nil), fileHeader...)
+	contents, err = crypter.Encrypt(contents, buf.Bytes(), [][]byte{fileHeader})
+	if err != nil {
+		err = fmt.Errorf("Encrypt: %v", err)
+		return
+	}
+
+	_, err = w.Write(contents)
+	if err != nil {
+		err = fmt.Errorf("Write: %v", err)
+		return
+	}
+
 	return
 }
@@ -17,10 +17,12 @@ package state_test
 
 import (
 	"bytes"
+	"encoding/gob"
 	"testing"
 	"time"
 
 	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/crypto"
 	"github.com/jacobsa/comeback/internal/state"
 	"github.com/jacobsa/comeback/internal/util"
 	. "github.com/jacobsa/oglematchers"
@@ -35,11 +37,36 @@ func TestState(t *testing.T) { RunTests(t) }
 ////////////////////////////////////////////////////////////////////////
 
 type StateTest struct {
-	s state.State
+	s       state.State
+	crypter crypto.Crypter
 }
 
 func init() { RegisterTestSuite(&StateTest{}) }
 
+func (t *StateTest) SetUp(ti *TestInfo) {
+	t.crypter = makeStateCrypter(0x17)
+}
+
+// Return a state file crypter for a repository whose key consists of the
+// supplied byte.
+func makeStateCrypter(b byte) (c crypto.Crypter) {
+	repoCrypter, err := crypto.NewCrypter(bytes.Repeat([]byte{b}, 32))
+	AssertEq(nil, err)
+
+	c, err = state.NewCrypter(repoCrypter)
+	AssertEq(nil, err)
+
+	return
+}
+
+func (t *StateTest) save() (b []byte) {
+	buf := new(bytes.Buffer)
+	AssertEq(nil, state.SaveState(buf, t.s, t.crypter))
+
+	b = buf.Bytes()
+	return
+}
+
 ////////////////////////////////////////////////////////////////////////
 // Tests
 ////////////////////////////////////////////////////////////////////////
@@ -49,6 +76,7 @@ func (t *StateTest) RoundTrip() {
 	t.s.ExistingScores.Add("taco")
 	t.s.ExistingScores.Add("burrito")
 	t.s.RelistTime = time.Now().Round(0)
+	t.s.GCEpoch = 17
 
 	t.s.ScoresForFiles = state.NewScoreMap()
 	key := state.ScoreMapKey{Path: "queso"}
@@ -56,17 +84,55 @@ func (t *StateTest) RoundTrip() {
 	t.s.ScoresForFiles.Set(key, scores)
 
 	// Save
-	buf := new(bytes.Buffer)
-	AssertEq(nil, state.SaveState(buf, t.s))
+	buf := bytes.NewBuffer(t.save())
 
 	// Load
-	loaded, err := state.LoadState(buf)
+	loaded, err := state.LoadState(buf, t.crypter)
 	AssertEq(nil, err)
 
 	ExpectTrue(loaded.ExistingScores.Contains("taco"))
 	ExpectTrue(loaded.ExistingScores.Contains("burrito"))
 	ExpectFalse(loaded.ExistingScores.Contains("enchilada"))
 	ExpectThat(loaded.RelistTime, timeutil.TimeEq(t.s.RelistTime))
+	ExpectEq(17, loaded.GCEpoch)
 
 	ExpectThat(loaded.ScoresForFiles.Get(key), DeepEquals(scores))
 }
+
+func (t *StateTest) Encrypted() {
+	t.s.ExistingScores = util.NewStringSet()
+	t.s.ExistingScores.Add("taco")
+
+	t.s.ScoresForFiles = state.NewScoreMap()
+	t.s.ScoresForFiles.Set(
+		state.ScoreMapKey{Path: "/secret/path"},
+		[]blob.Score{blob.ComputeScore([]byte("foo"))})
+
+	b := t.save()
+	ExpectFalse(bytes.Contains(b, []byte("taco")))
+	ExpectFalse(bytes.Contains(b, []byte("/secret/path")))
+}
+
+func (t *StateTest) WrongKey() {
+	b := t.save()
+
+	_, err := state.LoadState(bytes.NewReader(b), makeStateCrypter(0x19))
+	ExpectThat(err, Error(HasSubstr("another key")))
+}
+
+func (t *StateTest) Tampered() {
+	b := t.save()
+	b[len(b)-1]++
+
+	_, err := state.LoadState(bytes.NewReader(b), t.crypter)
+	ExpectThat(err, Error(HasSubstr("tampered")))
+}
+
+func (t *StateTest) Unencrypted() {
+	// A state file written by older versions of comeback is plain gob.
+	buf := new(bytes.Buffer)
+	AssertEq(nil, gob.NewEncoder(buf).Encode(t.s))
+
+	_, err := state.LoadState(buf, t.crypter)
+	ExpectThat(err, Error(HasSubstr("Not an encrypted state file")))
+}
@@ -0,0 +1,209 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/browse"
+	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/registry"
+)
+
+// Count the backups that reference each blob, given the scans of all of
+// them. A root score recorded by several jobs is counted once per job.
+func CountReferences(
+	jobs []registry.CompletedJob,
+	scans Scans) (counts map[blob.Score]int, err error) {
+	roots := make([]blob.Score, len(jobs))
+	for i, j := range jobs {
+		roots[i] = j.Score
+	}
+
+	counts = make(map[blob.Score]int)
+	err = scans.ForEach(roots, func(i int, s *Scan) (err error) {
+		for _, score := range s.FileChunks {
+			counts[score]++
+		}
+
+		for _, score := range s.DirListings {
+			counts[score]++
+		}
+
+		return
+	})
+
+	return
+}
+
+// DirUsage describes the space taken by a directory within a backup.
+type DirUsage struct {
+	// The directory's path relative to the root of the backup, and its depth
+	// relative to the directory with which DiskUsage was called.
+	Path  string
+	Depth int
+
+	// The sum of the sizes of the regular files within the directory,
+	// recursively, counting each hard link only once.
+	Apparent uint64
+
+	// The stored size of the distinct blobs within the directory, including
+	// its own listing, that no other backup references. This is the space
+	// that deleting the backup would free.
+	Unique uint64
+}
+
+// Compute usage for the directory with the supplied score and relative path
+// within a backup, and each directory within it down to maxDepth levels
+// (unlimited if negative). fn is called for each of them, children before
+// parents, in lexical order.
+//
+// others gives the number of other backups referencing each blob, as
+// returned by CountReferences for all backups but this one. sizes gives the
+// stored size of each blob, e.g. as returned by ListBlobSizes.
+func DiskUsage(
+	ctx context.Context,
+	blobStore blob.Store,
+	dir blob.Score,
+	relPath string,
+	others map[blob.Score]int,
+	sizes map[blob.Score]uint64,
+	maxDepth int,
+	fn func(u *DirUsage) error) (err error) {
+	w := &duWalker{
+		blobStore: blobStore,
+		others:    others,
+		sizes:     sizes,
+		maxDepth:  maxDepth,
+		fn:        fn,
+		memo:      make(map[blob.Score]*duResult),
+	}
+
+	_, err = w.visit(ctx, dir, relPath, 0)
+	return
+}
+
+// The usage of a directory with a particular score.
+type duResult struct {
+	apparent uint64
+
+	// The blobs counted toward the directory's unique size.
+	unique map[blob.Score]struct{}
+}
+
+type duWalker struct {
+	blobStore blob.Store
+	others    map[blob.Score]int
+	sizes     map[blob.Score]uint64
+	maxDepth  int
+	fn        func(u *DirUsage) error
+
+	// Results for each directory visited so far, so that a directory appearing
+	// more than once in the backup is loaded only once unless its children
+	// must be reported.
+	memo map[blob.Score]*duResult
+}
+
+func (w *duWalker) visit(
+	ctx context.Context,
+	dir blob.Score,
+	relPath string,
+	depth int) (r *duResult, err error) {
+	reportChildren := w.maxDepth < 0 || depth < w.maxDepth
+
+	r, ok := w.memo[dir]
+	if !ok || reportChildren {
+		r, err = w.compute(ctx, dir, relPath, depth)
+		if err != nil {
+			return
+		}
+
+		w.memo[dir] = r
+	}
+
+	if w.maxDepth >= 0 && depth > w.maxDepth {
+		return
+	}
+
+	u := &DirUsage{
+		Path:     relPath,
+		Depth:    depth,
+		Apparent: r.apparent,
+	}
+
+	for score := range r.unique {
+		u.Unique += w.sizes[score]
+	}
+
+	err = w.fn(u)
+	return
+}
+
+func (w *duWalker) compute(
+	ctx context.Context,
+	dir blob.Score,
+	relPath string,
+	depth int) (r *duResult, err error) {
+	r = &duResult{
+		unique: make(map[blob.Score]struct{}),
+	}
+
+	addBlob := func(score blob.Score) {
+		if w.others[score] == 0 {
+			r.unique[score] = struct{}{}
+		}
+	}
+
+	addBlob(dir)
+
+	entries, err := browse.ReadDir(ctx, w.blobStore, dir)
+	if err != nil {
+		err = fmt.Errorf("ReadDir: %v", err)
+		return
+	}
+
+	for _, e := range entries {
+		switch {
+		case e.Type == fs.TypeFile && e.HardLinkTarget == nil:
+			r.apparent += e.Size
+			for _, score := range e.Scores {
+				addBlob(score)
+			}
+
+		case e.Type == fs.TypeDirectory:
+			var child *duResult
+			child, err = w.visit(
+				ctx,
+				e.Scores[0],
+				path.Join(relPath, e.Name),
+				depth+1)
+
+			if err != nil {
+				return
+			}
+
+			r.apparent += child.apparent
+			for score := range child.unique {
+				r.unique[score] = struct{}{}
+			}
+		}
+	}
+
+	return
+}
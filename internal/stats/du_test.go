@@ -0,0 +1,154 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"fmt"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/registry"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+)
+
+func (t *StatsTest) CountReferences() {
+	a := t.storeBlob([]byte("taco"))
+	b := t.storeBlob([]byte("burrito"))
+	root0 := t.storeDir(file("x", 4, a))
+	root1 := t.storeDir(file("x", 4, a), file("y", 7, b))
+
+	scans := make(ScanMap)
+	for _, root := range []blob.Score{root0, root1} {
+		var err error
+		scans[root], err = ScanSnapshot(t.ctx, t.blobStore, root)
+		AssertEq(nil, err)
+	}
+
+	// root1 is recorded twice.
+	jobs := []registry.CompletedJob{
+		job("j", 1, root0),
+		job("j", 2, root1),
+		job("k", 2, root1),
+	}
+
+	counts, err := CountReferences(jobs, scans)
+	AssertEq(nil, err)
+
+	ExpectEq(4, len(counts))
+	ExpectEq(3, counts[a])
+	ExpectEq(2, counts[b])
+	ExpectEq(1, counts[root0])
+	ExpectEq(2, counts[root1])
+
+	// Missing scan
+	_, err = CountReferences(jobs, ScanMap{root0: scans[root0]})
+	ExpectThat(err, Error(HasSubstr("No scan")))
+}
+
+func (t *StatsTest) DiskUsage() {
+	old := t.storeBlob([]byte("old"))
+	fresh := t.storeBlob([]byte("fresh"))
+	fresher := t.storeBlob([]byte("fresher"))
+
+	// The same directory appears twice; its blob is unique but should be
+	// counted once at the root.
+	twice := t.storeDir(file("f", 5, fresh))
+	deep := t.storeDir(file("g", 7, fresher))
+	sub := t.storeDir(file("o", 3, old), dir("deep", deep))
+	root := t.storeDir(
+		dir("a", twice),
+		dir("b", twice),
+		dir("sub", sub),
+		file("o", 3, old))
+
+	sizes, err := ListBlobSizes(t.ctx, t.bucket, "blobs/")
+	AssertEq(nil, err)
+
+	// Another backup references old.
+	others := map[blob.Score]int{old: 1}
+
+	run := func(relPath string, dir blob.Score, maxDepth int) (lines []string) {
+		err := DiskUsage(
+			t.ctx,
+			t.blobStore,
+			dir,
+			relPath,
+			others,
+			sizes,
+			maxDepth,
+			func(u *DirUsage) (err error) {
+				lines = append(
+					lines,
+					fmt.Sprintf("%d %s %d %d", u.Depth, u.Path, u.Apparent, u.Unique))
+				return
+			})
+
+		AssertEq(nil, err)
+		return
+	}
+
+	size := func(scores ...blob.Score) (total uint64) {
+		for _, s := range scores {
+			total += sizes[s]
+		}
+
+		return
+	}
+
+	ExpectThat(
+		run("", root, -1),
+		ElementsAre(
+			fmt.Sprintf("1 a 5 %d", size(twice, fresh)),
+			fmt.Sprintf("1 b 5 %d", size(twice, fresh)),
+			fmt.Sprintf("2 sub/deep 7 %d", size(deep, fresher)),
+			fmt.Sprintf("1 sub 10 %d", size(sub, deep, fresher)),
+			fmt.Sprintf(
+				"0  23 %d",
+				size(root, twice, fresh, sub, deep, fresher)),
+		))
+
+	ExpectThat(
+		run("", root, 0),
+		ElementsAre(
+			fmt.Sprintf(
+				"0  23 %d",
+				size(root, twice, fresh, sub, deep, fresher)),
+		))
+
+	ExpectThat(
+		run("sub", sub, 1),
+		ElementsAre(
+			fmt.Sprintf("1 sub/deep 7 %d", size(deep, fresher)),
+			fmt.Sprintf("0 sub 10 %d", size(sub, deep, fresher)),
+		))
+}
+
+func (t *StatsTest) DiskUsage_CallbackError() {
+	root := t.storeDir(dir("a", t.storeDir()))
+
+	expected := fmt.Errorf("taco")
+	err := DiskUsage(
+		t.ctx,
+		t.blobStore,
+		root,
+		"",
+		nil,
+		nil,
+		-1,
+		func(u *DirUsage) error { return expected })
+
+	ExpectEq(expected, err)
+}
@@ -0,0 +1,306 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"math/bits"
+	"sort"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/registry"
+)
+
+// A Bucket counts the blobs whose sizes lie in [MinSize, MaxSize).
+type Bucket struct {
+	MinSize uint64 `json:"min_size"`
+	MaxSize uint64 `json:"max_size"`
+	Count   uint64 `json:"count"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// A Distribution counts blobs by size, in power of two buckets.
+type Distribution struct {
+	Count uint64 `json:"count"`
+	Bytes uint64 `json:"bytes"`
+
+	// The non-empty buckets, in increasing order of size.
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Account for a blob of the supplied size.
+func (d *Distribution) Add(size uint64) {
+	d.Count++
+	d.Bytes += size
+
+	// Find the bucket, creating it if necessary. Empty blobs get a bucket of
+	// their own.
+	var min, max uint64 = 0, 1
+	if size != 0 {
+		n := bits.Len64(size)
+		min = 1 << uint(n-1)
+		max = min << 1
+		if n == 64 {
+			max = 1<<64 - 1
+		}
+	}
+
+	i := sort.Search(len(d.Buckets), func(i int) bool {
+		return d.Buckets[i].MinSize >= min
+	})
+
+	if i == len(d.Buckets) || d.Buckets[i].MinSize != min {
+		d.Buckets = append(d.Buckets, Bucket{})
+		copy(d.Buckets[i+1:], d.Buckets[i:])
+		d.Buckets[i] = Bucket{MinSize: min, MaxSize: max}
+	}
+
+	d.Buckets[i].Count++
+	d.Buckets[i].Bytes += size
+}
+
+// Usage describes the space taken by a set of backups.
+type Usage struct {
+	// Totals over the backups, as recorded in their Scans.
+	Logical uint64 `json:"logical_bytes"`
+	Files   uint64 `json:"files"`
+	Dirs    uint64 `json:"dirs"`
+
+	// The stored size of the distinct blobs referenced by the backups, split
+	// into those referenced by nothing else and those shared with other
+	// backups.
+	Unique uint64 `json:"unique_bytes"`
+	Shared uint64 `json:"shared_bytes"`
+
+	// The number of referenced blobs that are missing from the bucket, and so
+	// aren't counted above.
+	Missing uint64 `json:"missing_blobs"`
+
+	// The sizes of the referenced blobs.
+	FileChunks  Distribution `json:"file_chunks"`
+	DirListings Distribution `json:"dir_listings"`
+}
+
+// Return the stored size of the blobs referenced by the backups.
+func (u *Usage) Physical() uint64 {
+	return u.Unique + u.Shared
+}
+
+// Return the ratio of logical to physical size, or zero if nothing is
+// stored.
+func (u *Usage) DedupRatio() float64 {
+	if u.Physical() == 0 {
+		return 0
+	}
+
+	return float64(u.Logical) / float64(u.Physical())
+}
+
+func (u *Usage) addScan(s *Scan) {
+	u.Logical += s.Logical
+	u.Files += s.Files
+	u.Dirs += s.Dirs
+}
+
+func (u *Usage) addBlob(r *ref, size uint64, ok bool, unique bool) {
+	if !ok {
+		u.Missing++
+		return
+	}
+
+	if unique {
+		u.Unique += size
+	} else {
+		u.Shared += size
+	}
+
+	if r.dirListing {
+		u.DirListings.Add(size)
+	} else {
+		u.FileChunks.Add(size)
+	}
+}
+
+type SnapshotUsage struct {
+	Job registry.CompletedJob
+
+	// Unique counts blobs referenced by no other backup.
+	Usage
+}
+
+type JobUsage struct {
+	Name      string
+	Snapshots int
+
+	// Unique counts blobs referenced by no backup of another job. The logical
+	// size and counts are summed over the job's backups.
+	Usage
+}
+
+type Report struct {
+	// One entry per backup, in the order supplied to Compute.
+	Snapshots []SnapshotUsage
+
+	// One entry per job, sorted by name.
+	Jobs []JobUsage
+
+	// Totals over all backups. Unique counts blobs referenced by exactly one
+	// backup.
+	Total Usage
+
+	// Blobs in the bucket that no backup references, which gc would reclaim.
+	Garbage Distribution
+}
+
+// Return the stored size of everything in the bucket.
+func (r *Report) Stored() uint64 {
+	return r.Total.Physical() + r.Garbage.Bytes
+}
+
+// The backups and jobs that reference a blob.
+type ref struct {
+	snapshots  int
+	jobs       int
+	dirListing bool
+
+	// The last job whose backups were seen to reference the blob. See Compute.
+	mark int
+}
+
+// Compute a report for the supplied backups, which must include every backup
+// in the bucket for the report to make sense. scans must supply the scan of
+// each backup, and sizes the stored size of each blob in the bucket, e.g. as
+// returned by ListBlobSizes.
+func Compute(
+	jobs []registry.CompletedJob,
+	scans Scans,
+	sizes map[blob.Score]uint64) (r *Report, err error) {
+	r = &Report{
+		Snapshots: make([]SnapshotUsage, len(jobs)),
+	}
+
+	// Group the backups by job name.
+	groups := make(map[string][]int)
+	for i, j := range jobs {
+		r.Snapshots[i].Job = j
+		groups[j.Name] = append(groups[j.Name], i)
+	}
+
+	for name, g := range groups {
+		r.Jobs = append(r.Jobs, JobUsage{Name: name, Snapshots: len(g)})
+	}
+
+	sort.Slice(r.Jobs, func(i, j int) bool {
+		return r.Jobs[i].Name < r.Jobs[j].Name
+	})
+
+	// forEachScan calls f for the scan of each backup, a job at a time.
+	forEachScan := func(f func(gi int, si int, s *Scan)) (err error) {
+		for gi := range r.Jobs {
+			g := groups[r.Jobs[gi].Name]
+			roots := make([]blob.Score, len(g))
+			for k, si := range g {
+				roots[k] = jobs[si].Score
+			}
+
+			err = scans.ForEach(roots, func(k int, s *Scan) error {
+				f(gi, g[k], s)
+				return nil
+			})
+
+			if err != nil {
+				return
+			}
+		}
+
+		return
+	}
+
+	// Count references. mark records the last job seen referencing a blob, so
+	// that each job is counted once.
+	refs := make(map[blob.Score]*ref)
+	countRef := func(gi int, score blob.Score, dir bool) {
+		b := refs[score]
+		if b == nil {
+			b = &ref{dirListing: dir}
+			refs[score] = b
+		}
+
+		b.snapshots++
+		if b.mark != gi+1 {
+			b.jobs++
+			b.mark = gi + 1
+		}
+	}
+
+	err = forEachScan(func(gi int, si int, s *Scan) {
+		for _, score := range s.FileChunks {
+			countRef(gi, score, false)
+		}
+
+		for _, score := range s.DirListings {
+			countRef(gi, score, true)
+		}
+	})
+
+	if err != nil {
+		return
+	}
+
+	// Attribute blobs. This time mark is negated to record the jobs that have
+	// already been credited with a blob.
+	attribute := func(gi int, si int, score blob.Score) {
+		b := refs[score]
+		size, ok := sizes[score]
+		r.Snapshots[si].addBlob(b, size, ok, b.snapshots == 1)
+
+		if b.mark != -(gi + 1) {
+			b.mark = -(gi + 1)
+			r.Jobs[gi].addBlob(b, size, ok, b.jobs == 1)
+		}
+	}
+
+	err = forEachScan(func(gi int, si int, s *Scan) {
+		for _, score := range s.FileChunks {
+			attribute(gi, si, score)
+		}
+
+		for _, score := range s.DirListings {
+			attribute(gi, si, score)
+		}
+
+		r.Snapshots[si].addScan(s)
+		r.Jobs[gi].addScan(s)
+		r.Total.addScan(s)
+	})
+
+	if err != nil {
+		return
+	}
+
+	for score, b := range refs {
+		size, ok := sizes[score]
+		r.Total.addBlob(b, size, ok, b.snapshots == 1)
+	}
+
+	// Anything else in the bucket is garbage.
+	for score, size := range sizes {
+		if _, ok := refs[score]; !ok {
+			r.Garbage.Add(size)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,554 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/browse"
+	"github.com/jacobsa/comeback/internal/crypto"
+	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/registry"
+)
+
+// A Scan records what a single backup contains and which blobs it references.
+type Scan struct {
+	// The sum of the sizes of the regular files in the backup, counting each
+	// hard link only once.
+	Logical uint64
+
+	// The number of regular files and directories in the backup, not counting
+	// the root directory.
+	Files uint64
+	Dirs  uint64
+
+	// The distinct blobs referenced by the backup's regular files and
+	// directories, in sorted order.
+	FileChunks  []blob.Score
+	DirListings []blob.Score
+}
+
+// Scan the backup with the supplied root score, loading each distinct
+// directory listing within it once.
+func ScanSnapshot(
+	ctx context.Context,
+	blobStore blob.Store,
+	root blob.Score) (s *Scan, err error) {
+	sc := &scanner{
+		blobStore: blobStore,
+		memo:      make(map[blob.Score]subtree),
+		chunks:    make(map[blob.Score]struct{}),
+	}
+
+	t, err := sc.visit(ctx, root)
+	if err != nil {
+		return
+	}
+
+	s = &Scan{
+		Logical: t.logical,
+		Files:   t.files,
+		Dirs:    t.dirs,
+	}
+
+	for score := range sc.chunks {
+		s.FileChunks = append(s.FileChunks, score)
+	}
+
+	for score := range sc.memo {
+		s.DirListings = append(s.DirListings, score)
+	}
+
+	sortScores(s.FileChunks)
+	sortScores(s.DirListings)
+
+	return
+}
+
+// Totals for the contents of a directory.
+type subtree struct {
+	logical uint64
+	files   uint64
+	dirs    uint64
+}
+
+type scanner struct {
+	blobStore blob.Store
+
+	// Totals for each directory visited so far, which are also the directory
+	// listings referenced by the backup.
+	memo map[blob.Score]subtree
+
+	// The file chunks referenced by the backup.
+	chunks map[blob.Score]struct{}
+}
+
+func (sc *scanner) visit(
+	ctx context.Context,
+	dir blob.Score) (t subtree, err error) {
+	// A directory may appear more than once in a backup. It contributes to the
+	// totals each time, but its blobs are referenced only once.
+	t, ok := sc.memo[dir]
+	if ok {
+		return
+	}
+
+	entries, err := browse.ReadDir(ctx, sc.blobStore, dir)
+	if err != nil {
+		err = fmt.Errorf("ReadDir: %v", err)
+		return
+	}
+
+	for _, e := range entries {
+		switch e.Type {
+		case fs.TypeFile:
+			t.files++
+			if e.HardLinkTarget != nil {
+				continue
+			}
+
+			t.logical += e.Size
+			for _, s := range e.Scores {
+				sc.chunks[s] = struct{}{}
+			}
+
+		case fs.TypeDirectory:
+			var child subtree
+			child, err = sc.visit(ctx, e.Scores[0])
+			if err != nil {
+				return
+			}
+
+			t.dirs += child.dirs + 1
+			t.files += child.files
+			t.logical += child.logical
+		}
+	}
+
+	sc.memo[dir] = t
+	return
+}
+
+func sortScores(scores []blob.Score) {
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].Compare(scores[j]) < 0
+	})
+}
+
+////////////////////////////////////////////////////////////////////////
+// Scans
+////////////////////////////////////////////////////////////////////////
+
+// Scans supplies the scans of backups.
+type Scans interface {
+	// Call f with the scan of each backup with the supplied root scores in
+	// turn, stopping at the first error. It is an error for a scan to be
+	// missing.
+	ForEach(roots []blob.Score, f func(i int, s *Scan) error) (err error)
+}
+
+// A ScanMap holds scans in memory, keyed by the backup's root score.
+type ScanMap map[blob.Score]*Scan
+
+func (m ScanMap) ForEach(
+	roots []blob.Score,
+	f func(i int, s *Scan) error) (err error) {
+	for i, root := range roots {
+		s, ok := m[root]
+		if !ok {
+			err = fmt.Errorf("No scan for backup %s", root.Hex())
+			return
+		}
+
+		err = f(i, s)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Return the scores in the sorted slice a that aren't in the sorted slice b.
+func difference(a []blob.Score, b []blob.Score) (d []blob.Score) {
+	for len(a) != 0 {
+		switch {
+		case len(b) == 0 || a[0].Compare(b[0]) < 0:
+			d = append(d, a[0])
+			a = a[1:]
+
+		case a[0].Compare(b[0]) > 0:
+			b = b[1:]
+
+		default:
+			a = a[1:]
+			b = b[1:]
+		}
+	}
+
+	return
+}
+
+// Merge the disjoint sorted slices a and b.
+func union(a []blob.Score, b []blob.Score) (u []blob.Score) {
+	u = make([]blob.Score, 0, len(a)+len(b))
+	for len(a) != 0 && len(b) != 0 {
+		if a[0].Compare(b[0]) < 0 {
+			u = append(u, a[0])
+			a = a[1:]
+		} else {
+			u = append(u, b[0])
+			b = b[1:]
+		}
+	}
+
+	u = append(u, a...)
+	u = append(u, b...)
+	return
+}
+
+////////////////////////////////////////////////////////////////////////
+// Cache
+////////////////////////////////////////////////////////////////////////
+
+// The version of the cache format. Caches written with other versions are
+// discarded.
+const cacheVersion = 3
+
+// A Cache holds the scans of backups examined by earlier runs. Backups are
+// immutable, so a scan never goes stale.
+//
+// Consecutive backups of a job mostly reference the same blobs, so each scan
+// is recorded as the difference from the previous backup of the same job.
+// The cache therefore grows with the changes between backups rather than
+// with their size, and only the latest scan of each job need be held in
+// memory while walking the backups in order.
+type Cache struct {
+	Version int
+
+	// Entries keyed by the backup's root score.
+	Entries map[blob.Score]*CacheEntry
+}
+
+// A CacheEntry records a scan relative to that of another backup.
+type CacheEntry struct {
+	// The totals for the backup itself.
+	Logical uint64
+	Files   uint64
+	Dirs    uint64
+
+	// If HasBase is set, the entry is relative to the scan of the backup with
+	// root score Base. Otherwise it is relative to an empty scan.
+	HasBase bool
+	Base    blob.Score
+
+	// The blobs referenced by the backup but not the base, and vice versa, in
+	// sorted order.
+	AddedChunks   []blob.Score
+	RemovedChunks []blob.Score
+	AddedDirs     []blob.Score
+	RemovedDirs   []blob.Score
+}
+
+// Return an entry recording s relative to base, which has the given root
+// score if hasBase is set.
+func newCacheEntry(
+	s *Scan,
+	hasBase bool,
+	baseRoot blob.Score,
+	base *Scan) (e *CacheEntry) {
+	e = &CacheEntry{
+		Logical:       s.Logical,
+		Files:         s.Files,
+		Dirs:          s.Dirs,
+		HasBase:       hasBase,
+		Base:          baseRoot,
+		AddedChunks:   difference(s.FileChunks, base.FileChunks),
+		RemovedChunks: difference(base.FileChunks, s.FileChunks),
+		AddedDirs:     difference(s.DirListings, base.DirListings),
+		RemovedDirs:   difference(base.DirListings, s.DirListings),
+	}
+
+	return
+}
+
+// Reconstruct the scan that the entry records, given the scan of its base.
+func (e *CacheEntry) apply(base *Scan) (s *Scan) {
+	s = &Scan{
+		Logical:     e.Logical,
+		Files:       e.Files,
+		Dirs:        e.Dirs,
+		FileChunks:  union(difference(base.FileChunks, e.RemovedChunks), e.AddedChunks),
+		DirListings: union(difference(base.DirListings, e.RemovedDirs), e.AddedDirs),
+	}
+
+	return
+}
+
+// Create an empty cache.
+func NewCache() (c *Cache) {
+	c = &Cache{
+		Version: cacheVersion,
+		Entries: make(map[blob.Score]*CacheEntry),
+	}
+
+	return
+}
+
+// Cache files begin with this header, followed by the gob-encoded Cache
+// encrypted with the crypter returned by NewCrypter. The header is also
+// authenticated as associated data.
+var cacheFileHeader = []byte("comeback encrypted stats cache v1\n")
+
+// The label with which the cache key is derived from the repository's key.
+const subkeyLabel = "stats cache"
+
+// Return a crypter for cache files, using a key derived from the repository's
+// key. The cache reveals the set of blobs that each backup references, so it
+// is protected like the bucket's contents.
+func NewCrypter(repoCrypter crypto.Crypter) (c crypto.Crypter, err error) {
+	c, err = crypto.NewCrypter(repoCrypter.DeriveSubkey(subkeyLabel))
+	if err != nil {
+		err = fmt.Errorf("NewCrypter: %v", err)
+		return
+	}
+
+	return
+}
+
+// Load a cache written by SaveCache with a crypter for the same repository.
+// A cache in an older format is returned as an empty cache. An error is
+// returned if the file can't be authenticated; since it is only a cache, the
+// caller may then start afresh.
+func LoadCache(
+	r io.Reader,
+	crypter crypto.Crypter) (c *Cache, err error) {
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		err = fmt.Errorf("ReadAll: %v", err)
+		return
+	}
+
+	if !bytes.HasPrefix(contents, cacheFileHeader) {
+		err = fmt.Errorf("Not an encrypted stats cache.")
+		return
+	}
+
+	plaintext, err := crypter.Decrypt(
+		contents[len(cacheFileHeader):],
+		[][]byte{cacheFileHeader})
+
+	if err != nil {
+		err = fmt.Errorf("Decrypt: %v", err)
+		return
+	}
+
+	c = new(Cache)
+	err = gob.NewDecoder(bytes.NewReader(plaintext)).Decode(c)
+	if err != nil {
+		err = fmt.Errorf("Decode: %v", err)
+		return
+	}
+
+	if c.Version != cacheVersion || c.Entries == nil {
+		c = NewCache()
+	}
+
+	return
+}
+
+// Write out the cache, encrypted with the supplied crypter.
+func SaveCache(
+	w io.Writer,
+	c *Cache,
+	crypter crypto.Crypter) (err error) {
+	var buf bytes.Buffer
+	err = gob.NewEncoder(&buf).Encode(c)
+	if err != nil {
+		err = fmt.Errorf("Encode: %v", err)
+		return
+	}
+
+	contents := append([]byte(nil), cacheFileHeader...)
+	contents, err = crypter.Encrypt(
+		contents,
+		buf.Bytes(),
+		[][]byte{cacheFileHeader})
+
+	if err != nil {
+		err = fmt.Errorf("Encrypt: %v", err)
+		return
+	}
+
+	_, err = w.Write(contents)
+	if err != nil {
+		err = fmt.Errorf("Write: %v", err)
+		return
+	}
+
+	return
+}
+
+// Bring the cache up to date with the supplied backups, oldest first,
+// scanning those that it doesn't already hold and discarding the entries of
+// any others, e.g. because they have been deleted from the registry. scanning
+// is called before each backup is scanned.
+//
+// If a scan fails, the cache still holds everything that it did before, along
+// with the backups scanned so far, so that no work is lost.
+func (c *Cache) Update(
+	ctx context.Context,
+	blobStore blob.Store,
+	jobs []registry.CompletedJob,
+	scanning func(j registry.CompletedJob)) (err error) {
+	old := newExpander(c.Entries)
+	c.Entries = make(map[blob.Score]*CacheEntry)
+	updated := newExpander(c.Entries)
+
+	// The latest backup of each job so far, and its scan.
+	type latest struct {
+		root blob.Score
+		scan *Scan
+	}
+
+	prev := make(map[string]latest)
+
+	for _, j := range jobs {
+		var s *Scan
+		_, done := c.Entries[j.Score]
+		_, cached := old.entries[j.Score]
+
+		switch {
+		// The same root recorded more than once.
+		case done:
+			s, err = updated.expand(j.Score)
+
+		case cached:
+			s, err = old.expand(j.Score)
+
+		default:
+			scanning(j)
+			s, err = ScanSnapshot(ctx, blobStore, j.Score)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("Scan(%s): %v", j.Score.Hex(), err)
+			break
+		}
+
+		// Record the scan relative to the job's previous backup.
+		if !done {
+			p, ok := prev[j.Name]
+			base := p.scan
+			if !ok {
+				base = &Scan{}
+			}
+
+			c.Entries[j.Score] = newCacheEntry(s, ok, p.root, base)
+		}
+
+		prev[j.Name] = latest{j.Score, s}
+	}
+
+	// Keep the remaining old entries if we didn't finish. Each entry still
+	// reconstructs its own backup's scan, and every base that they refer to is
+	// in one map or the other.
+	if err != nil {
+		for root, e := range old.entries {
+			if _, ok := c.Entries[root]; !ok {
+				c.Entries[root] = e
+			}
+		}
+	}
+
+	return
+}
+
+// Call f with the scan of each backup with the supplied root scores in turn,
+// stopping at the first error. Reconstructing the scans of a job's backups is
+// cheapest in the order in which they were passed to Update.
+func (c *Cache) ForEach(
+	roots []blob.Score,
+	f func(i int, s *Scan) error) (err error) {
+	x := newExpander(c.Entries)
+	for i, root := range roots {
+		var s *Scan
+		s, err = x.expand(root)
+		if err != nil {
+			return
+		}
+
+		err = f(i, s)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// An expander reconstructs scans from cache entries. It remembers the latest
+// scan that it has reconstructed in each chain of entries, so that walking a
+// chain in order applies each entry once.
+type expander struct {
+	entries map[blob.Score]*CacheEntry
+
+	// Scans that are not yet the base of another expanded scan, keyed by root
+	// score.
+	recent map[blob.Score]*Scan
+}
+
+func newExpander(entries map[blob.Score]*CacheEntry) (x *expander) {
+	x = &expander{
+		entries: entries,
+		recent:  make(map[blob.Score]*Scan),
+	}
+
+	return
+}
+
+func (x *expander) expand(root blob.Score) (s *Scan, err error) {
+	if s, ok := x.recent[root]; ok {
+		return s, nil
+	}
+
+	e, ok := x.entries[root]
+	if !ok {
+		err = fmt.Errorf("No scan for backup %s", root.Hex())
+		return
+	}
+
+	base := &Scan{}
+	if e.HasBase {
+		base, err = x.expand(e.Base)
+		if err != nil {
+			return
+		}
+
+		delete(x.recent, e.Base)
+	}
+
+	s = e.apply(base)
+	x.recent[root] = s
+	return
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/gcloud/gcs"
+)
+
+// Return the stored size of every blob in the bucket, keyed by score.
+func ListBlobSizes(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	namePrefix string) (sizes map[blob.Score]uint64, err error) {
+	eg, ctx := errgroup.WithContext(ctx)
+
+	// List object records into a channel.
+	objects := make(chan *gcs.Object, 100)
+	eg.Go(func() (err error) {
+		defer close(objects)
+		err = blob.ListBlobObjects(ctx, bucket, namePrefix, objects)
+		if err != nil {
+			err = fmt.Errorf("ListBlobObjects: %v", err)
+			return
+		}
+
+		return
+	})
+
+	// Parse and verify records.
+	sizes = make(map[blob.Score]uint64)
+	eg.Go(func() (err error) {
+		for o := range objects {
+			var score blob.Score
+			score, err = blob.ParseObjectRecord(o, namePrefix)
+			if err != nil {
+				err = fmt.Errorf("ParseObjectRecord: %v", err)
+				return
+			}
+
+			sizes[score] = o.Size
+		}
+
+		return
+	})
+
+	err = eg.Wait()
+	return
+}
@@ -0,0 +1,485 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/crypto"
+	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/registry"
+	"github.com/jacobsa/comeback/internal/repr"
+	"github.com/jacobsa/comeback/internal/util"
+	"github.com/jacobsa/gcloud/gcs"
+	"github.com/jacobsa/gcloud/gcs/gcsfake"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+	"github.com/jacobsa/timeutil"
+)
+
+func TestStats(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type StatsTest struct {
+	ctx       context.Context
+	bucket    gcs.Bucket
+	blobStore blob.Store
+}
+
+var _ SetUpInterface = &StatsTest{}
+
+func init() { RegisterTestSuite(&StatsTest{}) }
+
+func (t *StatsTest) SetUp(ti *TestInfo) {
+	t.ctx = ti.Ctx
+	t.bucket = gcsfake.NewFakeBucket(timeutil.RealClock(), "some_bucket")
+	t.blobStore = blob.NewExistingScoresStore(
+		util.NewStringSet(),
+		blob.NewGCSStore(t.bucket, "blobs/"))
+}
+
+func (t *StatsTest) storeBlob(b []byte) (s blob.Score) {
+	s, err := t.blobStore.Save(t.ctx, &blob.SaveRequest{Blob: b})
+	AssertEq(nil, err)
+	return
+}
+
+func (t *StatsTest) storeDir(entries ...*fs.FileInfo) (s blob.Score) {
+	b, err := repr.MarshalDir(entries)
+	AssertEq(nil, err)
+
+	s = t.storeBlob(b)
+	return
+}
+
+func (t *StatsTest) size(s blob.Score) uint64 {
+	o, err := t.bucket.StatObject(
+		t.ctx,
+		&gcs.StatObjectRequest{Name: "blobs/" + s.Hex()})
+
+	AssertEq(nil, err)
+	return o.Size
+}
+
+func file(name string, size uint64, scores ...blob.Score) *fs.FileInfo {
+	return &fs.FileInfo{
+		Type:   fs.TypeFile,
+		Name:   name,
+		Size:   size,
+		Scores: scores,
+	}
+}
+
+func dir(name string, s blob.Score) *fs.FileInfo {
+	return &fs.FileInfo{
+		Type:   fs.TypeDirectory,
+		Name:   name,
+		Scores: []blob.Score{s},
+	}
+}
+
+func job(name string, day int, s blob.Score) registry.CompletedJob {
+	return registry.CompletedJob{
+		Name:      name,
+		StartTime: time.Date(2026, time.January, day, 0, 0, 0, 0, time.UTC),
+		Score:     s,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *StatsTest) Distribution() {
+	var d Distribution
+	for _, size := range []uint64{5, 0, 4, 7, 1 << 20, 1} {
+		d.Add(size)
+	}
+
+	ExpectEq(6, d.Count)
+	ExpectEq(5+4+7+1<<20+1, d.Bytes)
+	ExpectThat(
+		d.Buckets,
+		DeepEquals([]Bucket{
+			{MinSize: 0, MaxSize: 1, Count: 1, Bytes: 0},
+			{MinSize: 1, MaxSize: 2, Count: 1, Bytes: 1},
+			{MinSize: 4, MaxSize: 8, Count: 3, Bytes: 16},
+			{MinSize: 1 << 20, MaxSize: 1 << 21, Count: 1, Bytes: 1 << 20},
+		}))
+}
+
+func (t *StatsTest) ScanSnapshot() {
+	a := t.storeBlob([]byte("taco"))
+	b := t.storeBlob([]byte("burrito"))
+
+	sub := t.storeDir(file("x", 4, a), file("y", 11, a, b))
+	link := file("z", 4)
+	link.HardLinkTarget = new(string)
+
+	// The subdirectory appears twice.
+	root := t.storeDir(dir("sub", sub), dir("copy", sub), file("w", 7, b), link)
+
+	s, err := ScanSnapshot(t.ctx, t.blobStore, root)
+	AssertEq(nil, err)
+
+	ExpectEq(4+11+4+11+7, s.Logical)
+	ExpectEq(6, s.Files)
+	ExpectEq(2, s.Dirs)
+	ExpectThat(s.FileChunks, ElementsAre(sorted(a, b)[0], sorted(a, b)[1]))
+	ExpectThat(s.DirListings, ElementsAre(sorted(sub, root)[0], sorted(sub, root)[1]))
+}
+
+func sorted(scores ...blob.Score) []blob.Score {
+	sortScores(scores)
+	return scores
+}
+
+func (t *StatsTest) ScanSnapshot_MissingListing() {
+	root := t.storeDir(dir("sub", blob.ComputeScore([]byte("nope"))))
+
+	_, err := ScanSnapshot(t.ctx, t.blobStore, root)
+	ExpectThat(err, Error(HasSubstr("ReadDir")))
+}
+
+// Return a crypter for caches in tests.
+func (t *StatsTest) cacheCrypter() (c crypto.Crypter) {
+	repoCrypter, err := crypto.NewCrypter(make([]byte, 32))
+	AssertEq(nil, err)
+
+	c, err = NewCrypter(repoCrypter)
+	AssertEq(nil, err)
+	return
+}
+
+// Return the scans that the cache supplies for the supplied roots.
+func scansFromCache(c *Cache, roots ...blob.Score) (scans []*Scan, err error) {
+	err = c.ForEach(roots, func(i int, s *Scan) (err error) {
+		scans = append(scans, s)
+		return
+	})
+
+	return
+}
+
+func (t *StatsTest) Cache() {
+	a := t.storeBlob([]byte("taco"))
+	b := t.storeBlob([]byte("burrito"))
+	c := t.storeBlob([]byte("enchilada"))
+	root0 := t.storeDir(file("x", 4, a), file("y", 7, b))
+	root1 := t.storeDir(file("x", 4, a), file("z", 9, c))
+	root2 := t.storeDir(file("y", 7, b))
+
+	jobs := []registry.CompletedJob{
+		job("j", 1, root0),
+		job("k", 2, root2),
+		job("j", 3, root1),
+	}
+
+	cache := NewCache()
+	var scanned []blob.Score
+	err := cache.Update(t.ctx, t.blobStore, jobs, func(j registry.CompletedJob) {
+		scanned = append(scanned, j.Score)
+	})
+
+	AssertEq(nil, err)
+	ExpectThat(scanned, ElementsAre(root0, root2, root1))
+
+	// The second backup of job j should be recorded relative to the first.
+	e := cache.Entries[root1]
+	AssertNe(nil, e)
+	ExpectTrue(e.HasBase)
+	ExpectEq(root0, e.Base)
+	ExpectThat(e.AddedChunks, ElementsAre(c))
+	ExpectThat(e.RemovedChunks, ElementsAre(b))
+	ExpectThat(e.AddedDirs, ElementsAre(root1))
+	ExpectThat(e.RemovedDirs, ElementsAre(root0))
+
+	// Round trip.
+	crypter := t.cacheCrypter()
+
+	var buf bytes.Buffer
+	AssertEq(nil, SaveCache(&buf, cache, crypter))
+
+	cache, err = LoadCache(&buf, crypter)
+	AssertEq(nil, err)
+
+	// The cache should supply the same scans as scanning afresh.
+	scans, err := scansFromCache(cache, root0, root1, root2)
+	AssertEq(nil, err)
+	AssertEq(3, len(scans))
+
+	for i, root := range []blob.Score{root0, root1, root2} {
+		expected, err := ScanSnapshot(t.ctx, t.blobStore, root)
+		AssertEq(nil, err)
+		ExpectThat(scans[i], DeepEquals(expected), "root %d", i)
+	}
+
+	// Updating again shouldn't touch the blob store.
+	err = cache.Update(t.ctx, nil, jobs, func(j registry.CompletedJob) {
+		AddFailure("Unexpected scan of %s", j.Score.Hex())
+	})
+
+	AssertEq(nil, err)
+}
+
+func (t *StatsTest) Cache_DeletedBase() {
+	a := t.storeBlob([]byte("taco"))
+	b := t.storeBlob([]byte("burrito"))
+	root0 := t.storeDir(file("x", 4, a))
+	root1 := t.storeDir(file("x", 4, a), file("y", 7, b))
+	root2 := t.storeDir(file("y", 7, b))
+
+	cache := NewCache()
+	noScan := func(j registry.CompletedJob) {}
+
+	err := cache.Update(
+		t.ctx,
+		t.blobStore,
+		[]registry.CompletedJob{
+			job("j", 1, root0),
+			job("j", 2, root1),
+			job("j", 3, root2),
+		},
+		noScan)
+
+	AssertEq(nil, err)
+
+	// Forget the middle backup. The last should be recorded relative to the
+	// first, without scanning anything.
+	err = cache.Update(
+		t.ctx,
+		nil,
+		[]registry.CompletedJob{
+			job("j", 1, root0),
+			job("j", 3, root2),
+		},
+		func(j registry.CompletedJob) {
+			AddFailure("Unexpected scan of %s", j.Score.Hex())
+		})
+
+	AssertEq(nil, err)
+	ExpectEq(2, len(cache.Entries))
+	AssertNe(nil, cache.Entries[root2])
+	ExpectEq(root0, cache.Entries[root2].Base)
+
+	scans, err := scansFromCache(cache, root2)
+	AssertEq(nil, err)
+
+	expected, err := ScanSnapshot(t.ctx, t.blobStore, root2)
+	AssertEq(nil, err)
+	ExpectThat(scans[0], DeepEquals(expected))
+}
+
+func (t *StatsTest) Cache_ScanFails() {
+	a := t.storeBlob([]byte("taco"))
+	root0 := t.storeDir(file("x", 4, a))
+	root1 := t.storeDir(dir("sub", blob.ComputeScore([]byte("nope"))))
+
+	cache := NewCache()
+	noScan := func(j registry.CompletedJob) {}
+
+	err := cache.Update(
+		t.ctx,
+		t.blobStore,
+		[]registry.CompletedJob{job("j", 1, root0)},
+		noScan)
+
+	AssertEq(nil, err)
+
+	// A failed scan shouldn't lose what was already cached.
+	err = cache.Update(
+		t.ctx,
+		t.blobStore,
+		[]registry.CompletedJob{job("k", 1, root1), job("j", 2, root0)},
+		noScan)
+
+	ExpectThat(err, Error(HasSubstr(root1.Hex())))
+	ExpectNe(nil, cache.Entries[root0])
+}
+
+func (t *StatsTest) Cache_MissingScan() {
+	_, err := scansFromCache(NewCache(), blob.ComputeScore([]byte("")))
+	ExpectThat(err, Error(HasSubstr("No scan")))
+}
+
+func (t *StatsTest) LoadCache_OldVersion() {
+	crypter := t.cacheCrypter()
+
+	var buf bytes.Buffer
+	AssertEq(nil, SaveCache(&buf, &Cache{
+		Version: cacheVersion - 1,
+		Entries: map[blob.Score]*CacheEntry{blob.Score{}: &CacheEntry{}},
+	}, crypter))
+
+	c, err := LoadCache(&buf, crypter)
+	AssertEq(nil, err)
+	ExpectEq(cacheVersion, c.Version)
+	ExpectEq(0, len(c.Entries))
+}
+
+func (t *StatsTest) LoadCache_WrongKey() {
+	var buf bytes.Buffer
+	AssertEq(nil, SaveCache(&buf, NewCache(), t.cacheCrypter()))
+
+	repoCrypter, err := crypto.NewCrypter(bytes.Repeat([]byte{1}, 32))
+	AssertEq(nil, err)
+
+	crypter, err := NewCrypter(repoCrypter)
+	AssertEq(nil, err)
+
+	_, err = LoadCache(&buf, crypter)
+	ExpectThat(err, Error(HasSubstr("Decrypt")))
+}
+
+func (t *StatsTest) LoadCache_Plaintext() {
+	_, err := LoadCache(bytes.NewReader([]byte("taco")), t.cacheCrypter())
+	ExpectThat(err, Error(HasSubstr("Not an encrypted stats cache")))
+}
+
+func (t *StatsTest) Compute() {
+	shared := t.storeBlob([]byte("shared by everything"))
+	docs1 := t.storeBlob([]byte("docs, first version"))
+	docs2 := t.storeBlob([]byte("docs, second version, which is longer"))
+	photo := t.storeBlob([]byte("a photo"))
+	garbage := t.storeBlob([]byte("nobody wants this"))
+
+	docsRoot1 := t.storeDir(file("s", 3, shared), file("d", 5, docs1))
+	docsRoot2 := t.storeDir(file("s", 3, shared), file("d", 8, docs2))
+	photosRoot := t.storeDir(file("s", 3, shared), file("p", 2, photo))
+
+	// List sizes, then drop one to simulate a missing blob.
+	sizes, err := ListBlobSizes(t.ctx, t.bucket, "blobs/")
+	AssertEq(nil, err)
+	AssertEq(8, len(sizes))
+
+	for s, size := range sizes {
+		ExpectEq(t.size(s), size)
+	}
+
+	delete(sizes, photo)
+
+	jobs := []registry.CompletedJob{
+		job("docs", 1, docsRoot1),
+		job("photos", 2, photosRoot),
+		job("docs", 3, docsRoot2),
+	}
+
+	scans := make(ScanMap)
+	for _, j := range jobs {
+		scans[j.Score], err = ScanSnapshot(t.ctx, t.blobStore, j.Score)
+		AssertEq(nil, err)
+	}
+
+	r, err := Compute(jobs, scans, sizes)
+	AssertEq(nil, err)
+
+	// Snapshots
+	AssertEq(3, len(r.Snapshots))
+
+	s := r.Snapshots[0]
+	ExpectThat(s.Job, DeepEquals(jobs[0]))
+	ExpectEq(8, s.Logical)
+	ExpectEq(2, s.Files)
+	ExpectEq(0, s.Dirs)
+	ExpectEq(t.size(docs1)+t.size(docsRoot1), s.Unique)
+	ExpectEq(t.size(shared), s.Shared)
+	ExpectEq(0, s.Missing)
+	ExpectEq(2, s.FileChunks.Count)
+	ExpectEq(1, s.DirListings.Count)
+
+	s = r.Snapshots[1]
+	ExpectThat(s.Job, DeepEquals(jobs[1]))
+	ExpectEq(t.size(photosRoot), s.Unique)
+	ExpectEq(t.size(shared), s.Shared)
+	ExpectEq(1, s.Missing)
+	ExpectEq(1, s.FileChunks.Count)
+
+	// Jobs
+	AssertEq(2, len(r.Jobs))
+
+	j := r.Jobs[0]
+	ExpectEq("docs", j.Name)
+	ExpectEq(2, j.Snapshots)
+	ExpectEq(8+11, j.Logical)
+	ExpectEq(4, j.Files)
+	ExpectEq(
+		t.size(docs1)+t.size(docs2)+t.size(docsRoot1)+t.size(docsRoot2),
+		j.Unique)
+	ExpectEq(t.size(shared), j.Shared)
+	ExpectEq(3, j.FileChunks.Count)
+	ExpectEq(2, j.DirListings.Count)
+
+	j = r.Jobs[1]
+	ExpectEq("photos", j.Name)
+	ExpectEq(1, j.Snapshots)
+	ExpectEq(t.size(photosRoot), j.Unique)
+	ExpectEq(t.size(shared), j.Shared)
+	ExpectEq(1, j.Missing)
+
+	// Totals
+	ExpectEq(8+11+5, r.Total.Logical)
+	ExpectEq(6, r.Total.Files)
+	ExpectEq(
+		t.size(docs1)+t.size(docs2)+t.size(docsRoot1)+t.size(docsRoot2)+t.size(photosRoot),
+		r.Total.Unique)
+	ExpectEq(t.size(shared), r.Total.Shared)
+	ExpectEq(1, r.Total.Missing)
+	ExpectEq(3, r.Total.FileChunks.Count)
+	ExpectEq(3, r.Total.DirListings.Count)
+
+	ExpectEq(1, r.Garbage.Count)
+	ExpectEq(t.size(garbage), r.Garbage.Bytes)
+	ExpectEq(r.Total.Physical()+t.size(garbage), r.Stored())
+}
+
+func (t *StatsTest) Compute_SameRootTwice() {
+	a := t.storeBlob([]byte("taco"))
+	root := t.storeDir(file("x", 4, a))
+
+	sizes, err := ListBlobSizes(t.ctx, t.bucket, "blobs/")
+	AssertEq(nil, err)
+
+	scan, err := ScanSnapshot(t.ctx, t.blobStore, root)
+	AssertEq(nil, err)
+
+	jobs := []registry.CompletedJob{job("j", 1, root), job("j", 2, root)}
+	r, err := Compute(jobs, ScanMap{root: scan}, sizes)
+	AssertEq(nil, err)
+
+	// Each backup shares everything with the other, but the job as a whole
+	// shares nothing.
+	ExpectEq(0, r.Snapshots[0].Unique)
+	ExpectEq(t.size(a)+t.size(root), r.Snapshots[0].Shared)
+	ExpectEq(t.size(a)+t.size(root), r.Jobs[0].Unique)
+	ExpectEq(8, r.Jobs[0].Logical)
+	ExpectEq(8, r.Total.Logical)
+	ExpectEq(t.size(a)+t.size(root), r.Total.Physical())
+	ExpectEq(0, r.Garbage.Count)
+}
+
+func (t *StatsTest) Compute_MissingScan() {
+	jobs := []registry.CompletedJob{job("j", 1, blob.ComputeScore([]byte("")))}
+
+	_, err := Compute(jobs, ScanMap{}, nil)
+	ExpectThat(err, Error(HasSubstr("No scan")))
+}
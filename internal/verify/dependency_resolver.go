@@ -106,7 +106,7 @@ func (dr *dependencyResolver) FindDependencies(
 	}
 
 	// Load the blob contents.
-	contents, err := dr.blobStore.Load(ctx, n.Score)
+	contents, err := dr.blobStore.Load(ctx, n.Score, blob.Kind_Dir)
 	if err != nil {
 		err = fmt.Errorf("Load(%s): %v", n.Score.Hex(), err)
 		return
@@ -199,7 +199,7 @@ func (t *DirsTest) NodeVisitedOnPastRun_ScorePresent() {
 
 func (t *DirsTest) CallsBlobStore() {
 	// Load
-	ExpectCall(t.blobStore, "Load")(Any(), t.score).
+	ExpectCall(t.blobStore, "Load")(Any(), t.score, blob.Kind_Dir).
 		WillOnce(Return(nil, errors.New("")))
 
 	// Call
@@ -208,7 +208,7 @@ func (t *DirsTest) CallsBlobStore() {
 
 func (t *DirsTest) BlobStoreReturnsError() {
 	// Load
-	ExpectCall(t.blobStore, "Load")(Any(), Any()).
+	ExpectCall(t.blobStore, "Load")(Any(), Any(), Any()).
 		WillOnce(Return(nil, errors.New("taco")))
 
 	// Call
@@ -227,7 +227,7 @@ func (t *DirsTest) BlobIsJunk() {
 	t.node = makeNode(true, t.score)
 
 	// Load
-	ExpectCall(t.blobStore, "Load")(Any(), Any()).
+	ExpectCall(t.blobStore, "Load")(Any(), Any(), Any()).
 		WillOnce(Return(t.contents, nil))
 
 	// Call
@@ -259,7 +259,7 @@ func (t *DirsTest) UnknownEntryType() {
 	t.node = makeNode(true, t.score)
 
 	// Load
-	ExpectCall(t.blobStore, "Load")(Any(), Any()).
+	ExpectCall(t.blobStore, "Load")(Any(), Any(), Any()).
 		WillOnce(Return(t.contents, nil))
 
 	// Call
@@ -291,7 +291,7 @@ func (t *DirsTest) SymlinkWithScores() {
 	t.node = makeNode(true, t.score)
 
 	// Load
-	ExpectCall(t.blobStore, "Load")(Any(), Any()).
+	ExpectCall(t.blobStore, "Load")(Any(), Any(), Any()).
 		WillOnce(Return(t.contents, nil))
 
 	// Call
@@ -305,7 +305,7 @@ func (t *DirsTest) SymlinkWithScores() {
 
 func (t *DirsTest) ReturnsAppropriateAdjacentNodesAndRecords() {
 	// Load
-	ExpectCall(t.blobStore, "Load")(Any(), Any()).
+	ExpectCall(t.blobStore, "Load")(Any(), Any(), Any()).
 		WillOnce(Return(t.contents, nil))
 
 	// Call
@@ -64,7 +64,7 @@ func (v *visitor) Visit(ctx context.Context, untyped dag.Node) (err error) {
 
 	// Make sure we can load the blob contents. We rely on the blob store to
 	// verify the content against the score.
-	_, err = v.blobStore.Load(ctx, n.Score)
+	_, err = v.blobStore.Load(ctx, n.Score, blob.Kind_File)
 	if err != nil {
 		err = fmt.Errorf("Load(%s): %v", n.Score.Hex(), err)
 		return
@@ -35,6 +35,7 @@ import (
 
 	"github.com/jacobsa/comeback/internal/blob"
 	"github.com/jacobsa/comeback/internal/fs"
+	"github.com/jacobsa/comeback/internal/registry"
 	"github.com/jacobsa/comeback/internal/restore"
 	"github.com/jacobsa/comeback/internal/save"
 	"github.com/jacobsa/comeback/internal/state"
@@ -185,14 +186,25 @@ func (t *WiringTest) WrongPassword() {
 	var err error
 
 	// Create a registry in the bucket with the "official" password.
-	_, _, err = wiring.MakeRegistryAndCrypter(t.ctx, password, t.bucket)
+	_, _, err = wiring.InitRegistryAndCrypter(
+		t.ctx,
+		password,
+		t.bucket,
+		registry.DefaultKDF,
+		false)
+
 	AssertEq(nil, err)
 
-	// Using a different password to do the same should fail.
+	// Using a different password to open it should fail.
 	_, _, err = wiring.MakeRegistryAndCrypter(t.ctx, wrongPassword, t.bucket)
 	ExpectThat(err, Error(HasSubstr("password is incorrect")))
 }
 
+func (t *WiringTest) NoRepository() {
+	_, _, err := wiring.MakeRegistryAndCrypter(t.ctx, password, t.bucket)
+	ExpectThat(err, Error(HasSubstr("comeback init")))
+}
+
 ////////////////////////////////////////////////////////////////////////
 // Saving and restoring
 ////////////////////////////////////////////////////////////////////////
@@ -204,9 +216,16 @@ type SaveAndRestoreTest struct {
 
 	scoreMap state.ScoreMap
 
+	// The password used when saving (rather than restoring).
+	savePassword string
+
 	// Temporary directories for saving from and restoring to.
 	src string
 	dst string
+
+	// A temporary directory holding the restore journal, which must live
+	// outside of t.dst.
+	journalDir string
 }
 
 var _ SetUpInterface = &SaveAndRestoreTest{}
@@ -218,8 +237,19 @@ func (t *SaveAndRestoreTest) SetUp(ti *TestInfo) {
 	var err error
 	t.commonTest.SetUp(ti)
 
+	// Create a repository in the bucket.
+	_, _, err = wiring.InitRegistryAndCrypter(
+		t.ctx,
+		password,
+		t.bucket,
+		registry.DefaultKDF,
+		false)
+
+	AssertEq(nil, err)
+
 	// Create a score map.
 	t.scoreMap = state.NewScoreMap()
+	t.savePassword = password
 
 	// Create the temporary directories.
 	t.src, err = ioutil.TempDir("", "comeback_integration_test")
@@ -227,26 +257,39 @@ func (t *SaveAndRestoreTest) SetUp(ti *TestInfo) {
 
 	t.dst, err = ioutil.TempDir("", "comeback_integration_test")
 	AssertEq(nil, err)
+
+	t.journalDir, err = ioutil.TempDir("", "comeback_integration_test")
+	AssertEq(nil, err)
 }
 
 func (t *SaveAndRestoreTest) TearDown() {
 	// Remove the temporary directories.
 	ExpectEq(nil, os.RemoveAll(t.src))
 	ExpectEq(nil, os.RemoveAll(t.dst))
+	ExpectEq(nil, os.RemoveAll(t.journalDir))
+}
+
+// The path to the journal used when restoring into t.dst.
+func (t *SaveAndRestoreTest) journalPath() string {
+	return path.Join(t.journalDir, "journal")
 }
 
 // Make a backup of the contents of t.src into t.bucket, returning a score for
 // the root listing.
 func (t *SaveAndRestoreTest) save() (score blob.Score, err error) {
 	// Create the crypter.
-	_, crypter, err := wiring.MakeRegistryAndCrypter(t.ctx, password, t.bucket)
+	_, crypter, err := wiring.MakeRegistryAndCrypter(
+		t.ctx,
+		t.savePassword,
+		t.bucket)
+
 	if err != nil {
 		err = fmt.Errorf("MakeRegistryAndCrypter: %v", err)
 		return
 	}
 
 	// Save the source directory.
-	score, err = save.Save(
+	score, _, err = save.Save(
 		t.ctx,
 		t.src,
 		t.exclusions,
@@ -268,8 +311,29 @@ func (t *SaveAndRestoreTest) save() (score blob.Score, err error) {
 
 // Restore a backup with the given root listing into t.dst.
 func (t *SaveAndRestoreTest) restore(score blob.Score) (err error) {
+	err = t.restoreFiltered(score, restore.Filter{})
+	return
+}
+
+// Restore the portion of a backup with the given root listing selected by the
+// supplied filter into t.dst.
+func (t *SaveAndRestoreTest) restoreFiltered(
+	score blob.Score,
+	filter restore.Filter) (err error) {
+	err = t.restoreWith(score, filter, restore.ConflictFail)
+	return
+}
+
+// Restore the portion of a backup with the given root listing selected by the
+// supplied filter into t.dst, dealing with existing entries according to the
+// given policy.
+func (t *SaveAndRestoreTest) restoreWith(
+	score blob.Score,
+	filter restore.Filter,
+	policy restore.ConflictPolicy) (err error) {
 	// Create the crypter.
 	_, crypter, err := wiring.MakeRegistryAndCrypter(t.ctx, password, t.bucket)
+
 	if err != nil {
 		err = fmt.Errorf("MakeRegistryAndCrypter: %v", err)
 		return
@@ -279,7 +343,11 @@ func (t *SaveAndRestoreTest) restore(score blob.Score) (err error) {
 	err = restore.Restore(
 		t.ctx,
 		t.dst,
+		t.journalPath(),
 		score,
+		filter,
+		policy,
+		false,
 		t.bucket,
 		objectNamePrefix,
 		crypter,
@@ -497,6 +565,248 @@ func (t *SaveAndRestoreTest) MultipleFilesAndDirs() {
 	ExpectEq("b", string(b))
 }
 
+func (t *SaveAndRestoreTest) PartialRestore() {
+	var b []byte
+	var entries []os.FileInfo
+	var err error
+
+	// Create.
+	AssertEq(nil, os.MkdirAll(path.Join(t.src, "foo/bar"), 0700))
+	AssertEq(nil, os.MkdirAll(path.Join(t.src, "foo/baz"), 0700))
+	AssertEq(nil, os.MkdirAll(path.Join(t.src, "qux"), 0700))
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.src, "foo/bar/a.txt"), []byte("a"), 0400))
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.src, "foo/bar/b.jpg"), []byte("b"), 0400))
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.src, "foo/baz/c.jpg"), []byte("c"), 0400))
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.src, "qux/d.jpg"), []byte("d"), 0400))
+
+	// Save, then restore only JPEGs from one directory.
+	score, err := t.save()
+	AssertEq(nil, err)
+
+	err = t.restoreFiltered(
+		score,
+		restore.Filter{
+			Paths:    []string{"foo/bar"},
+			Includes: []string{"*.jpg"},
+		})
+
+	AssertEq(nil, err)
+
+	// Only the selected file should have been written, at its original relative
+	// path.
+	entries, err = ioutil.ReadDir(t.dst)
+	AssertEq(nil, err)
+	AssertEq(1, len(entries))
+	ExpectEq("foo", entries[0].Name())
+
+	entries, err = ioutil.ReadDir(path.Join(t.dst, "foo"))
+	AssertEq(nil, err)
+	AssertEq(1, len(entries))
+	ExpectEq("bar", entries[0].Name())
+
+	entries, err = ioutil.ReadDir(path.Join(t.dst, "foo/bar"))
+	AssertEq(nil, err)
+	AssertEq(1, len(entries))
+	ExpectEq("b.jpg", entries[0].Name())
+
+	b, err = ioutil.ReadFile(path.Join(t.dst, "foo/bar/b.jpg"))
+	AssertEq(nil, err)
+	ExpectEq("b", string(b))
+}
+
+func (t *SaveAndRestoreTest) PartialRestore_MissingPath() {
+	// Save.
+	score, err := t.save()
+	AssertEq(nil, err)
+
+	// Restoring a path that doesn't exist should fail.
+	err = t.restoreFiltered(score, restore.Filter{Paths: []string{"taco"}})
+	ExpectThat(err, Error(HasSubstr("not found")))
+	ExpectThat(err, Error(HasSubstr("taco")))
+}
+
+func (t *SaveAndRestoreTest) PartialRestore_ExcludedPath() {
+	// Create.
+	AssertEq(nil, os.MkdirAll(path.Join(t.src, "foo/bar"), 0700))
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.src, "foo/bar/a.jpg"), []byte("a"), 0400))
+
+	// Save.
+	score, err := t.save()
+	AssertEq(nil, err)
+
+	// Selecting a path that is excluded, directly or by way of its parent,
+	// should say so rather than claiming it doesn't exist.
+	err = t.restoreFiltered(
+		score,
+		restore.Filter{
+			Paths:    []string{"foo/bar/a.jpg"},
+			Excludes: []string{"*.jpg"},
+		})
+
+	ExpectThat(err, Error(HasSubstr("foo/bar/a.jpg")))
+	ExpectThat(err, Error(HasSubstr("excluded by pattern \"*.jpg\"")))
+
+	err = t.restoreFiltered(
+		score,
+		restore.Filter{
+			Paths:    []string{"foo/bar/a.jpg"},
+			Excludes: []string{"foo/bar"},
+		})
+
+	ExpectThat(err, Error(HasSubstr("excluded by pattern \"foo/bar\"")))
+
+	// Nothing should have been written.
+	entries, err := ioutil.ReadDir(t.dst)
+	AssertEq(nil, err)
+	ExpectEq(0, len(entries))
+}
+
+func (t *SaveAndRestoreTest) NonEmptyDestination() {
+	// Save.
+	score, err := t.save()
+	AssertEq(nil, err)
+
+	// Put something in the destination.
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.dst, "foo"), []byte("taco"), 0600))
+
+	// By default, restoring should refuse to touch it.
+	err = t.restore(score)
+	ExpectThat(err, Error(HasSubstr("not empty")))
+
+	b, err := ioutil.ReadFile(path.Join(t.dst, "foo"))
+	AssertEq(nil, err)
+	ExpectEq("taco", string(b))
+}
+
+// Write a journal for an interrupted restore of the given score with the
+// fail policy, which had begun writing the given paths.
+func (t *SaveAndRestoreTest) writeJournal(score blob.Score, begun ...string) {
+	s := fmt.Sprintf(
+		"comeback restore journal v1 %s %s\n",
+		score.Hex(),
+		restore.ConflictFail)
+
+	for _, p := range begun {
+		s += fmt.Sprintf("begin %q\n", p)
+	}
+
+	AssertEq(nil, ioutil.WriteFile(t.journalPath(), []byte(s), 0600))
+}
+
+func (t *SaveAndRestoreTest) ResumedRestore_ReplacesJournaledPath() {
+	// Save.
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.src, "foo"), []byte("a"), 0600))
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.src, "bar"), []byte("b"), 0600))
+
+	score, err := t.save()
+	AssertEq(nil, err)
+
+	// Simulate an interrupted restore that had begun writing foo.
+	t.writeJournal(score, "foo")
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.dst, "foo"), []byte("x"), 0600))
+
+	// Resuming should replace foo despite the fail policy.
+	err = t.restore(score)
+	AssertEq(nil, err)
+
+	b, err := ioutil.ReadFile(path.Join(t.dst, "foo"))
+	AssertEq(nil, err)
+	ExpectEq("a", string(b))
+
+	b, err = ioutil.ReadFile(path.Join(t.dst, "bar"))
+	AssertEq(nil, err)
+	ExpectEq("b", string(b))
+}
+
+func (t *SaveAndRestoreTest) ResumedRestore_KeepsPolicyForOtherPaths() {
+	// Save.
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.src, "foo"), []byte("a"), 0600))
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.src, "bar"), []byte("b"), 0600))
+
+	score, err := t.save()
+	AssertEq(nil, err)
+
+	// Simulate an interrupted restore that had begun writing foo, and a file
+	// at bar that the earlier attempt never touched.
+	t.writeJournal(score, "foo")
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.dst, "bar"), []byte("y"), 0600))
+
+	// Resuming should still refuse to touch bar.
+	err = t.restore(score)
+	ExpectThat(err, Error(HasSubstr("bar")))
+	ExpectThat(err, Error(HasSubstr("already exists")))
+
+	b, err := ioutil.ReadFile(path.Join(t.dst, "bar"))
+	AssertEq(nil, err)
+	ExpectEq("y", string(b))
+}
+
+func (t *SaveAndRestoreTest) JournalIsOutsideDestination() {
+	// Save and restore.
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.src, "foo"), []byte("a"), 0600))
+
+	score, err := t.save()
+	AssertEq(nil, err)
+
+	err = t.restore(score)
+	AssertEq(nil, err)
+
+	// The destination should contain only the restored file.
+	entries, err := ioutil.ReadDir(t.dst)
+	AssertEq(nil, err)
+	AssertEq(1, len(entries))
+	ExpectEq("foo", entries[0].Name())
+}
+
+func (t *SaveAndRestoreTest) RepeatedRestore() {
+	var b []byte
+	var fi os.FileInfo
+	var err error
+
+	// Create.
+	AssertEq(nil, os.MkdirAll(path.Join(t.src, "foo"), 0700))
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.src, "foo/bar"), []byte("a"), 0600))
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.src, "baz"), []byte("b"), 0600))
+
+	// Save and restore.
+	score, err := t.save()
+	AssertEq(nil, err)
+
+	err = t.restore(score)
+	AssertEq(nil, err)
+
+	// Damage one of the files and add another that isn't in the backup.
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.dst, "baz"), []byte("c"), 0600))
+	AssertEq(nil, ioutil.WriteFile(path.Join(t.dst, "qux"), []byte("d"), 0600))
+
+	fi, err = os.Stat(path.Join(t.dst, "foo/bar"))
+	AssertEq(nil, err)
+	inode := fi.Sys().(*syscall.Stat_t).Ino
+
+	// Restore again over the top.
+	err = t.restoreWith(
+		score,
+		restore.Filter{},
+		restore.ConflictOverwriteIfDifferent)
+
+	AssertEq(nil, err)
+
+	// The undamaged file should have been left alone.
+	fi, err = os.Stat(path.Join(t.dst, "foo/bar"))
+	AssertEq(nil, err)
+	ExpectEq(inode, fi.Sys().(*syscall.Stat_t).Ino)
+
+	// The damaged one should have been fixed.
+	b, err = ioutil.ReadFile(path.Join(t.dst, "baz"))
+	AssertEq(nil, err)
+	ExpectEq("b", string(b))
+
+	// The extra file should still be there.
+	b, err = ioutil.ReadFile(path.Join(t.dst, "qux"))
+	AssertEq(nil, err)
+	ExpectEq("d", string(b))
+}
+
 func (t *SaveAndRestoreTest) ResultScoreIsStable() {
 	var err error
 
@@ -523,6 +833,75 @@ func (t *SaveAndRestoreTest) ResultScoreIsStable() {
 	ExpectEq(score0, score2)
 }
 
+func (t *SaveAndRestoreTest) WriteOnlyKeySlot() {
+	const contents = "taco"
+	var err error
+
+	// Use a fresh bucket set up for envelope encryption, and add a write-only
+	// slot to save with.
+	t.bucket = gcsfake.NewFakeBucket(timeutil.RealClock(), "")
+	_, _, err = wiring.InitRegistryAndCrypter(
+		t.ctx,
+		password,
+		t.bucket,
+		registry.DefaultKDF,
+		true)
+
+	AssertEq(nil, err)
+
+	err = wiring.AddWriteOnlyKeySlot(
+		t.ctx,
+		t.bucket,
+		password,
+		"laptop",
+		"write only")
+
+	AssertEq(nil, err)
+	t.savePassword = "write only"
+
+	// Save twice, with the second save deduplicating against the first.
+	err = ioutil.WriteFile(path.Join(t.src, "foo"), []byte(contents), 0400)
+	AssertEq(nil, err)
+
+	score0, err := t.save()
+	AssertEq(nil, err)
+
+	score1, err := t.save()
+	AssertEq(nil, err)
+	ExpectEq(score0, score1)
+
+	// The write-only client can't restore.
+	_, crypter, err := wiring.MakeRegistryAndCrypter(
+		t.ctx,
+		t.savePassword,
+		t.bucket)
+
+	AssertEq(nil, err)
+
+	err = restore.Restore(
+		t.ctx,
+		t.dst,
+		t.journalPath(),
+		score0,
+		restore.Filter{},
+		restore.ConflictFail,
+		false,
+		t.bucket,
+		objectNamePrefix,
+		crypter,
+		gDiscardLogger)
+
+	ExpectThat(err, Error(HasSubstr("write-only")))
+
+	// But the main password can.
+	err = t.restore(score0)
+	AssertEq(nil, err)
+
+	b, err := ioutil.ReadFile(path.Join(t.dst, "foo"))
+	AssertEq(nil, err)
+	ExpectEq(contents, string(b))
+}
+
 func (t *SaveAndRestoreTest) HardLinks() {
 	const contents = "taco"
 	var err error
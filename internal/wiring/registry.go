@@ -17,7 +17,6 @@ package wiring
 
 import (
 	"context"
-	"crypto/sha256"
 	"fmt"
 
 	"github.com/jacobsa/comeback/internal/crypto"
@@ -25,22 +24,42 @@ import (
 	"github.com/jacobsa/gcloud/gcs"
 )
 
-// Create a registry for the supplied bucket, given the supplied crypto key
-// password.
+// Create a repository in the supplied bucket, protected by the supplied
+// crypto key password using the given KDF, and return a registry and crypter
+// for it. If envelope is set, the repository is set up for envelope encryption
+// so that write-only key slots can be added.
+func InitRegistryAndCrypter(
+	ctx context.Context,
+	password string,
+	bucket gcs.Bucket,
+	kdf registry.KDF,
+	envelope bool) (r registry.Registry, crypter crypto.Crypter, err error) {
+	r, crypter, err = registry.InitGCSRegistry(
+		ctx,
+		bucket,
+		password,
+		registry.NewBucketOptions{
+			KDF:      kdf,
+			Envelope: envelope,
+		})
+
+	if err != nil {
+		err = fmt.Errorf("InitGCSRegistry: %v", err)
+		return
+	}
+
+	return
+}
+
+// Create a registry for the repository in the supplied bucket, given the
+// supplied crypto key password. The bucket must already contain a repository
+// created by InitRegistryAndCrypter.
 func MakeRegistryAndCrypter(
 	ctx context.Context,
 	password string,
 	bucket gcs.Bucket) (r registry.Registry, crypter crypto.Crypter, err error) {
-	// Derive a crypto key from the password using PBKDF2, recommended for use by
-	// NIST Special Publication 800-132. The latter says that PBKDF2 is approved
-	// for use with HMAC and any approved hash function. Special Publication
-	// 800-107 lists SHA-256 as an approved hash function.
-	const pbkdf2Iters = 4096
-	const keyLen = 32 // Minimum key length for AES-SIV
-	keyDeriver := crypto.NewPbkdf2KeyDeriver(pbkdf2Iters, keyLen, sha256.New)
-
 	// Create the registry and crypter.
-	r, crypter, err = registry.NewGCSRegistry(ctx, bucket, password, keyDeriver)
+	r, crypter, err = registry.NewGCSRegistry(ctx, bucket, password)
 	if err != nil {
 		err = fmt.Errorf("NewGCSRegistry: %v", err)
 		return
@@ -48,3 +67,132 @@ func MakeRegistryAndCrypter(
 
 	return
 }
+
+// Change the password for the supplied bucket without re-encrypting anything
+// but the marker.
+func ChangePassword(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	oldPassword string,
+	newPassword string) (err error) {
+	err = registry.ChangePassword(
+		ctx,
+		bucket,
+		oldPassword,
+		newPassword,
+		registry.DefaultKDF)
+
+	if err != nil {
+		err = fmt.Errorf("ChangePassword: %v", err)
+		return
+	}
+
+	return
+}
+
+// Add a key slot with the given label that allows newPassword to unlock the
+// supplied bucket. password must unlock an existing slot.
+func AddKeySlot(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	password string,
+	label string,
+	newPassword string) (err error) {
+	err = registry.AddKeySlot(
+		ctx,
+		bucket,
+		password,
+		label,
+		newPassword,
+		registry.DefaultKDF)
+
+	if err != nil {
+		err = fmt.Errorf("AddKeySlot: %v", err)
+		return
+	}
+
+	return
+}
+
+// Add a write-only key slot with the given label, allowing newPassword to be
+// used to save backups to the supplied bucket but not to read them.
+func AddWriteOnlyKeySlot(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	password string,
+	label string,
+	newPassword string) (err error) {
+	err = registry.AddWriteOnlyKeySlot(
+		ctx,
+		bucket,
+		password,
+		label,
+		newPassword,
+		registry.DefaultKDF)
+
+	if err != nil {
+		err = fmt.Errorf("AddWriteOnlyKeySlot: %v", err)
+		return
+	}
+
+	return
+}
+
+// Add a key slot with the given label holding a newly generated recovery key,
+// which is returned.
+func AddRecoveryKey(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	password string,
+	label string) (recoveryKey string, err error) {
+	recoveryKey, err = registry.AddRecoveryKey(
+		ctx,
+		bucket,
+		password,
+		label,
+		registry.DefaultKDF)
+
+	if err != nil {
+		err = fmt.Errorf("AddRecoveryKey: %v", err)
+		return
+	}
+
+	return
+}
+
+// Remove the key slot with the given label.
+func RevokeKeySlot(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	password string,
+	label string) (err error) {
+	err = registry.RevokeKeySlot(
+		ctx,
+		bucket,
+		password,
+		label,
+		registry.DefaultKDF)
+
+	if err != nil {
+		err = fmt.Errorf("RevokeKeySlot: %v", err)
+		return
+	}
+
+	return
+}
+
+// Re-wrap the bucket's master key for the slot unlocked by password using the
+// given KDF.
+func UpgradeKDF(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	password string,
+	kdf registry.KDF) (err error) {
+	err = registry.UpgradeKDF(ctx, bucket, password, kdf)
+	if err != nil {
+		err = fmt.Errorf("UpgradeKDF: %v", err)
+		return
+	}
+
+	return
+}
@@ -17,18 +17,139 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 	"time"
+
+	"github.com/jacobsa/comeback/internal/registry"
 )
 
 var cmdList = &Command{
 	Name: "list",
-	Run:  runList,
+}
+
+var fListJob = cmdList.Flags.String(
+	"job",
+	"",
+	"If set, list only backups of the job with this name.")
+
+var fListHost = cmdList.Flags.String(
+	"host",
+	"",
+	"If set, list only backups made on the host with this name.")
+
+var fListLong = cmdList.Flags.Bool(
+	"l",
+	false,
+	"Also print the base path, comeback version, and warning count.")
+
+var fListJSON = cmdList.Flags.Bool(
+	"json",
+	false,
+	"Print a JSON object for each backup, one per line.")
+
+var fListTags stringsFlag
+
+func init() {
+	cmdList.Flags.Var(
+		&fListTags,
+		"tag",
+		"If set, list only backups with this tag. May be repeated, in which "+
+			"case backups must have all of the tags.")
+
+	cmdList.Run = runList // Break flag-related dependency loop.
+}
+
+// Does the supplied backup satisfy the filters set by flags?
+func listFilterMatches(j *registry.CompletedJob) bool {
+	if *fListJob != "" && j.Name != *fListJob {
+		return false
+	}
+
+	if *fListHost != "" && j.Hostname != *fListHost {
+		return false
+	}
+
+	for _, want := range fListTags {
+		found := false
+		for _, t := range j.Tags {
+			if t == want {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// The JSON representation of a backup.
+type jsonJob struct {
+	StartTime     time.Time  `json:"start_time"`
+	EndTime       *time.Time `json:"end_time,omitempty"`
+	Name          string     `json:"name"`
+	Score         string     `json:"score"`
+	Hostname      string     `json:"hostname,omitempty"`
+	BasePath      string     `json:"base_path,omitempty"`
+	Version       string     `json:"comeback_version,omitempty"`
+	FilesScanned  uint64     `json:"files_scanned"`
+	DirsScanned   uint64     `json:"dirs_scanned"`
+	BytesScanned  uint64     `json:"bytes_scanned"`
+	BlobsUploaded uint64     `json:"blobs_uploaded"`
+	BytesUploaded uint64     `json:"bytes_uploaded"`
+	Warnings      uint64     `json:"warnings"`
+	Tags          []string   `json:"tags,omitempty"`
+}
+
+func makeJSONJob(j *registry.CompletedJob) (jj jsonJob) {
+	jj = jsonJob{
+		StartTime:     j.StartTime,
+		Name:          j.Name,
+		Score:         j.Score.Hex(),
+		Hostname:      j.Hostname,
+		BasePath:      j.BasePath,
+		Version:       j.Version,
+		FilesScanned:  j.FilesScanned,
+		DirsScanned:   j.DirsScanned,
+		BytesScanned:  j.BytesScanned,
+		BlobsUploaded: j.BlobsUploaded,
+		BytesUploaded: j.BytesUploaded,
+		Warnings:      j.Warnings,
+		Tags:          j.Tags,
+	}
+
+	if !j.EndTime.IsZero() {
+		jj.EndTime = &j.EndTime
+	}
+
+	return
+}
+
+// Return the supplied string, or a placeholder if it's empty, as is the case
+// for fields missing from older records.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
 }
 
 func runList(ctx context.Context, args []string) (err error) {
+	if len(args) != 0 {
+		err = fmt.Errorf(
+			"Usage: %s list [--job name] [--host name] [--tag tag ...] [-l] [--json]",
+			os.Args[0])
+		return
+	}
+
 	// Ask the registry for a list.
 	registry := getRegistry(ctx)
 	jobs, err := registry.ListBackups(ctx)
@@ -37,6 +158,24 @@ func runList(ctx context.Context, args []string) (err error) {
 		return
 	}
 
+	// Special case: JSON output.
+	if *fListJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for i := range jobs {
+			if !listFilterMatches(&jobs[i]) {
+				continue
+			}
+
+			err = enc.Encode(makeJSONJob(&jobs[i]))
+			if err != nil {
+				err = fmt.Errorf("Encode: %v", err)
+				return
+			}
+		}
+
+		return
+	}
+
 	// Print each.
 	const minwidth = 0
 	const tabwidth = 8
@@ -48,16 +187,60 @@ func runList(ctx context.Context, args []string) (err error) {
 	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
 
 	fmt.Fprintln(w)
-	fmt.Fprintln(w, "Start time\tJob name\tScore")
+	fmt.Fprint(w, "Start time\tJob name\tScore\tHost\tDuration\tFiles\tScanned\tUploaded\tTags")
+	if *fListLong {
+		fmt.Fprint(w, "\tBase path\tVersion\tWarnings")
+	}
+
+	fmt.Fprintln(w)
+
+	for i := range jobs {
+		job := &jobs[i]
+		if !listFilterMatches(job) {
+			continue
+		}
+
+		// Older records have no statistics.
+		duration := "-"
+		files := "-"
+		scanned := "-"
+		uploaded := "-"
+		if !job.EndTime.IsZero() {
+			duration = job.Duration().Round(time.Second).String()
+			files = fmt.Sprint(job.FilesScanned)
+			scanned = formatBytes(job.BytesScanned)
+			uploaded = formatBytes(job.BytesUploaded)
+		}
 
-	for _, job := range jobs {
 		fmt.Fprintf(
 			w,
-			"%s\t%s\t%s\n",
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
 			job.StartTime.Format(time.RFC3339Nano),
 			job.Name,
 			job.Score.Hex(),
+			orDash(job.Hostname),
+			duration,
+			files,
+			scanned,
+			uploaded,
+			orDash(strings.Join(job.Tags, ",")),
 		)
+
+		if *fListLong {
+			warnings := "-"
+			if !job.EndTime.IsZero() {
+				warnings = fmt.Sprint(job.Warnings)
+			}
+
+			fmt.Fprintf(
+				w,
+				"\t%s\t%s\t%s",
+				orDash(job.BasePath),
+				orDash(job.Version),
+				warnings)
+		}
+
+		fmt.Fprintln(w)
 	}
 
 	w.Flush()
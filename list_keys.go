@@ -0,0 +1,57 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jacobsa/comeback/internal/registry"
+)
+
+var cmdListKeys = &Command{
+	Name: "list_keys",
+	Run:  runListKeys,
+}
+
+func runListKeys(ctx context.Context, args []string) (err error) {
+	if len(args) != 0 {
+		err = fmt.Errorf("Usage: %s list_keys", os.Args[0])
+		return
+	}
+
+	slots, err := registry.ListKeySlots(ctx, getBucket(ctx))
+	if err != nil {
+		err = fmt.Errorf("ListKeySlots: %v", err)
+		return
+	}
+
+	for _, s := range slots {
+		kind := "password"
+		if s.Recovery {
+			kind = "recovery key"
+		}
+
+		if s.WriteOnly {
+			kind += ", write-only"
+		}
+
+		fmt.Printf("%s (%s, %v)\n", s.Label, kind, s.KDF)
+	}
+
+	return
+}
@@ -0,0 +1,175 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/browse"
+	"github.com/jacobsa/comeback/internal/save"
+	"github.com/jacobsa/comeback/internal/wiring"
+	"github.com/jacobsa/gcloud/gcs"
+)
+
+var cmdLocate = &Command{
+	Name: "locate",
+	Run:  runLocate,
+}
+
+// Return the subset of the supplied scores for which no blob object exists.
+func missingBlobs(
+	ctx context.Context,
+	scores []blob.Score) (missing []blob.Score, err error) {
+	bucket := getBucket(ctx)
+	for _, s := range scores {
+		_, err = bucket.StatObject(
+			ctx,
+			&gcs.StatObjectRequest{Name: wiring.BlobObjectNamePrefix + s.Hex()})
+
+		if _, ok := err.(*gcs.NotFoundError); ok {
+			err = nil
+			missing = append(missing, s)
+			continue
+		}
+
+		if err != nil {
+			err = fmt.Errorf("StatObject: %v", err)
+			return
+		}
+	}
+
+	return
+}
+
+func runLocate(ctx context.Context, args []string) (err error) {
+	// Extract and parse arguments.
+	if len(args) != 1 {
+		err = fmt.Errorf("Usage: %s locate local_file", os.Args[0])
+		return
+	}
+
+	// Compute the scores that each version of save would record for the
+	// file. Backups made by older versions refer to chunks by scores computed
+	// in older formats.
+	f, err := os.Open(args[0])
+	if err != nil {
+		err = fmt.Errorf("Open: %v", err)
+		return
+	}
+
+	defer f.Close()
+
+	var candidates [][]blob.Score
+	for _, format := range save.ChunkFormats {
+		_, err = f.Seek(0, io.SeekStart)
+		if err != nil {
+			err = fmt.Errorf("Seek: %v", err)
+			return
+		}
+
+		var scores []blob.Score
+		scores, err = save.ScoreFileInFormat(f, getCrypter(ctx), format)
+		if err != nil {
+			err = fmt.Errorf("ScoreFileInFormat: %v", err)
+			return
+		}
+
+		if len(scores) == 0 {
+			err = fmt.Errorf("%s is empty, and so matches every empty file", args[0])
+			return
+		}
+
+		// If any chunk was never stored in this format, no backup can contain
+		// the file in this format.
+		var missing []blob.Score
+		missing, err = missingBlobs(ctx, scores)
+		if err != nil {
+			err = fmt.Errorf("missingBlobs: %v", err)
+			return
+		}
+
+		if len(missing) == 0 {
+			candidates = append(candidates, scores)
+		}
+	}
+
+	if len(candidates) == 0 {
+		fmt.Printf(
+			"Some chunks of %s are not in the bucket, so no backup contains it.\n",
+			args[0])
+		return
+	}
+
+	// Search each backup, oldest first.
+	jobs, err := getRegistry(ctx).ListBackups(ctx)
+	if err != nil {
+		err = fmt.Errorf("ListBackups: %v", err)
+		return
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].StartTime.Before(jobs[j].StartTime)
+	})
+
+	const minwidth = 0
+	const tabwidth = 8
+	const padding = 4
+	const padchar = '\t'
+	const flags = 0
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Start time\tJob name\tScore\tPath")
+
+	l := browse.NewLocator(getBlobStore(ctx), candidates)
+	var found int
+	for _, j := range jobs {
+		var paths []string
+		paths, err = l.Locate(ctx, j.Score)
+		if err != nil {
+			err = fmt.Errorf("Locate(%s): %v", j.Score.Hex(), err)
+			return
+		}
+
+		for _, p := range paths {
+			found++
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%s\n",
+				j.StartTime.Format(time.RFC3339),
+				j.Name,
+				j.Score.Hex(),
+				p)
+		}
+	}
+
+	w.Flush()
+
+	if found == 0 {
+		fmt.Println("All chunks are stored, but no backup in the registry contains the file.")
+	}
+
+	return
+}
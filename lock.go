@@ -0,0 +1,69 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jacobsa/comeback/internal/lock"
+	"github.com/jacobsa/timeutil"
+)
+
+// Acquire a repository lock of the given kind and keep it alive in the
+// background until release is called. If the lock is lost in the meantime,
+// the returned context is cancelled. Callers that are about to rely on still
+// holding the lock should call l.Refresh first.
+func holdLock(
+	ctx context.Context,
+	kind lock.Kind) (
+	l *lock.Lock,
+	lockCtx context.Context,
+	release func(),
+	err error) {
+	bucket := getBucket(ctx)
+
+	l, err = lock.Acquire(ctx, bucket, timeutil.RealClock(), kind)
+	if err != nil {
+		err = fmt.Errorf("lock.Acquire: %v", err)
+		return
+	}
+
+	lockCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := l.KeepAlive(lockCtx)
+		if err != nil {
+			log.Printf("Lost %s repository lock: %v", kind, err)
+			cancel()
+		}
+	}()
+
+	release = func() {
+		cancel()
+		<-done
+
+		// Use the parent context, which may still be live.
+		err := l.Release(ctx)
+		if err != nil {
+			log.Printf("Releasing repository lock: %v", err)
+		}
+	}
+
+	return
+}
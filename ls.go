@@ -0,0 +1,106 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/jacobsa/comeback/internal/browse"
+	"github.com/jacobsa/comeback/internal/fs"
+)
+
+var cmdLs = &Command{
+	Name: "ls",
+}
+
+var fLsLong = cmdLs.Flags.Bool(
+	"l",
+	false,
+	"Print mode, owner, size, mtime, and chunk count for each entry.")
+
+var fLsJSON = cmdLs.Flags.Bool(
+	"json",
+	false,
+	"Print a JSON object for each entry, one per line.")
+
+func init() {
+	cmdLs.Run = runLs // Break flag-related dependency loop.
+}
+
+func runLs(ctx context.Context, args []string) (err error) {
+	// Extract and parse arguments.
+	args, err = parseInterspersed(&cmdLs.Flags, args)
+	if err != nil {
+		return
+	}
+
+	if len(args) != 1 {
+		err = fmt.Errorf(
+			"Usage: %s ls [-l] [--json] snapshot[:path]",
+			os.Args[0])
+		return
+	}
+
+	score, relPath, err := resolveSnapshotPath(ctx, args[0])
+	if err != nil {
+		err = fmt.Errorf("resolveSnapshotPath(%q): %v", args[0], err)
+		return
+	}
+
+	// Find the entry.
+	blobStore := getBlobStore(ctx)
+	fi, err := browse.LookUp(ctx, blobStore, score, relPath)
+	if err != nil {
+		return
+	}
+
+	ew := newEntryWriter(os.Stdout, *fLsLong, *fLsJSON)
+
+	// List a directory's contents, or anything else alone.
+	if fi.Type != fs.TypeDirectory {
+		err = ew.Write(fi.Name, relPath, fi)
+		if err != nil {
+			err = fmt.Errorf("Write: %v", err)
+			return
+		}
+	} else {
+		var entries []*fs.FileInfo
+		entries, err = browse.ReadDir(ctx, blobStore, fi.Scores[0])
+		if err != nil {
+			err = fmt.Errorf("ReadDir: %v", err)
+			return
+		}
+
+		for _, e := range entries {
+			err = ew.Write(e.Name, path.Join(relPath, e.Name), e)
+			if err != nil {
+				err = fmt.Errorf("Write: %v", err)
+				return
+			}
+		}
+	}
+
+	err = ew.Flush()
+	if err != nil {
+		err = fmt.Errorf("Flush: %v", err)
+		return
+	}
+
+	return
+}
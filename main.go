@@ -57,18 +57,45 @@ func raiseRlimit() (err error) {
 	return
 }
 
+// An error returned by a command that should cause the program to exit with
+// a particular status rather than the default of 1.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
 ////////////////////////////////////////////////////////////////////////
 // Commands
 ////////////////////////////////////////////////////////////////////////
 
 // The set of commands supported by the tool.
 var commands = []*Command{
+	cmdAddKey,
+	cmdCat,
 	cmdDeleteGarbage,
+	cmdDiff,
+	cmdDu,
+	cmdFind,
+	cmdForget,
 	cmdGC,
+	cmdHistory,
+	cmdInit,
 	cmdList,
+	cmdListKeys,
+	cmdLocate,
+	cmdLs,
+	cmdMigrateRegistry,
 	cmdMount,
+	cmdPasswd,
 	cmdRestore,
+	cmdRevokeKey,
 	cmdSave,
+	cmdStats,
+	cmdUpgradeKDF,
 	cmdVerify,
 }
 
@@ -264,6 +291,11 @@ func main() {
 	err = runCmd(context.Background(), cmdName, cmdArgs)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
+
+		if ee, ok := err.(*exitError); ok {
+			os.Exit(ee.code)
+		}
+
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,43 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+var cmdMigrateRegistry = &Command{
+	Name: "migrate_registry",
+	Run:  runMigrateRegistry,
+}
+
+func runMigrateRegistry(ctx context.Context, args []string) (err error) {
+	if len(args) != 0 {
+		err = fmt.Errorf("Usage: %s migrate_registry", os.Args[0])
+		return
+	}
+
+	migrated, err := getRegistry(ctx).MigrateLegacyRecords(ctx)
+	if err != nil {
+		err = fmt.Errorf("MigrateLegacyRecords: %v", err)
+		return
+	}
+
+	fmt.Printf("Rewrote %d registry records.\n", migrated)
+	return
+}
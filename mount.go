@@ -17,7 +17,6 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -25,9 +24,7 @@ import (
 	"os/user"
 	"strconv"
 
-	"github.com/jacobsa/comeback/internal/blob"
 	"github.com/jacobsa/comeback/internal/comebackfs"
-	"github.com/jacobsa/comeback/internal/registry"
 	"github.com/jacobsa/daemonize"
 	"github.com/jacobsa/fuse"
 	"github.com/jacobsa/fuse/fuseutil"
@@ -132,12 +129,22 @@ func startDaemon(ctx context.Context, args []string) (err error) {
 		return
 	}
 
+	// Hand the password to the daemon through an inherited pipe rather than
+	// its environment, which other processes may be able to read.
+	pipe, err := passwordPipe(password)
+	if err != nil {
+		err = fmt.Errorf("passwordPipe: %v", err)
+		return
+	}
+
+	defer pipe.Close()
+
 	// Re-execute as the daemon, forwarding status output to stderr.
 	err = daemonize.Run(
 		path,
 		append([]string{"mount"}, args...),
 		[]string{
-			fmt.Sprintf("%s=%s", passwordEnvVar, password),
+			fmt.Sprintf("%s=%d", passwordFDEnvVar, pipe.Fd()),
 			fmt.Sprintf("%s=", daemonEnvVar),
 		},
 		os.Stderr)
@@ -159,7 +166,7 @@ func mount(
 
 	// Check usage.
 	if len(args) < 1 || len(args) > 2 {
-		err = fmt.Errorf("Usage: %s mount_point [score]", os.Args[0])
+		err = fmt.Errorf("Usage: %s mount_point [snapshot]", os.Args[0])
 		return
 	}
 
@@ -168,44 +175,17 @@ func mount(
 	// Grab dependencies.
 	blobStore := getBlobStore(ctx)
 
-	var hexScore string
+	// Figure out which score to mount. By default, take the newest backup,
+	// insisting that there is only one job to avoid surprises.
+	sel := "latest"
 	if len(args) > 1 {
-		hexScore = args[1]
+		sel = args[1]
 	}
 
-	// Figure out which score to mount.
-	var score blob.Score
-	if hexScore != "" {
-		score, err = blob.ParseHexScore(hexScore)
-		if err != nil {
-			err = fmt.Errorf("ParseHexScore(%q): %v", hexScore, err)
-			return
-		}
-	} else {
-		r := getRegistry(ctx)
-
-		// List jobs.
-		var jobs []registry.CompletedJob
-		jobs, err = r.ListBackups(ctx)
-		if err != nil {
-			err = fmt.Errorf("ListBackups: %v", err)
-			return
-		}
-
-		if len(jobs) == 0 {
-			err = errors.New("No completed jobs found.")
-			return
-		}
-
-		// Find the job with the newest start time.
-		j := jobs[0]
-		for _, candidate := range jobs {
-			if j.StartTime.Before(candidate.StartTime) {
-				j = candidate
-			}
-		}
-
-		score = j.Score
+	score, err := resolveSnapshot(ctx, sel)
+	if err != nil {
+		err = fmt.Errorf("resolveSnapshot(%q): %v", sel, err)
+		return
 	}
 
 	logger.Printf("Mounting score %s.", score.Hex())
@@ -0,0 +1,60 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jacobsa/comeback/internal/wiring"
+)
+
+var cmdPasswd = &Command{
+	Name: "passwd",
+}
+
+var fPasswdNewPassword = registerNewPasswordFlags(&cmdPasswd.Flags)
+
+func init() {
+	cmdPasswd.Run = runPasswd // Break flag-related dependency loop.
+}
+
+func runPasswd(ctx context.Context, args []string) (err error) {
+	if len(args) != 0 {
+		err = fmt.Errorf(
+			"Usage: %s passwd [--new_password_file=path | --new_password_fd=n]",
+			os.Args[0])
+		return
+	}
+
+	bucket := getBucket(ctx)
+	oldPassword := getPassword()
+
+	newPassword, err := readNewPassword(fPasswdNewPassword)
+	if err != nil {
+		return
+	}
+
+	err = wiring.ChangePassword(ctx, bucket, oldPassword, newPassword)
+	if err != nil {
+		err = fmt.Errorf("ChangePassword: %v", err)
+		return
+	}
+
+	fmt.Println("Password changed. Nothing else in the bucket was modified.")
+	return
+}
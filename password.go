@@ -16,9 +16,18 @@
 package main
 
 import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"os/exec"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/jacobsa/util/password"
 )
@@ -29,21 +38,260 @@ var gPasswordOnce sync.Once
 // If set, the user will not be prompted.
 const passwordEnvVar = "COMEBACK_PASSWORD"
 
+// If set, the password is read from the file descriptor with this number,
+// which is then closed. Users may set it to pass the password without putting
+// it in the environment or on disk, and `comeback mount` uses it to hand the
+// password to its daemon. See config.Config.PasswordFile for the order in
+// which sources are consulted.
+const passwordFDEnvVar = "COMEBACK_PASSWORD_FD"
+
+// Find the password using the first source that is configured, in order: a
+// file descriptor, the environment, the password_command or password_file
+// config options, or prompting the user.
 func initPassword() {
-	// Is the environment variable set?
-	var ok bool
-	if gPassword, ok = os.LookupEnv(passwordEnvVar); ok {
-		return
+	var err error
+	gPassword, err = readPassword()
+	if err != nil {
+		log.Fatalln(err)
 	}
 
-	// Prompt the user.
-	gPassword = password.ReadPassword("Enter crypto password: ")
 	if len(gPassword) == 0 {
 		log.Fatalln("You must enter a password.")
 	}
 }
 
+func readPassword() (p string, err error) {
+	p, ok, err := readConfiguredPassword()
+	if err != nil || ok {
+		return
+	}
+
+	// Prompt the user.
+	p = password.ReadPassword("Enter crypto password: ")
+	return
+}
+
+// Read the password from the first source other than prompting that is
+// configured, in the order described for initPassword. ok is false if there
+// is none.
+func readConfiguredPassword() (p string, ok bool, err error) {
+	// Is there a file descriptor to read from?
+	if fdStr, set := os.LookupEnv(passwordFDEnvVar); set {
+		ok = true
+		p, err = readPasswordFD(fdStr)
+		if err != nil {
+			err = fmt.Errorf("Reading password from %s: %v", passwordFDEnvVar, err)
+			return
+		}
+
+		return
+	}
+
+	// Is the environment variable set?
+	if p, ok = os.LookupEnv(passwordEnvVar); ok {
+		return
+	}
+
+	// Is there a configured source?
+	cfg := getConfig()
+	switch {
+	case len(cfg.PasswordCommand) != 0:
+		ok = true
+		p, err = runPasswordCommand(cfg.PasswordCommand)
+		if err != nil {
+			err = fmt.Errorf("password_command: %v", err)
+			return
+		}
+
+		return
+
+	case cfg.PasswordFile != "":
+		ok = true
+		var contents []byte
+		contents, err = ioutil.ReadFile(cfg.PasswordFile)
+		if err != nil {
+			err = fmt.Errorf("password_file: %v", err)
+			return
+		}
+
+		p = trimNewline(string(contents))
+		return
+	}
+
+	return
+}
+
+// Remove a single trailing newline, as written by most editors and by echo.
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	s = strings.TrimSuffix(s, "\r")
+	return s
+}
+
+// Read the password from the file descriptor with the given number until EOF,
+// then close it.
+func readPasswordFD(fdStr string) (p string, err error) {
+	fd, err := strconv.ParseUint(fdStr, 10, 32)
+	if err != nil {
+		err = fmt.Errorf("ParseUint(%q): %v", fdStr, err)
+		return
+	}
+
+	f := os.NewFile(uintptr(fd), passwordFDEnvVar)
+	defer f.Close()
+
+	contents, err := ioutil.ReadAll(f)
+	if err != nil {
+		err = fmt.Errorf("ReadAll: %v", err)
+		return
+	}
+
+	p = trimNewline(string(contents))
+	return
+}
+
+// Run the supplied command, returning what it prints to stdout. Its stdin and
+// stderr are ours, so that it may prompt the user.
+func runPasswordCommand(args []string) (p string, err error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	err = cmd.Run()
+	if err != nil {
+		err = fmt.Errorf("%s: %v", args[0], err)
+		return
+	}
+
+	p = trimNewline(stdout.String())
+	return
+}
+
 func getPassword() string {
 	gPasswordOnce.Do(initPassword)
 	return gPassword
 }
+
+// The lowest file descriptor number that passwordPipe will return. exec.Cmd
+// assigns descriptors 0 through 2 to the child's standard streams and numbers
+// from 3 upward to its ExtraFiles, so we must stay clear of those.
+const minPasswordPipeFD = 10
+
+// Return the read end of a pipe that contains the supplied password, for
+// handing to a child process that we start along with
+// passwordFDEnvVar=f.Fd(). Unlike descriptors opened by the os package, it
+// lacks the close-on-exec flag, so that the child inherits it. The caller
+// must close it after starting the child.
+//
+// The password must fit in the pipe's buffer, since it is written before the
+// child starts reading.
+func passwordPipe(p string) (f *os.File, err error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		err = fmt.Errorf("Pipe: %v", err)
+		return
+	}
+
+	defer r.Close()
+
+	_, err = io.WriteString(w, p)
+	w.Close()
+	if err != nil {
+		err = fmt.Errorf("WriteString: %v", err)
+		return
+	}
+
+	// F_DUPFD doesn't copy the close-on-exec flag.
+	fd, _, errno := syscall.Syscall(
+		syscall.SYS_FCNTL,
+		r.Fd(),
+		syscall.F_DUPFD,
+		minPasswordPipeFD)
+
+	if errno != 0 {
+		err = fmt.Errorf("fcntl: %v", errno)
+		return
+	}
+
+	f = os.NewFile(fd, "password pipe")
+	return
+}
+
+// Flags for commands that set a new password, letting it be supplied without
+// prompting. Unlike the current password there is no environment variable for
+// it, to keep the secret out of the environments of child processes.
+type newPasswordFlags struct {
+	file string
+	fd   string
+}
+
+func registerNewPasswordFlags(fs *flag.FlagSet) (f *newPasswordFlags) {
+	f = new(newPasswordFlags)
+
+	fs.StringVar(
+		&f.file,
+		"new_password_file",
+		"",
+		"Read the new password from this file rather than prompting for it.")
+
+	fs.StringVar(
+		&f.fd,
+		"new_password_fd",
+		"",
+		"Read the new password from the file descriptor with this number, "+
+			"which is then closed, rather than prompting for it.")
+
+	return
+}
+
+// Were any of the flags set?
+func (f *newPasswordFlags) set() bool {
+	return f.file != "" || f.fd != ""
+}
+
+// Read a new password for a key slot, from the source named by the flags if
+// any.
+func readNewPassword(f *newPasswordFlags) (p string, err error) {
+	switch {
+	case f.file != "" && f.fd != "":
+		err = fmt.Errorf(
+			"--new_password_file and --new_password_fd are mutually exclusive.")
+		return
+
+	case f.file != "":
+		var contents []byte
+		contents, err = ioutil.ReadFile(f.file)
+		if err != nil {
+			err = fmt.Errorf("--new_password_file: %v", err)
+			return
+		}
+
+		p = trimNewline(string(contents))
+
+	case f.fd != "":
+		p, err = readPasswordFD(f.fd)
+		if err != nil {
+			err = fmt.Errorf("--new_password_fd: %v", err)
+			return
+		}
+
+	default:
+		// Prompt the user, twice to guard against typos.
+		p = password.ReadPassword("Enter new crypto password: ")
+		if len(p) != 0 &&
+			password.ReadPassword("Confirm new crypto password: ") != p {
+			err = fmt.Errorf("The passwords don't match.")
+			return
+		}
+	}
+
+	if len(p) == 0 {
+		err = fmt.Errorf("You must enter a password.")
+		return
+	}
+
+	return
+}
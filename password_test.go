@@ -0,0 +1,434 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/jacobsa/comeback/internal/config"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+)
+
+func TestPassword(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Boilerplate
+////////////////////////////////////////////////////////////////////////
+
+type PasswordTest struct {
+	// A temporary directory for password files.
+	dir string
+
+	// The values of the environment variables we touch, for restoring in
+	// TearDown.
+	env map[string]*string
+}
+
+var _ SetUpInterface = &PasswordTest{}
+var _ TearDownInterface = &PasswordTest{}
+
+func init() { RegisterTestSuite(&PasswordTest{}) }
+
+func (t *PasswordTest) SetUp(ti *TestInfo) {
+	var err error
+
+	t.dir, err = ioutil.TempDir("", "password_test")
+	AssertEq(nil, err)
+
+	// Start with no password in the environment.
+	t.env = make(map[string]*string)
+	for _, k := range []string{
+		passwordEnvVar,
+		passwordFDEnvVar,
+	} {
+		if v, ok := os.LookupEnv(k); ok {
+			t.env[k] = &v
+		} else {
+			t.env[k] = nil
+		}
+
+		AssertEq(nil, os.Unsetenv(k))
+	}
+
+	// Start with an empty config, without reading the user's.
+	g_configOnce.Do(func() {})
+	t.setConfig(&config.Config{})
+}
+
+func (t *PasswordTest) TearDown() {
+	for k, v := range t.env {
+		if v == nil {
+			ExpectEq(nil, os.Unsetenv(k))
+		} else {
+			ExpectEq(nil, os.Setenv(k, *v))
+		}
+	}
+
+	g_config = nil
+	ExpectEq(nil, os.RemoveAll(t.dir))
+}
+
+func (t *PasswordTest) setConfig(cfg *config.Config) {
+	g_config = cfg
+}
+
+// Write the supplied contents to a file in t.dir, returning its path.
+func (t *PasswordTest) writeFile(contents string) (p string) {
+	p = path.Join(t.dir, "password")
+	AssertEq(nil, ioutil.WriteFile(p, []byte(contents), 0600))
+	return
+}
+
+// Return the number of a file descriptor from which the supplied contents can
+// be read. Ownership of the descriptor passes to the caller.
+func (t *PasswordTest) pipeFD(contents string) (fd int) {
+	var fds [2]int
+	AssertEq(nil, syscall.Pipe(fds[:]))
+
+	_, err := syscall.Write(fds[1], []byte(contents))
+	AssertEq(nil, err)
+	AssertEq(nil, syscall.Close(fds[1]))
+
+	fd = fds[0]
+	return
+}
+
+// Is the supplied file descriptor open?
+func isOpen(fd int) bool {
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_FCNTL,
+		uintptr(fd),
+		syscall.F_GETFD,
+		0)
+
+	return errno == 0
+}
+
+////////////////////////////////////////////////////////////////////////
+// trimNewline
+////////////////////////////////////////////////////////////////////////
+
+func (t *PasswordTest) TrimNewline() {
+	testCases := []struct {
+		in       string
+		expected string
+	}{
+		{"", ""},
+		{"taco", "taco"},
+		{"taco\n", "taco"},
+		{"taco\r\n", "taco"},
+		{"taco\n\n", "taco\n"},
+		{"\n", ""},
+		{" taco \n", " taco "},
+		{"ta\nco", "ta\nco"},
+	}
+
+	for i, tc := range testCases {
+		ExpectEq(tc.expected, trimNewline(tc.in), "Test case %d: %q", i, tc.in)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////
+// readPasswordFD
+////////////////////////////////////////////////////////////////////////
+
+func (t *PasswordTest) ReadPasswordFD() {
+	fd := t.pipeFD("taco\n")
+
+	p, err := readPasswordFD(strconv.Itoa(fd))
+	AssertEq(nil, err)
+	ExpectEq("taco", p)
+
+	// The descriptor should have been closed.
+	ExpectFalse(isOpen(fd))
+}
+
+func (t *PasswordTest) ReadPasswordFD_NotANumber() {
+	_, err := readPasswordFD("taco")
+	ExpectThat(err, Error(HasSubstr("ParseUint")))
+	ExpectThat(err, Error(HasSubstr("taco")))
+}
+
+func (t *PasswordTest) ReadPasswordFD_Negative() {
+	_, err := readPasswordFD("-1")
+	ExpectThat(err, Error(HasSubstr("ParseUint")))
+}
+
+func (t *PasswordTest) ReadPasswordFD_Closed() {
+	fd := t.pipeFD("taco")
+	AssertEq(nil, syscall.Close(fd))
+
+	_, err := readPasswordFD(strconv.Itoa(fd))
+	ExpectThat(err, Error(HasSubstr("ReadAll")))
+}
+
+////////////////////////////////////////////////////////////////////////
+// runPasswordCommand
+////////////////////////////////////////////////////////////////////////
+
+func (t *PasswordTest) RunPasswordCommand() {
+	p, err := runPasswordCommand([]string{"echo", "taco"})
+	AssertEq(nil, err)
+	ExpectEq("taco", p)
+}
+
+func (t *PasswordTest) RunPasswordCommand_TrimsOnlyOneNewline() {
+	p, err := runPasswordCommand([]string{"printf", "taco\n\n"})
+	AssertEq(nil, err)
+	ExpectEq("taco\n", p)
+}
+
+func (t *PasswordTest) RunPasswordCommand_NonZeroExit() {
+	_, err := runPasswordCommand([]string{"sh", "-c", "echo taco; exit 3"})
+	ExpectThat(err, Error(HasSubstr("sh")))
+	ExpectThat(err, Error(HasSubstr("exit status 3")))
+}
+
+func (t *PasswordTest) RunPasswordCommand_MissingProgram() {
+	_, err := runPasswordCommand([]string{path.Join(t.dir, "taco")})
+	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, Error(HasSubstr("no such file")))
+}
+
+////////////////////////////////////////////////////////////////////////
+// passwordPipe
+////////////////////////////////////////////////////////////////////////
+
+func (t *PasswordTest) PasswordPipe() {
+	f, err := passwordPipe("taco")
+	AssertEq(nil, err)
+	defer f.Close()
+
+	ExpectGe(f.Fd(), minPasswordPipeFD)
+
+	// A child should inherit the descriptor and be able to read from it, the
+	// way that `comeback mount` hands the password to its daemon.
+	cmd := exec.Command("cat", fmt.Sprintf("/dev/fd/%d", f.Fd()))
+	out, err := cmd.Output()
+	AssertEq(nil, err)
+	ExpectEq("taco", string(out))
+}
+
+func (t *PasswordTest) PasswordPipe_RoundTrip() {
+	f, err := passwordPipe("taco")
+	AssertEq(nil, err)
+
+	// readPasswordFD takes ownership of the descriptor it's handed.
+	fd, err := syscall.Dup(int(f.Fd()))
+	AssertEq(nil, err)
+	AssertEq(nil, f.Close())
+
+	AssertEq(nil, os.Setenv(passwordFDEnvVar, strconv.Itoa(fd)))
+
+	p, err := readPassword()
+	AssertEq(nil, err)
+	ExpectEq("taco", p)
+}
+
+////////////////////////////////////////////////////////////////////////
+// readPassword
+////////////////////////////////////////////////////////////////////////
+
+func (t *PasswordTest) FDBeatsEverything() {
+	AssertEq(nil, os.Setenv(passwordFDEnvVar, strconv.Itoa(t.pipeFD("fd"))))
+	AssertEq(nil, os.Setenv(passwordEnvVar, "env"))
+	t.setConfig(&config.Config{
+		PasswordCommand: []string{"echo", "command"},
+		PasswordFile:    t.writeFile("file"),
+	})
+
+	p, err := readPassword()
+	AssertEq(nil, err)
+	ExpectEq("fd", p)
+}
+
+func (t *PasswordTest) EnvironmentBeatsConfig() {
+	AssertEq(nil, os.Setenv(passwordEnvVar, "env"))
+	t.setConfig(&config.Config{
+		PasswordCommand: []string{"echo", "command"},
+		PasswordFile:    t.writeFile("file"),
+	})
+
+	p, err := readPassword()
+	AssertEq(nil, err)
+	ExpectEq("env", p)
+}
+
+func (t *PasswordTest) EmptyEnvironmentVariableCounts() {
+	AssertEq(nil, os.Setenv(passwordEnvVar, ""))
+	t.setConfig(&config.Config{
+		PasswordCommand: []string{"echo", "command"},
+	})
+
+	p, err := readPassword()
+	AssertEq(nil, err)
+	ExpectEq("", p)
+}
+
+func (t *PasswordTest) CommandBeatsFile() {
+	t.setConfig(&config.Config{
+		PasswordCommand: []string{"echo", "command"},
+		PasswordFile:    t.writeFile("file"),
+	})
+
+	p, err := readPassword()
+	AssertEq(nil, err)
+	ExpectEq("command", p)
+}
+
+func (t *PasswordTest) File() {
+	t.setConfig(&config.Config{
+		PasswordFile: t.writeFile("taco\n"),
+	})
+
+	p, err := readPassword()
+	AssertEq(nil, err)
+	ExpectEq("taco", p)
+}
+
+func (t *PasswordTest) BadFD() {
+	AssertEq(nil, os.Setenv(passwordFDEnvVar, "taco"))
+	AssertEq(nil, os.Setenv(passwordEnvVar, "env"))
+
+	_, err := readPassword()
+	ExpectThat(err, Error(HasSubstr(passwordFDEnvVar)))
+	ExpectThat(err, Error(HasSubstr("taco")))
+}
+
+func (t *PasswordTest) CommandFails() {
+	t.setConfig(&config.Config{
+		PasswordCommand: []string{"false"},
+	})
+
+	_, err := readPassword()
+	ExpectThat(err, Error(HasSubstr("password_command")))
+	ExpectThat(err, Error(HasSubstr("exit status 1")))
+}
+
+func (t *PasswordTest) FileMissing() {
+	t.setConfig(&config.Config{
+		PasswordFile: path.Join(t.dir, "taco"),
+	})
+
+	_, err := readPassword()
+	ExpectThat(err, Error(HasSubstr("password_file")))
+	ExpectThat(err, Error(HasSubstr("no such file")))
+}
+
+////////////////////////////////////////////////////////////////////////
+// readNewPassword
+////////////////////////////////////////////////////////////////////////
+
+func (t *PasswordTest) ReadNewPassword_File() {
+	// The old password's sources should be ignored.
+	AssertEq(nil, os.Setenv(passwordEnvVar, "burrito"))
+	t.setConfig(&config.Config{
+		PasswordCommand: []string{"echo", "enchilada"},
+	})
+
+	p, err := readNewPassword(&newPasswordFlags{file: t.writeFile("taco\n")})
+	AssertEq(nil, err)
+	ExpectEq("taco", p)
+}
+
+func (t *PasswordTest) ReadNewPassword_FileMissing() {
+	_, err := readNewPassword(&newPasswordFlags{file: path.Join(t.dir, "taco")})
+	ExpectThat(err, Error(HasSubstr("--new_password_file")))
+	ExpectThat(err, Error(HasSubstr("no such file")))
+}
+
+func (t *PasswordTest) ReadNewPassword_FD() {
+	AssertEq(nil, os.Setenv(passwordFDEnvVar, strconv.Itoa(t.pipeFD("burrito"))))
+	fd := t.pipeFD("taco\n")
+
+	p, err := readNewPassword(&newPasswordFlags{fd: strconv.Itoa(fd)})
+	AssertEq(nil, err)
+	ExpectEq("taco", p)
+	ExpectFalse(isOpen(fd))
+}
+
+func (t *PasswordTest) ReadNewPassword_Empty() {
+	_, err := readNewPassword(&newPasswordFlags{file: t.writeFile("\n")})
+	ExpectThat(err, Error(HasSubstr("must enter a password")))
+}
+
+func (t *PasswordTest) ReadNewPassword_FileAndFD() {
+	fd := t.pipeFD("burrito")
+	defer syscall.Close(fd)
+
+	f := &newPasswordFlags{
+		file: t.writeFile("taco"),
+		fd:   strconv.Itoa(fd),
+	}
+
+	_, err := readNewPassword(f)
+	ExpectThat(err, Error(HasSubstr("mutually exclusive")))
+}
+
+////////////////////////////////////////////////////////////////////////
+// readInitPassword
+////////////////////////////////////////////////////////////////////////
+
+func (t *PasswordTest) ReadInitPassword_FD() {
+	// The descriptor should be used rather than prompting for a new password,
+	// just as later commands will use it.
+	AssertEq(nil, os.Setenv(passwordFDEnvVar, strconv.Itoa(t.pipeFD("taco"))))
+
+	p, err := readInitPassword(&newPasswordFlags{})
+	AssertEq(nil, err)
+	ExpectEq("taco", p)
+}
+
+func (t *PasswordTest) ReadInitPassword_Command() {
+	t.setConfig(&config.Config{
+		PasswordCommand: []string{"echo", "taco"},
+	})
+
+	p, err := readInitPassword(&newPasswordFlags{})
+	AssertEq(nil, err)
+	ExpectEq("taco", p)
+}
+
+func (t *PasswordTest) ReadInitPassword_Empty() {
+	AssertEq(nil, os.Setenv(passwordEnvVar, ""))
+
+	_, err := readInitPassword(&newPasswordFlags{})
+	ExpectThat(err, Error(HasSubstr("must enter a password")))
+}
+
+func (t *PasswordTest) ReadInitPassword_ConfiguredAndFlags() {
+	// Later commands wouldn't see the new password, so don't accept one.
+	t.setConfig(&config.Config{
+		PasswordCommand: []string{"echo", "taco"},
+	})
+
+	_, err := readInitPassword(&newPasswordFlags{file: t.writeFile("burrito")})
+	ExpectThat(err, Error(HasSubstr("--new_password_file")))
+}
+
+func (t *PasswordTest) ReadInitPassword_Unconfigured() {
+	p, err := readInitPassword(&newPasswordFlags{file: t.writeFile("burrito")})
+	AssertEq(nil, err)
+	ExpectEq("burrito", p)
+}
@@ -41,11 +41,7 @@ func initRegistryAndCrypter(ctx context.Context) {
 	bucket := getBucket(ctx)
 	password := getPassword()
 
-	gRegistry, gCrypter, err = wiring.MakeRegistryAndCrypter(
-		ctx,
-		password,
-		bucket)
-
+	gRegistry, gCrypter, err = wiring.MakeRegistryAndCrypter(ctx, password, bucket)
 	if err != nil {
 		err = fmt.Errorf("MakeRegistryAndCrypter: %v", err)
 		return
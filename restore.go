@@ -17,31 +17,99 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
-	"github.com/jacobsa/comeback/internal/blob"
 	"github.com/jacobsa/comeback/internal/restore"
 	"github.com/jacobsa/comeback/internal/wiring"
 )
 
 var cmdRestore = &Command{
 	Name: "restore",
-	Run:  runRestore,
+}
+
+var fRestoreIncludes stringsFlag
+var fRestoreExcludes stringsFlag
+var fRestoreOnConflict *string
+var fRestoreCompareContent *bool
+
+func init() {
+	cmdRestore.Run = runRestore // Break flag-related dependency loop.
+
+	cmdRestore.Flags.Var(
+		&fRestoreIncludes,
+		"include",
+		"If set, restore only entries matching this glob pattern. May be repeated.")
+
+	cmdRestore.Flags.Var(
+		&fRestoreExcludes,
+		"exclude",
+		"Don't restore entries matching this glob pattern. May be repeated.")
+
+	fRestoreOnConflict = cmdRestore.Flags.String(
+		"on_conflict",
+		"fail",
+		"What to do about existing entries in the destination: fail, skip, "+
+			"overwrite, overwrite_if_different, or rename. With fail, the "+
+			"destination must be empty.")
+
+	fRestoreCompareContent = cmdRestore.Flags.Bool(
+		"compare_content",
+		false,
+		"When checking whether existing files differ, compare their contents "+
+			"rather than their sizes and modification times.")
+}
+
+// Return the path of the journal for a restore into the supplied directory.
+// It lives next to the state file rather than in the destination, so that an
+// interrupted restore leaves nothing behind among the user's data, and is
+// named for the destination so that restores into different directories can
+// be resumed independently.
+func restoreJournalPath(dstDir string) (p string, err error) {
+	abs, err := filepath.Abs(dstDir)
+	if err != nil {
+		err = fmt.Errorf("Abs: %v", err)
+		return
+	}
+
+	sum := sha256.Sum256([]byte(abs))
+	p = fmt.Sprintf(
+		"%s.restore_journal.%s",
+		getConfig().StateFile,
+		hex.EncodeToString(sum[:8]))
+
+	return
 }
 
 func runRestore(ctx context.Context, args []string) (err error) {
 	// Extract and parse arguments.
-	if len(args) != 2 {
-		err = fmt.Errorf("Usage: %s restore dst_dir score", os.Args[0])
+	if len(args) < 2 {
+		err = fmt.Errorf(
+			"Usage: %s restore [flags] dst_dir snapshot [path ...]",
+			os.Args[0])
 		return
 	}
 
 	dstDir := args[0]
-	score, err := blob.ParseHexScore(args[1])
+	score, err := resolveSnapshot(ctx, args[1])
+	if err != nil {
+		err = fmt.Errorf("resolveSnapshot(%q): %v", args[1], err)
+		return
+	}
+
+	filter := restore.Filter{
+		Paths:    args[2:],
+		Includes: fRestoreIncludes,
+		Excludes: fRestoreExcludes,
+	}
+
+	policy, err := restore.ParseConflictPolicy(*fRestoreOnConflict)
 	if err != nil {
-		err = fmt.Errorf("ParseHexScore(%q): %v", args[1], err)
+		err = fmt.Errorf("--on_conflict: %v", err)
 		return
 	}
 
@@ -49,17 +117,17 @@ func runRestore(ctx context.Context, args []string) (err error) {
 	bucket := getBucket(ctx)
 	crypter := getCrypter(ctx)
 
-	// Make sure the target doesn't exist.
-	err = os.RemoveAll(dstDir)
+	// Create the destination if it doesn't already exist. Restore itself deals
+	// with anything that is already there.
+	err = os.MkdirAll(dstDir, 0700)
 	if err != nil {
-		err = fmt.Errorf("os.RemoveAll: %v", err)
+		err = fmt.Errorf("os.MkdirAll: %v", err)
 		return
 	}
 
-	// Create the destination.
-	err = os.Mkdir(dstDir, 0700)
+	journalPath, err := restoreJournalPath(dstDir)
 	if err != nil {
-		err = fmt.Errorf("os.Mkdir: %v", err)
+		err = fmt.Errorf("restoreJournalPath: %v", err)
 		return
 	}
 
@@ -67,7 +135,11 @@ func runRestore(ctx context.Context, args []string) (err error) {
 	err = restore.Restore(
 		ctx,
 		dstDir,
+		journalPath,
 		score,
+		filter,
+		policy,
+		*fRestoreCompareContent,
 		bucket,
 		wiring.BlobObjectNamePrefix,
 		crypter,
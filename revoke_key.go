@@ -0,0 +1,55 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jacobsa/comeback/internal/wiring"
+)
+
+var cmdRevokeKey = &Command{
+	Name: "revoke_key",
+	Run:  runRevokeKey,
+}
+
+func runRevokeKey(ctx context.Context, args []string) (err error) {
+	if len(args) != 1 {
+		err = fmt.Errorf("Usage: %s revoke_key label", os.Args[0])
+		return
+	}
+
+	label := args[0]
+	err = wiring.RevokeKeySlot(ctx, getBucket(ctx), getPassword(), label)
+	if err != nil {
+		err = fmt.Errorf("RevokeKeySlot: %v", err)
+		return
+	}
+
+	fmt.Printf("Removed key slot %q.\n", label)
+	fmt.Println()
+	fmt.Println(
+		"WARNING: This does not revoke access to the data. The master key is\n" +
+			"unchanged, so anyone who held this slot's password or recovery key and\n" +
+			"kept the master key, or an old copy of the marker, can still decrypt\n" +
+			"every backup in the bucket, including ones saved from now on. comeback\n" +
+			"can't rotate the master key; to cut off such a holder, save to a new\n" +
+			"bucket with a fresh key.")
+
+	return
+}
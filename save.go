@@ -21,9 +21,11 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/jacobsa/comeback/internal/config"
+	"github.com/jacobsa/comeback/internal/lock"
 	"github.com/jacobsa/comeback/internal/registry"
 	"github.com/jacobsa/comeback/internal/save"
 	"github.com/jacobsa/comeback/internal/wiring"
@@ -45,7 +47,14 @@ var fListOnly = cmdSave.Flags.Bool(
 	false,
 	"If set, list the files that would be backed up but do nothing further.")
 
+var fSaveTags stringsFlag
+
 func init() {
+	cmdSave.Flags.Var(
+		&fSaveTags,
+		"tag",
+		"A label to record with the backup. May be repeated.")
+
 	cmdSave.Run = runSave // Break flag-related dependency loop.
 }
 
@@ -125,6 +134,27 @@ func runSave(ctx context.Context, args []string) (err error) {
 	state := getState(ctx)
 	clock := timeutil.RealClock()
 
+	// Take a shared lock on the repository, so that gc doesn't collect blobs
+	// that we are relying on while we work.
+	lk, ctx, releaseLock, err := holdLock(ctx, lock.Shared)
+	if err != nil {
+		err = fmt.Errorf("holdLock: %v", err)
+		return
+	}
+
+	defer releaseLock()
+
+	// gc may have run between loading the state file and taking the lock, in
+	// which case some of the existing scores may now be gone.
+	err = validateExistingScores(ctx, bucket, state)
+	if err != nil {
+		err = fmt.Errorf("validateExistingScores: %v", err)
+		return
+	}
+
+	// Remember the epoch that the existing scores were validated against.
+	gcEpoch := state.GCEpoch
+
 	// Periodically save state.
 	const saveStatePeriod = 15 * time.Second
 	saveStateTicker := time.NewTicker(saveStatePeriod)
@@ -134,7 +164,7 @@ func runSave(ctx context.Context, args []string) (err error) {
 	startTime := clock.Now()
 
 	// Call the saving pipeline.
-	score, err := save.Save(
+	score, stats, err := save.Save(
 		ctx,
 		job.BasePath,
 		job.Excludes,
@@ -152,10 +182,36 @@ func runSave(ctx context.Context, args []string) (err error) {
 	}
 
 	// Register the successful backup.
+	hostname, err := os.Hostname()
+	if err != nil {
+		err = fmt.Errorf("Hostname: %v", err)
+		return
+	}
+
 	completedJob := registry.CompletedJob{
-		StartTime: startTime,
-		Name:      jobName,
-		Score:     score,
+		StartTime:     startTime,
+		Name:          jobName,
+		Score:         score,
+		EndTime:       clock.Now(),
+		Hostname:      hostname,
+		BasePath:      job.BasePath,
+		Version:       getVersion(),
+		FilesScanned:  stats.FilesScanned,
+		DirsScanned:   stats.DirsScanned,
+		BytesScanned:  stats.BytesScanned,
+		BlobsUploaded: stats.BlobsUploaded,
+		BytesUploaded: stats.BytesUploaded,
+		Warnings:      stats.Warnings,
+		Tags:          fSaveTags,
+	}
+
+	// If our lock was broken or gc has started since we took it, gc may have
+	// collected blobs that the backup refers to, including ones that we
+	// deduplicated against. Check immediately before registering the backup.
+	err = lk.Confirm(ctx, gcEpoch)
+	if err != nil {
+		err = fmt.Errorf("Confirm: %v", err)
+		return
 	}
 
 	err = reg.RecordBackup(ctx, completedJob)
@@ -169,6 +225,18 @@ func runSave(ctx context.Context, args []string) (err error) {
 		score.Hex(),
 		startTime.UTC())
 
+	log.Printf(
+		"Scanned %d files (%s) in %d directories; uploaded %d blobs (%s).",
+		stats.FilesScanned,
+		strings.TrimSpace(formatBytes(stats.BytesScanned)),
+		stats.DirsScanned,
+		stats.BlobsUploaded,
+		strings.TrimSpace(formatBytes(stats.BytesUploaded)))
+
+	if stats.Warnings != 0 {
+		log.Printf("There were %d warnings; see above.", stats.Warnings)
+	}
+
 	// Store state for next time.
 	saveStateTicker.Stop()
 	log.Println("Writing out final state file...")
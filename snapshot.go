@@ -0,0 +1,85 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jacobsa/comeback/internal/blob"
+	"github.com/jacobsa/comeback/internal/registry"
+)
+
+// Resolve the supplied snapshot selector, as described by
+// registry.ResolveSelector, to the score of a backup. A complete hex score is
+// accepted without consulting the registry.
+func resolveSnapshot(
+	ctx context.Context,
+	sel string) (score blob.Score, err error) {
+	score, err = blob.ParseHexScore(sel)
+	if err == nil {
+		return
+	}
+
+	jobs, err := getRegistry(ctx).ListBackups(ctx)
+	if err != nil {
+		err = fmt.Errorf("ListBackups: %v", err)
+		return
+	}
+
+	j, err := registry.ResolveSelector(jobs, sel)
+	if err != nil {
+		return
+	}
+
+	score = j.Score
+	return
+}
+
+// Resolve an argument of the form "snapshot:path", as described by
+// registry.ResolveSelectorAndPath, to the score of a backup and a path within
+// it. The path is optional. Arguments beginning with a complete hex score are
+// handled without consulting the registry.
+func resolveSnapshotPath(
+	ctx context.Context,
+	arg string) (score blob.Score, relPath string, err error) {
+	// Hex scores never contain a colon, so the score if any is everything
+	// before the first one.
+	parts := strings.SplitN(arg, ":", 2)
+	score, err = blob.ParseHexScore(parts[0])
+	if err == nil {
+		if len(parts) == 2 {
+			relPath = parts[1]
+		}
+
+		return
+	}
+
+	jobs, err := getRegistry(ctx).ListBackups(ctx)
+	if err != nil {
+		err = fmt.Errorf("ListBackups: %v", err)
+		return
+	}
+
+	j, relPath, err := registry.ResolveSelectorAndPath(jobs, arg)
+	if err != nil {
+		return
+	}
+
+	score = j.Score
+	return
+}
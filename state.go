@@ -24,6 +24,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jacobsa/comeback/internal/crypto"
+	"github.com/jacobsa/comeback/internal/lock"
 	"github.com/jacobsa/comeback/internal/state"
 	"github.com/jacobsa/comeback/internal/util"
 	"github.com/jacobsa/comeback/internal/wiring"
@@ -32,6 +34,7 @@ import (
 
 var g_stateOnce sync.Once
 var g_state state.State
+var g_stateCrypter crypto.Crypter
 
 var g_saveStateMutex sync.Mutex
 
@@ -58,7 +61,9 @@ func buildExistingScores(
 	return
 }
 
-func makeState(ctx context.Context) (s state.State, err error) {
+func makeState(
+	ctx context.Context,
+	crypter crypto.Crypter) (s state.State, err error) {
 	cfg := getConfig()
 	bucket := getBucket(ctx)
 
@@ -77,10 +82,15 @@ func makeState(ctx context.Context) (s state.State, err error) {
 	// If we opened a file above, load from it.
 	if f != nil {
 		defer f.Close()
-		s, err = state.LoadState(f)
+		s, err = state.LoadState(f, crypter)
+
+		// The state file is only a cache, so one we can't read (for example
+		// because it belongs to another repository or predates encryption) can
+		// simply be rebuilt.
 		if err != nil {
-			err = fmt.Errorf("LoadState: %v", err)
-			return
+			log.Printf("Rebuilding state: LoadState: %v", err)
+			s = state.State{}
+			err = nil
 		}
 	}
 
@@ -94,20 +104,50 @@ func makeState(ctx context.Context) (s state.State, err error) {
 		s.ScoresForFiles = state.NewScoreMap()
 	}
 
-	// If we don't know the set of hex scores in the store, or the set of scores
-	// is stale, re-list.
+	err = validateExistingScores(ctx, bucket, &s)
+	if err != nil {
+		err = fmt.Errorf("validateExistingScores: %v", err)
+		return
+	}
+
+	return
+}
+
+// If we don't know the set of hex scores in the store, or the set of scores
+// is stale or predates a garbage collection, re-list.
+func validateExistingScores(
+	ctx context.Context,
+	bucket gcs.Bucket,
+	s *state.State) (err error) {
+	// Read the epoch before listing, so that a collection that finishes while
+	// we list is noticed next time.
+	epoch, err := lock.ReadGCEpoch(ctx, bucket)
+	if err != nil {
+		err = fmt.Errorf("ReadGCEpoch: %v", err)
+		return
+	}
+
 	age := time.Now().Sub(s.RelistTime)
 	const maxAge = 30 * 24 * time.Hour
 
-	if s.ExistingScores == nil || age > maxAge {
-		log.Println("Listing existing scores...")
+	switch {
+	case s.ExistingScores == nil || age > maxAge:
 
-		s.RelistTime = time.Now()
-		s.ExistingScores, err = buildExistingScores(ctx, bucket)
-		if err != nil {
-			err = fmt.Errorf("buildExistingScores: %v", err)
-			return
-		}
+	case epoch != s.GCEpoch:
+		log.Println("Garbage has been collected since existing scores were listed.")
+
+	default:
+		return
+	}
+
+	log.Println("Listing existing scores...")
+
+	s.RelistTime = time.Now()
+	s.GCEpoch = epoch
+	s.ExistingScores, err = buildExistingScores(ctx, bucket)
+	if err != nil {
+		err = fmt.Errorf("buildExistingScores: %v", err)
+		return
 	}
 
 	return
@@ -116,10 +156,16 @@ func makeState(ctx context.Context) (s state.State, err error) {
 func initState(ctx context.Context) {
 	var err error
 
+	// Derive the key with which the state file is encrypted.
+	g_stateCrypter, err = state.NewCrypter(getCrypter(ctx))
+	if err != nil {
+		log.Fatalf("state.NewCrypter: %v", err)
+	}
+
 	// Load the state struct.
 	log.Println("Loading from state file...")
 
-	g_state, err = makeState(ctx)
+	g_state, err = makeState(ctx, g_stateCrypter)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -128,7 +174,7 @@ func initState(ctx context.Context) {
 	// existing scores).
 	log.Println("Saving to state file...")
 
-	err = saveStateStruct(getConfig().StateFile, &g_state)
+	err = saveStateStruct(getConfig().StateFile, &g_state, g_stateCrypter)
 	if err != nil {
 		log.Fatalf("saveStateStruct: %v", err)
 	}
@@ -141,7 +187,10 @@ func getState(ctx context.Context) *state.State {
 	return &g_state
 }
 
-func saveStateStruct(dst string, s *state.State) (err error) {
+func saveStateStruct(
+	dst string,
+	s *state.State,
+	crypter crypto.Crypter) (err error) {
 	// Create a temporary file.
 	f, err := ioutil.TempFile("", "comeback_state")
 	if err != nil {
@@ -153,7 +202,7 @@ func saveStateStruct(dst string, s *state.State) (err error) {
 	tempFilePath := f.Name()
 
 	// Write to the file.
-	err = state.SaveState(f, *s)
+	err = state.SaveState(f, *s, crypter)
 	if err != nil {
 		err = fmt.Errorf("SaveState: %v", err)
 		return
@@ -184,7 +233,7 @@ func saveState(ctx context.Context) {
 	cfg := getConfig()
 	stateStruct := getState(ctx)
 
-	err := saveStateStruct(cfg.StateFile, stateStruct)
+	err := saveStateStruct(cfg.StateFile, stateStruct, g_stateCrypter)
 	if err != nil {
 		log.Fatalf("saveStateStruct: %v", err)
 	}
@@ -0,0 +1,457 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jacobsa/comeback/internal/crypto"
+	"github.com/jacobsa/comeback/internal/registry"
+	"github.com/jacobsa/comeback/internal/stats"
+	"github.com/jacobsa/comeback/internal/wiring"
+)
+
+var cmdStats = &Command{
+	Name: "stats",
+}
+
+var fStatsJSON = cmdStats.Flags.Bool(
+	"json",
+	false,
+	"Print the statistics as a JSON document.")
+
+var fStatsCache = cmdStats.Flags.String(
+	"cache",
+	"",
+	"Path to a file in which to cache the results of scanning backups. "+
+		"Defaults to a file alongside the state file.")
+
+func init() {
+	cmdStats.Run = runStats // Break flag-related dependency loop.
+}
+
+////////////////////////////////////////////////////////////////////////
+// Cache
+////////////////////////////////////////////////////////////////////////
+
+// Return the path of the stats cache, which is the supplied flag value if
+// set. Otherwise it lives alongside the state file, named for the
+// repository so that caches for different buckets don't collide.
+func getStatsCachePath(ctx context.Context, flag string) (p string) {
+	if flag != "" {
+		p = flag
+		return
+	}
+
+	p = getConfig().StateFile + ".stats_cache"
+	if id := crypto.RepositoryID(getCrypter(ctx)); id != nil {
+		p += "." + hex.EncodeToString(id)
+	}
+
+	return
+}
+
+// Load the cache at the supplied path, starting afresh if it doesn't exist or
+// can't be read.
+func loadStatsCache(
+	p string,
+	crypter crypto.Crypter) (c *stats.Cache) {
+	f, err := os.Open(p)
+	switch {
+	case os.IsNotExist(err):
+		c = stats.NewCache()
+		return
+
+	case err != nil:
+		log.Printf("Ignoring stats cache: %v", err)
+		c = stats.NewCache()
+		return
+	}
+
+	defer f.Close()
+
+	c, err = stats.LoadCache(f, crypter)
+	if err != nil {
+		log.Printf("Ignoring stats cache: LoadCache: %v", err)
+		c = stats.NewCache()
+		return
+	}
+
+	return
+}
+
+func saveStatsCache(
+	p string,
+	c *stats.Cache,
+	crypter crypto.Crypter) (err error) {
+	// Write to a temporary file alongside the destination, then rename it into
+	// place.
+	f, err := ioutil.TempFile(path.Dir(p), ".comeback.stats_cache")
+	if err != nil {
+		err = fmt.Errorf("TempFile: %v", err)
+		return
+	}
+
+	defer f.Close()
+
+	err = stats.SaveCache(f, c, crypter)
+	if err != nil {
+		os.Remove(f.Name())
+		err = fmt.Errorf("SaveCache: %v", err)
+		return
+	}
+
+	err = f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		err = fmt.Errorf("Close: %v", err)
+		return
+	}
+
+	err = os.Rename(f.Name(), p)
+	if err != nil {
+		err = fmt.Errorf("Rename: %v", err)
+		return
+	}
+
+	return
+}
+
+// Return all of the backups in the registry, oldest first, along with their
+// scans, scanning only those that are not in the cache at the supplied path.
+func scanBackups(
+	ctx context.Context,
+	cachePath string) (
+	jobs []registry.CompletedJob,
+	scans stats.Scans,
+	err error) {
+	jobs, err = getRegistry(ctx).ListBackups(ctx)
+	if err != nil {
+		err = fmt.Errorf("ListBackups: %v", err)
+		return
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].StartTime.Before(jobs[j].StartTime)
+	})
+
+	crypter, err := stats.NewCrypter(getCrypter(ctx))
+	if err != nil {
+		err = fmt.Errorf("stats.NewCrypter: %v", err)
+		return
+	}
+
+	c := loadStatsCache(cachePath, crypter)
+
+	// Scan whatever is new.
+	err = c.Update(
+		ctx,
+		getBlobStore(ctx),
+		jobs,
+		func(j registry.CompletedJob) {
+			log.Printf("Scanning %s backup %s...", j.Name, j.Score.Hex())
+		})
+
+	if err != nil {
+		err = fmt.Errorf("Update: %v", err)
+	}
+
+	// Save what we have, even if a scan failed, so that the work isn't lost.
+	saveErr := saveStatsCache(cachePath, c, crypter)
+	if err == nil && saveErr != nil {
+		err = fmt.Errorf("saveStatsCache: %v", saveErr)
+		return
+	}
+
+	scans = c
+	return
+}
+
+////////////////////////////////////////////////////////////////////////
+// Output
+////////////////////////////////////////////////////////////////////////
+
+// The JSON representation of the usage of a backup.
+type jsonSnapshotUsage struct {
+	StartTime  time.Time `json:"start_time"`
+	Name       string    `json:"name"`
+	Score      string    `json:"score"`
+	DedupRatio float64   `json:"dedup_ratio"`
+	stats.Usage
+}
+
+// The JSON representation of the usage of a job.
+type jsonJobUsage struct {
+	Name       string  `json:"name"`
+	Snapshots  int     `json:"snapshots"`
+	DedupRatio float64 `json:"dedup_ratio"`
+	stats.Usage
+}
+
+func printStatsJSON(r *stats.Report) (err error) {
+	doc := struct {
+		Snapshots   []jsonSnapshotUsage `json:"snapshots"`
+		Jobs        []jsonJobUsage      `json:"jobs"`
+		Total       stats.Usage         `json:"total"`
+		DedupRatio  float64             `json:"dedup_ratio"`
+		Garbage     stats.Distribution  `json:"garbage"`
+		StoredBytes uint64              `json:"stored_bytes"`
+	}{
+		Snapshots:   []jsonSnapshotUsage{},
+		Jobs:        []jsonJobUsage{},
+		Total:       r.Total,
+		DedupRatio:  r.Total.DedupRatio(),
+		Garbage:     r.Garbage,
+		StoredBytes: r.Stored(),
+	}
+
+	for _, s := range r.Snapshots {
+		doc.Snapshots = append(doc.Snapshots, jsonSnapshotUsage{
+			StartTime:  s.Job.StartTime,
+			Name:       s.Job.Name,
+			Score:      s.Job.Score.Hex(),
+			DedupRatio: s.DedupRatio(),
+			Usage:      s.Usage,
+		})
+	}
+
+	for _, j := range r.Jobs {
+		doc.Jobs = append(doc.Jobs, jsonJobUsage{
+			Name:       j.Name,
+			Snapshots:  j.Snapshots,
+			DedupRatio: j.DedupRatio(),
+			Usage:      j.Usage,
+		})
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		err = fmt.Errorf("Encode: %v", err)
+		return
+	}
+
+	return
+}
+
+// Format a power of two number of bytes, e.g. "64 KiB".
+func formatPowerOfTwo(b uint64) string {
+	units := []string{"bytes", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+	var i int
+	for b >= 1<<10 && b%(1<<10) == 0 && i+1 < len(units) {
+		b >>= 10
+		i++
+	}
+
+	return fmt.Sprintf("%d %s", b, units[i])
+}
+
+// Print a table showing the supplied distributions side by side.
+func printDistributions(
+	names []string,
+	dists []*stats.Distribution) {
+	const minwidth = 0
+	const tabwidth = 8
+	const padding = 4
+	const padchar = '\t'
+	const flags = 0
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+	defer w.Flush()
+
+	// Collect the size ranges that appear anywhere, and the buckets by range.
+	var mins []uint64
+	buckets := make([]map[uint64]stats.Bucket, len(dists))
+	maxes := make(map[uint64]uint64)
+	for i, d := range dists {
+		buckets[i] = make(map[uint64]stats.Bucket)
+		for _, b := range d.Buckets {
+			if _, ok := maxes[b.MinSize]; !ok {
+				mins = append(mins, b.MinSize)
+				maxes[b.MinSize] = b.MaxSize
+			}
+
+			buckets[i][b.MinSize] = b
+		}
+	}
+
+	sort.Slice(mins, func(i, j int) bool { return mins[i] < mins[j] })
+
+	fmt.Fprint(w, "Size")
+	for _, n := range names {
+		fmt.Fprintf(w, "\t%s\t", n)
+	}
+
+	fmt.Fprintln(w)
+
+	for _, min := range mins {
+		fmt.Fprintf(
+			w,
+			"%s to %s",
+			formatPowerOfTwo(min),
+			formatPowerOfTwo(maxes[min]))
+
+		for i := range dists {
+			b := buckets[i][min]
+			fmt.Fprintf(w, "\t%d\t%s", b.Count, formatBytes(b.Bytes))
+		}
+
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprint(w, "Total")
+	for _, d := range dists {
+		fmt.Fprintf(w, "\t%d\t%s", d.Count, formatBytes(d.Bytes))
+	}
+
+	fmt.Fprintln(w)
+}
+
+func printStats(r *stats.Report) {
+	const minwidth = 0
+	const tabwidth = 8
+	const padding = 4
+	const padchar = '\t'
+	const flags = 0
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+
+	// Backups
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Start time\tJob name\tScore\tFiles\tDirs\tLogical\tUnique\tShared\tDedup")
+	for _, s := range r.Snapshots {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%d\t%d\t%s\t%s\t%s\t%.2fx\n",
+			s.Job.StartTime.Format(time.RFC3339),
+			s.Job.Name,
+			s.Job.Score.Hex(),
+			s.Files,
+			s.Dirs,
+			formatBytes(s.Logical),
+			formatBytes(s.Unique),
+			formatBytes(s.Shared),
+			s.DedupRatio())
+	}
+
+	// Jobs
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Job name\tBackups\tFiles\tDirs\tLogical\tUnique\tShared\tDedup")
+	for _, j := range r.Jobs {
+		fmt.Fprintf(
+			w,
+			"%s\t%d\t%d\t%d\t%s\t%s\t%s\t%.2fx\n",
+			j.Name,
+			j.Snapshots,
+			j.Files,
+			j.Dirs,
+			formatBytes(j.Logical),
+			formatBytes(j.Unique),
+			formatBytes(j.Shared),
+			j.DedupRatio())
+	}
+
+	w.Flush()
+
+	// Blob sizes
+	for _, j := range r.Jobs {
+		fmt.Printf("\nBlob sizes for job %s:\n\n", j.Name)
+		printDistributions(
+			[]string{"File chunks", "Dir listings"},
+			[]*stats.Distribution{&j.FileChunks, &j.DirListings})
+	}
+
+	fmt.Printf("\nBlob sizes for the whole bucket:\n\n")
+	printDistributions(
+		[]string{"File chunks", "Dir listings", "Garbage"},
+		[]*stats.Distribution{
+			&r.Total.FileChunks,
+			&r.Total.DirListings,
+			&r.Garbage,
+		})
+
+	// Totals
+	t := &r.Total
+	fmt.Println()
+	fmt.Printf("Backups:          %d\n", len(r.Snapshots))
+	fmt.Printf("Logical size:     %s\n", formatBytes(t.Logical))
+	fmt.Printf("Referenced blobs: %s\n", formatBytes(t.Physical()))
+	fmt.Printf("  by one backup:  %s\n", formatBytes(t.Unique))
+	fmt.Printf("  by several:     %s\n", formatBytes(t.Shared))
+	fmt.Printf("Garbage:          %s\n", formatBytes(r.Garbage.Bytes))
+	fmt.Printf("Total stored:     %s\n", formatBytes(r.Stored()))
+	fmt.Printf("Dedup ratio:      %.2fx\n", t.DedupRatio())
+
+	if t.Missing != 0 {
+		fmt.Printf("\nWARNING: %d referenced blobs are missing from the bucket.\n", t.Missing)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////
+// Stats
+////////////////////////////////////////////////////////////////////////
+
+func runStats(ctx context.Context, args []string) (err error) {
+	if len(args) != 0 {
+		err = fmt.Errorf("Usage: %s stats [--json] [--cache path]", os.Args[0])
+		return
+	}
+
+	jobs, scans, err := scanBackups(ctx, getStatsCachePath(ctx, *fStatsCache))
+	if err != nil {
+		err = fmt.Errorf("scanBackups: %v", err)
+		return
+	}
+
+	// Find the size of everything in the bucket.
+	sizes, err := stats.ListBlobSizes(
+		ctx,
+		getBucket(ctx),
+		wiring.BlobObjectNamePrefix)
+
+	if err != nil {
+		err = fmt.Errorf("ListBlobSizes: %v", err)
+		return
+	}
+
+	r, err := stats.Compute(jobs, scans, sizes)
+	if err != nil {
+		err = fmt.Errorf("Compute: %v", err)
+		return
+	}
+
+	if *fStatsJSON {
+		err = printStatsJSON(r)
+		return
+	}
+
+	printStats(r)
+	return
+}
@@ -0,0 +1,72 @@
+// Copyright 2026 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jacobsa/comeback/internal/registry"
+	"github.com/jacobsa/comeback/internal/wiring"
+)
+
+var cmdUpgradeKDF = &Command{
+	Name: "upgrade_kdf",
+}
+
+var fUpgradeKDFName = cmdUpgradeKDF.Flags.String(
+	"kdf",
+	registry.DefaultKDF.Name,
+	"The key derivation function to use: "+strings.Join(kdfNames(), ", ")+".")
+
+func init() {
+	cmdUpgradeKDF.Run = runUpgradeKDF // Break flag-related dependency loop.
+}
+
+func kdfNames() (names []string) {
+	for name := range registry.DefaultKDFs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return
+}
+
+func runUpgradeKDF(ctx context.Context, args []string) (err error) {
+	if len(args) != 0 {
+		err = fmt.Errorf("Usage: %s upgrade_kdf [--kdf=name]", os.Args[0])
+		return
+	}
+
+	kdf, ok := registry.DefaultKDFs[*fUpgradeKDFName]
+	if !ok {
+		err = fmt.Errorf("Unknown KDF %q", *fUpgradeKDFName)
+		return
+	}
+
+	err = wiring.UpgradeKDF(ctx, getBucket(ctx), getPassword(), kdf)
+	if err != nil {
+		err = fmt.Errorf("UpgradeKDF: %v", err)
+		return
+	}
+
+	fmt.Printf("The key slot for this password now uses %v.\n", kdf)
+	fmt.Println("Other key slots must be upgraded using their own passwords.")
+	return
+}
@@ -40,6 +40,10 @@
 //     *   Nodes of the form "f:<hex score>" represent a piece of a file,
 //         contained within the blob of the given score.
 //
+//     Hex scores are as returned by blob.Score.Hex: plain hex for SHA-1
+//     scores, and prefixed by the algorithm (e.g. "sha256-") otherwise. Logs
+//     written before scores were versioned therefore remain valid.
+//
 // An output line for a directory node means that at the given timestamp we
 // certified that a piece of content with the given score was parseable as a
 // directory listing that referred to the given scores for its direct children.